@@ -57,6 +57,85 @@ func (ctx *AddServiceProviderIdpContext) InternalServerError(r error) error {
 	return ctx.ResponseData.Service.Send(ctx.Context, 500, r)
 }
 
+// AddThemeIdpContext provides the idp addTheme action context.
+type AddThemeIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewAddThemeIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller addTheme action.
+func NewAddThemeIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*AddThemeIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := AddThemeIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// Created sends a HTTP response with status code 201.
+func (ctx *AddThemeIdpContext) Created() error {
+	ctx.ResponseData.WriteHeader(201)
+	return nil
+}
+
+// BadRequest sends a HTTP response with status code 400.
+func (ctx *AddThemeIdpContext) BadRequest(r error) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.error")
+	}
+	return ctx.ResponseData.Service.Send(ctx.Context, 400, r)
+}
+
+// InternalServerError sends a HTTP response with status code 500.
+func (ctx *AddThemeIdpContext) InternalServerError(r error) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.error")
+	}
+	return ctx.ResponseData.Service.Send(ctx.Context, 500, r)
+}
+
+// ConsentIdpContext provides the idp consent action context.
+type ConsentIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewConsentIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller consent action.
+func NewConsentIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*ConsentIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := ConsentIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// ConsentsIdpContext provides the idp consents action context.
+type ConsentsIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewConsentsIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller consents action.
+func NewConsentsIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*ConsentsIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := ConsentsIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
 // DeleteServiceProviderIdpContext provides the idp deleteServiceProvider action context.
 type DeleteServiceProviderIdpContext struct {
 	context.Context
@@ -149,6 +228,52 @@ func (ctx *DeleteSessionIdpContext) InternalServerError(r error) error {
 	return ctx.ResponseData.Service.Send(ctx.Context, 500, r)
 }
 
+// DeleteThemeIdpContext provides the idp deleteTheme action context.
+type DeleteThemeIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+	Payload *DeleteSPPayload
+}
+
+// NewDeleteThemeIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller deleteTheme action.
+func NewDeleteThemeIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*DeleteThemeIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := DeleteThemeIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// OK sends a HTTP response with status code 200.
+func (ctx *DeleteThemeIdpContext) OK(resp []byte) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "text/plain")
+	}
+	ctx.ResponseData.WriteHeader(200)
+	_, err := ctx.ResponseData.Write(resp)
+	return err
+}
+
+// NotFound sends a HTTP response with status code 404.
+func (ctx *DeleteThemeIdpContext) NotFound(r error) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.error")
+	}
+	return ctx.ResponseData.Service.Send(ctx.Context, 404, r)
+}
+
+// InternalServerError sends a HTTP response with status code 500.
+func (ctx *DeleteThemeIdpContext) InternalServerError(r error) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.error")
+	}
+	return ctx.ResponseData.Service.Send(ctx.Context, 500, r)
+}
+
 // GetGoogleMetadataIdpContext provides the idp getGoogleMetadata action context.
 type GetGoogleMetadataIdpContext struct {
 	context.Context
@@ -297,6 +422,51 @@ func (ctx *GetSessionsIdpContext) InternalServerError(r error) error {
 	return ctx.ResponseData.Service.Send(ctx.Context, 500, r)
 }
 
+// GetThemesIdpContext provides the idp getThemes action context.
+type GetThemesIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewGetThemesIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller getThemes action.
+func NewGetThemesIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*GetThemesIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := GetThemesIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// OK sends a HTTP response with status code 200.
+func (ctx *GetThemesIdpContext) OK(resp []byte) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "text/plain")
+	}
+	ctx.ResponseData.WriteHeader(200)
+	_, err := ctx.ResponseData.Write(resp)
+	return err
+}
+
+// NotFound sends a HTTP response with status code 404.
+func (ctx *GetThemesIdpContext) NotFound(r error) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.error")
+	}
+	return ctx.ResponseData.Service.Send(ctx.Context, 404, r)
+}
+
+// InternalServerError sends a HTTP response with status code 500.
+func (ctx *GetThemesIdpContext) InternalServerError(r error) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.error")
+	}
+	return ctx.ResponseData.Service.Send(ctx.Context, 500, r)
+}
+
 // LoginUserIdpContext provides the idp loginUser action context.
 type LoginUserIdpContext struct {
 	context.Context
@@ -316,6 +486,139 @@ func NewLoginUserIdpContext(ctx context.Context, r *http.Request, service *goa.S
 	return &rctx, err
 }
 
+// MagicLinkFormIdpContext provides the idp magicLinkForm action context.
+type MagicLinkFormIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewMagicLinkFormIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller magicLinkForm action.
+func NewMagicLinkFormIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*MagicLinkFormIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := MagicLinkFormIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// MagicLinkLoginIdpContext provides the idp magicLinkLogin action context.
+type MagicLinkLoginIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewMagicLinkLoginIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller magicLinkLogin action.
+func NewMagicLinkLoginIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*MagicLinkLoginIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := MagicLinkLoginIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// NewPasswordFormIdpContext provides the idp newPasswordForm action context.
+type NewPasswordFormIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewNewPasswordFormIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller newPasswordForm action.
+func NewNewPasswordFormIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*NewPasswordFormIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := NewPasswordFormIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// PasswordResetFormIdpContext provides the idp passwordResetForm action context.
+type PasswordResetFormIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewPasswordResetFormIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller passwordResetForm action.
+func NewPasswordResetFormIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*PasswordResetFormIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := PasswordResetFormIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// RequestPasswordResetIdpContext provides the idp requestPasswordReset action context.
+type RequestPasswordResetIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewRequestPasswordResetIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller requestPasswordReset action.
+func NewRequestPasswordResetIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*RequestPasswordResetIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := RequestPasswordResetIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// ResetPasswordIdpContext provides the idp resetPassword action context.
+type ResetPasswordIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewResetPasswordIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller resetPassword action.
+func NewResetPasswordIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*ResetPasswordIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := ResetPasswordIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// RevokeConsentIdpContext provides the idp revokeConsent action context.
+type RevokeConsentIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewRevokeConsentIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller revokeConsent action.
+func NewRevokeConsentIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*RevokeConsentIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := RevokeConsentIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
 // ServeLoginIdpContext provides the idp serveLogin action context.
 type ServeLoginIdpContext struct {
 	context.Context
@@ -372,3 +675,70 @@ func NewServeSSOIdpContext(ctx context.Context, r *http.Request, service *goa.Se
 	rctx := ServeSSOIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
 	return &rctx, err
 }
+
+// SocialCallbackIdpContext provides the idp socialCallback action context.
+type SocialCallbackIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewSocialCallbackIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller socialCallback action.
+func NewSocialCallbackIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*SocialCallbackIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := SocialCallbackIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// UpstreamACSIdpContext provides the idp upstreamACS action context.
+type UpstreamACSIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewUpstreamACSIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller upstreamACS action.
+func NewUpstreamACSIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*UpstreamACSIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := UpstreamACSIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// UpstreamMetadataIdpContext provides the idp upstreamMetadata action context.
+type UpstreamMetadataIdpContext struct {
+	context.Context
+	*goa.ResponseData
+	*goa.RequestData
+}
+
+// NewUpstreamMetadataIdpContext parses the incoming request URL and body, performs validations and creates the
+// context used by the idp controller upstreamMetadata action.
+func NewUpstreamMetadataIdpContext(ctx context.Context, r *http.Request, service *goa.Service) (*UpstreamMetadataIdpContext, error) {
+	var err error
+	resp := goa.ContextResponse(ctx)
+	resp.Service = service
+	req := goa.ContextRequest(ctx)
+	req.Request = r
+	rctx := UpstreamMetadataIdpContext{Context: ctx, ResponseData: resp, RequestData: req}
+	return &rctx, err
+}
+
+// OK sends a HTTP response with status code 200.
+func (ctx *UpstreamMetadataIdpContext) OK(resp []byte) error {
+	if ctx.ResponseData.Header().Get("Content-Type") == "" {
+		ctx.ResponseData.Header().Set("Content-Type", "text/plain")
+	}
+	ctx.ResponseData.WriteHeader(200)
+	_, err := ctx.ResponseData.Write(resp)
+	return err
+}
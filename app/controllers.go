@@ -36,16 +36,31 @@ func initService(service *goa.Service) {
 type IdpController interface {
 	goa.Muxer
 	AddServiceProvider(*AddServiceProviderIdpContext) error
+	AddTheme(*AddThemeIdpContext) error
+	Consent(*ConsentIdpContext) error
+	Consents(*ConsentsIdpContext) error
 	DeleteServiceProvider(*DeleteServiceProviderIdpContext) error
 	DeleteSession(*DeleteSessionIdpContext) error
+	DeleteTheme(*DeleteThemeIdpContext) error
 	GetGoogleMetadata(*GetGoogleMetadataIdpContext) error
 	GetMetadata(*GetMetadataIdpContext) error
 	GetServiceProviders(*GetServiceProvidersIdpContext) error
 	GetSessions(*GetSessionsIdpContext) error
+	GetThemes(*GetThemesIdpContext) error
 	LoginUser(*LoginUserIdpContext) error
+	MagicLinkForm(*MagicLinkFormIdpContext) error
+	MagicLinkLogin(*MagicLinkLoginIdpContext) error
+	NewPasswordForm(*NewPasswordFormIdpContext) error
+	PasswordResetForm(*PasswordResetFormIdpContext) error
+	RequestPasswordReset(*RequestPasswordResetIdpContext) error
+	ResetPassword(*ResetPasswordIdpContext) error
+	RevokeConsent(*RevokeConsentIdpContext) error
 	ServeLogin(*ServeLoginIdpContext) error
 	ServeLoginUser(*ServeLoginUserIdpContext) error
 	ServeSSO(*ServeSSOIdpContext) error
+	SocialCallback(*SocialCallbackIdpContext) error
+	UpstreamACS(*UpstreamACSIdpContext) error
+	UpstreamMetadata(*UpstreamMetadataIdpContext) error
 }
 
 // MountIdpController "mounts" a Idp resource controller on the given service.
@@ -53,11 +68,20 @@ func MountIdpController(service *goa.Service, ctrl IdpController) {
 	initService(service)
 	var h goa.Handler
 	service.Mux.Handle("OPTIONS", "/saml/idp/services", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/themes", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/consent", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/consents", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
 	service.Mux.Handle("OPTIONS", "/saml/idp/sessions", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
 	service.Mux.Handle("OPTIONS", "/saml/idp/metadata/google", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
 	service.Mux.Handle("OPTIONS", "/saml/idp/metadata", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
 	service.Mux.Handle("OPTIONS", "/saml/idp/login", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/magic-link", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/password-reset/confirm", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/password-reset", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
 	service.Mux.Handle("OPTIONS", "/saml/idp/sso", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/social/callback", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/upstream/acs", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
+	service.Mux.Handle("OPTIONS", "/saml/idp/upstream/metadata", ctrl.MuxHandler("preflight", handleIdpOrigin(cors.HandlePreflight()), nil))
 
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		// Check if there was an error loading the request
@@ -75,6 +99,54 @@ func MountIdpController(service *goa.Service, ctrl IdpController) {
 	service.Mux.Handle("POST", "/saml/idp/services", ctrl.MuxHandler("addServiceProvider", h, nil))
 	service.LogInfo("mount", "ctrl", "Idp", "action", "AddServiceProvider", "route", "POST /saml/idp/services")
 
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewAddThemeIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.AddTheme(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/themes", ctrl.MuxHandler("addTheme", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "AddTheme", "route", "POST /saml/idp/themes")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewConsentIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.Consent(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/consent", ctrl.MuxHandler("consent", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "Consent", "route", "POST /saml/idp/consent")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewConsentsIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.Consents(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/consents", ctrl.MuxHandler("consents", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "Consents", "route", "GET /saml/idp/consents")
+
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		// Check if there was an error loading the request
 		if err := goa.ContextError(ctx); err != nil {
@@ -119,6 +191,28 @@ func MountIdpController(service *goa.Service, ctrl IdpController) {
 	service.Mux.Handle("DELETE", "/saml/idp/sessions", ctrl.MuxHandler("deleteSession", h, unmarshalDeleteSessionIdpPayload))
 	service.LogInfo("mount", "ctrl", "Idp", "action", "DeleteSession", "route", "DELETE /saml/idp/sessions")
 
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewDeleteThemeIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		// Build the payload
+		if rawPayload := goa.ContextRequest(ctx).Payload; rawPayload != nil {
+			rctx.Payload = rawPayload.(*DeleteSPPayload)
+		} else {
+			return goa.MissingPayloadError()
+		}
+		return ctrl.DeleteTheme(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("DELETE", "/saml/idp/themes", ctrl.MuxHandler("deleteTheme", h, unmarshalDeleteThemeIdpPayload))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "DeleteTheme", "route", "DELETE /saml/idp/themes")
+
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		// Check if there was an error loading the request
 		if err := goa.ContextError(ctx); err != nil {
@@ -183,6 +277,22 @@ func MountIdpController(service *goa.Service, ctrl IdpController) {
 	service.Mux.Handle("GET", "/saml/idp/sessions", ctrl.MuxHandler("getSessions", h, nil))
 	service.LogInfo("mount", "ctrl", "Idp", "action", "GetSessions", "route", "GET /saml/idp/sessions")
 
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewGetThemesIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.GetThemes(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/themes", ctrl.MuxHandler("getThemes", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "GetThemes", "route", "GET /saml/idp/themes")
+
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		// Check if there was an error loading the request
 		if err := goa.ContextError(ctx); err != nil {
@@ -199,6 +309,118 @@ func MountIdpController(service *goa.Service, ctrl IdpController) {
 	service.Mux.Handle("GET", "/saml/idp/login", ctrl.MuxHandler("loginUser", h, nil))
 	service.LogInfo("mount", "ctrl", "Idp", "action", "LoginUser", "route", "GET /saml/idp/login")
 
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewMagicLinkFormIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.MagicLinkForm(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/magic-link", ctrl.MuxHandler("magicLinkForm", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "MagicLinkForm", "route", "GET /saml/idp/magic-link")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewMagicLinkLoginIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.MagicLinkLogin(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/magic-link", ctrl.MuxHandler("magicLinkLogin", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "MagicLinkLogin", "route", "POST /saml/idp/magic-link")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewNewPasswordFormIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.NewPasswordForm(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/password-reset/confirm", ctrl.MuxHandler("newPasswordForm", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "NewPasswordForm", "route", "GET /saml/idp/password-reset/confirm")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewPasswordResetFormIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.PasswordResetForm(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/password-reset", ctrl.MuxHandler("passwordResetForm", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "PasswordResetForm", "route", "GET /saml/idp/password-reset")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewRequestPasswordResetIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.RequestPasswordReset(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/password-reset", ctrl.MuxHandler("requestPasswordReset", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "RequestPasswordReset", "route", "POST /saml/idp/password-reset")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewResetPasswordIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.ResetPassword(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/password-reset/confirm", ctrl.MuxHandler("resetPassword", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "ResetPassword", "route", "POST /saml/idp/password-reset/confirm")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewRevokeConsentIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.RevokeConsent(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/consents", ctrl.MuxHandler("revokeConsent", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "RevokeConsent", "route", "POST /saml/idp/consents")
+
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		// Check if there was an error loading the request
 		if err := goa.ContextError(ctx); err != nil {
@@ -246,6 +468,54 @@ func MountIdpController(service *goa.Service, ctrl IdpController) {
 	h = handleIdpOrigin(h)
 	service.Mux.Handle("GET", "/saml/idp/sso", ctrl.MuxHandler("serveSSO", h, nil))
 	service.LogInfo("mount", "ctrl", "Idp", "action", "ServeSSO", "route", "GET /saml/idp/sso")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewSocialCallbackIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.SocialCallback(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/social/callback", ctrl.MuxHandler("socialCallback", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "SocialCallback", "route", "GET /saml/idp/social/callback")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewUpstreamACSIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.UpstreamACS(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("POST", "/saml/idp/upstream/acs", ctrl.MuxHandler("upstreamACS", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "UpstreamACS", "route", "POST /saml/idp/upstream/acs")
+
+	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Check if there was an error loading the request
+		if err := goa.ContextError(ctx); err != nil {
+			return err
+		}
+		// Build the context
+		rctx, err := NewUpstreamMetadataIdpContext(ctx, req, service)
+		if err != nil {
+			return err
+		}
+		return ctrl.UpstreamMetadata(rctx)
+	}
+	h = handleIdpOrigin(h)
+	service.Mux.Handle("GET", "/saml/idp/upstream/metadata", ctrl.MuxHandler("upstreamMetadata", h, nil))
+	service.LogInfo("mount", "ctrl", "Idp", "action", "UpstreamMetadata", "route", "GET /saml/idp/upstream/metadata")
 }
 
 // handleIdpOrigin applies the CORS response headers corresponding to the origin.
@@ -302,6 +572,21 @@ func unmarshalDeleteSessionIdpPayload(ctx context.Context, service *goa.Service,
 	return nil
 }
 
+// unmarshalDeleteThemeIdpPayload unmarshals the request body into the context request data Payload field.
+func unmarshalDeleteThemeIdpPayload(ctx context.Context, service *goa.Service, req *http.Request) error {
+	payload := &deleteSPPayload{}
+	if err := service.DecodeRequest(req, payload); err != nil {
+		return err
+	}
+	if err := payload.Validate(); err != nil {
+		// Initialize payload with private data structure so it can be logged
+		goa.ContextRequest(ctx).Payload = payload
+		return err
+	}
+	goa.ContextRequest(ctx).Payload = payload.Publicize()
+	return nil
+}
+
 // PublicController is the controller interface for the Public actions.
 type PublicController interface {
 	goa.Muxer
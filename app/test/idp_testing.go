@@ -220,11 +220,437 @@ func AddServiceProviderIdpInternalServerError(t goatest.TInterface, ctx context.
 	return rw, mt
 }
 
+// AddThemeIdpBadRequest runs the method AddTheme of the given controller with the given parameters.
+// It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func AddThemeIdpBadRequest(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController) (http.ResponseWriter, error) {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+		resp   interface{}
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) { resp = r }
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/themes"),
+	}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		panic("invalid test " + err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	addThemeCtx, _err := app.NewAddThemeIdpContext(goaCtx, req, service)
+	if _err != nil {
+		e, ok := _err.(goa.ServiceError)
+		if !ok {
+			panic("invalid test data " + _err.Error()) // bug
+		}
+		return nil, e
+	}
+
+	// Perform action
+	_err = ctrl.AddTheme(addThemeCtx)
+
+	// Validate response
+	if _err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", _err, logBuf.String())
+	}
+	if rw.Code != 400 {
+		t.Errorf("invalid response status code: got %+v, expected 400", rw.Code)
+	}
+	var mt error
+	if resp != nil {
+		var _ok bool
+		mt, _ok = resp.(error)
+		if !_ok {
+			t.Fatalf("invalid response media: got variable of type %T, value %+v, expected instance of error", resp, resp)
+		}
+	}
+
+	// Return results
+	return rw, mt
+}
+
+// AddThemeIdpCreated runs the method AddTheme of the given controller with the given parameters.
+// It returns the response writer so it's possible to inspect the response headers.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func AddThemeIdpCreated(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController) http.ResponseWriter {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) {}
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/themes"),
+	}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		panic("invalid test " + err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	addThemeCtx, _err := app.NewAddThemeIdpContext(goaCtx, req, service)
+	if _err != nil {
+		e, ok := _err.(goa.ServiceError)
+		if !ok {
+			panic("invalid test data " + _err.Error()) // bug
+		}
+		t.Errorf("unexpected parameter validation error: %+v", e)
+		return nil
+	}
+
+	// Perform action
+	_err = ctrl.AddTheme(addThemeCtx)
+
+	// Validate response
+	if _err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", _err, logBuf.String())
+	}
+	if rw.Code != 201 {
+		t.Errorf("invalid response status code: got %+v, expected 201", rw.Code)
+	}
+
+	// Return results
+	return rw
+}
+
+// AddThemeIdpInternalServerError runs the method AddTheme of the given controller with the given parameters.
+// It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func AddThemeIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController) (http.ResponseWriter, error) {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+		resp   interface{}
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) { resp = r }
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/themes"),
+	}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		panic("invalid test " + err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	addThemeCtx, _err := app.NewAddThemeIdpContext(goaCtx, req, service)
+	if _err != nil {
+		e, ok := _err.(goa.ServiceError)
+		if !ok {
+			panic("invalid test data " + _err.Error()) // bug
+		}
+		return nil, e
+	}
+
+	// Perform action
+	_err = ctrl.AddTheme(addThemeCtx)
+
+	// Validate response
+	if _err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", _err, logBuf.String())
+	}
+	if rw.Code != 500 {
+		t.Errorf("invalid response status code: got %+v, expected 500", rw.Code)
+	}
+	var mt error
+	if resp != nil {
+		var _ok bool
+		mt, _ok = resp.(error)
+		if !_ok {
+			t.Fatalf("invalid response media: got variable of type %T, value %+v, expected instance of error", resp, resp)
+		}
+	}
+
+	// Return results
+	return rw, mt
+}
+
 // DeleteServiceProviderIdpInternalServerError runs the method DeleteServiceProvider of the given controller with the given parameters and payload.
 // It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
 // If ctx is nil then context.Background() is used.
 // If service is nil then a default service is created.
-func DeleteServiceProviderIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) (http.ResponseWriter, error) {
+func DeleteServiceProviderIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) (http.ResponseWriter, error) {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+		resp   interface{}
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) { resp = r }
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Validate payload
+	err := payload.Validate()
+	if err != nil {
+		e, ok := err.(goa.ServiceError)
+		if !ok {
+			panic(err) // bug
+		}
+		return nil, e
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/services"),
+	}
+	req, _err := http.NewRequest("DELETE", u.String(), nil)
+	if _err != nil {
+		panic("invalid test " + _err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	deleteServiceProviderCtx, __err := app.NewDeleteServiceProviderIdpContext(goaCtx, req, service)
+	if __err != nil {
+		_e, _ok := __err.(goa.ServiceError)
+		if !_ok {
+			panic("invalid test data " + __err.Error()) // bug
+		}
+		return nil, _e
+	}
+	deleteServiceProviderCtx.Payload = payload
+
+	// Perform action
+	__err = ctrl.DeleteServiceProvider(deleteServiceProviderCtx)
+
+	// Validate response
+	if __err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", __err, logBuf.String())
+	}
+	if rw.Code != 500 {
+		t.Errorf("invalid response status code: got %+v, expected 500", rw.Code)
+	}
+	var mt error
+	if resp != nil {
+		var __ok bool
+		mt, __ok = resp.(error)
+		if !__ok {
+			t.Fatalf("invalid response media: got variable of type %T, value %+v, expected instance of error", resp, resp)
+		}
+	}
+
+	// Return results
+	return rw, mt
+}
+
+// DeleteServiceProviderIdpNotFound runs the method DeleteServiceProvider of the given controller with the given parameters and payload.
+// It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func DeleteServiceProviderIdpNotFound(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) (http.ResponseWriter, error) {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+		resp   interface{}
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) { resp = r }
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Validate payload
+	err := payload.Validate()
+	if err != nil {
+		e, ok := err.(goa.ServiceError)
+		if !ok {
+			panic(err) // bug
+		}
+		return nil, e
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/services"),
+	}
+	req, _err := http.NewRequest("DELETE", u.String(), nil)
+	if _err != nil {
+		panic("invalid test " + _err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	deleteServiceProviderCtx, __err := app.NewDeleteServiceProviderIdpContext(goaCtx, req, service)
+	if __err != nil {
+		_e, _ok := __err.(goa.ServiceError)
+		if !_ok {
+			panic("invalid test data " + __err.Error()) // bug
+		}
+		return nil, _e
+	}
+	deleteServiceProviderCtx.Payload = payload
+
+	// Perform action
+	__err = ctrl.DeleteServiceProvider(deleteServiceProviderCtx)
+
+	// Validate response
+	if __err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", __err, logBuf.String())
+	}
+	if rw.Code != 404 {
+		t.Errorf("invalid response status code: got %+v, expected 404", rw.Code)
+	}
+	var mt error
+	if resp != nil {
+		var __ok bool
+		mt, __ok = resp.(error)
+		if !__ok {
+			t.Fatalf("invalid response media: got variable of type %T, value %+v, expected instance of error", resp, resp)
+		}
+	}
+
+	// Return results
+	return rw, mt
+}
+
+// DeleteServiceProviderIdpOK runs the method DeleteServiceProvider of the given controller with the given parameters and payload.
+// It returns the response writer so it's possible to inspect the response headers.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func DeleteServiceProviderIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) http.ResponseWriter {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) {}
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Validate payload
+	err := payload.Validate()
+	if err != nil {
+		e, ok := err.(goa.ServiceError)
+		if !ok {
+			panic(err) // bug
+		}
+		t.Errorf("unexpected payload validation error: %+v", e)
+		return nil
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/services"),
+	}
+	req, _err := http.NewRequest("DELETE", u.String(), nil)
+	if _err != nil {
+		panic("invalid test " + _err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	deleteServiceProviderCtx, __err := app.NewDeleteServiceProviderIdpContext(goaCtx, req, service)
+	if __err != nil {
+		_e, _ok := __err.(goa.ServiceError)
+		if !_ok {
+			panic("invalid test data " + __err.Error()) // bug
+		}
+		t.Errorf("unexpected parameter validation error: %+v", _e)
+		return nil
+	}
+	deleteServiceProviderCtx.Payload = payload
+
+	// Perform action
+	__err = ctrl.DeleteServiceProvider(deleteServiceProviderCtx)
+
+	// Validate response
+	if __err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", __err, logBuf.String())
+	}
+	if rw.Code != 200 {
+		t.Errorf("invalid response status code: got %+v, expected 200", rw.Code)
+	}
+
+	// Return results
+	return rw
+}
+
+// DeleteSessionIdpInternalServerError runs the method DeleteSession of the given controller with the given parameters and payload.
+// It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func DeleteSessionIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSessionPayload) (http.ResponseWriter, error) {
 	// Setup service
 	var (
 		logBuf bytes.Buffer
@@ -255,7 +681,7 @@ func DeleteServiceProviderIdpInternalServerError(t goatest.TInterface, ctx conte
 	// Setup request context
 	rw := httptest.NewRecorder()
 	u := &url.URL{
-		Path: fmt.Sprintf("/saml/idp/services"),
+		Path: fmt.Sprintf("/saml/idp/sessions"),
 	}
 	req, _err := http.NewRequest("DELETE", u.String(), nil)
 	if _err != nil {
@@ -266,7 +692,7 @@ func DeleteServiceProviderIdpInternalServerError(t goatest.TInterface, ctx conte
 		ctx = context.Background()
 	}
 	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
-	deleteServiceProviderCtx, __err := app.NewDeleteServiceProviderIdpContext(goaCtx, req, service)
+	deleteSessionCtx, __err := app.NewDeleteSessionIdpContext(goaCtx, req, service)
 	if __err != nil {
 		_e, _ok := __err.(goa.ServiceError)
 		if !_ok {
@@ -274,10 +700,10 @@ func DeleteServiceProviderIdpInternalServerError(t goatest.TInterface, ctx conte
 		}
 		return nil, _e
 	}
-	deleteServiceProviderCtx.Payload = payload
+	deleteSessionCtx.Payload = payload
 
 	// Perform action
-	__err = ctrl.DeleteServiceProvider(deleteServiceProviderCtx)
+	__err = ctrl.DeleteSession(deleteSessionCtx)
 
 	// Validate response
 	if __err != nil {
@@ -299,11 +725,11 @@ func DeleteServiceProviderIdpInternalServerError(t goatest.TInterface, ctx conte
 	return rw, mt
 }
 
-// DeleteServiceProviderIdpNotFound runs the method DeleteServiceProvider of the given controller with the given parameters and payload.
+// DeleteSessionIdpNotFound runs the method DeleteSession of the given controller with the given parameters and payload.
 // It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
 // If ctx is nil then context.Background() is used.
 // If service is nil then a default service is created.
-func DeleteServiceProviderIdpNotFound(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) (http.ResponseWriter, error) {
+func DeleteSessionIdpNotFound(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSessionPayload) (http.ResponseWriter, error) {
 	// Setup service
 	var (
 		logBuf bytes.Buffer
@@ -334,7 +760,7 @@ func DeleteServiceProviderIdpNotFound(t goatest.TInterface, ctx context.Context,
 	// Setup request context
 	rw := httptest.NewRecorder()
 	u := &url.URL{
-		Path: fmt.Sprintf("/saml/idp/services"),
+		Path: fmt.Sprintf("/saml/idp/sessions"),
 	}
 	req, _err := http.NewRequest("DELETE", u.String(), nil)
 	if _err != nil {
@@ -345,7 +771,7 @@ func DeleteServiceProviderIdpNotFound(t goatest.TInterface, ctx context.Context,
 		ctx = context.Background()
 	}
 	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
-	deleteServiceProviderCtx, __err := app.NewDeleteServiceProviderIdpContext(goaCtx, req, service)
+	deleteSessionCtx, __err := app.NewDeleteSessionIdpContext(goaCtx, req, service)
 	if __err != nil {
 		_e, _ok := __err.(goa.ServiceError)
 		if !_ok {
@@ -353,10 +779,10 @@ func DeleteServiceProviderIdpNotFound(t goatest.TInterface, ctx context.Context,
 		}
 		return nil, _e
 	}
-	deleteServiceProviderCtx.Payload = payload
+	deleteSessionCtx.Payload = payload
 
 	// Perform action
-	__err = ctrl.DeleteServiceProvider(deleteServiceProviderCtx)
+	__err = ctrl.DeleteSession(deleteSessionCtx)
 
 	// Validate response
 	if __err != nil {
@@ -378,11 +804,11 @@ func DeleteServiceProviderIdpNotFound(t goatest.TInterface, ctx context.Context,
 	return rw, mt
 }
 
-// DeleteServiceProviderIdpOK runs the method DeleteServiceProvider of the given controller with the given parameters and payload.
+// DeleteSessionIdpOK runs the method DeleteSession of the given controller with the given parameters and payload.
 // It returns the response writer so it's possible to inspect the response headers.
 // If ctx is nil then context.Background() is used.
 // If service is nil then a default service is created.
-func DeleteServiceProviderIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) http.ResponseWriter {
+func DeleteSessionIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSessionPayload) http.ResponseWriter {
 	// Setup service
 	var (
 		logBuf bytes.Buffer
@@ -413,7 +839,7 @@ func DeleteServiceProviderIdpOK(t goatest.TInterface, ctx context.Context, servi
 	// Setup request context
 	rw := httptest.NewRecorder()
 	u := &url.URL{
-		Path: fmt.Sprintf("/saml/idp/services"),
+		Path: fmt.Sprintf("/saml/idp/sessions"),
 	}
 	req, _err := http.NewRequest("DELETE", u.String(), nil)
 	if _err != nil {
@@ -424,7 +850,7 @@ func DeleteServiceProviderIdpOK(t goatest.TInterface, ctx context.Context, servi
 		ctx = context.Background()
 	}
 	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
-	deleteServiceProviderCtx, __err := app.NewDeleteServiceProviderIdpContext(goaCtx, req, service)
+	deleteSessionCtx, __err := app.NewDeleteSessionIdpContext(goaCtx, req, service)
 	if __err != nil {
 		_e, _ok := __err.(goa.ServiceError)
 		if !_ok {
@@ -433,10 +859,10 @@ func DeleteServiceProviderIdpOK(t goatest.TInterface, ctx context.Context, servi
 		t.Errorf("unexpected parameter validation error: %+v", _e)
 		return nil
 	}
-	deleteServiceProviderCtx.Payload = payload
+	deleteSessionCtx.Payload = payload
 
 	// Perform action
-	__err = ctrl.DeleteServiceProvider(deleteServiceProviderCtx)
+	__err = ctrl.DeleteSession(deleteSessionCtx)
 
 	// Validate response
 	if __err != nil {
@@ -450,11 +876,11 @@ func DeleteServiceProviderIdpOK(t goatest.TInterface, ctx context.Context, servi
 	return rw
 }
 
-// DeleteSessionIdpInternalServerError runs the method DeleteSession of the given controller with the given parameters and payload.
+// DeleteThemeIdpInternalServerError runs the method DeleteTheme of the given controller with the given parameters and payload.
 // It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
 // If ctx is nil then context.Background() is used.
 // If service is nil then a default service is created.
-func DeleteSessionIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSessionPayload) (http.ResponseWriter, error) {
+func DeleteThemeIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) (http.ResponseWriter, error) {
 	// Setup service
 	var (
 		logBuf bytes.Buffer
@@ -485,7 +911,7 @@ func DeleteSessionIdpInternalServerError(t goatest.TInterface, ctx context.Conte
 	// Setup request context
 	rw := httptest.NewRecorder()
 	u := &url.URL{
-		Path: fmt.Sprintf("/saml/idp/sessions"),
+		Path: fmt.Sprintf("/saml/idp/themes"),
 	}
 	req, _err := http.NewRequest("DELETE", u.String(), nil)
 	if _err != nil {
@@ -496,7 +922,7 @@ func DeleteSessionIdpInternalServerError(t goatest.TInterface, ctx context.Conte
 		ctx = context.Background()
 	}
 	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
-	deleteSessionCtx, __err := app.NewDeleteSessionIdpContext(goaCtx, req, service)
+	deleteThemeCtx, __err := app.NewDeleteThemeIdpContext(goaCtx, req, service)
 	if __err != nil {
 		_e, _ok := __err.(goa.ServiceError)
 		if !_ok {
@@ -504,10 +930,10 @@ func DeleteSessionIdpInternalServerError(t goatest.TInterface, ctx context.Conte
 		}
 		return nil, _e
 	}
-	deleteSessionCtx.Payload = payload
+	deleteThemeCtx.Payload = payload
 
 	// Perform action
-	__err = ctrl.DeleteSession(deleteSessionCtx)
+	__err = ctrl.DeleteTheme(deleteThemeCtx)
 
 	// Validate response
 	if __err != nil {
@@ -529,11 +955,11 @@ func DeleteSessionIdpInternalServerError(t goatest.TInterface, ctx context.Conte
 	return rw, mt
 }
 
-// DeleteSessionIdpNotFound runs the method DeleteSession of the given controller with the given parameters and payload.
+// DeleteThemeIdpNotFound runs the method DeleteTheme of the given controller with the given parameters and payload.
 // It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
 // If ctx is nil then context.Background() is used.
 // If service is nil then a default service is created.
-func DeleteSessionIdpNotFound(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSessionPayload) (http.ResponseWriter, error) {
+func DeleteThemeIdpNotFound(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) (http.ResponseWriter, error) {
 	// Setup service
 	var (
 		logBuf bytes.Buffer
@@ -564,7 +990,7 @@ func DeleteSessionIdpNotFound(t goatest.TInterface, ctx context.Context, service
 	// Setup request context
 	rw := httptest.NewRecorder()
 	u := &url.URL{
-		Path: fmt.Sprintf("/saml/idp/sessions"),
+		Path: fmt.Sprintf("/saml/idp/themes"),
 	}
 	req, _err := http.NewRequest("DELETE", u.String(), nil)
 	if _err != nil {
@@ -575,7 +1001,7 @@ func DeleteSessionIdpNotFound(t goatest.TInterface, ctx context.Context, service
 		ctx = context.Background()
 	}
 	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
-	deleteSessionCtx, __err := app.NewDeleteSessionIdpContext(goaCtx, req, service)
+	deleteThemeCtx, __err := app.NewDeleteThemeIdpContext(goaCtx, req, service)
 	if __err != nil {
 		_e, _ok := __err.(goa.ServiceError)
 		if !_ok {
@@ -583,10 +1009,10 @@ func DeleteSessionIdpNotFound(t goatest.TInterface, ctx context.Context, service
 		}
 		return nil, _e
 	}
-	deleteSessionCtx.Payload = payload
+	deleteThemeCtx.Payload = payload
 
 	// Perform action
-	__err = ctrl.DeleteSession(deleteSessionCtx)
+	__err = ctrl.DeleteTheme(deleteThemeCtx)
 
 	// Validate response
 	if __err != nil {
@@ -608,11 +1034,11 @@ func DeleteSessionIdpNotFound(t goatest.TInterface, ctx context.Context, service
 	return rw, mt
 }
 
-// DeleteSessionIdpOK runs the method DeleteSession of the given controller with the given parameters and payload.
+// DeleteThemeIdpOK runs the method DeleteTheme of the given controller with the given parameters and payload.
 // It returns the response writer so it's possible to inspect the response headers.
 // If ctx is nil then context.Background() is used.
 // If service is nil then a default service is created.
-func DeleteSessionIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSessionPayload) http.ResponseWriter {
+func DeleteThemeIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController, payload *app.DeleteSPPayload) http.ResponseWriter {
 	// Setup service
 	var (
 		logBuf bytes.Buffer
@@ -643,7 +1069,7 @@ func DeleteSessionIdpOK(t goatest.TInterface, ctx context.Context, service *goa.
 	// Setup request context
 	rw := httptest.NewRecorder()
 	u := &url.URL{
-		Path: fmt.Sprintf("/saml/idp/sessions"),
+		Path: fmt.Sprintf("/saml/idp/themes"),
 	}
 	req, _err := http.NewRequest("DELETE", u.String(), nil)
 	if _err != nil {
@@ -654,7 +1080,7 @@ func DeleteSessionIdpOK(t goatest.TInterface, ctx context.Context, service *goa.
 		ctx = context.Background()
 	}
 	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
-	deleteSessionCtx, __err := app.NewDeleteSessionIdpContext(goaCtx, req, service)
+	deleteThemeCtx, __err := app.NewDeleteThemeIdpContext(goaCtx, req, service)
 	if __err != nil {
 		_e, _ok := __err.(goa.ServiceError)
 		if !_ok {
@@ -663,10 +1089,10 @@ func DeleteSessionIdpOK(t goatest.TInterface, ctx context.Context, service *goa.
 		t.Errorf("unexpected parameter validation error: %+v", _e)
 		return nil
 	}
-	deleteSessionCtx.Payload = payload
+	deleteThemeCtx.Payload = payload
 
 	// Perform action
-	__err = ctrl.DeleteSession(deleteSessionCtx)
+	__err = ctrl.DeleteTheme(deleteThemeCtx)
 
 	// Validate response
 	if __err != nil {
@@ -1191,3 +1617,199 @@ func GetSessionsIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Se
 	// Return results
 	return rw
 }
+
+// GetThemesIdpInternalServerError runs the method GetThemes of the given controller with the given parameters.
+// It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func GetThemesIdpInternalServerError(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController) (http.ResponseWriter, error) {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+		resp   interface{}
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) { resp = r }
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/themes"),
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		panic("invalid test " + err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	getThemesCtx, _err := app.NewGetThemesIdpContext(goaCtx, req, service)
+	if _err != nil {
+		e, ok := _err.(goa.ServiceError)
+		if !ok {
+			panic("invalid test data " + _err.Error()) // bug
+		}
+		return nil, e
+	}
+
+	// Perform action
+	_err = ctrl.GetThemes(getThemesCtx)
+
+	// Validate response
+	if _err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", _err, logBuf.String())
+	}
+	if rw.Code != 500 {
+		t.Errorf("invalid response status code: got %+v, expected 500", rw.Code)
+	}
+	var mt error
+	if resp != nil {
+		var _ok bool
+		mt, _ok = resp.(error)
+		if !_ok {
+			t.Fatalf("invalid response media: got variable of type %T, value %+v, expected instance of error", resp, resp)
+		}
+	}
+
+	// Return results
+	return rw, mt
+}
+
+// GetThemesIdpNotFound runs the method GetThemes of the given controller with the given parameters.
+// It returns the response writer so it's possible to inspect the response headers and the media type struct written to the response.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func GetThemesIdpNotFound(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController) (http.ResponseWriter, error) {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+		resp   interface{}
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) { resp = r }
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/themes"),
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		panic("invalid test " + err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	getThemesCtx, _err := app.NewGetThemesIdpContext(goaCtx, req, service)
+	if _err != nil {
+		e, ok := _err.(goa.ServiceError)
+		if !ok {
+			panic("invalid test data " + _err.Error()) // bug
+		}
+		return nil, e
+	}
+
+	// Perform action
+	_err = ctrl.GetThemes(getThemesCtx)
+
+	// Validate response
+	if _err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", _err, logBuf.String())
+	}
+	if rw.Code != 404 {
+		t.Errorf("invalid response status code: got %+v, expected 404", rw.Code)
+	}
+	var mt error
+	if resp != nil {
+		var _ok bool
+		mt, _ok = resp.(error)
+		if !_ok {
+			t.Fatalf("invalid response media: got variable of type %T, value %+v, expected instance of error", resp, resp)
+		}
+	}
+
+	// Return results
+	return rw, mt
+}
+
+// GetThemesIdpOK runs the method GetThemes of the given controller with the given parameters.
+// It returns the response writer so it's possible to inspect the response headers.
+// If ctx is nil then context.Background() is used.
+// If service is nil then a default service is created.
+func GetThemesIdpOK(t goatest.TInterface, ctx context.Context, service *goa.Service, ctrl app.IdpController) http.ResponseWriter {
+	// Setup service
+	var (
+		logBuf bytes.Buffer
+
+		respSetter goatest.ResponseSetterFunc = func(r interface{}) {}
+	)
+	if service == nil {
+		service = goatest.Service(&logBuf, respSetter)
+	} else {
+		logger := log.New(&logBuf, "", log.Ltime)
+		service.WithLogger(goa.NewLogger(logger))
+		newEncoder := func(io.Writer) goa.Encoder { return respSetter }
+		service.Encoder = goa.NewHTTPEncoder() // Make sure the code ends up using this decoder
+		service.Encoder.Register(newEncoder, "*/*")
+	}
+
+	// Setup request context
+	rw := httptest.NewRecorder()
+	u := &url.URL{
+		Path: fmt.Sprintf("/saml/idp/themes"),
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		panic("invalid test " + err.Error()) // bug
+	}
+	prms := url.Values{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	goaCtx := goa.NewContext(goa.WithAction(ctx, "IdpTest"), rw, req, prms)
+	getThemesCtx, _err := app.NewGetThemesIdpContext(goaCtx, req, service)
+	if _err != nil {
+		e, ok := _err.(goa.ServiceError)
+		if !ok {
+			panic("invalid test data " + _err.Error()) // bug
+		}
+		t.Errorf("unexpected parameter validation error: %+v", e)
+		return nil
+	}
+
+	// Perform action
+	_err = ctrl.GetThemes(getThemesCtx)
+
+	// Validate response
+	if _err != nil {
+		t.Fatalf("controller returned %+v, logs:\n%s", _err, logBuf.String())
+	}
+	if rw.Code != 200 {
+		t.Errorf("invalid response status code: got %+v, expected 200", rw.Code)
+	}
+
+	// Return results
+	return rw
+}
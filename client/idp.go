@@ -47,6 +47,93 @@ func (c *Client) NewAddServiceProviderIdpRequest(ctx context.Context, path strin
 	return req, nil
 }
 
+// AddThemeIdpPath computes a request path to the addTheme action of idp.
+func AddThemeIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/themes")
+}
+
+// Add or update the branding theme of a service provider
+func (c *Client) AddThemeIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewAddThemeIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewAddThemeIdpRequest create the request corresponding to the addTheme action endpoint of the idp resource.
+func (c *Client) NewAddThemeIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ConsentIdpPath computes a request path to the consent action of idp.
+func ConsentIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/consent")
+}
+
+// Consent to the release of the attributes to the service provider
+func (c *Client) ConsentIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewConsentIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewConsentIdpRequest create the request corresponding to the consent action endpoint of the idp resource.
+func (c *Client) NewConsentIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ConsentsIdpPath computes a request path to the consents action of idp.
+func ConsentsIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/consents")
+}
+
+// Show the service providers the user has consented to
+func (c *Client) ConsentsIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewConsentsIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewConsentsIdpRequest create the request corresponding to the consents action endpoint of the idp resource.
+func (c *Client) NewConsentsIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // DeleteServiceProviderIdpPath computes a request path to the deleteServiceProvider action of idp.
 func DeleteServiceProviderIdpPath() string {
 
@@ -133,6 +220,49 @@ func (c *Client) NewDeleteSessionIdpRequest(ctx context.Context, path string, pa
 	return req, nil
 }
 
+// DeleteThemeIdpPath computes a request path to the deleteTheme action of idp.
+func DeleteThemeIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/themes")
+}
+
+// Delete the branding theme of a service provider
+func (c *Client) DeleteThemeIdp(ctx context.Context, path string, payload *DeleteSPPayload, contentType string) (*http.Response, error) {
+	req, err := c.NewDeleteThemeIdpRequest(ctx, path, payload, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewDeleteThemeIdpRequest create the request corresponding to the deleteTheme action endpoint of the idp resource.
+func (c *Client) NewDeleteThemeIdpRequest(ctx context.Context, path string, payload *DeleteSPPayload, contentType string) (*http.Request, error) {
+	var body bytes.Buffer
+	if contentType == "" {
+		contentType = "*/*" // Use default encoder
+	}
+	err := c.Encoder.Encode(payload, &body, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %s", err)
+	}
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("DELETE", u.String(), &body)
+	if err != nil {
+		return nil, err
+	}
+	header := req.Header
+	if contentType == "*/*" {
+		header.Set("Content-Type", "application/json")
+	} else {
+		header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
 // GetGoogleMetadataIdpPath computes a request path to the getGoogleMetadata action of idp.
 func GetGoogleMetadataIdpPath() string {
 
@@ -249,6 +379,35 @@ func (c *Client) NewGetSessionsIdpRequest(ctx context.Context, path string) (*ht
 	return req, nil
 }
 
+// GetThemesIdpPath computes a request path to the getThemes action of idp.
+func GetThemesIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/themes")
+}
+
+// Get all branding themes
+func (c *Client) GetThemesIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewGetThemesIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewGetThemesIdpRequest create the request corresponding to the getThemes action endpoint of the idp resource.
+func (c *Client) NewGetThemesIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // LoginUserIdpPath computes a request path to the loginUser action of idp.
 func LoginUserIdpPath() string {
 
@@ -278,6 +437,180 @@ func (c *Client) NewLoginUserIdpRequest(ctx context.Context, path string) (*http
 	return req, nil
 }
 
+// MagicLinkLoginIdpPath computes a request path to the magicLinkLogin action of idp.
+func MagicLinkLoginIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/magic-link")
+}
+
+// Log in with the sign-in link sent by email
+func (c *Client) MagicLinkLoginIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewMagicLinkLoginIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewMagicLinkLoginIdpRequest create the request corresponding to the magicLinkLogin action endpoint of the idp resource.
+func (c *Client) NewMagicLinkLoginIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// NewPasswordFormIdpPath computes a request path to the newPasswordForm action of idp.
+func NewPasswordFormIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/password-reset/confirm")
+}
+
+// Show the new password form
+func (c *Client) NewPasswordFormIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewNewPasswordFormIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewNewPasswordFormIdpRequest create the request corresponding to the newPasswordForm action endpoint of the idp resource.
+func (c *Client) NewNewPasswordFormIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// PasswordResetFormIdpPath computes a request path to the passwordResetForm action of idp.
+func PasswordResetFormIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/password-reset")
+}
+
+// Show the password reset form
+func (c *Client) PasswordResetFormIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewPasswordResetFormIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewPasswordResetFormIdpRequest create the request corresponding to the passwordResetForm action endpoint of the idp resource.
+func (c *Client) NewPasswordResetFormIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// RequestPasswordResetIdpPath computes a request path to the requestPasswordReset action of idp.
+func RequestPasswordResetIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/password-reset")
+}
+
+// Send the password reset email
+func (c *Client) RequestPasswordResetIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewRequestPasswordResetIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewRequestPasswordResetIdpRequest create the request corresponding to the requestPasswordReset action endpoint of the idp resource.
+func (c *Client) NewRequestPasswordResetIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ResetPasswordIdpPath computes a request path to the resetPassword action of idp.
+func ResetPasswordIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/password-reset/confirm")
+}
+
+// Set the new password
+func (c *Client) ResetPasswordIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewResetPasswordIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewResetPasswordIdpRequest create the request corresponding to the resetPassword action endpoint of the idp resource.
+func (c *Client) NewResetPasswordIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// RevokeConsentIdpPath computes a request path to the revokeConsent action of idp.
+func RevokeConsentIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/consents")
+}
+
+// Revoke the consent to a service provider
+func (c *Client) RevokeConsentIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewRevokeConsentIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewRevokeConsentIdpRequest create the request corresponding to the revokeConsent action endpoint of the idp resource.
+func (c *Client) NewRevokeConsentIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // ServeLoginIdpPath computes a request path to the serveLogin action of idp.
 func ServeLoginIdpPath() string {
 
@@ -364,3 +697,90 @@ func (c *Client) NewServeSSOIdpRequest(ctx context.Context, path string) (*http.
 	}
 	return req, nil
 }
+
+// SocialCallbackIdpPath computes a request path to the socialCallback action of idp.
+func SocialCallbackIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/social/callback")
+}
+
+// Receive the authorization code of the social login provider
+func (c *Client) SocialCallbackIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewSocialCallbackIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewSocialCallbackIdpRequest create the request corresponding to the socialCallback action endpoint of the idp resource.
+func (c *Client) NewSocialCallbackIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// UpstreamACSIdpPath computes a request path to the upstreamACS action of idp.
+func UpstreamACSIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/upstream/acs")
+}
+
+// Receive the SAML response of the upstream identity provider
+func (c *Client) UpstreamACSIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewUpstreamACSIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewUpstreamACSIdpRequest create the request corresponding to the upstreamACS action endpoint of the idp resource.
+func (c *Client) NewUpstreamACSIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// UpstreamMetadataIdpPath computes a request path to the upstreamMetadata action of idp.
+func UpstreamMetadataIdpPath() string {
+
+	return fmt.Sprintf("/saml/idp/upstream/metadata")
+}
+
+// Get the service provider metadata the upstream identity providers trust
+func (c *Client) UpstreamMetadataIdp(ctx context.Context, path string) (*http.Response, error) {
+	req, err := c.NewUpstreamMetadataIdpRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.Do(ctx, req)
+}
+
+// NewUpstreamMetadataIdpRequest create the request corresponding to the upstreamMetadata action endpoint of the idp resource.
+func (c *Client) NewUpstreamMetadataIdpRequest(ctx context.Context, path string) (*http.Request, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
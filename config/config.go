@@ -40,6 +40,393 @@ type Config struct {
 	// Client is a map of <client-name>:<url>
 	// "redirect-from-login": "http://client-root-url"
 	Client map[string]string `json:"client"`
+
+	// AllowedRedirects is the list of origins and paths the user may be redirected to after the standalone login
+	// (given as return_to, next or RelayState). An absolute target is allowed if it has the same scheme and host as
+	// one of the URLs and is within its path. A relative target is allowed if it is within one of the paths (e.g. "/profiles").
+	AllowedRedirects []string `json:"allowedRedirects,omitempty"`
+
+	// ClockSkew is the clock skew, in seconds, allowed between the IdP and the service providers when
+	// checking the IssueInstant of the AuthnRequests. Defaults to 90.
+	ClockSkew int `json:"clockSkew,omitempty"`
+
+	// TrustedProxies are the addresses or networks (CIDR) of the proxies in front of the IdP, e.g. the API gateway,
+	// whose X-Forwarded-Proto header tells if the client connected over HTTPS and whose X-Forwarded-For header
+	// tells the address of the client. The headers of the other clients are ignored.
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+
+	// SessionCookie configures the cookie that references the sessions of the browser.
+	SessionCookie SessionCookieConfig `json:"sessionCookie,omitempty"`
+
+	// Templates configures the HTML templates of the login and error pages.
+	Templates TemplatesConfig `json:"templates,omitempty"`
+
+	// I18n configures the languages of the login and error pages.
+	I18n I18nConfig `json:"i18n,omitempty"`
+
+	// Mail configures the delivery of the emails sent by the IdP.
+	Mail MailConfig `json:"mail,omitempty"`
+
+	// PasswordReset configures the self-service password reset.
+	PasswordReset PasswordResetConfig `json:"passwordReset,omitempty"`
+
+	// PasswordPolicy configures the rules the new passwords must follow. The rules apply when the user
+	// sets a new password, not when the user logs in.
+	PasswordPolicy PasswordPolicyConfig `json:"passwordPolicy,omitempty"`
+
+	// Login configures the identifiers the users log in with.
+	Login LoginConfig `json:"login,omitempty"`
+
+	// MagicLink configures the passwordless login with the sign-in links sent by email. The login is
+	// enabled per service provider, see ServiceProviderConfig.MagicLink.
+	MagicLink MagicLinkConfig `json:"magicLink,omitempty"`
+
+	// Upstreams is a map of <name>:<settings> of the upstream SAML identity providers the users may log in
+	// with. The IdP acts as a service provider toward them and passes the login on to the service
+	// provider that sent the AuthnRequest.
+	Upstreams map[string]*UpstreamConfig `json:"upstreams,omitempty"`
+
+	// SocialProviders is a map of <name>:<settings> of the OAuth 2.0 and OpenID Connect providers, e.g.
+	// Google, Microsoft or GitHub, the users may sign in with. The users are matched to the local
+	// users by the verified email.
+	SocialProviders map[string]*SocialProviderConfig `json:"socialProviders,omitempty"`
+
+	// Consent configures the consent of the users to the release of their attributes to the service providers.
+	Consent ConsentConfig `json:"consent,omitempty"`
+
+	// SessionBinding configures the binding of the sessions to the client the user logged in from.
+	SessionBinding SessionBindingConfig `json:"sessionBinding,omitempty"`
+
+	// SessionLimits configures the limits of the concurrent sessions of the users.
+	SessionLimits SessionLimitsConfig `json:"sessionLimits,omitempty"`
+
+	// ServiceProviders is a map of <service provider entity ID>:<settings> holding the
+	// settings that apply only to a particular service provider.
+	ServiceProviders map[string]*ServiceProviderConfig `json:"serviceProviders,omitempty"`
+}
+
+// TemplatesConfig holds the settings of the HTML templates.
+type TemplatesConfig struct {
+	// Dir is the directory with the template files. Defaults to "public/templates". The embedded
+	// default template is used for every file missing from the directory.
+	Dir string `json:"dir,omitempty"`
+
+	// Reload enables parsing the templates on every request, so changes are visible without
+	// restarting the service. Use only for development.
+	Reload bool `json:"reload,omitempty"`
+}
+
+// I18nConfig holds the settings of the message catalogs.
+type I18nConfig struct {
+	// Dir is the directory with the message catalogs, one <language>.json file per language.
+	// Defaults to "public/locales". The messages missing from a catalog are shown in English.
+	Dir string `json:"dir,omitempty"`
+
+	// DefaultLanguage is the language used when none of the languages accepted by the browser
+	// is available. Defaults to "en".
+	DefaultLanguage string `json:"defaultLanguage,omitempty"`
+}
+
+// MailConfig holds the settings of the mailer.
+type MailConfig struct {
+	// Mailer is the type of the mailer: "smtp" sends the emails through the SMTP server, "file" writes them
+	// to Dir and "memory" keeps them in memory (for tests). Defaults to "smtp".
+	Mailer string `json:"mailer,omitempty"`
+
+	// From is the sender address of the emails.
+	From string `json:"from,omitempty"`
+
+	// Host is the host of the SMTP server.
+	Host string `json:"host,omitempty"`
+
+	// Port is the port of the SMTP server. Defaults to 587.
+	Port int `json:"port,omitempty"`
+
+	// Username is the user name used to authenticate with the SMTP server, no authentication if empty.
+	Username string `json:"username,omitempty"`
+
+	// Password is the password used to authenticate with the SMTP server.
+	Password string `json:"password,omitempty"`
+
+	// Dir is the directory the "file" mailer writes the emails to.
+	Dir string `json:"dir,omitempty"`
+}
+
+// PasswordResetConfig holds the settings of the self-service password reset.
+type PasswordResetConfig struct {
+	// Enabled adds the "Forgot password?" link to the login form.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// TokenMaxAge is the time, in seconds, the password reset link can be used. Defaults to 3600.
+	TokenMaxAge int `json:"tokenMaxAge,omitempty"`
+
+	// RateLimit is the number of password resets that can be requested for one email address within
+	// RateLimitWindow. Defaults to 5.
+	RateLimit int `json:"rateLimit,omitempty"`
+
+	// RateLimitWindow is the time window, in seconds, of the RateLimit. Defaults to 3600.
+	RateLimitWindow int `json:"rateLimitWindow,omitempty"`
+}
+
+// PasswordPolicyConfig holds the rules of the new passwords.
+type PasswordPolicyConfig struct {
+	// MinLength is the minimal number of characters of the password. Defaults to 6.
+	MinLength int `json:"minLength,omitempty"`
+
+	// MaxLength is the maximal number of characters of the password. Defaults to 128.
+	MaxLength int `json:"maxLength,omitempty"`
+
+	// CharacterClasses is the number of character classes (lowercase letters, uppercase letters, digits
+	// and symbols) the password must contain. No classes are required by default.
+	CharacterClasses int `json:"characterClasses,omitempty"`
+
+	// BreachedPasswordsDir is the path to the list of the SHA-1 hashes of the breached passwords, as downloaded
+	// from the Pwned Passwords k-anonymity range API: one file per 5-hex-character hash prefix, holding one
+	// "<35-char suffix>:<count>" line per password. The passwords in the list are rejected.
+	BreachedPasswordsDir string `json:"breachedPasswordsDir,omitempty"`
+}
+
+// LoginConfig holds the settings of the login identifiers.
+type LoginConfig struct {
+	// Identifiers are the types of the identifiers the users may log in with: "email", "username" and
+	// "phone". Defaults to ["email"]. The user service must be able to find the users by each type.
+	Identifiers []string `json:"identifiers,omitempty"`
+
+	// CaseSensitiveUsernames keeps the case of the usernames. The usernames are lower-cased by default,
+	// the emails are always lower-cased.
+	CaseSensitiveUsernames bool `json:"caseSensitiveUsernames,omitempty"`
+
+	// Realms is a map of <domain hint>:<realm>. The logins "user@<domain hint>" and "<domain hint>\user"
+	// are sent to the user service as the username "user" in the realm. The hints are case-insensitive.
+	Realms map[string]string `json:"realms,omitempty"`
+
+	// DefaultRealm is the realm of the usernames entered without a domain hint, none by default.
+	DefaultRealm string `json:"defaultRealm,omitempty"`
+
+	// PhoneCountryCode is the country calling code, e.g. "49", of the phone numbers entered without
+	// one. The phone numbers must start with "+" or "00" if not set.
+	PhoneCountryCode string `json:"phoneCountryCode,omitempty"`
+}
+
+// MagicLinkConfig holds the settings of the sign-in links.
+type MagicLinkConfig struct {
+	// TokenMaxAge is the time, in seconds, the sign-in link can be used. Defaults to 600. The link
+	// cannot be used after the login transaction has expired either.
+	TokenMaxAge int `json:"tokenMaxAge,omitempty"`
+
+	// RateLimit is the number of sign-in links that can be sent to one email address within
+	// RateLimitWindow. Defaults to 5.
+	RateLimit int `json:"rateLimit,omitempty"`
+
+	// RateLimitWindow is the time window, in seconds, of the RateLimit. Defaults to 3600.
+	RateLimitWindow int `json:"rateLimitWindow,omitempty"`
+}
+
+// ConsentConfig holds the settings of the attribute release consent.
+type ConsentConfig struct {
+	// Enabled asks the users to consent before their attributes are released to a service provider for the
+	// first time, or when the service provider gets more attributes than the user has consented to. The
+	// internal service providers are not asked for, see ServiceProviderConfig.Internal.
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// SessionCookieConfig holds the settings of the session cookie.
+type SessionCookieConfig struct {
+	// Name is the name of the cookie. Defaults to "session".
+	Name string `json:"name,omitempty"`
+
+	// Domain is the domain of the cookie, e.g. "example.com" to share it with the services on the subdomains.
+	// The cookie is sent only to the host of the IdP if not set.
+	Domain string `json:"domain,omitempty"`
+
+	// Path is the path of the cookie. Defaults to "/".
+	Path string `json:"path,omitempty"`
+
+	// SameSite is the SameSite mode of the cookie: "none", "lax" or "strict". Defaults to "none", so the cookie
+	// is sent with the AuthnRequests the service providers post to the IdP (HTTP-POST binding). The cookie
+	// with SameSite=None is always Secure, the others only when the client connected over HTTPS.
+	SameSite string `json:"sameSite,omitempty"`
+
+	// HostPrefix adds the "__Host-" prefix to the name of the Secure cookie without a Domain and with the
+	// Path "/", so the cookie cannot be set by the other hosts of the domain. The services that read the cookie
+	// must use the prefixed name.
+	HostPrefix bool `json:"hostPrefix,omitempty"`
+}
+
+// SessionBindingConfig holds the settings of the session binding. The session is bound to the characteristics
+// of the client it was created for, so the stolen session cookie cannot be used from a different client.
+type SessionBindingConfig struct {
+	// Mode is "audit" to record the sessions used from a different client in the audit log, or "strict" to
+	// also end them, so the user must log in again. The sessions are not bound if not set.
+	Mode string `json:"mode,omitempty"`
+
+	// Characteristics are the characteristics of the client the sessions are bound to: "ip" (the network of
+	// the address of the client), "userAgent" and "tls" (the TLS client certificate, if the client presented
+	// one). Defaults to ["ip", "userAgent"].
+	Characteristics []string `json:"characteristics,omitempty"`
+
+	// IPv4Prefix is the length of the prefix of the IPv4 network the sessions are bound to. Defaults to 24.
+	IPv4Prefix int `json:"ipv4Prefix,omitempty"`
+
+	// IPv6Prefix is the length of the prefix of the IPv6 network the sessions are bound to. Defaults to 64.
+	IPv6Prefix int `json:"ipv6Prefix,omitempty"`
+}
+
+// SessionLimitsConfig holds the limits of the concurrent sessions of the users. The sessions that have expired
+// are not counted.
+type SessionLimitsConfig struct {
+	// MaxSessions is the number of the concurrent sessions a user may hold. The number is not limited if not set.
+	MaxSessions int `json:"maxSessions,omitempty"`
+
+	// Roles is a map of <role>:<number of sessions> of the limits of the users with the role, used instead of
+	// MaxSessions. The lowest limit of the roles of the user applies.
+	Roles map[string]int `json:"roles,omitempty"`
+
+	// Policy is what happens when the user who holds the maximal number of sessions logs in: "evictOldest" ends
+	// the oldest session of the user and "reject" rejects the login. Defaults to "evictOldest".
+	Policy string `json:"policy,omitempty"`
+}
+
+// UpstreamConfig holds the settings of an upstream identity provider.
+type UpstreamConfig struct {
+	// DisplayName is the name of the upstream IdP shown on the login form. Defaults to the name of the upstream.
+	DisplayName string `json:"displayName,omitempty"`
+
+	// MetadataFile is the path to the SAML metadata of the upstream IdP.
+	MetadataFile string `json:"metadataFile,omitempty"`
+
+	// MetadataURL is the URL the SAML metadata of the upstream IdP is fetched from at startup, used when
+	// MetadataFile is not set.
+	MetadataURL string `json:"metadataUrl,omitempty"`
+
+	// Domains are the email domains of the users of the upstream IdP. The users who enter an email in one
+	// of the domains on the login form are sent to the upstream IdP instead of being asked for the password.
+	Domains []string `json:"domains,omitempty"`
+
+	// Attributes is a map of <user field>:<attribute name> of the upstream assertion attributes the user
+	// fields are taken from. The fields are "email", "fullname" and "roles". The email defaults to the
+	// "email" or "mail" attribute, or the NameID in the email format. The roles are not mapped by default.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Roles are the roles every user of the upstream IdP gets, in addition to the mapped roles.
+	Roles []string `json:"roles,omitempty"`
+
+	// AllowedRoles are the mapped roles the upstream IdP may assert. The other asserted roles are ignored, so
+	// the users get only the Roles without it.
+	AllowedRoles []string `json:"allowedRoles,omitempty"`
+
+	// Provisioning holds the rules the users of the upstream IdP are linked to the local users by. Without it,
+	// the users log in with the attributes of the upstream assertion and have no local user.
+	Provisioning *ProvisioningConfig `json:"provisioning,omitempty"`
+}
+
+// SocialProviderConfig holds the settings of a social login provider.
+type SocialProviderConfig struct {
+	// Type is "google", "microsoft" or "github" for the well-known providers, whose endpoints need not be
+	// configured, "oidc" (default) for the other OpenID Connect providers and "oauth2" for the plain
+	// OAuth 2.0 providers.
+	Type string `json:"type,omitempty"`
+
+	// DisplayName is the name of the provider shown on the login form. Defaults to the name of the well-known
+	// provider, or the name of the provider.
+	DisplayName string `json:"displayName,omitempty"`
+
+	// ClientID is the client ID the IdP is registered with at the provider.
+	ClientID string `json:"clientId"`
+
+	// ClientSecret is the client secret the IdP is registered with at the provider.
+	ClientSecret string `json:"clientSecret,omitempty"`
+
+	// Issuer is the issuer of the OpenID Connect provider. The endpoints are discovered from it at startup.
+	// Set it to "https://login.microsoftonline.com/<tenant>/v2.0" to limit the Microsoft logins to a tenant.
+	Issuer string `json:"issuer,omitempty"`
+
+	// AuthURL is the URL of the authorization endpoint, if not discovered.
+	AuthURL string `json:"authUrl,omitempty"`
+
+	// TokenURL is the URL of the token endpoint, if not discovered.
+	TokenURL string `json:"tokenUrl,omitempty"`
+
+	// UserInfoURL is the URL of the user info endpoint, if not discovered.
+	UserInfoURL string `json:"userInfoUrl,omitempty"`
+
+	// EmailsURL is the URL of the list of the emails of the user, used by GitHub.
+	EmailsURL string `json:"emailsUrl,omitempty"`
+
+	// Scopes are the requested scopes. Defaults to "openid email profile" for the OpenID Connect providers.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Claims is a map of <field>:<claim name> of the claims the user fields are taken from. The fields are
+	// "subject", "email", "emailVerified" and "name", and default to the standard OpenID Connect claims.
+	Claims map[string]string `json:"claims,omitempty"`
+
+	// TrustEmails is set when the provider returns only verified emails, even without the
+	// "email_verified" claim.
+	TrustEmails bool `json:"trustEmails,omitempty"`
+
+	// Provisioning holds the rules the users of the provider are linked to the local users by. By default the
+	// users are linked to the local user with the same email, and no users are created.
+	Provisioning *ProvisioningConfig `json:"provisioning,omitempty"`
+}
+
+// ProvisioningConfig holds the rules the users of an external identity source are linked to the local users
+// by, on their first login.
+type ProvisioningConfig struct {
+	// Create is set when a local user is created for the users who have no account with their email.
+	Create bool `json:"create,omitempty"`
+
+	// Domains are the email domains of the users who may log in from the source. All domains are allowed if
+	// not set. For the upstream IdPs, the domains default to the domains of the upstream, and only the emails
+	// in them are trusted to link the users to the local users.
+	Domains []string `json:"domains,omitempty"`
+
+	// Roles are the roles of the created users. Defaults to "user".
+	Roles []string `json:"roles,omitempty"`
+
+	// Attributes is a map of <user field>:<attribute name> of the attributes, or claims, of the external user
+	// copied to the created user, e.g. {"fullname": "displayName", "roles": "groups"}. The values of the
+	// "roles" attribute are added to the roles.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ServiceProviderConfig holds the IdP settings for a single service provider.
+type ServiceProviderConfig struct {
+	// AttributeProfile is the name of the attribute profile used when creating
+	// the assertions for this service provider. Supported profiles: "aws".
+	AttributeProfile string `json:"attributeProfile,omitempty"`
+
+	// MagicLink enables the passwordless login with the sign-in link sent to the email of the user.
+	MagicLink bool `json:"magicLink,omitempty"`
+
+	// Internal marks the service providers of the organization running the IdP. The users are not asked to
+	// consent to the release of their attributes to them.
+	Internal bool `json:"internal,omitempty"`
+
+	// AWS holds the settings for the "aws" attribute profile.
+	AWS *AWSConfig `json:"aws,omitempty"`
+}
+
+// AWSConfig holds the settings for AWS IAM/console federation.
+type AWSConfig struct {
+	// Roles is a map of <user role>:<list of IAM roles> the user with that role may assume.
+	Roles map[string][]AWSRole `json:"roles"`
+
+	// SessionDuration is the duration of the AWS console session in seconds. Must be
+	// between 900 and 43200. Defaults to 3600.
+	SessionDuration int `json:"sessionDuration,omitempty"`
+
+	// RoleSessionName is the session property used as AWS RoleSessionName.
+	// Either "email" (default) or "username".
+	RoleSessionName string `json:"roleSessionName,omitempty"`
+}
+
+// AWSRole is an IAM role together with the SAML provider trusted by that role.
+type AWSRole struct {
+	// RoleARN is the ARN of the IAM role, "arn:aws:iam::<account>:role/<name>"
+	RoleARN string `json:"roleArn"`
+
+	// ProviderARN is the ARN of the SAML provider, "arn:aws:iam::<account>:saml-provider/<name>"
+	ProviderARN string `json:"providerArn"`
 }
 
 // LoadConfig loads a Config from a configuration JSON file.
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the address of the client of the request. Behind the trusted proxies, the client is the
+// last address of the X-Forwarded-For header that is not a trusted proxy itself; the header sent by the other
+// clients is ignored, as anyone can set it. The addresses the proxies appended are the only ones that can be
+// trusted, so the header is read from the end.
+func ClientIP(r *http.Request, trustedProxies []string) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+
+	if !IsTrustedProxy(addr, trustedProxies) {
+		return addr
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		addr = hop
+		if !IsTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+
+	return addr
+}
+
+// IsTrustedProxy checks if the address is one of the trusted proxies, given as addresses or networks (CIDR).
+// The address may have a port.
+func IsTrustedProxy(addr string, trustedProxies []string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range trustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
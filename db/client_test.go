@@ -0,0 +1,38 @@
+package db
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := []string{"10.0.0.5", "172.16.0.0/12"}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{"direct client", "192.0.2.10:52000", nil, "192.0.2.10"},
+		{"spoofed header", "192.0.2.10:52000", []string{"198.51.100.10"}, "192.0.2.10"},
+		{"trusted proxy", "10.0.0.5:52000", []string{"192.0.2.10"}, "192.0.2.10"},
+		{"chained proxies", "10.0.0.5:52000", []string{"192.0.2.10, 172.20.1.2"}, "192.0.2.10"},
+		{"spoofed entry behind proxy", "10.0.0.5:52000", []string{"198.51.100.10, 192.0.2.10"}, "192.0.2.10"},
+		{"multiple headers", "10.0.0.5:52000", []string{"198.51.100.10", "192.0.2.10"}, "192.0.2.10"},
+		{"invalid entry", "10.0.0.5:52000", []string{"unknown, 172.20.1.2"}, "172.20.1.2"},
+		{"no header", "10.0.0.5:52000", nil, "10.0.0.5"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+		r.RemoteAddr = c.remoteAddr
+		for _, forwarded := range c.forwarded {
+			r.Header.Add("X-Forwarded-For", forwarded)
+		}
+
+		if ip := ClientIP(r, trustedProxies); ip != c.expected {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.expected, ip)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Microkubes/backends"
+	"github.com/keitaroinc/goa"
+)
+
+// Consent is the consent of a user to release the attributes to a service provider.
+type Consent struct {
+	// ID is "<user ID>:<service provider entity ID>", see ConsentID
+	ID string `json:"id"`
+	// UserID is the ID of the user
+	UserID string `json:"userId"`
+	// ServiceProviderID is the entity ID of the service provider
+	ServiceProviderID string `json:"serviceProviderId"`
+	// Attributes are the names of the attributes the user consented to release, sorted
+	Attributes []string `json:"attributes"`
+	// CreateTime is the time the user consented
+	CreateTime time.Time `json:"createTime"`
+}
+
+// ConsentID returns the ID of the consent of the user to release the attributes to the service provider.
+func ConsentID(userID string, serviceProviderID string) string {
+	return userID + ":" + serviceProviderID
+}
+
+// AddConsent saves the consent, update if already exists.
+func (s *IDPStore) AddConsent(consent *Consent) error {
+	var filter backends.Filter
+	_, err := s.Consents.GetOne(backends.NewFilter().Match("id", consent.ID), &Consent{})
+	if err != nil {
+		if !backends.IsErrNotFound(err) {
+			return goa.ErrInternal(err)
+		}
+	} else {
+		// Consent exists, make update
+		filter = backends.NewFilter().Match("id", consent.ID)
+	}
+
+	if _, err := s.Consents.Save(consent, filter); err != nil {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetConsent looks up the consent by its ID
+func (s *IDPStore) GetConsent(consentID string) (*Consent, error) {
+	consent := &Consent{}
+	_, err := s.Consents.GetOne(backends.NewFilter().Match("id", consentID), consent)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("consent not found")
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	return consent, nil
+}
+
+// DeleteConsent deletes the consent
+func (s *IDPStore) DeleteConsent(consentID string) error {
+	err := s.Consents.DeleteOne(backends.NewFilter().Match("id", consentID))
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return goa.ErrNotFound("consent not found")
+		}
+
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetUserConsents returns the consents of the user, an empty list if the user has not consented to any
+// service provider
+func (s *IDPStore) GetUserConsents(userID string) ([]Consent, error) {
+	var consents []Consent
+	var typeHint map[string]interface{}
+
+	items, err := s.Consents.GetAll(backends.NewFilter().Match("userId", userID), typeHint, "serviceProviderId", "asc", 0, 0)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return []Consent{}, nil
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	if err := backends.MapToInterface(items, &consents); err != nil {
+		return nil, goa.ErrInternal(err)
+	}
+
+	return consents, nil
+}
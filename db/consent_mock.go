@@ -0,0 +1,63 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/keitaroinc/goa"
+)
+
+// AddConsent saves the consent
+func (db *DB) AddConsent(consent *Consent) error {
+	if consent.UserID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	saved := *consent
+	db.consents[consent.ID] = &saved
+	return nil
+}
+
+// GetConsent returns the consent
+func (db *DB) GetConsent(consentID string) (*Consent, error) {
+	if strings.HasPrefix(consentID, "internal-server-error:") {
+		return nil, goa.ErrInternal("Internal Server Error")
+	}
+
+	consent, ok := db.consents[consentID]
+	if !ok {
+		return nil, goa.ErrNotFound("consent not found")
+	}
+
+	loaded := *consent
+	return &loaded, nil
+}
+
+// DeleteConsent deletes the consent
+func (db *DB) DeleteConsent(consentID string) error {
+	if _, ok := db.consents[consentID]; !ok {
+		return goa.ErrNotFound("consent not found")
+	}
+
+	delete(db.consents, consentID)
+	return nil
+}
+
+// GetUserConsents lists the consents of the user, ordered by the service provider
+func (db *DB) GetUserConsents(userID string) ([]Consent, error) {
+	if userID == "internal-server-error" {
+		return nil, goa.ErrInternal("Internal Server Error")
+	}
+
+	consents := []Consent{}
+	for _, consent := range db.consents {
+		if consent.UserID == userID {
+			consents = append(consents, *consent)
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool {
+		return consents[i].ServiceProviderID < consents[j].ServiceProviderID
+	})
+
+	return consents, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Microkubes/backends"
+	"github.com/keitaroinc/goa"
+)
+
+// UserLink links the account of a user at an external identity source, an upstream identity provider or a
+// social login provider, to the local user in the user store.
+type UserLink struct {
+	// ID is "<source>:<subject>"
+	ID string `json:"id"`
+	// Source is the external identity source, "upstream:<name>" or "social:<name>"
+	Source string `json:"source"`
+	// Subject is the ID of the user at the source
+	Subject string `json:"subject"`
+	// UserID is the ID of the local user
+	UserID string `json:"userId"`
+	// Email is the email of the user at the source when the link was created
+	Email string `json:"email"`
+	// Provisioned is set when the local user was created for the link
+	Provisioned bool `json:"provisioned,omitempty"`
+	// CreateTime is the time the link was created, on the first login from the source
+	CreateTime time.Time `json:"createTime"`
+	// LastLoginTime is the time of the last login from the source
+	LastLoginTime time.Time `json:"lastLoginTime"`
+}
+
+// AddUserLink saves the user link, update if already exists.
+func (s *IDPStore) AddUserLink(link *UserLink) error {
+	var filter backends.Filter
+	_, err := s.Links.GetOne(backends.NewFilter().Match("id", link.ID), &UserLink{})
+	if err != nil {
+		if !backends.IsErrNotFound(err) {
+			return goa.ErrInternal(err)
+		}
+	} else {
+		// Link exists, make update
+		filter = backends.NewFilter().Match("id", link.ID)
+	}
+
+	if _, err := s.Links.Save(link, filter); err != nil {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetUserLink looks up the user link by its ID
+func (s *IDPStore) GetUserLink(linkID string) (*UserLink, error) {
+	link := &UserLink{}
+	_, err := s.Links.GetOne(backends.NewFilter().Match("id", linkID), link)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("user link not found")
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	return link, nil
+}
+
+// DeleteUserLink deletes the user link
+func (s *IDPStore) DeleteUserLink(linkID string) error {
+	err := s.Links.DeleteOne(backends.NewFilter().Match("id", linkID))
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return goa.ErrNotFound("user link not found")
+		}
+
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
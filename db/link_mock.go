@@ -0,0 +1,37 @@
+package db
+
+import (
+	"github.com/keitaroinc/goa"
+)
+
+// AddUserLink saves the user link
+func (db *DB) AddUserLink(link *UserLink) error {
+	if link.ID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	saved := *link
+	db.links[link.ID] = &saved
+	return nil
+}
+
+// GetUserLink returns the user link
+func (db *DB) GetUserLink(linkID string) (*UserLink, error) {
+	link, ok := db.links[linkID]
+	if !ok {
+		return nil, goa.ErrNotFound("user link not found")
+	}
+
+	loaded := *link
+	return &loaded, nil
+}
+
+// DeleteUserLink deletes the user link
+func (db *DB) DeleteUserLink(linkID string) error {
+	if _, ok := db.links[linkID]; !ok {
+		return goa.ErrNotFound("user link not found")
+	}
+
+	delete(db.links, linkID)
+	return nil
+}
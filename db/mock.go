@@ -11,6 +11,8 @@ import (
 
 	"github.com/crewjam/saml"
 	"github.com/keitaroinc/goa"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
 )
 
 const spMetadata = "<EntityDescriptor xmlns=\"urn:oasis:names:tc:SAML:2.0:metadata\" entityID=\"https://localhost:8082/user-profile/saml/metadata\" validUntil=\"2025-12-03T01:57:09Z\"><SPSSODescriptor xmlns=\"urn:oasis:names:tc:SAML:2.0:metadata\" validUntil=\"0001-01-01T00:00:00Z\" protocolSupportEnumeration=\"urn:oasis:names:tc:SAML:2.0:protocol\" AuthnRequestsSigned=\"false\" WantAssertionsSigned=\"true\"><KeyDescriptor use=\"signing\"><KeyInfo xmlns=\"http://www.w3.org/2000/09/xmldsig#\"><X509Data><X509Certificate>MIIB7zCCAVgCCQDFzbKIp7b3MTANBgkqhkiG9w0BAQUFADA8MQswCQYDVQQGEwJVUzELMAkGA1UECAwCR0ExDDAKBgNVBAoMA2ZvbzESMBAGA1UEAwwJbG9jYWxob3N0MB4XDTEzMTAwMjAwMDg1MVoXDTE0MTAwMjAwMDg1MVowPDELMAkGA1UEBhMCVVMxCzAJBgNVBAgMAkdBMQwwCgYDVQQKDANmb28xEjAQBgNVBAMMCWxvY2FsaG9zdDCBnzANBgkqhkiG9w0BAQEFAAOBjQAwgYkCgYEA1PMHYmhZj308kWLhZVT4vOulqx/9ibm5B86fPWwUKKQ2i12MYtz07tzukPymisTDhQaqyJ8Kqb/6JjhmeMnEOdTvSPmHO8m1ZVveJU6NoKRn/mP/BD7FW52WhbrUXLSeHVSKfWkNk6S4hk9MV9TswTvyRIKvRsw0X/gfnqkroJcCAwEAATANBgkqhkiG9w0BAQUFAAOBgQCMMlIO+GNcGekevKgkakpMdAqJfs24maGb90DvTLbRZRD7Xvn1MnVBBS9hzlXiFLYOInXACMW5gcoRFfeTQLSouMM8o57h0uKjfTmuoWHLQLi6hnF+cvCsEFiJZ4AbF+DgmO6TarJ8O05t8zvnOwJlNCASPZRH/JmF8tX0hoHuAQ==</X509Certificate></X509Data></KeyInfo></KeyDescriptor><KeyDescriptor use=\"encryption\"><KeyInfo xmlns=\"http://www.w3.org/2000/09/xmldsig#\"><X509Data><X509Certificate>MIIB7zCCAVgCCQDFzbKIp7b3MTANBgkqhkiG9w0BAQUFADA8MQswCQYDVQQGEwJVUzELMAkGA1UECAwCR0ExDDAKBgNVBAoMA2ZvbzESMBAGA1UEAwwJbG9jYWxob3N0MB4XDTEzMTAwMjAwMDg1MVoXDTE0MTAwMjAwMDg1MVowPDELMAkGA1UEBhMCVVMxCzAJBgNVBAgMAkdBMQwwCgYDVQQKDANmb28xEjAQBgNVBAMMCWxvY2FsaG9zdDCBnzANBgkqhkiG9w0BAQEFAAOBjQAwgYkCgYEA1PMHYmhZj308kWLhZVT4vOulqx/9ibm5B86fPWwUKKQ2i12MYtz07tzukPymisTDhQaqyJ8Kqb/6JjhmeMnEOdTvSPmHO8m1ZVveJU6NoKRn/mP/BD7FW52WhbrUXLSeHVSKfWkNk6S4hk9MV9TswTvyRIKvRsw0X/gfnqkroJcCAwEAATANBgkqhkiG9w0BAQUFAAOBgQCMMlIO+GNcGekevKgkakpMdAqJfs24maGb90DvTLbRZRD7Xvn1MnVBBS9hzlXiFLYOInXACMW5gcoRFfeTQLSouMM8o57h0uKjfTmuoWHLQLi6hnF+cvCsEFiJZ4AbF+DgmO6TarJ8O05t8zvnOwJlNCASPZRH/JmF8tX0hoHuAQ==</X509Certificate></X509Data></KeyInfo><EncryptionMethod Algorithm=\"http://www.w3.org/2001/04/xmlenc#aes128-cbc\"></EncryptionMethod><EncryptionMethod Algorithm=\"http://www.w3.org/2001/04/xmlenc#aes192-cbc\"></EncryptionMethod><EncryptionMethod Algorithm=\"http://www.w3.org/2001/04/xmlenc#aes256-cbc\"></EncryptionMethod><EncryptionMethod Algorithm=\"http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p\"></EncryptionMethod></KeyDescriptor><AssertionConsumerService Binding=\"urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST\" Location=\"https://localhost:8082/user-profile/saml/acs\" index=\"1\"></AssertionConsumerService></SPSSODescriptor></EntityDescriptor>"
@@ -20,28 +22,57 @@ var sessionMaxAge = time.Hour * 24
 // DB emulates a database driver using in-memory data structures.
 type DB struct {
 	sync.Mutex
-	sessions map[string]*saml.Session
-	services map[string]*saml.EntityDescriptor
+	sessions     map[string]*Session
+	services     map[string]*saml.EntityDescriptor
+	requests     map[string]time.Time
+	transactions map[string]*LoginTransaction
+	themes       map[string]*Theme
+
+	passwordResets map[string]*PasswordReset
+	links          map[string]*UserLink
+	consents       map[string]*Consent
+
+	// SessionBinding holds the settings of the session binding, the sessions are not bound if nil
+	SessionBinding *idpconfig.SessionBindingConfig
+
+	// SessionLimits holds the limits of the concurrent sessions of the users, not limited if nil
+	SessionLimits *idpconfig.SessionLimitsConfig
+
+	// SessionCookie holds the settings of the session cookie, the default cookie is used if nil
+	SessionCookie *idpconfig.SessionCookieConfig
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header is trusted, see ClientIP
+	TrustedProxies []string
 }
 
 // New initializes a new "DB" with dummy data.
 func New() *DB {
-	session := &saml.Session{
-		ID:            "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=",
-		CreateTime:    saml.TimeNow(),
-		ExpireTime:    saml.TimeNow().Add(sessionMaxAge),
-		Index:         "2f5eefac59e6fa6b24a078e4f8da1e48441ec3afc25222e00ac127a4ab1db1ed",
-		UserName:      "59ce17c60000000000000000",
-		Groups:        []string{"user"},
-		UserEmail:     "example@host.com",
-		UserGivenName: "john",
+	session := &Session{
+		Session: saml.Session{
+			ID:            "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=",
+			CreateTime:    saml.TimeNow(),
+			ExpireTime:    saml.TimeNow().Add(sessionMaxAge),
+			Index:         "2f5eefac59e6fa6b24a078e4f8da1e48441ec3afc25222e00ac127a4ab1db1ed",
+			UserName:      "59ce17c60000000000000000",
+			Groups:        []string{"user"},
+			UserEmail:     "example@host.com",
+			UserGivenName: "john",
+		},
+		AuthnMethods: []string{"password"},
 	}
 
 	entityDesc, _ := getSPMetadata(strings.NewReader(spMetadata))
 
 	return &DB{
-		sessions: map[string]*saml.Session{"K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=": session},
-		services: map[string]*saml.EntityDescriptor{"https://localhost:8082/user-profile/saml/metadata": entityDesc},
+		sessions:     map[string]*Session{"K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=": session},
+		services:     map[string]*saml.EntityDescriptor{"https://localhost:8082/user-profile/saml/metadata": entityDesc},
+		requests:     map[string]time.Time{},
+		transactions: map[string]*LoginTransaction{},
+		themes:       map[string]*Theme{},
+
+		passwordResets: map[string]*PasswordReset{},
+		links:          map[string]*UserLink{},
+		consents:       map[string]*Consent{},
 	}
 }
 
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Microkubes/backends"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// PasswordReset is a password reset requested by the user. It is deleted when the password is reset,
+// so the reset link can be used only once.
+type PasswordReset struct {
+	// ID is the random ID of the reset, the ID of the signed reset token
+	ID string `json:"id"`
+	// UserID is the ID of the user in the user store
+	UserID string `json:"userId"`
+	// Email is the email the reset link was sent to
+	Email string `json:"email"`
+	// CreateTime is the time the reset was requested
+	CreateTime time.Time `json:"createTime"`
+	// ExpireTime is the time after which the reset link can no longer be used
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// AddPasswordReset saves the password reset
+func (s *IDPStore) AddPasswordReset(reset *PasswordReset) error {
+	if _, err := s.PasswordResets.Save(reset, nil); err != nil {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetPasswordReset looks up the password reset by its ID. Expired resets are not returned.
+func (s *IDPStore) GetPasswordReset(resetID string) (*PasswordReset, error) {
+	reset := &PasswordReset{}
+	_, err := s.PasswordResets.GetOne(backends.NewFilter().Match("id", resetID), reset)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("password reset not found")
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	if saml.TimeNow().After(reset.ExpireTime) {
+		return nil, goa.ErrNotFound("password reset has expired")
+	}
+
+	return reset, nil
+}
+
+// DeletePasswordReset deletes the password reset
+func (s *IDPStore) DeletePasswordReset(resetID string) error {
+	err := s.PasswordResets.DeleteOne(backends.NewFilter().Match("id", resetID))
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return goa.ErrNotFound("password reset not found")
+		}
+
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// DeleteUserPasswordResets deletes all password resets of the user, so none of the links sent earlier can
+// be used anymore
+func (s *IDPStore) DeleteUserPasswordResets(userID string) error {
+	err := s.PasswordResets.DeleteAll(backends.NewFilter().Match("userId", userID))
+	if err != nil && !backends.IsErrNotFound(err) {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// AddPasswordReset saves the password reset
+func (db *DB) AddPasswordReset(reset *PasswordReset) error {
+	if reset.UserID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	saved := *reset
+	db.passwordResets[reset.ID] = &saved
+	return nil
+}
+
+// GetPasswordReset returns the password reset
+func (db *DB) GetPasswordReset(resetID string) (*PasswordReset, error) {
+	reset, ok := db.passwordResets[resetID]
+	if !ok || saml.TimeNow().After(reset.ExpireTime) {
+		return nil, goa.ErrNotFound("password reset not found")
+	}
+
+	loaded := *reset
+	return &loaded, nil
+}
+
+// DeletePasswordReset deletes the password reset
+func (db *DB) DeletePasswordReset(resetID string) error {
+	if _, ok := db.passwordResets[resetID]; !ok {
+		return goa.ErrNotFound("password reset not found")
+	}
+
+	delete(db.passwordResets, resetID)
+	return nil
+}
+
+// DeleteUserPasswordResets deletes all password resets of the user
+func (db *DB) DeleteUserPasswordResets(userID string) error {
+	for id, reset := range db.passwordResets {
+		if reset.UserID == userID {
+			delete(db.passwordResets, id)
+		}
+	}
+
+	return nil
+}
@@ -2,24 +2,32 @@ package db
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/crewjam/saml"
 	"github.com/crewjam/saml/samlidp"
 
 	"github.com/Microkubes/backends"
+	idpconfig "github.com/Microkubes/identity-provider/config"
 	"github.com/Microkubes/microservice-tools/config"
 )
 
 // Repository defines interface for accessing DB
 type Repository interface {
-	// AddSession adds new session in DB
-	AddSession(session *saml.Session) error
+	// AddSession adds new session in DB. The oldest sessions of the user are ended, or a *SessionLimitError is
+	// returned, if the user already holds the maximal number of sessions
+	AddSession(session *Session) error
 	// GetSession looks up a Sessions by the session ID.
-	GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) (*saml.Session, error)
+	GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) (*Session, error)
+	// GetBrowserSessions returns the valid sessions referenced by the session cookie, the selected session first.
+	// The bound sessions used from a different client are returned in a *SessionBindingError in the strict mode.
+	GetBrowserSessions(r *http.Request) ([]*Session, error)
 	// DeleteSession deletes session by sessionID which is cookie value
 	DeleteSession(sessionID string) error
-	// GetSessions returns all sessions
-	GetSessions() (*[]saml.Session, error)
+	// GetSessions returns all sessions, with the limits of the concurrent sessions of their users
+	GetSessions() (*[]Session, error)
+	// DeleteUserSessions deletes all sessions of the user
+	DeleteUserSessions(userID string) error
 
 	// AddServiceProvider register new service provider
 	AddServiceProvider(service *samlidp.Service) error
@@ -29,23 +37,93 @@ type Repository interface {
 	DeleteServiceProvider(serviceID string) error
 	// GetServiceProviders returns all SP
 	GetServiceProviders() (*[]samlidp.Service, error)
+
+	// AddAuthnRequest records the ID of an AuthnRequest the IdP has responded to, ErrAuthnRequestRecorded if
+	// already recorded
+	AddAuthnRequest(requestID string, expireTime time.Time) error
+	// HasAuthnRequest checks if the IdP has already responded to the AuthnRequest
+	HasAuthnRequest(requestID string) (bool, error)
+
+	// AddLoginTransaction saves the login transaction
+	AddLoginTransaction(transaction *LoginTransaction) error
+	// GetLoginTransaction looks up the login transaction by its ID
+	GetLoginTransaction(transactionID string) (*LoginTransaction, error)
+	// DeleteLoginTransaction deletes the login transaction
+	DeleteLoginTransaction(transactionID string) error
+	// RedeemMagicLink removes the sign-in link from the login transaction, unless it has already been removed
+	RedeemMagicLink(transactionID string, linkID string) (*LoginTransaction, error)
+
+	// AddTheme saves the login page theme of a service provider
+	AddTheme(theme *Theme) error
+	// GetTheme returns the login page theme of the service provider
+	GetTheme(serviceID string) (*Theme, error)
+	// DeleteTheme deletes the login page theme of the service provider
+	DeleteTheme(serviceID string) error
+	// GetThemes returns all themes
+	GetThemes() (*[]Theme, error)
+
+	// AddPasswordReset saves the password reset requested by the user
+	AddPasswordReset(reset *PasswordReset) error
+	// GetPasswordReset looks up the password reset by its ID
+	GetPasswordReset(resetID string) (*PasswordReset, error)
+	// DeletePasswordReset deletes the password reset
+	DeletePasswordReset(resetID string) error
+	// DeleteUserPasswordResets deletes all password resets of the user
+	DeleteUserPasswordResets(userID string) error
+
+	// AddUserLink saves the link of an external user to the local user
+	AddUserLink(link *UserLink) error
+	// GetUserLink looks up the user link by its ID
+	GetUserLink(linkID string) (*UserLink, error)
+	// DeleteUserLink deletes the user link
+	DeleteUserLink(linkID string) error
+
+	// AddConsent saves the consent of the user to release the attributes to a service provider
+	AddConsent(consent *Consent) error
+	// GetConsent looks up the consent by its ID, see ConsentID
+	GetConsent(consentID string) (*Consent, error)
+	// DeleteConsent deletes the consent
+	DeleteConsent(consentID string) error
+	// GetUserConsents returns the consents of the user
+	GetUserConsents(userID string) ([]Consent, error)
 }
 
-// IDPStore represents the IDP store containing the Services and Sessions repositories
+// IDPStore represents the IDP store containing the Services, Sessions, Requests, Transactions, Themes,
+// PasswordResets, Links and Consents repositories
 type IDPStore struct {
-	Services backends.Repository
-	Sessions backends.Repository
+	Services       backends.Repository
+	Sessions       backends.Repository
+	Requests       backends.Repository
+	Transactions   backends.Repository
+	Themes         backends.Repository
+	PasswordResets backends.Repository
+	Links          backends.Repository
+	Consents       backends.Repository
+
+	// SessionBinding holds the settings of the binding of the sessions to the clients, the sessions are not
+	// bound if nil
+	SessionBinding *idpconfig.SessionBindingConfig
+
+	// SessionLimits holds the limits of the concurrent sessions of the users, not limited if nil
+	SessionLimits *idpconfig.SessionLimitsConfig
+
+	// SessionCookie holds the settings of the session cookie, the default cookie is used if nil
+	SessionCookie *idpconfig.SessionCookieConfig
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header is trusted, see ClientIP
+	TrustedProxies []string
 }
 
-// NewIDPStore creates IDP's repositories
-func NewIDPStore(cfg *config.DBConfig) (store Repository, cleanup func(), err error) {
+// NewIDPStore creates IDP's repositories with the database configuration of the service. The sessions are
+// checked against the session cookie, session binding and session limits settings.
+func NewIDPStore(cfg *idpconfig.Config) (store Repository, cleanup func(), err error) {
 	manager := backends.NewBackendSupport(map[string]*config.DBInfo{
-		"mongodb":  &cfg.DBInfo,
-		"dynamodb": &cfg.DBInfo,
+		"mongodb":  &cfg.Database.DBInfo,
+		"dynamodb": &cfg.Database.DBInfo,
 	})
 
 	noop := func() {}
-	backend, err := manager.GetBackend(cfg.DBName)
+	backend, err := manager.GetBackend(cfg.Database.DBName)
 	if err != nil {
 		return nil, noop, err
 	}
@@ -90,9 +168,113 @@ func NewIDPStore(cfg *config.DBConfig) (store Repository, cleanup func(), err er
 			},
 		},
 	})
+	if err != nil {
+		return nil, noop, err
+	}
+
+	requests, err := backend.DefineRepository("requests", backends.RepositoryDefinitionMap{
+		"name": "requests",
+		"indexes": []backends.Index{
+			backends.NewUniqueIndex("id"),
+		},
+		"hashKey":       "id",
+		"readCapacity":  5, // FIXME: read these from config
+		"writeCapacity": 5, // FIXME: read these from config
+		"enableTtl":     true,
+		"ttlAttribute":  "expireTime",
+		"ttl":           0,
+	})
+	if err != nil {
+		return nil, noop, err
+	}
+
+	transactions, err := backend.DefineRepository("transactions", backends.RepositoryDefinitionMap{
+		"name": "transactions",
+		"indexes": []backends.Index{
+			backends.NewUniqueIndex("id"),
+		},
+		"hashKey":       "id",
+		"readCapacity":  5, // FIXME: read these from config
+		"writeCapacity": 5, // FIXME: read these from config
+		"enableTtl":     true,
+		"ttlAttribute":  "expireTime",
+		"ttl":           0,
+	})
+	if err != nil {
+		return nil, noop, err
+	}
+
+	themes, err := backend.DefineRepository("themes", backends.RepositoryDefinitionMap{
+		"name": "themes",
+		"indexes": []backends.Index{
+			backends.NewUniqueIndex("serviceId"),
+		},
+		"hashKey":       "serviceId",
+		"readCapacity":  5, // FIXME: read these from config
+		"writeCapacity": 5, // FIXME: read these from config
+	})
+	if err != nil {
+		return nil, noop, err
+	}
+
+	passwordResets, err := backend.DefineRepository("resets", backends.RepositoryDefinitionMap{
+		"name": "resets",
+		"indexes": []backends.Index{
+			backends.NewUniqueIndex("id"),
+			backends.NewNonUniqueIndex("userId"),
+		},
+		"hashKey":       "id",
+		"readCapacity":  5, // FIXME: read these from config
+		"writeCapacity": 5, // FIXME: read these from config
+		"enableTtl":     true,
+		"ttlAttribute":  "expireTime",
+		"ttl":           0,
+	})
+	if err != nil {
+		return nil, noop, err
+	}
+
+	links, err := backend.DefineRepository("links", backends.RepositoryDefinitionMap{
+		"name": "links",
+		"indexes": []backends.Index{
+			backends.NewUniqueIndex("id"),
+			backends.NewNonUniqueIndex("userId"),
+		},
+		"hashKey":       "id",
+		"readCapacity":  5, // FIXME: read these from config
+		"writeCapacity": 5, // FIXME: read these from config
+	})
+	if err != nil {
+		return nil, noop, err
+	}
+
+	consents, err := backend.DefineRepository("consents", backends.RepositoryDefinitionMap{
+		"name": "consents",
+		"indexes": []backends.Index{
+			backends.NewUniqueIndex("id"),
+			backends.NewNonUniqueIndex("userId"),
+		},
+		"hashKey":       "id",
+		"readCapacity":  5, // FIXME: read these from config
+		"writeCapacity": 5, // FIXME: read these from config
+	})
+	if err != nil {
+		return nil, noop, err
+	}
 
 	return &IDPStore{
-		Services: services,
-		Sessions: sessions,
+		Services:       services,
+		Sessions:       sessions,
+		Requests:       requests,
+		Transactions:   transactions,
+		Themes:         themes,
+		PasswordResets: passwordResets,
+		Links:          links,
+		Consents:       consents,
+
+		SessionBinding: &cfg.SessionBinding,
+		SessionLimits:  &cfg.SessionLimits,
+		SessionCookie:  &cfg.SessionCookie,
+		TrustedProxies: cfg.TrustedProxies,
 	}, cleanup, err
 }
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Microkubes/backends"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// AuthnRequest is an AuthnRequest the IdP has already responded to.
+type AuthnRequest struct {
+	// ID is the ID of the AuthnRequest
+	ID string `json:"id"`
+	// ExpireTime is the time after which the request is no longer valid and the record can be removed
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// ErrAuthnRequestRecorded is returned by AddAuthnRequest when the ID of the AuthnRequest is already recorded,
+// i.e. the IdP has already responded to the request.
+var ErrAuthnRequestRecorded = errors.New("the AuthnRequest has already been responded to")
+
+// AddAuthnRequest records the ID of an AuthnRequest the IdP has responded to. The record is inserted only if
+// there is none (unique index on "id"), so of two concurrent responses to the same request only one succeeds,
+// the other gets ErrAuthnRequestRecorded.
+func (s *IDPStore) AddAuthnRequest(requestID string, expireTime time.Time) error {
+	request := &AuthnRequest{
+		ID:         requestID,
+		ExpireTime: expireTime,
+	}
+
+	if _, err := s.Requests.Save(request, nil); err != nil {
+		if backends.IsErrAlreadyExists(err) {
+			return ErrAuthnRequestRecorded
+		}
+
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// HasAuthnRequest checks if the IdP has already responded to the AuthnRequest with the given ID.
+// Expired records are ignored.
+func (s *IDPStore) HasAuthnRequest(requestID string) (bool, error) {
+	request := &AuthnRequest{}
+	_, err := s.Requests.GetOne(backends.NewFilter().Match("id", requestID), request)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return false, nil
+		}
+
+		return false, goa.ErrInternal(err)
+	}
+
+	return saml.TimeNow().Before(request.ExpireTime), nil
+}
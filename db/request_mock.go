@@ -0,0 +1,38 @@
+package db
+
+import (
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// AddAuthnRequest records the ID of the AuthnRequest, unless already recorded
+func (db *DB) AddAuthnRequest(requestID string, expireTime time.Time) error {
+	if requestID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	db.Lock()
+	defer db.Unlock()
+
+	if recorded, ok := db.requests[requestID]; ok && saml.TimeNow().Before(recorded) {
+		return ErrAuthnRequestRecorded
+	}
+
+	db.requests[requestID] = expireTime
+	return nil
+}
+
+// HasAuthnRequest checks if the AuthnRequest was recorded
+func (db *DB) HasAuthnRequest(requestID string) (bool, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	expireTime, ok := db.requests[requestID]
+	if !ok {
+		return false, nil
+	}
+
+	return saml.TimeNow().Before(expireTime), nil
+}
@@ -2,41 +2,195 @@ package db
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/Microkubes/backends"
 
 	"github.com/crewjam/saml"
 	"github.com/keitaroinc/goa"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
 )
 
+// Session is the IdP session. Besides the SAML session it records how the user has authenticated.
+type Session struct {
+	saml.Session `bson:",inline"`
+
+	// AuthnMethods are the authentication methods the user completed in this session (password, totp, webauthn...)
+	AuthnMethods []string `json:"authnMethods,omitempty"`
+
+	// Binding holds the characteristics of the client the session is bound to, nil if the session is not bound
+	Binding *SessionBinding `json:"binding,omitempty"`
+
+	// BindingMismatches are the characteristics of the client the session is used from that differ from the
+	// ones the session is bound to. Set by GetSession and GetBrowserSessions, not saved.
+	BindingMismatches []string `json:"-" bson:"-"`
+
+	// Limit is the limit of the concurrent sessions of the user. Set by GetSessions, not saved.
+	Limit *SessionLimit `json:"limit,omitempty" bson:"-"`
+}
+
+// DefaultSessionCookieName is the name of the cookie that references the sessions of the browser, if not configured.
+const DefaultSessionCookieName = "session"
+
+// HostCookiePrefix is the prefix of the name of the cookies bound to the host of the IdP, see
+// config.SessionCookieConfig.HostPrefix.
+const HostCookiePrefix = "__Host-"
+
+// MaxBrowserSessions is the maximal number of sessions, one per account, the browser holds at once.
+const MaxBrowserSessions = 5
+
+// sessionIDSeparator separates the session IDs in the session cookie. It is not used by the JWT session IDs.
+const sessionIDSeparator = "|"
+
+// SessionCookieName returns the configured name of the session cookie, without the __Host- prefix.
+func SessionCookieName(cfg *idpconfig.SessionCookieConfig) string {
+	if cfg == nil || cfg.Name == "" {
+		return DefaultSessionCookieName
+	}
+
+	return cfg.Name
+}
+
+// SessionIDs returns the IDs of the sessions referenced by the session cookie, the selected session first. The
+// cookie of the browser with one session holds just its ID. The cookie with the __Host- prefix is preferred
+// over the one without it.
+func SessionIDs(cfg *idpconfig.SessionCookieConfig, r *http.Request) []string {
+	name := SessionCookieName(cfg)
+	sessionCookie, err := r.Cookie(HostCookiePrefix + name)
+	if err != nil {
+		sessionCookie, err = r.Cookie(name)
+	}
+	if err != nil {
+		return []string{}
+	}
+
+	ids := []string{}
+	for _, id := range strings.Split(sessionCookie.Value, sessionIDSeparator) {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// SelectSessionID returns the session IDs with the ID first, removed from its earlier position. The IDs
+// exceeding MaxBrowserSessions are dropped, the least recently selected first.
+func SelectSessionID(ids []string, id string) []string {
+	selected := append([]string{id}, RemoveSessionID(ids, id)...)
+	if len(selected) > MaxBrowserSessions {
+		selected = selected[:MaxBrowserSessions]
+	}
+
+	return selected
+}
+
+// RemoveSessionID returns the session IDs without the ID.
+func RemoveSessionID(ids []string, id string) []string {
+	remaining := []string{}
+	for _, sessionID := range ids {
+		if sessionID != id {
+			remaining = append(remaining, sessionID)
+		}
+	}
+
+	return remaining
+}
+
+// SessionCookieValue returns the value of the session cookie referencing the sessions.
+func SessionCookieValue(ids []string) string {
+	return strings.Join(ids, sessionIDSeparator)
+}
+
 // GetSession returns the *Session for this request.
-// If a session cookie already exists and represents a valid session, then the session is returned
-func (s *IDPStore) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) (*saml.Session, error) {
-	if sessionCookie, err := r.Cookie("session"); err == nil {
-		session := &saml.Session{}
-		id := sessionCookie.Value
+// If a session cookie already exists and its selected session is valid, then the session is returned. The bound
+// session used from a different client is returned in a *SessionBindingError in the strict mode.
+func (s *IDPStore) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) (*Session, error) {
+	ids := SessionIDs(s.SessionCookie, r)
+	if len(ids) == 0 {
+		return nil, goa.ErrNotFound("session is not set in the request")
+	}
+
+	session, err := s.getSession(ids[0])
+	if err != nil {
+		return nil, err
+	}
 
-		_, err := s.Sessions.GetOne(backends.NewFilter().Match("id", id), session)
+	if saml.TimeNow().After(session.ExpireTime) {
+		return nil, goa.ErrInvalidRequest("session has expired")
+	}
+
+	if _, err := checkSessionBindings(s.SessionBinding, []*Session{session}, r, s.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetBrowserSessions returns the valid sessions referenced by the session cookie, the selected session
+// first. The sessions that have ended or expired are left out. In the strict mode, the bound sessions used from a
+// different client are left out too, and returned in a *SessionBindingError along with the valid sessions.
+func (s *IDPStore) GetBrowserSessions(r *http.Request) ([]*Session, error) {
+	sessions := []*Session{}
+	for _, id := range SessionIDs(s.SessionCookie, r) {
+		session, err := s.getSession(id)
 		if err != nil {
-			if backends.IsErrNotFound(err) {
-				return nil, goa.ErrNotFound("session not found in database")
+			if e, ok := err.(*goa.ErrorResponse); ok && e.Status == http.StatusNotFound {
+				continue
 			}
-
-			return nil, goa.ErrInternal(err)
+			return nil, err
 		}
 
 		if saml.TimeNow().After(session.ExpireTime) {
-			return nil, goa.ErrInvalidRequest("session has expired")
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return checkSessionBindings(s.SessionBinding, sessions, r, s.TrustedProxies)
+}
+
+// getSession looks up the session by its ID
+func (s *IDPStore) getSession(id string) (*Session, error) {
+	session := &Session{}
+
+	_, err := s.Sessions.GetOne(backends.NewFilter().Match("id", id), session)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("session not found in database")
 		}
 
-		return session, nil
+		return nil, goa.ErrInternal(err)
 	}
 
-	return nil, goa.ErrNotFound("session is not set in the request")
+	return session, nil
 }
 
-// AddSession adds new session in DB
-func (s *IDPStore) AddSession(session *saml.Session) error {
+// AddSession adds new session in DB. If the user already holds the maximal number of sessions, the oldest
+// sessions of the user are deleted, or a *SessionLimitError is returned, depending on the policy.
+func (s *IDPStore) AddSession(session *Session) error {
+	if MaxSessions(s.SessionLimits, session.Groups) > 0 {
+		userSessions, err := s.getUserSessions(session.UserName)
+		if err != nil {
+			return err
+		}
+
+		evicted, err := limitSessions(s.SessionLimits, session, userSessions)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range evicted {
+			if err := s.DeleteSession(e.ID); err != nil {
+				if e, ok := err.(*goa.ErrorResponse); !ok || e.Status != http.StatusNotFound {
+					return err
+				}
+			}
+		}
+	}
+
 	if _, err := s.Sessions.Save(session, nil); err != nil {
 		return err
 	}
@@ -44,6 +198,27 @@ func (s *IDPStore) AddSession(session *saml.Session) error {
 	return nil
 }
 
+// getUserSessions returns the sessions of the user
+func (s *IDPStore) getUserSessions(userID string) ([]*Session, error) {
+	var sessions []*Session
+	var typeHint map[string]interface{}
+
+	items, err := s.Sessions.GetAll(backends.NewFilter().Match("username", userID), typeHint, "", "", 0, 0)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return []*Session{}, nil
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	if err := backends.MapToInterface(items, &sessions); err != nil {
+		return nil, goa.ErrInternal(err)
+	}
+
+	return sessions, nil
+}
+
 // DeleteSession deletes session by sessionID which is cookie value
 func (s *IDPStore) DeleteSession(sessionID string) error {
 	err := s.Sessions.DeleteOne(backends.NewFilter().Match("id", sessionID))
@@ -58,9 +233,19 @@ func (s *IDPStore) DeleteSession(sessionID string) error {
 	return nil
 }
 
-// GetSessions returns all sessions
-func (s *IDPStore) GetSessions() (*[]saml.Session, error) {
-	var sessions []saml.Session
+// DeleteUserSessions deletes all sessions of the user, logging the user out everywhere
+func (s *IDPStore) DeleteUserSessions(userID string) error {
+	err := s.Sessions.DeleteAll(backends.NewFilter().Match("username", userID))
+	if err != nil && !backends.IsErrNotFound(err) {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetSessions returns all sessions, with the limits of the concurrent sessions of their users
+func (s *IDPStore) GetSessions() (*[]Session, error) {
+	var sessions []Session
 	var typeHint map[string]interface{}
 
 	items, err := s.Sessions.GetAll(nil, typeHint, "", "", 0, 0)
@@ -76,5 +261,7 @@ func (s *IDPStore) GetSessions() (*[]saml.Session, error) {
 		return nil, goa.ErrNotFound("no sessions found!")
 	}
 
+	setSessionLimits(s.SessionLimits, sessions)
+
 	return &sessions, nil
 }
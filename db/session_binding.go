@@ -0,0 +1,168 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
+)
+
+// Session binding modes, see config.SessionBindingConfig.
+const (
+	// SessionBindingAudit records the sessions used from a different client.
+	SessionBindingAudit = "audit"
+	// SessionBindingStrict ends the sessions used from a different client.
+	SessionBindingStrict = "strict"
+)
+
+// Client characteristics the sessions are bound to.
+const (
+	// BindIP binds the session to the network of the address of the client.
+	BindIP = "ip"
+	// BindUserAgent binds the session to the user agent of the client.
+	BindUserAgent = "userAgent"
+	// BindTLS binds the session to the TLS client certificate, if the client presented one.
+	BindTLS = "tls"
+)
+
+// DefaultBindCharacteristics are the characteristics the sessions are bound to by default.
+var DefaultBindCharacteristics = []string{BindIP, BindUserAgent}
+
+// SessionBinding holds the characteristics of the client the session was created for. The empty characteristics
+// are not checked.
+type SessionBinding struct {
+	// Network is the network of the address of the client, e.g. "192.0.2.0/24"
+	Network string `json:"network,omitempty"`
+	// UserAgentHash is the SHA-256 hash of the User-Agent header, hex encoded
+	UserAgentHash string `json:"userAgentHash,omitempty"`
+	// ClientCertHash is the SHA-256 hash of the TLS client certificate, hex encoded
+	ClientCertHash string `json:"clientCertHash,omitempty"`
+}
+
+// SessionBindingError is returned in the strict mode for the bound sessions used from a different client.
+type SessionBindingError struct {
+	// Sessions are the sessions used from a different client, see Session.BindingMismatches
+	Sessions []*Session
+}
+
+// Error returns the error message.
+func (e *SessionBindingError) Error() string {
+	return fmt.Sprintf("%d session(s) bound to a different client", len(e.Sessions))
+}
+
+// NewSessionBinding returns the binding of the new session to the client of the request, or nil if the sessions
+// are not bound. The address of the client is taken from the X-Forwarded-For header of the trusted proxies,
+// see ClientIP.
+func NewSessionBinding(cfg *idpconfig.SessionBindingConfig, r *http.Request, trustedProxies []string) *SessionBinding {
+	if cfg == nil || cfg.Mode == "" {
+		return nil
+	}
+
+	binding := &SessionBinding{}
+	for _, characteristic := range bindCharacteristics(cfg) {
+		switch characteristic {
+		case BindIP:
+			binding.Network = clientNetwork(cfg, r, trustedProxies)
+		case BindUserAgent:
+			binding.UserAgentHash = hash([]byte(r.UserAgent()))
+		case BindTLS:
+			binding.ClientCertHash = clientCertHash(r)
+		}
+	}
+
+	return binding
+}
+
+// SessionBindingMismatches returns the characteristics of the client of the request that differ from the ones
+// the session is bound to. There are none if the session is not bound or the sessions are not bound anymore.
+func SessionBindingMismatches(cfg *idpconfig.SessionBindingConfig, session *Session, r *http.Request, trustedProxies []string) []string {
+	mismatches := []string{}
+	if cfg == nil || cfg.Mode == "" || session.Binding == nil {
+		return mismatches
+	}
+
+	binding := session.Binding
+	if binding.Network != "" && binding.Network != clientNetwork(cfg, r, trustedProxies) {
+		mismatches = append(mismatches, BindIP)
+	}
+	if binding.UserAgentHash != "" && binding.UserAgentHash != hash([]byte(r.UserAgent())) {
+		mismatches = append(mismatches, BindUserAgent)
+	}
+	if binding.ClientCertHash != "" && binding.ClientCertHash != clientCertHash(r) {
+		mismatches = append(mismatches, BindTLS)
+	}
+
+	return mismatches
+}
+
+// checkSessionBindings records the mismatches of the bound sessions used from the client of the request, see
+// Session.BindingMismatches. In the strict mode, the sessions with mismatches are left out and returned in
+// a *SessionBindingError.
+func checkSessionBindings(cfg *idpconfig.SessionBindingConfig, sessions []*Session, r *http.Request, trustedProxies []string) ([]*Session, error) {
+	valid := []*Session{}
+	rejected := []*Session{}
+	for _, session := range sessions {
+		session.BindingMismatches = SessionBindingMismatches(cfg, session, r, trustedProxies)
+		if len(session.BindingMismatches) > 0 && cfg.Mode == SessionBindingStrict {
+			rejected = append(rejected, session)
+			continue
+		}
+
+		valid = append(valid, session)
+	}
+
+	if len(rejected) > 0 {
+		return valid, &SessionBindingError{Sessions: rejected}
+	}
+
+	return valid, nil
+}
+
+// bindCharacteristics returns the configured characteristics, or the default ones
+func bindCharacteristics(cfg *idpconfig.SessionBindingConfig) []string {
+	if len(cfg.Characteristics) == 0 {
+		return DefaultBindCharacteristics
+	}
+
+	return cfg.Characteristics
+}
+
+// clientNetwork returns the network of the address of the client, empty if the address is not known
+func clientNetwork(cfg *idpconfig.SessionBindingConfig, r *http.Request, trustedProxies []string) string {
+	ip := net.ParseIP(ClientIP(r, trustedProxies))
+	if ip == nil {
+		return ""
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		prefix := cfg.IPv4Prefix
+		if prefix <= 0 || prefix > 32 {
+			prefix = 24
+		}
+		return fmt.Sprintf("%s/%d", ip4.Mask(net.CIDRMask(prefix, 32)), prefix)
+	}
+
+	prefix := cfg.IPv6Prefix
+	if prefix <= 0 || prefix > 128 {
+		prefix = 64
+	}
+	return fmt.Sprintf("%s/%d", ip.Mask(net.CIDRMask(prefix, 128)), prefix)
+}
+
+// clientCertHash returns the hash of the TLS client certificate, empty if the client presented none
+func clientCertHash(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+
+	return hash(r.TLS.PeerCertificates[0].Raw)
+}
+
+// hash returns the SHA-256 hash of the data, hex encoded
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
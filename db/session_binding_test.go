@@ -0,0 +1,108 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
+)
+
+func TestNewSessionBinding(t *testing.T) {
+	r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	r.RemoteAddr = "192.0.2.10:52000"
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+
+	if binding := NewSessionBinding(&idpconfig.SessionBindingConfig{}, r, nil); binding != nil {
+		t.Fatalf("Expected no binding without the mode, got %+v", binding)
+	}
+
+	binding := NewSessionBinding(&idpconfig.SessionBindingConfig{Mode: SessionBindingStrict}, r, nil)
+	if binding == nil || binding.Network != "192.0.2.0/24" || binding.UserAgentHash == "" || binding.ClientCertHash != "" {
+		t.Fatalf("Expected the binding to the network and the user agent, got %+v", binding)
+	}
+
+	r.RemoteAddr = "[2001:db8:1:2:3::4]:52000"
+	binding = NewSessionBinding(&idpconfig.SessionBindingConfig{Mode: SessionBindingStrict, Characteristics: []string{BindIP}, IPv6Prefix: 48}, r, nil)
+	if binding.Network != "2001:db8:1::/48" || binding.UserAgentHash != "" {
+		t.Fatalf("Expected the binding to the IPv6 network, got %+v", binding)
+	}
+}
+
+func TestSessionBindingBehindProxy(t *testing.T) {
+	cfg := &idpconfig.SessionBindingConfig{Mode: SessionBindingStrict, Characteristics: []string{BindIP}}
+	trustedProxies := []string{"10.0.0.5"}
+
+	r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	r.RemoteAddr = "10.0.0.5:52000"
+	r.Header.Set("X-Forwarded-For", "192.0.2.10")
+	session := &Session{Binding: NewSessionBinding(cfg, r, trustedProxies)}
+	if session.Binding.Network != "192.0.2.0/24" {
+		t.Fatalf("Expected the binding to the network of the client behind the proxy, got %+v", session.Binding)
+	}
+
+	// another client behind the same proxy
+	other := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	other.RemoteAddr = "10.0.0.5:52000"
+	other.Header.Set("X-Forwarded-For", "198.51.100.10")
+	if mismatches := SessionBindingMismatches(cfg, session, other, trustedProxies); !reflect.DeepEqual(mismatches, []string{BindIP}) {
+		t.Fatalf("Expected the client behind the proxy to mismatch, got %v", mismatches)
+	}
+}
+
+func TestSessionBindingMismatches(t *testing.T) {
+	cfg := &idpconfig.SessionBindingConfig{Mode: SessionBindingStrict, Characteristics: []string{BindIP, BindUserAgent, BindTLS}}
+
+	r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	r.RemoteAddr = "192.0.2.10:52000"
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("client certificate")}}}
+	session := &Session{Binding: NewSessionBinding(cfg, r, nil)}
+
+	cases := []struct {
+		remoteAddr string
+		userAgent  string
+		tls        *tls.ConnectionState
+		expected   []string
+	}{
+		{"192.0.2.200:40000", "Mozilla/5.0", r.TLS, []string{}},
+		{"198.51.100.10:52000", "Mozilla/5.0", r.TLS, []string{BindIP}},
+		{"192.0.2.10:52000", "curl/7.64.0", r.TLS, []string{BindUserAgent}},
+		{"192.0.2.10:52000", "Mozilla/5.0", nil, []string{BindTLS}},
+	}
+
+	for _, c := range cases {
+		other := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+		other.RemoteAddr = c.remoteAddr
+		other.Header.Set("User-Agent", c.userAgent)
+		other.TLS = c.tls
+
+		if mismatches := SessionBindingMismatches(cfg, session, other, nil); !reflect.DeepEqual(mismatches, c.expected) {
+			t.Fatalf("Expected mismatches %v for %+v, got %v", c.expected, c, mismatches)
+		}
+	}
+
+	if mismatches := SessionBindingMismatches(cfg, &Session{}, r, nil); len(mismatches) != 0 {
+		t.Fatalf("Expected no mismatches for the unbound session, got %v", mismatches)
+	}
+}
+
+func TestCheckSessionBindings(t *testing.T) {
+	bound := &Session{Binding: &SessionBinding{Network: "10.0.0.0/24"}}
+	unbound := &Session{}
+	r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	r.RemoteAddr = "192.0.2.10:52000"
+
+	sessions, err := checkSessionBindings(&idpconfig.SessionBindingConfig{Mode: SessionBindingAudit}, []*Session{bound, unbound}, r, nil)
+	if err != nil || len(sessions) != 2 || len(bound.BindingMismatches) != 1 {
+		t.Fatalf("Expected the flagged sessions in the audit mode, got %v, %v", sessions, err)
+	}
+
+	sessions, err = checkSessionBindings(&idpconfig.SessionBindingConfig{Mode: SessionBindingStrict}, []*Session{bound, unbound}, r, nil)
+	e, ok := err.(*SessionBindingError)
+	if !ok || len(e.Sessions) != 1 || e.Sessions[0] != bound || len(sessions) != 1 || sessions[0] != unbound {
+		t.Fatalf("Expected the bound session to be rejected in the strict mode, got %v, %v", sessions, err)
+	}
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crewjam/saml"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
+)
+
+// Session limit policies, see config.SessionLimitsConfig.
+const (
+	// SessionLimitEvictOldest ends the oldest session of the user to make room for the new one.
+	SessionLimitEvictOldest = "evictOldest"
+	// SessionLimitReject rejects the new session of the user.
+	SessionLimitReject = "reject"
+)
+
+// SessionLimit is the limit of the concurrent sessions of the user of a session, shown in the session listing.
+type SessionLimit struct {
+	// MaxSessions is the number of the concurrent sessions the user may hold
+	MaxSessions int `json:"maxSessions"`
+	// Policy is the policy applied when the user reaches the limit, SessionLimitEvictOldest or SessionLimitReject
+	Policy string `json:"policy"`
+	// Sessions is the number of the sessions the user holds
+	Sessions int `json:"sessions"`
+}
+
+// SessionLimitError is returned by AddSession when the user already holds the maximal number of sessions and
+// the policy rejects the new session.
+type SessionLimitError struct {
+	// MaxSessions is the number of the concurrent sessions the user may hold
+	MaxSessions int
+}
+
+// Error returns the error message.
+func (e *SessionLimitError) Error() string {
+	return fmt.Sprintf("the user already holds the maximal number of sessions (%d)", e.MaxSessions)
+}
+
+// MaxSessions returns the number of the concurrent sessions the user with the roles may hold, 0 if the number
+// is not limited. The lowest limit of the roles applies, MaxSessions if none of the roles has a limit.
+func MaxSessions(cfg *idpconfig.SessionLimitsConfig, roles []string) int {
+	if cfg == nil {
+		return 0
+	}
+
+	max := 0
+	for _, role := range roles {
+		if limit := cfg.Roles[role]; limit > 0 && (max == 0 || limit < max) {
+			max = limit
+		}
+	}
+	if max == 0 {
+		max = cfg.MaxSessions
+	}
+
+	return max
+}
+
+// SessionLimitPolicy returns the configured policy, SessionLimitEvictOldest by default.
+func SessionLimitPolicy(cfg *idpconfig.SessionLimitsConfig) string {
+	if cfg == nil || cfg.Policy == "" {
+		return SessionLimitEvictOldest
+	}
+
+	return cfg.Policy
+}
+
+// limitSessions checks the new session against the limit of its user, who holds the userSessions. It returns the
+// sessions to end to make room for the new one, the oldest first, or a *SessionLimitError if the policy rejects
+// the new session. The expired sessions and the earlier copy of the new session are not counted.
+func limitSessions(cfg *idpconfig.SessionLimitsConfig, session *Session, userSessions []*Session) ([]*Session, error) {
+	max := MaxSessions(cfg, session.Groups)
+	if max <= 0 {
+		return nil, nil
+	}
+
+	active := []*Session{}
+	for _, s := range userSessions {
+		if s.ID != session.ID && !saml.TimeNow().After(s.ExpireTime) {
+			active = append(active, s)
+		}
+	}
+	if len(active) < max {
+		return nil, nil
+	}
+
+	if SessionLimitPolicy(cfg) == SessionLimitReject {
+		return nil, &SessionLimitError{MaxSessions: max}
+	}
+
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].CreateTime.Before(active[j].CreateTime)
+	})
+
+	return active[:len(active)-max+1], nil
+}
+
+// setSessionLimits sets the limit of the concurrent sessions of the users of the listed sessions, see Session.Limit.
+// The sessions are not annotated if the number of the sessions is not limited.
+func setSessionLimits(cfg *idpconfig.SessionLimitsConfig, sessions []Session) {
+	counts := map[string]int{}
+	for _, session := range sessions {
+		if !saml.TimeNow().After(session.ExpireTime) {
+			counts[session.UserName]++
+		}
+	}
+
+	for i := range sessions {
+		max := MaxSessions(cfg, sessions[i].Groups)
+		if max <= 0 {
+			continue
+		}
+
+		sessions[i].Limit = &SessionLimit{
+			MaxSessions: max,
+			Policy:      SessionLimitPolicy(cfg),
+			Sessions:    counts[sessions[i].UserName],
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Microkubes/backends"
+	"github.com/crewjam/saml"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
+)
+
+// sessionRepository keeps the sessions of the IDPStore in memory
+type sessionRepository struct {
+	sessions map[string]*Session
+}
+
+func (r *sessionRepository) GetOne(filter backends.Filter, result interface{}) (interface{}, error) {
+	session, ok := r.sessions[filter["id"].(string)]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	*result.(*Session) = *session
+	return result, nil
+}
+
+func (r *sessionRepository) GetAll(filter backends.Filter, resultsTypeHint interface{}, order string, sorting string, limit int, offset int) (interface{}, error) {
+	sessions := []*Session{}
+	for _, session := range r.sessions {
+		if userID, ok := filter["username"]; !ok || session.UserName == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) Save(object interface{}, filter backends.Filter) (interface{}, error) {
+	session := object.(*Session)
+	r.sessions[session.ID] = session
+	return session, nil
+}
+
+func (r *sessionRepository) DeleteOne(filter backends.Filter) error {
+	delete(r.sessions, filter["id"].(string))
+	return nil
+}
+
+func (r *sessionRepository) DeleteAll(filter backends.Filter) error {
+	for id, session := range r.sessions {
+		if session.UserName == filter["username"] {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+// userSession returns the session of the user with the roles, created age ago
+func userSession(id string, userID string, roles []string, age time.Duration) *Session {
+	return &Session{
+		Session: saml.Session{
+			ID:         id,
+			CreateTime: saml.TimeNow().Add(-age),
+			ExpireTime: saml.TimeNow().Add(time.Hour - age),
+			UserName:   userID,
+			Groups:     roles,
+		},
+	}
+}
+
+func TestMaxSessions(t *testing.T) {
+	cfg := &idpconfig.SessionLimitsConfig{MaxSessions: 3, Roles: map[string]int{"admin": 1, "support": 2}}
+
+	cases := []struct {
+		cfg      *idpconfig.SessionLimitsConfig
+		roles    []string
+		expected int
+	}{
+		{nil, []string{"user"}, 0},
+		{cfg, []string{"user"}, 3},
+		{cfg, []string{"user", "support"}, 2},
+		{cfg, []string{"support", "admin"}, 1},
+		{&idpconfig.SessionLimitsConfig{Roles: map[string]int{"admin": 1}}, []string{"user"}, 0},
+	}
+
+	for _, c := range cases {
+		if max := MaxSessions(c.cfg, c.roles); max != c.expected {
+			t.Fatalf("Expected %d sessions for %v, got %d", c.expected, c.roles, max)
+		}
+	}
+}
+
+func TestLimitSessions(t *testing.T) {
+	oldest := userSession("oldest", "user-1", []string{"user"}, 50*time.Minute)
+	older := userSession("older", "user-1", []string{"user"}, 40*time.Minute)
+	recent := userSession("recent", "user-1", []string{"user"}, 10*time.Minute)
+	expired := userSession("expired", "user-1", []string{"user"}, 2*time.Hour)
+	session := userSession("new", "user-1", []string{"user"}, 0)
+	userSessions := []*Session{recent, expired, oldest, older}
+
+	evicted, err := limitSessions(&idpconfig.SessionLimitsConfig{MaxSessions: 2}, session, userSessions)
+	if err != nil || len(evicted) != 2 || evicted[0] != oldest || evicted[1] != older {
+		t.Fatalf("Expected the two oldest sessions to be evicted, got %v, %v", evicted, err)
+	}
+
+	evicted, err = limitSessions(&idpconfig.SessionLimitsConfig{MaxSessions: 4}, session, userSessions)
+	if err != nil || len(evicted) != 0 {
+		t.Fatalf("Expected no sessions to be evicted below the limit, got %v, %v", evicted, err)
+	}
+
+	_, err = limitSessions(&idpconfig.SessionLimitsConfig{MaxSessions: 3, Policy: SessionLimitReject}, session, userSessions)
+	if e, ok := err.(*SessionLimitError); !ok || e.MaxSessions != 3 {
+		t.Fatalf("Expected the new session to be rejected, got %v", err)
+	}
+
+	// the session of the same login saved again is not counted
+	_, err = limitSessions(&idpconfig.SessionLimitsConfig{MaxSessions: 1, Policy: SessionLimitReject}, recent, []*Session{recent})
+	if err != nil {
+		t.Fatalf("Expected the session to be saved again, got %v", err)
+	}
+}
+
+// addUserSessions adds the three sessions of the user with the session limit of 2 to the repository
+func addUserSessions(t *testing.T, repository Repository) {
+	for _, session := range []*Session{
+		userSession("first", "user-1", []string{"user"}, 30*time.Minute),
+		userSession("second", "user-1", []string{"user"}, 20*time.Minute),
+		userSession("other-user", "user-2", []string{"user"}, 10*time.Minute),
+	} {
+		if err := repository.AddSession(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testSessionLimits checks the limits of the repository with the limit of 2 sessions per user
+func testSessionLimits(t *testing.T, repository Repository, limits *idpconfig.SessionLimitsConfig) {
+	addUserSessions(t, repository)
+
+	limits.Policy = SessionLimitReject
+	if err := repository.AddSession(userSession("third", "user-1", []string{"user"}, 0)); err == nil {
+		t.Fatal("Expected the third session of the user to be rejected")
+	}
+
+	limits.Policy = SessionLimitEvictOldest
+	if err := repository.AddSession(userSession("third", "user-1", []string{"user"}, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := repository.GetSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]*Session{}
+	for i, session := range *sessions {
+		ids[session.ID] = &(*sessions)[i]
+	}
+	if _, ok := ids["first"]; ok {
+		t.Fatal("Expected the oldest session of the user to be evicted")
+	}
+	for _, id := range []string{"second", "third", "other-user"} {
+		if _, ok := ids[id]; !ok {
+			t.Fatalf("Expected the session %s to be kept", id)
+		}
+	}
+
+	if limit := ids["third"].Limit; limit == nil || limit.MaxSessions != 2 || limit.Sessions != 2 || limit.Policy != SessionLimitEvictOldest {
+		t.Fatalf("Expected the session limit of the user in the listing, got %+v", limit)
+	}
+	if limit := ids["other-user"].Limit; limit == nil || limit.Sessions != 1 {
+		t.Fatalf("Expected the session limit of the other user in the listing, got %+v", limit)
+	}
+}
+
+func TestDBSessionLimits(t *testing.T) {
+	limits := &idpconfig.SessionLimitsConfig{MaxSessions: 2}
+	repository := New()
+	repository.sessions = map[string]*Session{}
+	repository.SessionLimits = limits
+
+	testSessionLimits(t, repository, limits)
+}
+
+func TestIDPStoreSessionLimits(t *testing.T) {
+	limits := &idpconfig.SessionLimitsConfig{MaxSessions: 2}
+	repository := &IDPStore{
+		Sessions:      &sessionRepository{sessions: map[string]*Session{}},
+		SessionLimits: limits,
+	}
+
+	testSessionLimits(t, repository, limits)
+}
@@ -8,17 +8,54 @@ import (
 )
 
 // GetSession return saml Session
-func (db *DB) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) (*saml.Session, error) {
-	if sessionCookie, err := r.Cookie("session"); err == nil {
-		id := sessionCookie.Value
-		return db.sessions[id], nil
+func (db *DB) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) (*Session, error) {
+	if ids := SessionIDs(db.SessionCookie, r); len(ids) > 0 {
+		session := db.sessions[ids[0]]
+		if session == nil {
+			return nil, nil
+		}
+
+		if _, err := checkSessionBindings(db.SessionBinding, []*Session{session}, r, db.TrustedProxies); err != nil {
+			return nil, err
+		}
+		return session, nil
 	}
 
 	return nil, goa.ErrNotFound("session not found")
 }
 
-// AddSession adds new sessions
-func (db *DB) AddSession(session *saml.Session) error {
+// GetBrowserSessions returns the sessions referenced by the session cookie
+func (db *DB) GetBrowserSessions(r *http.Request) ([]*Session, error) {
+	sessions := []*Session{}
+	for _, id := range SessionIDs(db.SessionCookie, r) {
+		if id == "internal-server-error" {
+			return nil, goa.ErrInternal("Internal Server Error")
+		}
+		if session, ok := db.sessions[id]; ok && session != nil && !saml.TimeNow().After(session.ExpireTime) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return checkSessionBindings(db.SessionBinding, sessions, r, db.TrustedProxies)
+}
+
+// AddSession adds new sessions, ending the oldest sessions of the user over the limit
+func (db *DB) AddSession(session *Session) error {
+	userSessions := []*Session{}
+	for _, s := range db.sessions {
+		if s != nil && s.UserName == session.UserName {
+			userSessions = append(userSessions, s)
+		}
+	}
+
+	evicted, err := limitSessions(db.SessionLimits, session, userSessions)
+	if err != nil {
+		return err
+	}
+	for _, s := range evicted {
+		delete(db.sessions, s.ID)
+	}
+
 	db.sessions[session.ID] = session
 	return nil
 }
@@ -35,8 +72,23 @@ func (db *DB) DeleteSession(sessionID string) error {
 	return nil
 }
 
+// DeleteUserSessions deletes all sessions of the user
+func (db *DB) DeleteUserSessions(userID string) error {
+	if userID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	for id, session := range db.sessions {
+		if session != nil && session.UserName == userID {
+			delete(db.sessions, id)
+		}
+	}
+
+	return nil
+}
+
 // GetSessions lists all session
-func (db *DB) GetSessions() (*[]saml.Session, error) {
+func (db *DB) GetSessions() (*[]Session, error) {
 	if _, ok := db.sessions["not-found"]; ok {
 		delete(db.sessions, "not-found")
 		return nil, goa.ErrNotFound("no sessions found")
@@ -46,13 +98,14 @@ func (db *DB) GetSessions() (*[]saml.Session, error) {
 		return nil, goa.ErrInternal("Internal Server Error")
 	}
 
-	var sessions []saml.Session
-	var session *saml.Session
+	var sessions []Session
+	var session *Session
 
 	for _, value := range db.sessions {
 		sessions = append(sessions, *value)
 		session = value
 	}
+	setSessionLimits(db.SessionLimits, sessions)
 	db.sessions["not-found"] = session
 	db.sessions["internal-server-error"] = session
 
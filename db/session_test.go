@@ -0,0 +1,34 @@
+package db
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	idpconfig "github.com/Microkubes/identity-provider/config"
+)
+
+func TestSessionIDs(t *testing.T) {
+	r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	if ids := SessionIDs(nil, r); len(ids) != 0 {
+		t.Fatalf("Expected no sessions without the cookie, got %v", ids)
+	}
+
+	r.AddCookie(&http.Cookie{Name: "session", Value: "first|second"})
+	if ids := SessionIDs(nil, r); !reflect.DeepEqual(ids, []string{"first", "second"}) {
+		t.Fatalf("Expected the sessions of the cookie, got %v", ids)
+	}
+
+	// the cookie with the __Host- prefix is preferred
+	r.AddCookie(&http.Cookie{Name: "__Host-session", Value: "third"})
+	if ids := SessionIDs(nil, r); !reflect.DeepEqual(ids, []string{"third"}) {
+		t.Fatalf("Expected the sessions of the prefixed cookie, got %v", ids)
+	}
+
+	cfg := &idpconfig.SessionCookieConfig{Name: "idp_session"}
+	r.AddCookie(&http.Cookie{Name: "idp_session", Value: "fourth"})
+	if ids := SessionIDs(cfg, r); !reflect.DeepEqual(ids, []string{"fourth"}) {
+		t.Fatalf("Expected the sessions of the configured cookie, got %v", ids)
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"github.com/Microkubes/backends"
+	"github.com/keitaroinc/goa"
+)
+
+// Theme is the branding of the login page shown to the users of a service provider.
+type Theme struct {
+	// ServiceID is the entity ID of the service provider, or "default" for the theme of all service providers
+	// that do not have their own
+	ServiceID string `json:"serviceId"`
+	// DisplayName is the name of the application shown on the login page
+	DisplayName string `json:"displayName,omitempty"`
+	// LogoURL is the URL of the logo image
+	LogoURL string `json:"logoUrl,omitempty"`
+	// PrimaryColor is the color of the buttons and the heading, as #rgb or #rrggbb
+	PrimaryColor string `json:"primaryColor,omitempty"`
+	// BackgroundColor is the background color of the page, as #rgb or #rrggbb
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	// HelpLinks are shown below the login form
+	HelpLinks []HelpLink `json:"helpLinks,omitempty"`
+	// CustomText is shown above the login form
+	CustomText string `json:"customText,omitempty"`
+}
+
+// HelpLink is a link shown on the login page, e.g. to the support page of the application.
+type HelpLink struct {
+	// Title is the text of the link
+	Title string `json:"title"`
+	// URL is the target of the link
+	URL string `json:"url"`
+}
+
+// AddTheme saves the theme of the service provider, update if already exists.
+func (s *IDPStore) AddTheme(theme *Theme) error {
+	var filter backends.Filter
+	_, err := s.Themes.GetOne(backends.NewFilter().Match("serviceId", theme.ServiceID), &Theme{})
+	if err != nil {
+		if !backends.IsErrNotFound(err) {
+			return goa.ErrInternal(err)
+		}
+	} else {
+		// Theme exists, make update
+		filter = backends.NewFilter().Match("serviceId", theme.ServiceID)
+	}
+
+	if _, err := s.Themes.Save(theme, filter); err != nil {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetTheme returns the theme of the service provider
+func (s *IDPStore) GetTheme(serviceID string) (*Theme, error) {
+	theme := &Theme{}
+	_, err := s.Themes.GetOne(backends.NewFilter().Match("serviceId", serviceID), theme)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("theme not found")
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	return theme, nil
+}
+
+// DeleteTheme deletes the theme of the service provider
+func (s *IDPStore) DeleteTheme(serviceID string) error {
+	err := s.Themes.DeleteOne(backends.NewFilter().Match("serviceId", serviceID))
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return goa.ErrNotFound("theme not found")
+		}
+
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetThemes returns all themes
+func (s *IDPStore) GetThemes() (*[]Theme, error) {
+	var themes []Theme
+	var typeHint map[string]interface{}
+
+	items, err := s.Themes.GetAll(nil, typeHint, "", "", 0, 0)
+	if err != nil {
+		return nil, goa.ErrInternal(err)
+	}
+
+	if err := backends.MapToInterface(items, &themes); err != nil {
+		return nil, goa.ErrInternal(err)
+	}
+
+	if len(themes) == 0 {
+		return nil, goa.ErrNotFound("no themes found!")
+	}
+
+	return &themes, nil
+}
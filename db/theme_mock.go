@@ -0,0 +1,55 @@
+package db
+
+import (
+	"github.com/keitaroinc/goa"
+)
+
+// AddTheme saves the theme of the service provider
+func (db *DB) AddTheme(theme *Theme) error {
+	if theme.ServiceID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	saved := *theme
+	db.themes[theme.ServiceID] = &saved
+	return nil
+}
+
+// GetTheme returns the theme of the service provider
+func (db *DB) GetTheme(serviceID string) (*Theme, error) {
+	theme, ok := db.themes[serviceID]
+	if !ok {
+		return nil, goa.ErrNotFound("theme not found")
+	}
+
+	loaded := *theme
+	return &loaded, nil
+}
+
+// DeleteTheme deletes the theme of the service provider
+func (db *DB) DeleteTheme(serviceID string) error {
+	if serviceID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	if _, ok := db.themes[serviceID]; !ok {
+		return goa.ErrNotFound("theme not found")
+	}
+
+	delete(db.themes, serviceID)
+	return nil
+}
+
+// GetThemes lists all themes
+func (db *DB) GetThemes() (*[]Theme, error) {
+	var themes []Theme
+	for _, theme := range db.themes {
+		themes = append(themes, *theme)
+	}
+
+	if len(themes) == 0 {
+		return nil, goa.ErrNotFound("no themes found")
+	}
+
+	return &themes, nil
+}
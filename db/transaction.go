@@ -0,0 +1,182 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Microkubes/backends"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// LoginTransaction is an interactive login in progress. It holds the validated AuthnRequest, so the
+// request does not have to be sent through the login form and validated again on every step.
+type LoginTransaction struct {
+	// ID is the random transaction ID posted back by the login form
+	ID string `json:"id"`
+	// AuthnRequest is the XML of the validated AuthnRequest
+	AuthnRequest string `json:"authnRequest"`
+	// RelayState is the RelayState sent with the AuthnRequest
+	RelayState string `json:"relayState,omitempty"`
+	// ServiceProviderID is the entity ID of the service provider that sent the request
+	ServiceProviderID string `json:"serviceProviderId"`
+	// ACSBinding is the binding of the assertion consumer service the response is sent to
+	ACSBinding string `json:"acsBinding"`
+	// ACSLocation is the URL of the assertion consumer service the response is sent to
+	ACSLocation string `json:"acsLocation"`
+	// ACSIndex is the index of the assertion consumer service the response is sent to
+	ACSIndex int `json:"acsIndex"`
+	// Attempts is the number of failed login attempts
+	Attempts int `json:"attempts"`
+	// Steps are the authentication methods the user has to complete, in order
+	Steps []string `json:"steps"`
+	// AuthnMethods are the authentication methods the user has completed so far
+	AuthnMethods []string `json:"authnMethods,omitempty"`
+	// MagicLinkEnabled is set when the user may log in with a sign-in link sent by email instead of
+	// completing the Steps
+	MagicLinkEnabled bool `json:"magicLinkEnabled,omitempty"`
+	// MagicLink is the last sign-in link sent to the user. It is removed when used, so it can be used only once
+	MagicLink *MagicLink `json:"magicLink,omitempty"`
+	// MagicLinksSent is the number of sign-in links requested in the transaction
+	MagicLinksSent int `json:"magicLinksSent,omitempty"`
+	// UpstreamsEnabled is set when the user may log in with an upstream identity provider instead of
+	// completing the Steps
+	UpstreamsEnabled bool `json:"upstreamsEnabled,omitempty"`
+	// UpstreamLogin is the login started at an upstream identity provider, if any. It is removed when the
+	// response of the upstream IdP is received, so the response is accepted only once
+	UpstreamLogin *UpstreamLogin `json:"upstreamLogin,omitempty"`
+	// SocialEnabled is set when the user may log in with a social login provider instead of completing
+	// the Steps
+	SocialEnabled bool `json:"socialEnabled,omitempty"`
+	// SocialLogin is the login started at a social login provider, if any. It is removed when the user
+	// returns from the provider, so the authorization code is accepted only once
+	SocialLogin *SocialLogin `json:"socialLogin,omitempty"`
+	// Consent is the consent the user is asked for before the response is sent, if any. It is set once the
+	// user is logged in
+	Consent *PendingConsent `json:"consent,omitempty"`
+	// CreateTime is the time the transaction was started
+	CreateTime time.Time `json:"createTime"`
+	// ExpireTime is the time after which the transaction can no longer be completed
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// MagicLink is a sign-in link sent to the user by email.
+type MagicLink struct {
+	// ID is the random ID of the link, the ID of the signed sign-in token
+	ID string `json:"id"`
+	// UserID is the ID of the user in the user store
+	UserID string `json:"userId"`
+	// Email is the email the link was sent to
+	Email string `json:"email"`
+	// ExpireTime is the time after which the link can no longer be used
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// UpstreamLogin is a login at an upstream identity provider.
+type UpstreamLogin struct {
+	// Upstream is the name of the upstream IdP
+	Upstream string `json:"upstream"`
+	// RequestID is the ID of the AuthnRequest sent to the upstream IdP
+	RequestID string `json:"requestId"`
+}
+
+// SocialLogin is a login at an OAuth 2.0 or OpenID Connect social login provider.
+type SocialLogin struct {
+	// Provider is the name of the social login provider
+	Provider string `json:"provider"`
+	// State is the random state sent to the provider with the authorization request
+	State string `json:"state"`
+	// Nonce is the random nonce the ID token of the provider must contain
+	Nonce string `json:"nonce,omitempty"`
+	// CodeVerifier is the PKCE code verifier of the authorization code
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+// PendingConsent is the release of the attributes the user is asked to consent to.
+type PendingConsent struct {
+	// UserID is the ID of the user of the session the response is made for
+	UserID string `json:"userId"`
+	// Attributes are the names of the attributes shown to the user, sorted
+	Attributes []string `json:"attributes"`
+}
+
+// AddLoginTransaction saves the login transaction, update if already exists.
+func (s *IDPStore) AddLoginTransaction(transaction *LoginTransaction) error {
+	var filter backends.Filter
+	_, err := s.Transactions.GetOne(backends.NewFilter().Match("id", transaction.ID), &LoginTransaction{})
+	if err != nil {
+		if !backends.IsErrNotFound(err) {
+			return goa.ErrInternal(err)
+		}
+	} else {
+		// Transaction exists, make update
+		filter = backends.NewFilter().Match("id", transaction.ID)
+	}
+
+	if _, err := s.Transactions.Save(transaction, filter); err != nil {
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// GetLoginTransaction looks up the login transaction by its ID. Expired transactions are not returned.
+func (s *IDPStore) GetLoginTransaction(transactionID string) (*LoginTransaction, error) {
+	transaction := &LoginTransaction{}
+	_, err := s.Transactions.GetOne(backends.NewFilter().Match("id", transactionID), transaction)
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("login transaction not found")
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	if saml.TimeNow().After(transaction.ExpireTime) {
+		return nil, goa.ErrNotFound("login transaction has expired")
+	}
+
+	return transaction, nil
+}
+
+// RedeemMagicLink removes the sign-in link from the login transaction and returns the transaction as it was
+// with the link. The transaction is updated only if it still holds the link, so of the concurrent requests
+// with the same link only one redeems it. The not found error is returned if the transaction has expired or
+// no longer holds the link.
+func (s *IDPStore) RedeemMagicLink(transactionID string, linkID string) (*LoginTransaction, error) {
+	transaction, err := s.GetLoginTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	link := transaction.MagicLink
+	if link == nil || link.ID != linkID {
+		return nil, goa.ErrNotFound("sign-in link not found")
+	}
+
+	transaction.MagicLink = nil
+	filter := backends.NewFilter().Match("id", transactionID).Match("magicLink.id", linkID)
+	if _, err := s.Transactions.Save(transaction, filter); err != nil {
+		if backends.IsErrNotFound(err) {
+			return nil, goa.ErrNotFound("sign-in link not found")
+		}
+
+		return nil, goa.ErrInternal(err)
+	}
+
+	transaction.MagicLink = link
+	return transaction, nil
+}
+
+// DeleteLoginTransaction deletes the login transaction
+func (s *IDPStore) DeleteLoginTransaction(transactionID string) error {
+	err := s.Transactions.DeleteOne(backends.NewFilter().Match("id", transactionID))
+	if err != nil {
+		if backends.IsErrNotFound(err) {
+			return goa.ErrNotFound("login transaction not found")
+		}
+
+		return goa.ErrInternal(err)
+	}
+
+	return nil
+}
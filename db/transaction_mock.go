@@ -0,0 +1,56 @@
+package db
+
+import (
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// AddLoginTransaction saves the login transaction
+func (db *DB) AddLoginTransaction(transaction *LoginTransaction) error {
+	if transaction.ID == "internal-server-error" {
+		return goa.ErrInternal("Internal Server Error")
+	}
+
+	saved := *transaction
+	db.transactions[transaction.ID] = &saved
+	return nil
+}
+
+// GetLoginTransaction returns the login transaction
+func (db *DB) GetLoginTransaction(transactionID string) (*LoginTransaction, error) {
+	transaction, ok := db.transactions[transactionID]
+	if !ok || saml.TimeNow().After(transaction.ExpireTime) {
+		return nil, goa.ErrNotFound("login transaction not found")
+	}
+
+	loaded := *transaction
+	return &loaded, nil
+}
+
+// RedeemMagicLink removes the sign-in link from the login transaction, if it still holds the link
+func (db *DB) RedeemMagicLink(transactionID string, linkID string) (*LoginTransaction, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	transaction, ok := db.transactions[transactionID]
+	if !ok || saml.TimeNow().After(transaction.ExpireTime) {
+		return nil, goa.ErrNotFound("login transaction not found")
+	}
+	if transaction.MagicLink == nil || transaction.MagicLink.ID != linkID {
+		return nil, goa.ErrNotFound("sign-in link not found")
+	}
+
+	loaded := *transaction
+	transaction.MagicLink = nil
+	return &loaded, nil
+}
+
+// DeleteLoginTransaction deletes the login transaction
+func (db *DB) DeleteLoginTransaction(transactionID string) error {
+	if _, ok := db.transactions[transactionID]; !ok {
+		return goa.ErrNotFound("login transaction not found")
+	}
+
+	delete(db.transactions, transactionID)
+	return nil
+}
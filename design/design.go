@@ -48,6 +48,55 @@ var _ = Resource("idp", func() {
 		Description("Creare user session")
 		Routing(POST("/sso"))
 	})
+	Action("passwordResetForm", func() {
+		Description("Show the password reset form")
+		Routing(GET("/password-reset"))
+	})
+	Action("requestPasswordReset", func() {
+		Description("Send the password reset email")
+		Routing(POST("/password-reset"))
+	})
+	Action("newPasswordForm", func() {
+		Description("Show the new password form")
+		Routing(GET("/password-reset/confirm"))
+	})
+	Action("resetPassword", func() {
+		Description("Set the new password")
+		Routing(POST("/password-reset/confirm"))
+	})
+	Action("magicLinkForm", func() {
+		Description("Show the confirmation of the sign-in link sent by email")
+		Routing(GET("/magic-link"))
+	})
+	Action("magicLinkLogin", func() {
+		Description("Log in with the sign-in link sent by email")
+		Routing(POST("/magic-link"))
+	})
+	Action("upstreamMetadata", func() {
+		Description("Get the service provider metadata the upstream identity providers trust")
+		Routing(GET("/upstream/metadata"))
+		Response(OK)
+	})
+	Action("upstreamACS", func() {
+		Description("Receive the SAML response of the upstream identity provider")
+		Routing(POST("/upstream/acs"))
+	})
+	Action("socialCallback", func() {
+		Description("Receive the authorization code of the social login provider")
+		Routing(GET("/social/callback"))
+	})
+	Action("consent", func() {
+		Description("Consent to the release of the attributes to the service provider")
+		Routing(POST("/consent"))
+	})
+	Action("consents", func() {
+		Description("Show the service providers the user has consented to")
+		Routing(GET("/consents"))
+	})
+	Action("revokeConsent", func() {
+		Description("Revoke the consent to a service provider")
+		Routing(POST("/consents"))
+	})
 
 	Action("addServiceProvider", func() {
 		Description("Add new service provider")
@@ -72,6 +121,29 @@ var _ = Resource("idp", func() {
 		Response(InternalServerError, ErrorMedia)
 	})
 
+	Action("addTheme", func() {
+		Description("Add or update the branding theme of a service provider")
+		Routing(POST("/themes"))
+		Response(Created)
+		Response(BadRequest, ErrorMedia)
+		Response(InternalServerError, ErrorMedia)
+	})
+	Action("deleteTheme", func() {
+		Description("Delete the branding theme of a service provider")
+		Routing(DELETE("/themes"))
+		Payload(DeleteSPPayload)
+		Response(OK)
+		Response(NotFound, ErrorMedia)
+		Response(InternalServerError, ErrorMedia)
+	})
+	Action("getThemes", func() {
+		Description("Get all branding themes")
+		Routing(GET("/themes"))
+		Response(OK)
+		Response(NotFound, ErrorMedia)
+		Response(InternalServerError, ErrorMedia)
+	})
+
 	Action("deleteSession", func() {
 		Description("Delete a service provider")
 		Routing(DELETE("/sessions"))
@@ -0,0 +1,99 @@
+package i18n
+
+// defaultCatalogs are the message catalogs used when the catalog files are not found in the locales directory.
+// They are the same as the catalogs in public/locales.
+var defaultCatalogs = map[string]Catalog{
+	"en": {
+		"language.name":                    "English",
+		"login.title":                      "Sign In",
+		"login.welcome":                    "Welcome",
+		"login.please-sign-in":             "Please Sign In",
+		"login.to":                         "to %s",
+		"login.email":                      "email",
+		"login.email-title":                "Please enter the email",
+		"login.identifier.email":           "email",
+		"login.identifier.username":        "username",
+		"login.identifier.phone":           "phone number",
+		"login.or":                         "%s or %s",
+		"login.identifier-title":           "Please enter the %s",
+		"login.password":                   "password",
+		"login.password-title":             "Please enter your password",
+		"login.sign-in":                    "Sign In",
+		"login.cancel":                     "Cancel",
+		"login.create-account":             "Create Account",
+		"error.heading":                    "Oops! Something went wrong!",
+		"bad-request.heading":              "Bad request!",
+		"credentials-required":             "Credentials required!",
+		"invalid-email":                    "You have entered invalid email",
+		"invalid-identifier":               "You have entered an invalid email, username or phone number",
+		"wrong-credentials":                "Wrong email or password!",
+		"invalid-csrf-token":               "The form has expired or was not submitted from this site. Please try again.",
+		"login-expired":                    "The login has expired, return to the application and log in again.",
+		"server-error":                     "A server error has occured. %s",
+		"account-not-activated":            "Your account is not activated.",
+		"account-locked":                   "Your account is locked.",
+		"password-expired":                 "Your password has expired.",
+		"social-login-failed":              "The sign-in with %s failed.",
+		"email-not-verified":               "The email of your %s account is not verified.",
+		"no-linked-account":                "There is no account with the email of your %s account.",
+		"domain-not-allowed":               "Your %s account cannot be used to sign in here.",
+		"account-inactive.heading":         "Account not activated",
+		"account-inactive.check-email":     "Please follow the link in the activation email we have sent you.",
+		"account-inactive.resend":          "Resend Activation Email",
+		"account-locked.heading":           "Account locked",
+		"password-expired.heading":         "Password expired",
+		"account.contact-administrator":    "Please contact your administrator.",
+		"account.back-to-login":            "Back to Sign In",
+		"login.forgot-password":            "Forgot password?",
+		"password-reset.title":             "Reset Password",
+		"password-reset.instructions":      "Enter the email of your account and we will send you a link to set a new password.",
+		"password-reset.send":              "Send Link",
+		"password-reset.sent":              "If there is an account with that email, we have sent you a link to set a new password. The link can be used only once.",
+		"password-reset.mail-subject":      "Reset your password",
+		"password-reset.mail-body":         "Hello,\n\nfollow the link to set a new password:\n\n%s\n\nThe link expires in %d minutes. If you did not request a password reset, ignore this email.\n",
+		"password-reset-invalid":           "The password reset link is invalid or has expired.",
+		"new-password.title":               "Set New Password",
+		"new-password.password":            "new password",
+		"new-password.confirm":             "confirm the new password",
+		"new-password.save":                "Save",
+		"new-password.changed":             "Your password has been changed and you have been signed out everywhere. Sign in with the new password.",
+		"password-mismatch":                "The passwords do not match.",
+		"password-too-short":               "The password must be at least %d characters long.",
+		"password-too-long":                "The password must be at most %d characters long.",
+		"password-too-simple":              "The password must contain at least %d of the following: lowercase letters, uppercase letters, digits and symbols.",
+		"password-breached":                "The password has appeared in a data breach. Choose a different password.",
+		"password-reset-disabled":          "The password reset is not enabled.",
+		"login.magic-link":                 "Email Me a Sign-In Link",
+		"login.upstream":                   "Sign In with %s",
+		"login.social":                     "Continue with %s",
+		"magic-link.title":                 "Check Your Email",
+		"magic-link.sent":                  "If there is an account with that email, we have sent you a link to sign in. The link expires in %d minutes and can be used only once.",
+		"magic-link.mail-subject":          "Your sign-in link",
+		"magic-link.mail-body":             "Hello,\n\nfollow the link to sign in:\n\n%s\n\nThe link expires in %d minutes. If you did not try to sign in, ignore this email.\n",
+		"magic-link-invalid":               "The sign-in link is invalid or has expired.",
+		"magic-link-limit":                 "Too many sign-in links were requested. Check your email or try again later.",
+		"consent.title":                    "Share Your Information",
+		"consent.instructions":             "%s would like to receive the following information about you:",
+		"consent.accept":                   "Allow",
+		"consent.deny":                     "Deny",
+		"consents.title":                   "Shared Information",
+		"consents.none":                    "You have not shared your information with any application.",
+		"consents.shared":                  "Shared since %s:",
+		"consents.revoke":                  "Revoke",
+		"attribute.uid":                    "User ID",
+		"attribute.eduPersonPrincipalName": "Email",
+		"attribute.cn":                     "Full name",
+		"attribute.givenName":              "Given name",
+		"attribute.sn":                     "Surname",
+		"attribute.eduPersonAffiliation":   "Roles",
+		"accounts.title":                   "Choose an account",
+		"accounts.add":                     "Use another account",
+		"accounts.sign-out":                "Sign out",
+		"accounts.sign-out-title":          "Sign out of %s",
+		"session-limit":                    "You are already signed in on the maximal number of devices. Sign out on one of them and log in again.",
+		"password-reset-limit":             "Too many password resets were requested. Check your email or try again later.",
+		"magic-link.confirm-title":         "Sign In",
+		"magic-link.confirm":               "Continue to sign in as %s.",
+		"magic-link.continue":              "Sign In",
+	},
+}
@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"fmt"
+
+	"github.com/keitaroinc/goa"
+)
+
+// MessageCodeKey is the key of the message code in the meta of the goa errors, e.g.
+// goa.ErrBadRequest("the login has expired", i18n.MessageCodeKey, "login-expired").
+const MessageCodeKey = "messageCode"
+
+// Error is an error shown to the user. Code is the key of the message in the message catalogs, so the pages
+// can show the error in the language of the user. Message is the English message, formatted with Args.
+type Error struct {
+	Code    string
+	Message string
+	Args    []interface{}
+}
+
+// NewError creates an error with the message code and the English message.
+func NewError(code string, message string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Args:    args,
+	}
+}
+
+// Error returns the English message.
+func (e *Error) Error() string {
+	if len(e.Args) == 0 {
+		return e.Message
+	}
+
+	return fmt.Sprintf(e.Message, e.Args...)
+}
+
+// Code returns the message code of the error, or empty string if the error has no code.
+func Code(err error) string {
+	switch e := err.(type) {
+	case *Error:
+		return e.Code
+	case *goa.ErrorResponse:
+		if code, ok := e.Meta[MessageCodeKey].(string); ok {
+			return code
+		}
+	}
+
+	return ""
+}
+
+// args returns the arguments of the error message
+func args(err error) []interface{} {
+	if e, ok := err.(*Error); ok {
+		return e.Args
+	}
+
+	return nil
+}
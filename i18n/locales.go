@@ -0,0 +1,274 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLanguage is the language used if not configured.
+const DefaultLanguage = "en"
+
+// DefaultLocalesDir is the directory the message catalogs are loaded from if not configured.
+const DefaultLocalesDir = "public/locales"
+
+// LanguageParam is the name of the query parameter used by the language switcher.
+const LanguageParam = "lang"
+
+// LanguageCookieName is the name of the cookie that remembers the language chosen with the language switcher.
+const LanguageCookieName = "lang"
+
+// languageCookieMaxAge is the time the chosen language is remembered
+const languageCookieMaxAge = 365 * 24 * time.Hour
+
+// Catalog maps the message codes to the messages of one language. The messages are fmt format strings.
+type Catalog map[string]string
+
+// Locales holds the message catalogs of the languages the pages are available in.
+type Locales struct {
+	defaultLanguage string
+	catalogs        map[string]Catalog
+	languages       []string
+}
+
+// NewLocales loads the message catalogs from the <language>.json files in the directory. The embedded
+// default catalog of the language is used for the messages missing from the file.
+func NewLocales(dir string, defaultLanguage string) (*Locales, error) {
+	if dir == "" {
+		dir = DefaultLocalesDir
+	}
+	if defaultLanguage == "" {
+		defaultLanguage = DefaultLanguage
+	}
+
+	catalogs := map[string]Catalog{}
+	for language, catalog := range defaultCatalogs {
+		catalogs[language] = Catalog{}
+		for code, message := range catalog {
+			catalogs[language][code] = message
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		catalog := Catalog{}
+		if err := json.Unmarshal(b, &catalog); err != nil {
+			return nil, fmt.Errorf("invalid message catalog %s: %s", file, err)
+		}
+
+		language := normalize(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if catalogs[language] == nil {
+			catalogs[language] = Catalog{}
+		}
+		for code, message := range catalog {
+			catalogs[language][code] = message
+		}
+	}
+
+	return newLocales(catalogs, normalize(defaultLanguage))
+}
+
+// DefaultLocales returns the locales with the embedded default catalogs only.
+func DefaultLocales() *Locales {
+	locales, _ := newLocales(defaultCatalogs, DefaultLanguage)
+	return locales
+}
+
+func newLocales(catalogs map[string]Catalog, defaultLanguage string) (*Locales, error) {
+	if _, ok := catalogs[defaultLanguage]; !ok {
+		return nil, fmt.Errorf("no message catalog for the default language %s", defaultLanguage)
+	}
+
+	languages := []string{}
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	return &Locales{
+		defaultLanguage: defaultLanguage,
+		catalogs:        catalogs,
+		languages:       languages,
+	}, nil
+}
+
+// Languages returns the available languages, sorted.
+func (l *Locales) Languages() []string {
+	return l.languages
+}
+
+// Locale returns the locale of the language, or of the default language if the language is not available.
+func (l *Locales) Locale(language string) *Locale {
+	language = normalize(language)
+	if _, ok := l.catalogs[language]; !ok {
+		language = l.defaultLanguage
+	}
+
+	return &Locale{
+		Language: language,
+		catalog:  l.catalogs[language],
+		fallback: l.catalogs[DefaultLanguage],
+	}
+}
+
+// Negotiate returns the locale of the request. The language chosen with the language switcher (the lang
+// query parameter) is remembered in the lang cookie and takes precedence over the Accept-Language header.
+// The cookie is Secure if the client connected over HTTPS, as told by secure.
+func (l *Locales) Negotiate(w http.ResponseWriter, r *http.Request, secure bool) *Locale {
+	if language := l.match(r.URL.Query().Get(LanguageParam)); language != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     LanguageCookieName,
+			Value:    language,
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteLaxMode,
+			Path:     "/",
+			MaxAge:   int(languageCookieMaxAge.Seconds()),
+		})
+		return l.Locale(language)
+	}
+
+	if cookie, err := r.Cookie(LanguageCookieName); err == nil {
+		if language := l.match(cookie.Value); language != "" {
+			return l.Locale(language)
+		}
+	}
+
+	for _, tag := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+		if language := l.match(tag); language != "" {
+			return l.Locale(language)
+		}
+	}
+
+	return l.Locale(l.defaultLanguage)
+}
+
+// match returns the available language for the language tag, trying the primary language of the tag
+// (e.g. "de" for "de-AT") if there is no catalog for the tag itself
+func (l *Locales) match(tag string) string {
+	tag = normalize(tag)
+	if tag == "" {
+		return ""
+	}
+
+	if _, ok := l.catalogs[tag]; ok {
+		return tag
+	}
+
+	if i := strings.Index(tag, "-"); i > 0 {
+		if _, ok := l.catalogs[tag[:i]]; ok {
+			return tag[:i]
+		}
+	}
+
+	return ""
+}
+
+// Locale translates the messages to one language.
+type Locale struct {
+	// Language is the language of the messages
+	Language string
+
+	catalog  Catalog
+	fallback Catalog
+}
+
+// T returns the message for the code formatted with the arguments. The English message is returned if
+// the message is missing from the catalog of the language, and the code if there is no message at all.
+func (l *Locale) T(code string, args ...interface{}) string {
+	message, ok := l.catalog[code]
+	if !ok {
+		message, ok = l.fallback[code]
+	}
+	if !ok {
+		return code
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
+
+// Error returns the message of the error in the language of the locale. Errors without a message code, or
+// with a code missing from the catalogs, are shown with their own message.
+func (l *Locale) Error(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	code := Code(err)
+	if code == "" {
+		return err.Error()
+	}
+	if _, ok := l.catalog[code]; !ok {
+		if _, ok := l.fallback[code]; !ok {
+			return err.Error()
+		}
+	}
+
+	return l.T(code, args(err)...)
+}
+
+// acceptedLanguages returns the language tags of the Accept-Language header, the preferred first
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	accepted := []weighted{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		accepted = append(accepted, weighted{tag, q})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+
+	tags := make([]string, len(accepted))
+	for i, a := range accepted {
+		tags[i] = a.tag
+	}
+
+	return tags
+}
+
+// normalize returns the language tag in lower case with "-" as separator
+func normalize(tag string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(tag), "_", "-", -1))
+}
@@ -0,0 +1,148 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keitaroinc/goa"
+)
+
+func createLocales(t *testing.T) *Locales {
+	locales, err := NewLocales("../public/locales", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return locales
+}
+
+func TestNegotiate(t *testing.T) {
+	locales := createLocales(t)
+
+	cases := []struct {
+		url            string
+		cookie         string
+		acceptLanguage string
+		expected       string
+	}{
+		{"https://idp.example.com/login", "", "", "en"},
+		{"https://idp.example.com/login", "", "fr;q=0.9, de-AT", "de"},
+		{"https://idp.example.com/login", "", "fr, en;q=0.5", "en"},
+		{"https://idp.example.com/login", "de", "en", "de"},
+		{"https://idp.example.com/login", "fr", "de", "de"},
+		{"https://idp.example.com/login?lang=en", "de", "de", "en"},
+		{"https://idp.example.com/login?lang=fr", "", "de", "de"},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", c.url, nil)
+		if c.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: LanguageCookieName, Value: c.cookie})
+		}
+		r.Header.Set("Accept-Language", c.acceptLanguage)
+
+		if locale := locales.Negotiate(w, r, true); locale.Language != c.expected {
+			t.Fatalf("Expected %s for %+v, got %s", c.expected, c, locale.Language)
+		}
+	}
+}
+
+func TestNegotiateSetsCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/login?lang=DE", nil)
+
+	createLocales(t).Negotiate(w, r, true)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != LanguageCookieName || cookies[0].Value != "de" || !cookies[0].Secure {
+		t.Fatalf("Expected the Secure lang cookie, got %v", cookies)
+	}
+
+	w = httptest.NewRecorder()
+	createLocales(t).Negotiate(w, r, false)
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Secure {
+		t.Fatalf("Expected the lang cookie not to be Secure over HTTP, got %v", cookies)
+	}
+}
+
+func TestLocaleT(t *testing.T) {
+	locale := createLocales(t).Locale("de")
+
+	if message := locale.T("login.to", "Example"); message == "to Example" || message == "login.to" {
+		t.Fatalf("Expected the German message, got %s", message)
+	}
+	if message := locale.T("does-not-exist"); message != "does-not-exist" {
+		t.Fatalf("Expected the code, got %s", message)
+	}
+
+	locale.catalog = Catalog{}
+	if message := locale.T("login.to", "Example"); message != "to Example" {
+		t.Fatalf("Expected the English fallback, got %s", message)
+	}
+}
+
+func TestLocaleError(t *testing.T) {
+	locale := DefaultLocales().Locale("en")
+
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{nil, ""},
+		{NewError("server-error", "A server error has occured. %s", "timeout"), "A server error has occured. timeout"},
+		{NewError("unknown-code", "Unknown"), "Unknown"},
+		{goa.ErrBadRequest("expired", MessageCodeKey, "login-expired"), defaultCatalogs["en"]["login-expired"]},
+		{goa.ErrBadRequest("no code"), goa.ErrBadRequest("no code").Error()},
+	}
+
+	for _, c := range cases {
+		if message := locale.Error(c.err); message != c.expected {
+			t.Fatalf("Expected %q, got %q", c.expected, message)
+		}
+	}
+}
+
+func TestNewLocalesDefaultLanguage(t *testing.T) {
+	if _, err := NewLocales("does-not-exist", "fr"); err == nil {
+		t.Fatal("Nil error, expected: no message catalog for the default language fr")
+	}
+
+	if languages := DefaultLocales().Languages(); len(languages) != 1 || languages[0] != "en" {
+		t.Fatalf("Expected the embedded English catalog only, got %v", languages)
+	}
+}
+
+func TestDefaultCatalogsMatchFiles(t *testing.T) {
+	b, err := ioutil.ReadFile("../public/locales/en.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog := Catalog{}
+	if err := json.Unmarshal(b, &catalog); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(catalog) != len(defaultCatalogs["en"]) {
+		t.Fatalf("Expected %d messages in public/locales/en.json, got %d", len(defaultCatalogs["en"]), len(catalog))
+	}
+	for code, message := range defaultCatalogs["en"] {
+		if catalog[code] != message {
+			t.Fatalf("The embedded message %s differs from public/locales/en.json", code)
+		}
+	}
+
+	de, err := NewLocales("../public/locales", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code := range defaultCatalogs["en"] {
+		if _, ok := de.catalogs["de"][code]; !ok {
+			t.Fatalf("Missing German message %s", code)
+		}
+	}
+}
@@ -7,12 +7,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/mail"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/Microkubes/identity-provider/app"
 	"github.com/Microkubes/identity-provider/config"
 	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/Microkubes/identity-provider/mailer"
+	"github.com/Microkubes/identity-provider/oidc"
+	"github.com/Microkubes/identity-provider/provisioning"
 	jormungandrSamlIdp "github.com/Microkubes/identity-provider/samlidp"
 	"github.com/Microkubes/identity-provider/service"
 	"github.com/crewjam/saml"
@@ -22,9 +28,40 @@ import (
 
 var sessionMaxAge = time.Hour * 24
 
-var badRequestFile = "public/bad-request.html"
-var errorFile = "public/error.html"
-var loginFile = "public/login/login-form.html"
+var errNoPassive = jormungandrSamlIdp.NewStatusError(jormungandrSamlIdp.StatusResponder, jormungandrSamlIdp.StatusNoPassive, "The user cannot be authenticated passively.")
+var errLoginCancelled = jormungandrSamlIdp.NewStatusError(jormungandrSamlIdp.StatusResponder, jormungandrSamlIdp.StatusAuthnFailed, "The user cancelled the login.")
+var errConsentDenied = jormungandrSamlIdp.NewStatusError(jormungandrSamlIdp.StatusResponder, jormungandrSamlIdp.StatusRequestDenied, "The user denied the release of the attributes.")
+
+// errLoginExpired is shown when the session the login transaction continues with has ended
+var errLoginExpired = goa.ErrBadRequest("The login has expired, return to the application and log in again.", i18n.MessageCodeKey, "login-expired")
+
+// errWrongCredentials is shown on the login form when the user could not be found with the credentials
+var errWrongCredentials = i18n.NewError("wrong-credentials", "Wrong email or password!")
+
+// errSessionLimit is shown on the login form when the user already holds the maximal number of sessions and
+// the new session is rejected
+var errSessionLimit = i18n.NewError("session-limit", "You are already signed in on the maximal number of devices. Sign out on one of them and log in again.")
+
+// errPasswordResetDisabled is shown when the password reset pages are requested but the password reset is not enabled
+var errPasswordResetDisabled = i18n.NewError("password-reset-disabled", "The password reset is not enabled.")
+
+// provisioningError returns the error shown on the login form when the user of the external identity source
+// cannot be linked to a local user, nil for the other errors
+func provisioningError(err error, source string) error {
+	switch err {
+	case provisioning.ErrEmailNotVerified:
+		return i18n.NewError("email-not-verified", "The email of your %s account is not verified.", source)
+	case provisioning.ErrDomainNotAllowed:
+		return i18n.NewError("domain-not-allowed", "Your %s account cannot be used to sign in here.", source)
+	case provisioning.ErrNoAccount:
+		return i18n.NewError("no-linked-account", "There is no account with the email of your %s account.", source)
+	}
+
+	return nil
+}
+
+// loginSteps are the authentication steps of the SAML login
+var loginSteps = []string{jormungandrSamlIdp.AuthnMethodPassword}
 
 // IdpController implements the idp resource.
 type IdpController struct {
@@ -32,6 +69,17 @@ type IdpController struct {
 	Repository db.Repository
 	IDP        *saml.IdentityProvider
 	Config     *config.Config
+	Templates  *jormungandrSamlIdp.Templates
+	Auditor    jormungandrSamlIdp.Auditor
+	Mailer     mailer.Mailer
+	// MagicLinkLimiter limits the sign-in links sent to one email address
+	MagicLinkLimiter *jormungandrSamlIdp.RateLimiter
+	// PasswordResetLimiter limits the password resets requested for one email address
+	PasswordResetLimiter *jormungandrSamlIdp.RateLimiter
+	// Upstreams are the upstream identity providers the users may log in with
+	Upstreams []*jormungandrSamlIdp.Upstream
+	// SocialProviders are the OAuth 2.0 and OpenID Connect providers the users may sign in with
+	SocialProviders []*oidc.Provider
 }
 
 type SamlIdentityProvider struct {
@@ -39,12 +87,18 @@ type SamlIdentityProvider struct {
 }
 
 // NewIdpController creates a idp controller.
-func NewIdpController(service *goa.Service, repository db.Repository, idp *saml.IdentityProvider, config *config.Config) *IdpController {
+func NewIdpController(service *goa.Service, repository db.Repository, idp *saml.IdentityProvider, config *config.Config, templates *jormungandrSamlIdp.Templates, mailer mailer.Mailer) *IdpController {
 	return &IdpController{
 		Controller: service.NewController("IdpController"),
 		Repository: repository,
 		IDP:        idp,
 		Config:     config,
+		Templates:  templates,
+		Auditor:    jormungandrSamlIdp.LogAuditor(service),
+		Mailer:     mailer,
+
+		MagicLinkLimiter:     jormungandrSamlIdp.NewMagicLinkLimiter(&config.MagicLink),
+		PasswordResetLimiter: jormungandrSamlIdp.NewPasswordResetLimiter(&config.PasswordReset),
 	}
 }
 
@@ -76,10 +130,10 @@ func (c *IdpController) LoginUser(ctx *app.LoginUserIdpContext) error {
 	req := &saml.IdpAuthnRequest{
 		IDP:         c.IDP,
 		HTTPRequest: r,
-		RelayState:  "relayState",
+		RelayState:  c.standaloneRelayState(r),
 	}
 
-	jormungandrSamlIdp.LoginForm(w, r, req, fmt.Sprintf("%s/saml/idp/login", c.Config.GatewayURL), "", loginFile)
+	c.Templates.LoginForm(w, r, req, c.theme(req), c.standaloneLoginURL(r), nil)
 
 	return nil
 }
@@ -93,24 +147,35 @@ func (c *IdpController) ServeLoginUser(ctx *app.ServeLoginUserIdpContext) error
 	req := &saml.IdpAuthnRequest{
 		IDP:         c.IDP,
 		HTTPRequest: r,
-		RelayState:  "relayState",
+		RelayState:  c.standaloneRelayState(r),
+	}
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
 	}
 
-	email, password, err := service.CheckUserCredentials(r, w, req)
+	identifier, password, err := service.CheckUserCredentials(r, w, req, c.Config)
 	if err != nil {
-		jormungandrSamlIdp.LoginForm(w, r, req, fmt.Sprintf("%s/saml/idp/login", c.Config.GatewayURL), err.Error(), loginFile)
+		c.Templates.LoginForm(w, r, req, c.theme(req), c.standaloneLoginURL(r), err)
 		return nil
 	}
 
-	user, err := service.FindUser(email, password, c.IDP, c.Config)
+	user, err := service.FindUser(identifier, password, c.IDP, c.Config)
 	if err != nil {
-		jormungandrSamlIdp.LoginForm(w, r, req, fmt.Sprintf("%s/saml/idp/login", c.Config.GatewayURL), "Wrong email or password!", loginFile)
+		if c.accountError(w, r, req, identifier.String(), jormungandrSamlIdp.RelayStateURL(c.standaloneLoginURL(r), req.RelayState), err) {
+			return nil
+		}
+
+		c.audit(r, req, jormungandrSamlIdp.AuditLoginFailed, identifier.String())
+		c.Templates.LoginForm(w, r, req, c.theme(req), c.standaloneLoginURL(r), errWrongCredentials)
 		return nil
 	}
 
 	tokenStr, err := service.GenerateSignedSAMLToken(c.IDP, user)
 	if err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+		c.Templates.ErrorForm(w, r, serverError(err), 500)
+		return nil
 	}
 
 	roles := []string{}
@@ -118,31 +183,35 @@ func (c *IdpController) ServeLoginUser(ctx *app.ServeLoginUserIdpContext) error
 		roles = append(roles, v.(string))
 	}
 
-	session := &saml.Session{
-		ID:         tokenStr,
-		CreateTime: saml.TimeNow(),
-		ExpireTime: saml.TimeNow().Add(sessionMaxAge),
-		Index:      hex.EncodeToString(jormungandrSamlIdp.RandomBytes(32)),
-		UserName:   user["id"].(string),
-		Groups:     roles,
-		UserEmail:  user["email"].(string),
+	session := &db.Session{
+		Session: saml.Session{
+			ID:         tokenStr,
+			CreateTime: saml.TimeNow(),
+			ExpireTime: saml.TimeNow().Add(sessionMaxAge),
+			Index:      hex.EncodeToString(jormungandrSamlIdp.RandomBytes(32)),
+			UserName:   user["id"].(string),
+			Groups:     roles,
+			UserEmail:  user["email"].(string),
+		},
+		AuthnMethods: []string{jormungandrSamlIdp.AuthnMethodPassword},
+		Binding:      db.NewSessionBinding(&c.Config.SessionBinding, r, c.Config.TrustedProxies),
 	}
 
 	if err = c.Repository.AddSession(session); err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+		if _, ok := err.(*db.SessionLimitError); ok {
+			c.audit(r, req, jormungandrSamlIdp.AuditSessionLimitReached, identifier.String())
+			c.Templates.LoginForm(w, r, req, c.theme(req), c.standaloneLoginURL(r), errSessionLimit)
+			return nil
+		}
+
+		c.Templates.ErrorForm(w, r, serverError(err), 500)
 		return nil
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session",
-		Value:    session.ID,
-		MaxAge:   int(sessionMaxAge.Seconds()),
-		HttpOnly: true,
-		Secure:   r.URL.Scheme == "https",
-		Path:     "/",
-	})
+	c.addBrowserSession(w, r, session)
 
-	http.Redirect(w, r, c.Config.Client["redirect-from-login"], http.StatusFound)
+	c.audit(r, req, jormungandrSamlIdp.AuditLoginSucceeded, identifier.String())
+	http.Redirect(w, r, c.standaloneRedirectURL(r, req), http.StatusFound)
 
 	return nil
 }
@@ -153,28 +222,72 @@ func (c *IdpController) ServeSSO(ctx *app.ServeSSOIdpContext) error {
 	w := ctx.ResponseData
 	c.IDP.ServiceProviderProvider = c.Repository
 
+	// the language switcher of the login form shows the form of the login transaction again
+	if r.URL.Query().Get(jormungandrSamlIdp.LoginTransactionParam) != "" {
+		req, transaction, err := jormungandrSamlIdp.LoadLoginTransaction(c.IDP, c.Repository, r)
+		if err != nil {
+			c.samlError(w, r, req, err)
+			return nil
+		}
+
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), nil)
+		return nil
+	}
+
 	req, err := jormungandrSamlIdp.ValidateSamlRequest(c.IDP, r)
 	if err != nil {
-		jormungandrSamlIdp.BadRequestForm(w, r, err.Error(), badRequestFile)
+		c.samlError(w, r, req, err)
 		return nil
 	}
 
-	session, _ := c.Repository.GetSession(w, r, req)
-	if session == nil {
-		jormungandrSamlIdp.LoginForm(w, r, req, req.IDP.SSOURL.String(), "", loginFile)
+	if err := jormungandrSamlIdp.CheckReplay(c.Repository, req); err != nil {
+		c.samlError(w, r, req, err)
 		return nil
 	}
 
-	if err = jormungandrSamlIdp.MakeAssertion(req, c.IDP, session); err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+	sessions, err := c.browserSessions(w, r, req)
+	if err != nil {
+		c.samlError(w, r, req, err)
 		return nil
 	}
 
-	if err := req.WriteResponse(w); err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+	// step-up: the user must log in again if no session satisfies the requested authentication context
+	accounts := jormungandrSamlIdp.AccountSessions(sessions, req)
+	if len(accounts) == 0 {
+		if _, err := jormungandrSamlIdp.SelectAuthnContext(req, jormungandrSamlIdp.SupportedAuthnMethods); err != nil {
+			c.samlError(w, r, req, err)
+			return nil
+		}
+
+		if jormungandrSamlIdp.IsPassive(req) {
+			c.samlError(w, r, req, errNoPassive)
+			return nil
+		}
+
+		transaction, err := jormungandrSamlIdp.StartLoginTransaction(c.Repository, req, loginSteps, c.loginAlternatives(req))
+		if err != nil {
+			c.samlError(w, r, req, err)
+			return nil
+		}
+
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), nil)
+		return nil
+	}
+
+	// the browser with one session and the passive requests continue with the selected session
+	if len(sessions) == 1 || jormungandrSamlIdp.IsPassive(req) {
+		c.sendResponse(w, r, req, nil, accounts[0])
 		return nil
 	}
 
+	transaction, err := jormungandrSamlIdp.StartLoginTransaction(c.Repository, req, loginSteps, c.loginAlternatives(req))
+	if err != nil {
+		c.samlError(w, r, req, err)
+		return nil
+	}
+
+	c.Templates.AccountChooserForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), accounts)
+
 	return nil
 }
 
@@ -184,128 +297,1225 @@ func (c *IdpController) ServeLogin(ctx *app.ServeLoginIdpContext) error {
 	w := ctx.ResponseData
 	c.IDP.ServiceProviderProvider = c.Repository
 
-	relayState := strings.TrimSpace(r.FormValue("RelayState"))
-	SAMLRequest := strings.TrimSpace(r.FormValue("SAMLRequest"))
+	req, transaction, err := c.loginTransaction(r)
+	if err != nil {
+		c.samlError(w, r, req, err)
+		return nil
+	}
 
-	req, err := jormungandrSamlIdp.ValidateSamlRequest(c.IDP, r)
+	if err := jormungandrSamlIdp.CheckReplay(c.Repository, req); err != nil {
+		c.samlError(w, r, req, err)
+		return nil
+	}
+
+	// The login form is never shown for passive requests, so the user cannot log in interactively.
+	if jormungandrSamlIdp.IsPassive(req) {
+		c.samlError(w, r, req, errNoPassive)
+		return nil
+	}
+
+	// The AuthnRequest posted by the service provider starts the login, the credentials are accepted
+	// only from the login form of the transaction.
+	if transaction == nil {
+		transaction, err := jormungandrSamlIdp.StartLoginTransaction(c.Repository, req, loginSteps, c.loginAlternatives(req))
+		if err != nil {
+			c.samlError(w, r, req, err)
+			return nil
+		}
+
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), nil)
+		return nil
+	}
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
+	}
+
+	if r.FormValue("cancel") != "" {
+		c.Repository.DeleteLoginTransaction(transaction.ID)
+		c.samlError(w, r, req, errLoginCancelled)
+		return nil
+	}
+
+	if r.FormValue(jormungandrSamlIdp.AddAccountParam) != "" {
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), nil)
+		return nil
+	}
+
+	if index := r.FormValue(jormungandrSamlIdp.AccountParam); index != "" {
+		c.chooseAccount(w, r, req, transaction, index)
+		return nil
+	}
+
+	if index := r.FormValue(jormungandrSamlIdp.SignOutParam); index != "" {
+		c.signOutAccount(w, r, req, transaction, index)
+		return nil
+	}
+
+	if r.FormValue("magic-link") != "" {
+		c.requestMagicLink(w, r, req, transaction)
+		return nil
+	}
+
+	if name := r.FormValue(jormungandrSamlIdp.UpstreamParam); name != "" {
+		c.startUpstreamLogin(w, r, req, transaction, jormungandrSamlIdp.FindUpstream(c.Upstreams, name))
+		return nil
+	}
+
+	if name := r.FormValue(jormungandrSamlIdp.SocialProviderParam); name != "" {
+		c.startSocialLogin(w, r, req, transaction, jormungandrSamlIdp.FindSocialProvider(c.SocialProviders, name))
+		return nil
+	}
+
+	// the users in the email domains of the upstream IdPs are sent to their IdP instead of being asked for the password
+	if transaction.UpstreamsEnabled {
+		if upstream := jormungandrSamlIdp.UpstreamForLogin(c.Upstreams, r.FormValue("email")); upstream != nil {
+			c.startUpstreamLogin(w, r, req, transaction, upstream)
+			return nil
+		}
+	}
+
+	identifier, password, err := service.CheckUserCredentials(r, w, req, c.Config)
 	if err != nil {
-		jormungandrSamlIdp.BadRequestForm(w, r, err.Error(), badRequestFile)
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), err)
 		return nil
 	}
 
-	email, password, err := service.CheckUserCredentials(r, w, req)
+	user, err := service.FindUser(identifier, password, c.IDP, c.Config)
 	if err != nil {
-		jormungandrSamlIdp.LoginForm(w, r, req, fmt.Sprintf("%s?RelayState=%s&SAMLRequest=%s", req.IDP.SSOURL.String(), relayState, SAMLRequest), err.Error(), loginFile)
+		if c.accountError(w, r, req, identifier.String(), jormungandrSamlIdp.LoginTransactionURL(req.IDP.SSOURL.String(), transaction), err) {
+			return nil
+		}
+
+		c.audit(r, req, jormungandrSamlIdp.AuditLoginFailed, identifier.String())
+		if err := jormungandrSamlIdp.FailLoginAttempt(c.Repository, transaction); err != nil {
+			c.samlError(w, r, req, err)
+			return nil
+		}
+
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), errWrongCredentials)
 		return nil
 	}
 
-	user, err := service.FindUser(email, password, c.IDP, c.Config)
+	jormungandrSamlIdp.CompleteAuthnStep(transaction, jormungandrSamlIdp.AuthnMethodPassword)
+	c.completeLogin(w, r, req, transaction, user, identifier.String())
+
+	return nil
+}
+
+// MagicLinkForm runs the magicLinkForm action. Opening the sign-in link only asks the user to confirm the
+// sign-in, so the mail scanners and the link previews that fetch the link do not use it up.
+func (c *IdpController) MagicLinkForm(ctx *app.MagicLinkFormIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+	c.IDP.ServiceProviderProvider = c.Repository
+
+	token := r.FormValue(jormungandrSamlIdp.MagicLinkTokenParam)
+	req, _, link, err := jormungandrSamlIdp.LoadMagicLink(c.Repository, c.IDP, r, token)
 	if err != nil {
-		jormungandrSamlIdp.LoginForm(w, r, req, fmt.Sprintf("%s?RelayState=%s&SAMLRequest=%s", req.IDP.SSOURL.String(), relayState, SAMLRequest), "Wrong email or password!", loginFile)
+		if err == jormungandrSamlIdp.ErrInvalidMagicLink {
+			c.Templates.BadRequestForm(w, r, err)
+			return nil
+		}
+		c.samlError(w, r, req, err)
 		return nil
 	}
 
-	roles := []string{}
-	for _, v := range user["roles"].([]interface{}) {
-		roles = append(roles, v.(string))
+	c.Templates.MagicLinkConfirmForm(w, r, c.theme(req), c.magicLinkURL(), token, link.Email)
+
+	return nil
+}
+
+// MagicLinkLogin runs the magicLinkLogin action. The confirmed sign-in link completes the login transaction
+// it was sent for, so the response is sent to the service provider that started the login. The link is
+// removed from the transaction before the login completes, so it can be used only once.
+func (c *IdpController) MagicLinkLogin(ctx *app.MagicLinkLoginIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+	c.IDP.ServiceProviderProvider = c.Repository
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
 	}
 
-	tokenStr, err := service.GenerateSignedSAMLToken(c.IDP, user)
+	req, transaction, link, err := jormungandrSamlIdp.RedeemMagicLink(c.Repository, c.IDP, r, r.FormValue(jormungandrSamlIdp.MagicLinkTokenParam))
 	if err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+		if err == jormungandrSamlIdp.ErrInvalidMagicLink {
+			c.Templates.BadRequestForm(w, r, err)
+			return nil
+		}
+		c.samlError(w, r, req, err)
+		return nil
 	}
 
-	session := &saml.Session{
-		ID:         tokenStr,
-		CreateTime: saml.TimeNow(),
-		ExpireTime: saml.TimeNow().Add(sessionMaxAge),
-		Index:      hex.EncodeToString(jormungandrSamlIdp.RandomBytes(32)),
-		UserName:   user["id"].(string),
-		Groups:     roles,
-		UserEmail:  user["email"].(string),
+	if err := jormungandrSamlIdp.CheckReplay(c.Repository, req); err != nil {
+		c.samlError(w, r, req, err)
+		return nil
 	}
 
-	if err = c.Repository.AddSession(session); err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+	// the account may have been disabled since the link was sent
+	user, err := service.FindUserByEmail(link.Email, c.IDP, c.Config)
+	if err == nil {
+		err = service.CheckAccount(user)
+	}
+	if err != nil {
+		if c.accountError(w, r, req, link.Email, jormungandrSamlIdp.LoginTransactionURL(req.IDP.SSOURL.String(), transaction), err) {
+			return nil
+		}
+		c.samlError(w, r, req, err)
+		return nil
+	}
+	if userID, _ := user["id"].(string); userID != link.UserID {
+		c.Templates.BadRequestForm(w, r, jormungandrSamlIdp.ErrInvalidMagicLink)
 		return nil
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session",
-		Value:    session.ID,
-		MaxAge:   int(sessionMaxAge.Seconds()),
-		HttpOnly: true,
-		Secure:   r.URL.Scheme == "https",
-		Path:     "/",
-	})
+	jormungandrSamlIdp.CompleteAuthnStep(transaction, jormungandrSamlIdp.AuthnMethodMagicLink)
+	c.completeLogin(w, r, req, transaction, user, link.Email)
+
+	return nil
+}
+
+// UpstreamACS runs the upstreamACS action. The response of the upstream identity provider completes the login
+// transaction that sent the user to it, so the response is sent to the service provider that started the login.
+func (c *IdpController) UpstreamACS(ctx *app.UpstreamACSIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+	c.IDP.ServiceProviderProvider = c.Repository
 
-	if err = jormungandrSamlIdp.MakeAssertion(req, c.IDP, session); err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+	req, transaction, upstream, assertion, err := jormungandrSamlIdp.CompleteUpstreamLogin(c.IDP, c.Repository, c.Upstreams, r)
+	if err != nil {
+		if err == jormungandrSamlIdp.ErrUpstreamLoginFailed {
+			c.audit(r, req, jormungandrSamlIdp.AuditUpstreamLoginFailed, "")
+			c.Repository.DeleteLoginTransaction(transaction.ID)
+		}
+		c.samlError(w, r, req, err)
 		return nil
+	}
 
+	if err := jormungandrSamlIdp.CheckReplay(c.Repository, req); err != nil {
+		c.samlError(w, r, req, err)
+		return nil
 	}
 
-	if err := req.WriteResponse(w); err != nil {
-		jormungandrSamlIdp.ErrorForm(w, r, fmt.Sprintf("A server error has occured. %s", err.Error()), 500, errorFile)
+	if upstream.Provisioning != nil {
+		external, err := jormungandrSamlIdp.UpstreamExternalUser(upstream, assertion)
+		if err != nil {
+			c.audit(r, req, jormungandrSamlIdp.AuditUpstreamLoginFailed, "")
+			c.Repository.DeleteLoginTransaction(transaction.ID)
+			c.samlError(w, r, req, err)
+			return nil
+		}
+
+		c.completeExternalLogin(w, r, req, transaction, external, upstream.Provisioning, upstream.DisplayName, jormungandrSamlIdp.AuthnMethodUpstream)
+		return nil
+	}
+
+	user, err := jormungandrSamlIdp.UpstreamUser(upstream, assertion)
+	if err != nil {
+		c.audit(r, req, jormungandrSamlIdp.AuditUpstreamLoginFailed, "")
+		c.Repository.DeleteLoginTransaction(transaction.ID)
+		c.samlError(w, r, req, err)
 		return nil
 	}
 
+	jormungandrSamlIdp.CompleteAuthnStep(transaction, jormungandrSamlIdp.AuthnMethodUpstream)
+	c.completeLogin(w, r, req, transaction, user, user["email"].(string))
+
 	return nil
 }
 
-// AddService runs the add service action.
-func (c *IdpController) AddServiceProvider(ctx *app.AddServiceProviderIdpContext) error {
+// UpstreamMetadata runs the upstreamMetadata action. The upstream identity providers trust the IdP as a
+// service provider with this metadata.
+func (c *IdpController) UpstreamMetadata(ctx *app.UpstreamMetadataIdpContext) error {
+	buf, err := xml.MarshalIndent(jormungandrSamlIdp.NewUpstreamServiceProvider(c.Config, c.IDP).Metadata(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ctx.OK(buf)
+}
+
+// SocialCallback runs the socialCallback action. The social login provider sends the user back with the
+// authorization code, which completes the login transaction that sent the user to the provider. The user
+// is linked to the local user with the verified email, or provisioned if the provider allows it.
+func (c *IdpController) SocialCallback(ctx *app.SocialCallbackIdpContext) error {
 	r := ctx.Request
-	service := samlidp.Service{}
+	w := ctx.ResponseData
+	c.IDP.ServiceProviderProvider = c.Repository
 
-	metadata, err := jormungandrSamlIdp.GetSPMetadata(r.Body)
+	req, transaction, provider, identity, err := jormungandrSamlIdp.CompleteSocialLogin(c.IDP, c.Repository, c.SocialProviders, r)
 	if err != nil {
-		return ctx.BadRequest(err)
+		if provider != nil {
+			c.audit(r, req, jormungandrSamlIdp.AuditSocialLoginFailed, "")
+			c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), err)
+			return nil
+		}
+		c.samlError(w, r, req, err)
+		return nil
 	}
 
-	service.Metadata = *metadata
-	service.Name = service.Metadata.EntityID
+	if err := jormungandrSamlIdp.CheckReplay(c.Repository, req); err != nil {
+		c.samlError(w, r, req, err)
+		return nil
+	}
 
-	err = c.Repository.AddServiceProvider(&service)
+	external := jormungandrSamlIdp.SocialExternalUser(provider, identity)
+	c.completeExternalLogin(w, r, req, transaction, external, provider.Provisioning, provider.DisplayName, jormungandrSamlIdp.AuthnMethodSocial)
+
+	return nil
+}
+
+// completeExternalLogin completes the login of the user of an external identity source as the local user it is
+// linked to. The user is linked, or created, on the first login by the provisioning rules of the source. When
+// the user cannot be linked, the login form is shown again, so the user may log in otherwise.
+func (c *IdpController) completeExternalLogin(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, external *provisioning.ExternalUser, rules *config.ProvisioningConfig, source string, method string) {
+	account, err := provisioning.Provision(c.Repository, external, rules, c.IDP, c.Config)
+	if err == nil {
+		// the password is not used for the external logins, so it does not matter if it has expired
+		if err = service.CheckAccount(account.User); err == service.ErrPasswordExpired {
+			err = nil
+		}
+	}
 	if err != nil {
-		ctx.InternalServerError(err)
+		if message := provisioningError(err, source); message != nil {
+			c.audit(r, req, jormungandrSamlIdp.AuditProvisioningFailed, external.Email)
+			c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), message)
+			return
+		}
+		if c.accountError(w, r, req, external.Email, jormungandrSamlIdp.LoginTransactionURL(req.IDP.SSOURL.String(), transaction), err) {
+			return
+		}
+		c.samlError(w, r, req, err)
+		return
 	}
 
-	return ctx.Created()
+	email, _ := account.User["email"].(string)
+	if account.Created {
+		event := jormungandrSamlIdp.AuditUserLinked
+		if account.Link.Provisioned {
+			event = jormungandrSamlIdp.AuditUserProvisioned
+		}
+		c.audit(r, req, event, email)
+	}
+
+	jormungandrSamlIdp.CompleteAuthnStep(transaction, method)
+	c.completeLogin(w, r, req, transaction, account.User, email)
 }
 
-// DeleteServiceProvider runs the delete SP action.
-func (c *IdpController) DeleteServiceProvider(ctx *app.DeleteServiceProviderIdpContext) error {
-	err := c.Repository.DeleteServiceProvider(ctx.Payload.ServiceID)
+// startSocialLogin sends the user to the social login provider to sign in. The social login must be enabled
+// for the login transaction.
+func (c *IdpController) startSocialLogin(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, provider *oidc.Provider) {
+	if !transaction.SocialEnabled || provider == nil {
+		c.Templates.BadRequestForm(w, r, goa.ErrBadRequest("the social login provider is not available for the service provider"))
+		return
+	}
+
+	c.audit(r, req, jormungandrSamlIdp.AuditSocialLoginStarted, "")
+	if err := jormungandrSamlIdp.StartSocialLogin(w, r, c.Repository, provider, transaction); err != nil {
+		c.samlError(w, r, req, err)
+	}
+}
+
+// startUpstreamLogin sends the user to the upstream identity provider to log in. The upstream IdPs must be
+// enabled for the login transaction.
+func (c *IdpController) startUpstreamLogin(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, upstream *jormungandrSamlIdp.Upstream) {
+	if !transaction.UpstreamsEnabled || upstream == nil {
+		c.Templates.BadRequestForm(w, r, goa.ErrBadRequest("the upstream identity provider is not available for the service provider"))
+		return
+	}
+
+	c.audit(r, req, jormungandrSamlIdp.AuditUpstreamLoginStarted, strings.TrimSpace(r.FormValue("email")))
+	if err := jormungandrSamlIdp.StartUpstreamLogin(w, r, c.Repository, upstream, transaction); err != nil {
+		c.samlError(w, r, req, err)
+	}
+}
+
+// requestMagicLink sends the sign-in link of the login transaction to the email posted with the login form
+// and tells the user to check the email. The same page is shown whether there is an account with the email
+// or not, so the form does not reveal the registered emails.
+func (c *IdpController) requestMagicLink(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction) {
+	if !transaction.MagicLinkEnabled {
+		c.Templates.BadRequestForm(w, r, goa.ErrBadRequest("the sign-in links are not enabled for the service provider"))
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), service.ErrInvalidEmail)
+		return
+	}
+
+	if err := jormungandrSamlIdp.CountMagicLink(c.Repository, transaction); err != nil {
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), err)
+		return
+	}
+	if !c.MagicLinkLimiter.Allow(strings.ToLower(email)) {
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), jormungandrSamlIdp.ErrTooManyMagicLinks)
+		return
+	}
+
+	c.audit(r, req, jormungandrSamlIdp.AuditMagicLinkRequested, email)
+	if err := c.sendMagicLink(w, r, transaction, email); err != nil {
+		c.Service.LogError("Sending of the sign-in link failed", "err", err)
+	}
+
+	c.Templates.MagicLinkSentForm(w, r, c.theme(req), jormungandrSamlIdp.LoginTransactionURL(req.IDP.SSOURL.String(), transaction))
+}
+
+// sendMagicLink sends the sign-in link of the login transaction to the user with the email, in the language
+// of the request. Nothing is sent if there is no user with the email that can log in.
+func (c *IdpController) sendMagicLink(w http.ResponseWriter, r *http.Request, transaction *db.LoginTransaction, email string) error {
+	user, err := service.FindUserByEmail(email, c.IDP, c.Config)
 	if err != nil {
-		e := err.(*goa.ErrorResponse)
+		return err
+	}
 
-		switch e.Status {
-		case 404:
-			return ctx.NotFound(err)
-		default:
-			return ctx.InternalServerError(err)
-		}
+	userID, _ := user["id"].(string)
+	if userID == "" || service.CheckAccount(user) != nil {
+		return nil
+	}
+	if userEmail, ok := user["email"].(string); ok && userEmail != "" {
+		email = userEmail
 	}
 
-	return ctx.OK([]byte("OK"))
+	token, err := jormungandrSamlIdp.StartMagicLink(c.Repository, c.IDP, transaction, userID, email)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?%s=%s", c.magicLinkURL(), jormungandrSamlIdp.MagicLinkTokenParam, url.QueryEscape(token))
+	locale := c.Templates.Locale(w, r)
+
+	return c.Mailer.Send(&mailer.Message{
+		To:      email,
+		Subject: locale.T("magic-link.mail-subject"),
+		Body:    locale.T("magic-link.mail-body", link, int(transaction.MagicLink.ExpireTime.Sub(saml.TimeNow()).Minutes())),
+	})
 }
 
-// GetServiceProviders runs the get Service Providers action.
-func (c *IdpController) GetServiceProviders(ctx *app.GetServiceProvidersIdpContext) error {
-	services, err := c.Repository.GetServiceProviders()
+// chooseAccount continues the login transaction with the session the user chose on the account chooser. The
+// chosen session becomes the selected session of the browser.
+func (c *IdpController) chooseAccount(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, index string) {
+	sessions, err := c.browserSessions(w, r, req)
 	if err != nil {
-		e := err.(*goa.ErrorResponse)
+		c.samlError(w, r, req, err)
+		return
+	}
 
-		switch e.Status {
-		case 404:
-			return ctx.NotFound(err)
-		default:
-			return ctx.InternalServerError(err)
+	session := jormungandrSamlIdp.FindSession(jormungandrSamlIdp.AccountSessions(sessions, req), index)
+	if session == nil {
+		// the session has ended since the account chooser was shown
+		c.accountChooser(w, r, req, transaction, sessions)
+		return
+	}
+
+	c.setSessionCookie(w, r, db.SelectSessionID(sessionIDs(sessions), session.ID))
+	c.audit(r, req, jormungandrSamlIdp.AuditAccountChosen, session.UserEmail)
+	c.sendResponse(w, r, req, transaction, session)
+}
+
+// signOutAccount ends the session the user signed out of on the account chooser and shows the account chooser
+// with the remaining sessions of the browser.
+func (c *IdpController) signOutAccount(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, index string) {
+	sessions, err := c.browserSessions(w, r, req)
+	if err != nil {
+		c.samlError(w, r, req, err)
+		return
+	}
+
+	if session := jormungandrSamlIdp.FindSession(sessions, index); session != nil {
+		err := c.Repository.DeleteSession(session.ID)
+		if e, ok := err.(*goa.ErrorResponse); err != nil && (!ok || e.Status != http.StatusNotFound) {
+			c.samlError(w, r, req, err)
+			return
+		}
+
+		remaining := []*db.Session{}
+		for _, s := range sessions {
+			if s.ID != session.ID {
+				remaining = append(remaining, s)
+			}
 		}
+		sessions = remaining
+
+		c.audit(r, req, jormungandrSamlIdp.AuditSignedOut, session.UserEmail)
 	}
 
-	resp, err := json.Marshal(services)
+	c.setSessionCookie(w, r, sessionIDs(sessions))
+	c.accountChooser(w, r, req, transaction, sessions)
+}
+
+// accountChooser shows the account chooser of the login transaction with the sessions the user may continue
+// with, or the login form if there are none.
+func (c *IdpController) accountChooser(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, sessions []*db.Session) {
+	accounts := jormungandrSamlIdp.AccountSessions(sessions, req)
+	if len(accounts) == 0 {
+		c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), nil)
+		return
+	}
+
+	c.Templates.AccountChooserForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), accounts)
+}
+
+// session returns the selected session of the browser, nil if there is none. The bound session used from a
+// different client is recorded in the audit log, and ended in the strict mode, so the user must log in again.
+func (c *IdpController) session(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *db.Session {
+	session, err := c.Repository.GetSession(w, r, req)
+	if e, ok := err.(*db.SessionBindingError); ok {
+		c.endBoundSessions(w, r, req, e.Sessions)
+		return nil
+	}
+	if session != nil {
+		c.auditBindingMismatches(r, req, []*db.Session{session})
+	}
+
+	return session
+}
+
+// browserSessions returns the valid sessions of the browser, see session for the bound sessions used from a
+// different client.
+func (c *IdpController) browserSessions(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) ([]*db.Session, error) {
+	sessions, err := c.Repository.GetBrowserSessions(r)
+	if e, ok := err.(*db.SessionBindingError); ok {
+		c.endBoundSessions(w, r, req, e.Sessions)
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.auditBindingMismatches(r, req, sessions)
+
+	return sessions, nil
+}
+
+// endBoundSessions ends the bound sessions used from a different client and removes them from the session cookie
+func (c *IdpController) endBoundSessions(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, sessions []*db.Session) {
+	c.auditBindingMismatches(r, req, sessions)
+
+	ids := db.SessionIDs(&c.Config.SessionCookie, r)
+	for _, session := range sessions {
+		c.Repository.DeleteSession(session.ID)
+		ids = db.RemoveSessionID(ids, session.ID)
+	}
+
+	c.setSessionCookie(w, r, ids)
+}
+
+// auditBindingMismatches records the bound sessions used from a different client in the audit log
+func (c *IdpController) auditBindingMismatches(r *http.Request, req *saml.IdpAuthnRequest, sessions []*db.Session) {
+	for _, session := range sessions {
+		if len(session.BindingMismatches) > 0 {
+			c.audit(r, req, jormungandrSamlIdp.AuditSessionBindingMismatch, session.UserEmail)
+		}
+	}
+}
+
+// addBrowserSession adds the new session to the sessions of the browser as the selected session. The sessions
+// of the browser that have ended are dropped.
+func (c *IdpController) addBrowserSession(w http.ResponseWriter, r *http.Request, session *db.Session) {
+	sessions, _ := c.Repository.GetBrowserSessions(r)
+
+	c.setSessionCookie(w, r, db.SelectSessionID(sessionIDs(sessions), session.ID))
+}
+
+// setSessionCookie sets the session cookie referencing the sessions of the browser, the selected session
+// first. The cookie is removed if there are no sessions.
+func (c *IdpController) setSessionCookie(w http.ResponseWriter, r *http.Request, ids []string) {
+	maxAge := int(sessionMaxAge.Seconds())
+	if len(ids) == 0 {
+		maxAge = -1
+	}
+
+	for _, cookie := range jormungandrSamlIdp.SessionCookies(c.Config, r, db.SessionCookieValue(ids), maxAge) {
+		http.SetCookie(w, cookie)
+	}
+}
+
+// sessionIDs returns the IDs of the sessions
+func sessionIDs(sessions []*db.Session) []string {
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+
+	return ids
+}
+
+// completeLogin creates the session of the user that completed the login transaction and sends the
+// response to the service provider.
+func (c *IdpController) completeLogin(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, user map[string]interface{}, email string) {
+	roles := []string{}
+	for _, v := range user["roles"].([]interface{}) {
+		roles = append(roles, v.(string))
+	}
+
+	tokenStr, err := service.GenerateSignedSAMLToken(c.IDP, user)
+	if err != nil {
+		c.samlError(w, r, req, err)
+		return
+	}
+
+	session := &db.Session{
+		Session: saml.Session{
+			ID:         tokenStr,
+			CreateTime: saml.TimeNow(),
+			ExpireTime: saml.TimeNow().Add(sessionMaxAge),
+			Index:      hex.EncodeToString(jormungandrSamlIdp.RandomBytes(32)),
+			UserName:   user["id"].(string),
+			Groups:     roles,
+			UserEmail:  user["email"].(string),
+		},
+		AuthnMethods: transaction.AuthnMethods,
+		Binding:      db.NewSessionBinding(&c.Config.SessionBinding, r, c.Config.TrustedProxies),
+	}
+
+	if err = c.Repository.AddSession(session); err != nil {
+		if _, ok := err.(*db.SessionLimitError); ok {
+			c.audit(r, req, jormungandrSamlIdp.AuditSessionLimitReached, email)
+			c.Templates.LoginTransactionForm(w, r, transaction, c.theme(req), req.IDP.SSOURL.String(), errSessionLimit)
+			return
+		}
+
+		c.samlError(w, r, req, err)
+		return
+	}
+
+	c.addBrowserSession(w, r, session)
+
+	c.audit(r, req, jormungandrSamlIdp.AuditLoginSucceeded, email)
+	c.sendResponse(w, r, req, transaction, session)
+}
+
+// sendResponse makes the assertion of the session and sends the response to the service provider. If the user
+// must consent to the release of the attributes first, the consent form is shown instead, and the request is
+// kept in the login transaction, a new one if the user did not log in with a transaction.
+func (c *IdpController) sendResponse(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, transaction *db.LoginTransaction, session *db.Session) {
+	if err := jormungandrSamlIdp.MakeAssertion(req, c.IDP, session); err != nil {
+		c.samlError(w, r, req, err)
+		return
+	}
+
+	required, err := jormungandrSamlIdp.ConsentRequired(c.Repository, c.Config, req, session)
+	if err != nil {
+		c.samlError(w, r, req, err)
+		return
+	}
+	if required {
+		// the consent form is never shown for passive requests
+		if jormungandrSamlIdp.IsPassive(req) {
+			c.samlError(w, r, req, errNoPassive)
+			return
+		}
+
+		if transaction == nil {
+			if transaction, err = jormungandrSamlIdp.StartLoginTransaction(c.Repository, req, nil, nil); err != nil {
+				c.samlError(w, r, req, err)
+				return
+			}
+		}
+
+		attributes := jormungandrSamlIdp.ReleasedAttributes(req)
+		transaction.Consent = &db.PendingConsent{
+			UserID:     session.UserName,
+			Attributes: jormungandrSamlIdp.AttributeNames(attributes),
+		}
+		if err := c.Repository.AddLoginTransaction(transaction); err != nil {
+			c.samlError(w, r, req, err)
+			return
+		}
+
+		c.Templates.ConsentForm(w, r, transaction, c.theme(req), c.consentURL(), attributes)
+		return
+	}
+
+	if err := jormungandrSamlIdp.RecordResponse(c.Repository, req); err != nil {
+		c.samlError(w, r, req, err)
+		return
+	}
+
+	if transaction != nil {
+		c.Repository.DeleteLoginTransaction(transaction.ID)
+	}
+
+	if err := req.WriteResponse(w); err != nil {
+		c.samlError(w, r, req, err)
+	}
+}
+
+// Consent runs the consent action. The user consents to the release of the attributes shown on the consent
+// form, or denies it, and the service provider gets the response.
+func (c *IdpController) Consent(ctx *app.ConsentIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+	c.IDP.ServiceProviderProvider = c.Repository
+
+	req, transaction, err := jormungandrSamlIdp.LoadLoginTransaction(c.IDP, c.Repository, r)
+	if err != nil {
+		c.samlError(w, r, req, err)
+		return nil
+	}
+	if transaction.Consent == nil {
+		c.samlError(w, r, nil, goa.ErrBadRequest("no consent was requested in the login transaction"))
+		return nil
+	}
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
+	}
+
+	// the consent is given for the user of the session the response is made for
+	session := c.session(w, r, req)
+	if session == nil || session.UserName != transaction.Consent.UserID {
+		c.Repository.DeleteLoginTransaction(transaction.ID)
+		c.samlError(w, r, nil, errLoginExpired)
+		return nil
+	}
+
+	if r.FormValue(jormungandrSamlIdp.ConsentParam) != jormungandrSamlIdp.ConsentAccept {
+		c.Repository.DeleteLoginTransaction(transaction.ID)
+		c.audit(r, req, jormungandrSamlIdp.AuditConsentDenied, session.UserEmail)
+		c.samlError(w, r, req, errConsentDenied)
+		return nil
+	}
+
+	if err := jormungandrSamlIdp.SaveConsent(c.Repository, session.UserName, transaction.ServiceProviderID, transaction.Consent.Attributes); err != nil {
+		c.samlError(w, r, req, err)
+		return nil
+	}
+	c.audit(r, req, jormungandrSamlIdp.AuditConsentGiven, session.UserEmail)
+
+	transaction.Consent = nil
+	c.sendResponse(w, r, req, transaction, session)
+
+	return nil
+}
+
+// Consents runs the consents action. The page lists the service providers the user of the session has
+// consented to. The user without a session is sent to the standalone login first.
+func (c *IdpController) Consents(ctx *app.ConsentsIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+
+	session := c.session(w, r, nil)
+	if session == nil {
+		http.Redirect(w, r, jormungandrSamlIdp.ReturnToFormURL(fmt.Sprintf("%s/saml/idp/login", c.Config.GatewayURL), c.consentsURL()), http.StatusFound)
+		return nil
+	}
+
+	consents, err := c.Repository.GetUserConsents(session.UserName)
+	if err != nil {
+		c.Templates.ErrorForm(w, r, serverError(err), http.StatusInternalServerError)
+		return nil
+	}
+
+	serviceProviders := []jormungandrSamlIdp.ConsentedServiceProvider{}
+	for _, consent := range consents {
+		serviceProviders = append(serviceProviders, jormungandrSamlIdp.ConsentedServiceProvider{
+			ID:         consent.ServiceProviderID,
+			Name:       jormungandrSamlIdp.ServiceProviderName(c.Repository, consent.ServiceProviderID),
+			Attributes: consent.Attributes,
+			CreateTime: consent.CreateTime,
+		})
+	}
+
+	c.Templates.ConsentsForm(w, r, c.defaultTheme(), c.consentsURL(), serviceProviders)
+
+	return nil
+}
+
+// RevokeConsent runs the revokeConsent action. The user of the session revokes the consent to the service
+// provider, so the user is asked again on the next login to it.
+func (c *IdpController) RevokeConsent(ctx *app.RevokeConsentIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
+	}
+
+	session := c.session(w, r, nil)
+	if session == nil {
+		http.Redirect(w, r, jormungandrSamlIdp.ReturnToFormURL(fmt.Sprintf("%s/saml/idp/login", c.Config.GatewayURL), c.consentsURL()), http.StatusFound)
+		return nil
+	}
+
+	serviceProviderID := r.FormValue(jormungandrSamlIdp.ConsentServiceProviderParam)
+	err := c.Repository.DeleteConsent(db.ConsentID(session.UserName, serviceProviderID))
+	if err == nil {
+		if c.Auditor != nil {
+			event := jormungandrSamlIdp.NewAuditEvent(jormungandrSamlIdp.AuditConsentRevoked, r, nil, session.UserEmail, c.Config.TrustedProxies)
+			event.ServiceProvider = serviceProviderID
+			c.Auditor.Audit(event)
+		}
+	} else if e, ok := err.(*goa.ErrorResponse); !ok || e.Status != http.StatusNotFound {
+		c.Templates.ErrorForm(w, r, serverError(err), http.StatusInternalServerError)
+		return nil
+	}
+
+	http.Redirect(w, r, c.consentsURL(), http.StatusSeeOther)
+
+	return nil
+}
+
+// PasswordResetForm runs the passwordResetForm action.
+func (c *IdpController) PasswordResetForm(ctx *app.PasswordResetFormIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+
+	if !c.Config.PasswordReset.Enabled {
+		c.Templates.ErrorForm(w, r, errPasswordResetDisabled, http.StatusNotFound)
+		return nil
+	}
+
+	c.Templates.PasswordResetForm(w, r, c.defaultTheme(), c.passwordResetURL(), c.standaloneLoginURL(r), nil)
+
+	return nil
+}
+
+// RequestPasswordReset runs the requestPasswordReset action. The same page is shown whether there is an
+// account with the email or not, so the form does not reveal the registered emails.
+func (c *IdpController) RequestPasswordReset(ctx *app.RequestPasswordResetIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+
+	if !c.Config.PasswordReset.Enabled {
+		c.Templates.ErrorForm(w, r, errPasswordResetDisabled, http.StatusNotFound)
+		return nil
+	}
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.Templates.PasswordResetForm(w, r, c.defaultTheme(), c.passwordResetURL(), c.standaloneLoginURL(r), service.ErrInvalidEmail)
+		return nil
+	}
+	if !c.PasswordResetLimiter.Allow(strings.ToLower(email)) {
+		c.Templates.PasswordResetForm(w, r, c.defaultTheme(), c.passwordResetURL(), c.standaloneLoginURL(r), jormungandrSamlIdp.ErrTooManyPasswordResets)
+		return nil
+	}
+
+	c.audit(r, nil, jormungandrSamlIdp.AuditPasswordResetRequested, email)
+	if err := c.sendPasswordReset(w, r, email); err != nil {
+		c.Service.LogError("Sending of the password reset email failed", "err", err)
+	}
+
+	c.Templates.PasswordResetSentForm(w, r, c.defaultTheme(), c.standaloneLoginURL(r))
+
+	return nil
+}
+
+// NewPasswordForm runs the newPasswordForm action.
+func (c *IdpController) NewPasswordForm(ctx *app.NewPasswordFormIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+
+	if !c.Config.PasswordReset.Enabled {
+		c.Templates.ErrorForm(w, r, errPasswordResetDisabled, http.StatusNotFound)
+		return nil
+	}
+
+	token := r.FormValue(jormungandrSamlIdp.PasswordResetTokenParam)
+	if _, err := jormungandrSamlIdp.LoadPasswordReset(c.Repository, c.IDP, token); err != nil {
+		c.passwordResetError(w, r, err)
+		return nil
+	}
+
+	c.Templates.NewPasswordForm(w, r, c.defaultTheme(), c.newPasswordURL(), token, nil)
+
+	return nil
+}
+
+// ResetPassword runs the resetPassword action. The password reset is deleted before the password is
+// changed, so the link cannot be used again. The other resets of the user and all sessions of the user are
+// revoked afterwards.
+func (c *IdpController) ResetPassword(ctx *app.ResetPasswordIdpContext) error {
+	r := ctx.Request
+	w := ctx.ResponseData
+
+	if !c.Config.PasswordReset.Enabled {
+		c.Templates.ErrorForm(w, r, errPasswordResetDisabled, http.StatusNotFound)
+		return nil
+	}
+
+	if err := jormungandrSamlIdp.CheckCSRF(r); err != nil {
+		c.Templates.ErrorForm(w, r, err, http.StatusForbidden)
+		return nil
+	}
+
+	token := r.FormValue(jormungandrSamlIdp.PasswordResetTokenParam)
+	reset, err := jormungandrSamlIdp.LoadPasswordReset(c.Repository, c.IDP, token)
+	if err != nil {
+		c.passwordResetError(w, r, err)
+		return nil
+	}
+
+	password := strings.TrimSpace(r.FormValue("password"))
+	if err := service.ValidateNewPassword(password, strings.TrimSpace(r.FormValue("confirm")), c.Config); err != nil {
+		if i18n.Code(err) == "" {
+			c.Templates.ErrorForm(w, r, serverError(err), 500)
+			return nil
+		}
+		c.Templates.NewPasswordForm(w, r, c.defaultTheme(), c.newPasswordURL(), token, err)
+		return nil
+	}
+
+	if err := c.Repository.DeletePasswordReset(reset.ID); err != nil {
+		c.passwordResetError(w, r, err)
+		return nil
+	}
+
+	if err := service.UpdatePassword(reset.UserID, password, c.IDP, c.Config); err != nil {
+		c.Templates.ErrorForm(w, r, serverError(err), 500)
+		return nil
+	}
+
+	if err := c.Repository.DeleteUserPasswordResets(reset.UserID); err != nil {
+		c.Templates.ErrorForm(w, r, serverError(err), 500)
+		return nil
+	}
+
+	if err := c.Repository.DeleteUserSessions(reset.UserID); err != nil {
+		c.Templates.ErrorForm(w, r, serverError(err), 500)
+		return nil
+	}
+
+	c.audit(r, nil, jormungandrSamlIdp.AuditPasswordReset, reset.Email)
+	c.Templates.PasswordChangedForm(w, r, c.defaultTheme(), c.standaloneLoginURL(r))
+
+	return nil
+}
+
+// sendPasswordReset sends the password reset link to the user with the email, in the language of the
+// request. Nothing is sent if there is no active user with the email.
+func (c *IdpController) sendPasswordReset(w http.ResponseWriter, r *http.Request, email string) error {
+	user, err := service.FindUserByEmail(email, c.IDP, c.Config)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := user["id"].(string)
+	if active, ok := user["active"].(bool); userID == "" || (ok && !active) {
+		return nil
+	}
+	if userEmail, ok := user["email"].(string); ok && userEmail != "" {
+		email = userEmail
+	}
+
+	token, err := jormungandrSamlIdp.StartPasswordReset(c.Repository, c.IDP, userID, email)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?%s=%s", c.newPasswordURL(), jormungandrSamlIdp.PasswordResetTokenParam, url.QueryEscape(token))
+	locale := c.Templates.Locale(w, r)
+
+	return c.Mailer.Send(&mailer.Message{
+		To:      email,
+		Subject: locale.T("password-reset.mail-subject"),
+		Body:    locale.T("password-reset.mail-body", link, int(jormungandrSamlIdp.PasswordResetMaxAge.Minutes())),
+	})
+}
+
+// passwordResetError shows the bad request page for the invalid password reset links and the error page
+// for the other errors
+func (c *IdpController) passwordResetError(w http.ResponseWriter, r *http.Request, err error) {
+	if e, ok := err.(*goa.ErrorResponse); ok && e.Status == http.StatusNotFound {
+		err = jormungandrSamlIdp.ErrInvalidPasswordResetToken
+	}
+
+	if err == jormungandrSamlIdp.ErrInvalidPasswordResetToken {
+		c.Templates.BadRequestForm(w, r, err)
+		return
+	}
+
+	c.Templates.ErrorForm(w, r, serverError(err), 500)
+}
+
+// passwordResetURL returns the URL of the form requesting the password reset email
+func (c *IdpController) passwordResetURL() string {
+	return fmt.Sprintf("%s/saml/idp/password-reset", c.Config.GatewayURL)
+}
+
+// newPasswordURL returns the URL of the form setting the new password
+func (c *IdpController) newPasswordURL() string {
+	return fmt.Sprintf("%s/saml/idp/password-reset/confirm", c.Config.GatewayURL)
+}
+
+// consentURL returns the URL the attribute release consent is posted to
+func (c *IdpController) consentURL() string {
+	return fmt.Sprintf("%s/saml/idp/consent", c.Config.GatewayURL)
+}
+
+// consentsURL returns the URL of the page listing the consents of the user
+func (c *IdpController) consentsURL() string {
+	return fmt.Sprintf("%s/saml/idp/consents", c.Config.GatewayURL)
+}
+
+// magicLinkURL returns the URL of the sign-in links
+func (c *IdpController) magicLinkURL() string {
+	return fmt.Sprintf("%s/saml/idp/magic-link", c.Config.GatewayURL)
+}
+
+// loginAlternatives returns the authentication methods the user may log in to the service provider that sent the
+// request with instead of the login steps: the sign-in links, the upstream identity providers and the social
+// login providers
+func (c *IdpController) loginAlternatives(req *saml.IdpAuthnRequest) []string {
+	alternatives := []string{}
+	if jormungandrSamlIdp.MagicLinkEnabled(c.Config, req) {
+		alternatives = append(alternatives, jormungandrSamlIdp.AuthnMethodMagicLink)
+	}
+	if jormungandrSamlIdp.UpstreamsEnabled(c.Upstreams, req) {
+		alternatives = append(alternatives, jormungandrSamlIdp.AuthnMethodUpstream)
+	}
+	if jormungandrSamlIdp.SocialLoginEnabled(c.SocialProviders, req) {
+		alternatives = append(alternatives, jormungandrSamlIdp.AuthnMethodSocial)
+	}
+
+	return alternatives
+}
+
+// defaultTheme returns the theme of the pages that are not shown for a particular service provider
+func (c *IdpController) defaultTheme() *db.Theme {
+	return jormungandrSamlIdp.ServiceProviderTheme(c.Repository, nil)
+}
+
+// loginTransaction returns the request the login form was posted for together with its login transaction.
+// The forms shown by the IdP post back the transaction ID. Without it, the AuthnRequest was sent by the
+// service provider with the HTTP-POST binding, so it is validated and no transaction is returned.
+func (c *IdpController) loginTransaction(r *http.Request) (*saml.IdpAuthnRequest, *db.LoginTransaction, error) {
+	if r.FormValue(jormungandrSamlIdp.LoginTransactionParam) != "" {
+		return jormungandrSamlIdp.LoadLoginTransaction(c.IDP, c.Repository, r)
+	}
+
+	req, err := jormungandrSamlIdp.ValidateSamlRequest(c.IDP, r)
+	return req, nil, err
+}
+
+// theme returns the login page theme of the service provider that sent the request. The standalone login
+// has no service provider, so it gets the default theme.
+func (c *IdpController) theme(req *saml.IdpAuthnRequest) *db.Theme {
+	return jormungandrSamlIdp.ServiceProviderTheme(c.Repository, req.ServiceProviderMetadata)
+}
+
+// standaloneRelayState returns the RelayState of the standalone login, which is the URL the user is redirected
+// to after the login. RelayState that is too long or not an allowed redirect target is dropped.
+func (c *IdpController) standaloneRelayState(r *http.Request) string {
+	relayState := strings.TrimSpace(r.FormValue("RelayState"))
+
+	if jormungandrSamlIdp.CheckRelayState(relayState) != nil || !jormungandrSamlIdp.IsAllowedRedirect(relayState, c.Config.AllowedRedirects) {
+		return ""
+	}
+
+	return relayState
+}
+
+// standaloneLoginURL returns the URL the standalone login form is posted to. The allowed return_to URL
+// of the request is passed on, so the user is redirected to it after the login.
+func (c *IdpController) standaloneLoginURL(r *http.Request) string {
+	formURL := fmt.Sprintf("%s/saml/idp/login", c.Config.GatewayURL)
+
+	return jormungandrSamlIdp.ReturnToFormURL(formURL, jormungandrSamlIdp.ReturnTo(r, c.Config.AllowedRedirects))
+}
+
+// standaloneRedirectURL returns the URL the user is redirected to after the standalone login: the allowed
+// return_to (or next) URL, the allowed RelayState or the configured redirect-from-login URL, in that order.
+func (c *IdpController) standaloneRedirectURL(r *http.Request, req *saml.IdpAuthnRequest) string {
+	if returnTo := jormungandrSamlIdp.ReturnTo(r, c.Config.AllowedRedirects); returnTo != "" {
+		return returnTo
+	}
+
+	return jormungandrSamlIdp.RedirectURL(req.RelayState, c.Config.AllowedRedirects, c.Config.Client["redirect-from-login"])
+}
+
+// accountError shows the page of the account that cannot log in: the user has not activated the account, the
+// account is locked or the password has expired. The user with the expired password is redirected to the
+// configured change-password page, which can return the user to loginURL. False is returned for the other
+// errors, which are all shown as wrong credentials.
+func (c *IdpController) accountError(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, email string, loginURL string, err error) bool {
+	switch err {
+	case service.ErrAccountNotActivated:
+		c.audit(r, req, jormungandrSamlIdp.AuditAccountInactive, email)
+		c.Templates.AccountInactiveForm(w, r, c.theme(req), loginURL, c.Config.Client["resend-activation"])
+	case service.ErrAccountLocked:
+		c.audit(r, req, jormungandrSamlIdp.AuditAccountLocked, email)
+		c.Templates.AccountLockedForm(w, r, c.theme(req), loginURL)
+	case service.ErrPasswordExpired:
+		c.audit(r, req, jormungandrSamlIdp.AuditPasswordExpired, email)
+		if changePasswordURL := c.Config.Client["change-password"]; changePasswordURL != "" {
+			http.Redirect(w, r, jormungandrSamlIdp.ReturnToFormURL(changePasswordURL, loginURL), http.StatusFound)
+		} else {
+			c.Templates.PasswordExpiredForm(w, r, c.theme(req), loginURL)
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// audit records the audit event of the login
+func (c *IdpController) audit(r *http.Request, req *saml.IdpAuthnRequest, eventType string, email string) {
+	if c.Auditor == nil {
+		return
+	}
+
+	c.Auditor.Audit(jormungandrSamlIdp.NewAuditEvent(eventType, r, req, email, c.Config.TrustedProxies))
+}
+
+// serverError is shown on the error page when the request failed because of an error on the server
+func serverError(err error) error {
+	return i18n.NewError("server-error", "A server error has occured. %s", err.Error())
+}
+
+// samlError reports the error to the service provider with SAML Response. The error pages are shown
+// only when the request has no trustworthy assertion consumer service to send the response to.
+func (c *IdpController) samlError(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, err error) {
+	if req != nil && req.ACSEndpoint != nil {
+		// the request is answered with the status response, so it must not be accepted again. The status
+		// response carries no assertion, so it is sent even if the request has already been responded to.
+		if recordErr := jormungandrSamlIdp.RecordResponse(c.Repository, req); recordErr != nil {
+			if _, replayed := recordErr.(*jormungandrSamlIdp.StatusError); !replayed {
+				c.Service.LogError("Recording of the response failed", "err", recordErr)
+			}
+		}
+
+		if jormungandrSamlIdp.WriteStatusError(w, req, err) == nil {
+			return
+		}
+	}
+
+	switch e := err.(type) {
+	case *jormungandrSamlIdp.StatusError:
+		if e.Status == jormungandrSamlIdp.StatusRequester {
+			c.Templates.BadRequestForm(w, r, e)
+			return
+		}
+	case *goa.ErrorResponse:
+		if e.Status == http.StatusBadRequest {
+			c.Templates.BadRequestForm(w, r, e)
+			return
+		}
+	}
+
+	c.Templates.ErrorForm(w, r, serverError(err), 500)
+}
+
+// AddService runs the add service action.
+func (c *IdpController) AddServiceProvider(ctx *app.AddServiceProviderIdpContext) error {
+	r := ctx.Request
+	service := samlidp.Service{}
+
+	metadata, err := jormungandrSamlIdp.GetSPMetadata(r.Body)
+	if err != nil {
+		return ctx.BadRequest(err)
+	}
+
+	service.Metadata = *metadata
+	service.Name = service.Metadata.EntityID
+
+	err = c.Repository.AddServiceProvider(&service)
+	if err != nil {
+		ctx.InternalServerError(err)
+	}
+
+	return ctx.Created()
+}
+
+// DeleteServiceProvider runs the delete SP action.
+func (c *IdpController) DeleteServiceProvider(ctx *app.DeleteServiceProviderIdpContext) error {
+	err := c.Repository.DeleteServiceProvider(ctx.Payload.ServiceID)
+	if err != nil {
+		e := err.(*goa.ErrorResponse)
+
+		switch e.Status {
+		case 404:
+			return ctx.NotFound(err)
+		default:
+			return ctx.InternalServerError(err)
+		}
+	}
+
+	return ctx.OK([]byte("OK"))
+}
+
+// GetServiceProviders runs the get Service Providers action.
+func (c *IdpController) GetServiceProviders(ctx *app.GetServiceProvidersIdpContext) error {
+	services, err := c.Repository.GetServiceProviders()
+	if err != nil {
+		e := err.(*goa.ErrorResponse)
+
+		switch e.Status {
+		case 404:
+			return ctx.NotFound(err)
+		default:
+			return ctx.InternalServerError(err)
+		}
+	}
+
+	resp, err := json.Marshal(services)
+	if err != nil {
+		return ctx.InternalServerError(goa.ErrInternal(err))
+	}
+
+	return ctx.OK(resp)
+}
+
+// AddTheme runs the add theme action. The theme is replaced if the service provider already has one.
+func (c *IdpController) AddTheme(ctx *app.AddThemeIdpContext) error {
+	theme, err := jormungandrSamlIdp.ParseTheme(ctx.Request.Body)
+	if err != nil {
+		return ctx.BadRequest(err)
+	}
+
+	if err := c.Repository.AddTheme(theme); err != nil {
+		return ctx.InternalServerError(err)
+	}
+
+	return ctx.Created()
+}
+
+// DeleteTheme runs the delete theme action.
+func (c *IdpController) DeleteTheme(ctx *app.DeleteThemeIdpContext) error {
+	err := c.Repository.DeleteTheme(ctx.Payload.ServiceID)
+	if err != nil {
+		e := err.(*goa.ErrorResponse)
+
+		switch e.Status {
+		case 404:
+			return ctx.NotFound(err)
+		default:
+			return ctx.InternalServerError(err)
+		}
+	}
+
+	return ctx.OK([]byte("OK"))
+}
+
+// GetThemes runs the get themes action.
+func (c *IdpController) GetThemes(ctx *app.GetThemesIdpContext) error {
+	themes, err := c.Repository.GetThemes()
+	if err != nil {
+		e := err.(*goa.ErrorResponse)
+
+		switch e.Status {
+		case 404:
+			return ctx.NotFound(err)
+		default:
+			return ctx.InternalServerError(err)
+		}
+	}
+
+	resp, err := json.Marshal(themes)
 	if err != nil {
 		return ctx.InternalServerError(goa.ErrInternal(err))
 	}
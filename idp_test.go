@@ -1,20 +1,30 @@
 package main
 
 import (
+	"bytes"
+	"compress/flate"
 	"context"
 	"crypto"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"encoding/xml"
 	"flag"
 	"fmt"
+	"html"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,6 +34,10 @@ import (
 	"github.com/Microkubes/identity-provider/app/test"
 	"github.com/Microkubes/identity-provider/config"
 	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/mailer"
+	"github.com/Microkubes/identity-provider/oidc"
+	"github.com/Microkubes/identity-provider/oidc/oidctest"
+	jormungandrSamlIdp "github.com/Microkubes/identity-provider/samlidp"
 	jormungandrTest "github.com/Microkubes/identity-provider/test"
 	"github.com/crewjam/saml"
 	"github.com/crewjam/saml/logger"
@@ -65,7 +79,9 @@ var (
 	goaService = goa.New("identity-provider")
 	repository = db.New()
 	samlServer = createSAMLIdP()
-	ctrl       = NewIdpController(goaService, repository, &samlServer.IDP, cfg)
+	templates  = createTemplates()
+	mailSink   = mailer.NewMemoryMailer("idp@example.com")
+	ctrl       = NewIdpController(goaService, repository, &samlServer.IDP, cfg, templates, mailSink)
 )
 
 var key = func() crypto.PrivateKey {
@@ -129,6 +145,15 @@ var rootURLInternalError, _ = url.Parse("http://internal-error")
 var idpMetadataURL, _ = url.Parse("http://example.com/providers.xml")
 var data, _ = ioutil.ReadFile("providers.xml")
 
+func createTemplates() *jormungandrSamlIdp.Templates {
+	templates, err := jormungandrSamlIdp.NewTemplates(jormungandrSamlIdp.DefaultTemplatesDir, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return templates
+}
+
 func createSAMLIdP() *samlidp.Server {
 	logr := logger.DefaultLogger
 	flag.Parse()
@@ -289,6 +314,69 @@ func TestGetServiceProvidersIdpInternalServerError(t *testing.T) {
 	test.GetServiceProvidersIdpInternalServerError(t, context.Background(), goaService, ctrl)
 }
 
+func TestAddThemeIdpCreated(t *testing.T) {
+	payload := []byte(`{"serviceId": "https://localhost:8082/user-profile/saml/metadata", "displayName": "User Profile", "primaryColor": "#336699"}`)
+	jormungandrTest.AddThemeIdpCreated(t, context.Background(), goaService, ctrl, payload)
+	defer repository.DeleteTheme("https://localhost:8082/user-profile/saml/metadata")
+
+	theme, err := repository.GetTheme("https://localhost:8082/user-profile/saml/metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.DisplayName != "User Profile" || theme.PrimaryColor != "#336699" {
+		t.Fatalf("Unexpected theme %+v", theme)
+	}
+}
+
+func TestAddThemeIdpBadRequest(t *testing.T) {
+	payload := []byte(`{"serviceId": "https://localhost:8082/user-profile/saml/metadata", "primaryColor": "red;}"}`)
+	_, err := jormungandrTest.AddThemeIdpBadRequest(t, context.Background(), goaService, ctrl, payload)
+	if err == nil {
+		t.Fatal("Nil error: AddThemeIdpBadRequest")
+	}
+}
+
+func TestAddThemeIdpInternalServerError(t *testing.T) {
+	payload := []byte(`{"serviceId": "internal-server-error"}`)
+	jormungandrTest.AddThemeIdpInternalServerError(t, context.Background(), goaService, ctrl, payload)
+}
+
+func TestDeleteThemeIdpOK(t *testing.T) {
+	if err := repository.AddTheme(&db.Theme{ServiceID: "http://localhost:8081/saml/metadata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := &app.DeleteSPPayload{
+		ServiceID: "http://localhost:8081/saml/metadata",
+	}
+	test.DeleteThemeIdpOK(t, context.Background(), goaService, ctrl, payload)
+}
+
+func TestDeleteThemeIdpNotFound(t *testing.T) {
+	payload := &app.DeleteSPPayload{
+		ServiceID: "not-found",
+	}
+	test.DeleteThemeIdpNotFound(t, context.Background(), goaService, ctrl, payload)
+}
+
+func TestDeleteThemeIdpInternalServerError(t *testing.T) {
+	payload := &app.DeleteSPPayload{
+		ServiceID: "internal-server-error",
+	}
+	test.DeleteThemeIdpInternalServerError(t, context.Background(), goaService, ctrl, payload)
+}
+
+func TestGetThemesIdp(t *testing.T) {
+	test.GetThemesIdpNotFound(t, context.Background(), goaService, ctrl)
+
+	if err := repository.AddTheme(&db.Theme{ServiceID: jormungandrSamlIdp.DefaultThemeID}); err != nil {
+		t.Fatal(err)
+	}
+	defer repository.DeleteTheme(jormungandrSamlIdp.DefaultThemeID)
+
+	test.GetThemesIdpOK(t, context.Background(), goaService, ctrl)
+}
+
 func TestDeleteSessionIdpOK(t *testing.T) {
 	payload := &app.DeleteSessionPayload{
 		SessionID: "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=",
@@ -460,3 +548,1894 @@ func TestServeLoginUser(t *testing.T) {
 
 	ctrl.ServeLoginUser(serveLoginUserCtx)
 }
+
+// newSamlRequestURL creates the SSO URL with fresh AuthnRequest from the test service provider.
+// The attributes and the child elements are added to the AuthnRequest element.
+func newSamlRequestURL(attributes string, elements string) string {
+	authnRequest := fmt.Sprintf(`<samlp:AuthnRequest xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" `+
+		`ID="id-%x" Version="2.0" IssueInstant="%s" Destination="http://localhost:8080/saml/idp/sso" `+
+		`AssertionConsumerServiceURL="https://localhost:8082/user-profile/saml/acs" ProtocolBinding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" %s>`+
+		`<saml:Issuer Format="urn:oasis:names:tc:SAML:2.0:nameid-format:entity">https://localhost:8082/user-profile/saml/metadata</saml:Issuer>`+
+		`<samlp:NameIDPolicy Format="urn:oasis:names:tc:SAML:2.0:nameid-format:transient" AllowCreate="true"/>%s`+
+		`</samlp:AuthnRequest>`, jormungandrSamlIdp.RandomBytes(20), saml.TimeNow().UTC().Format(time.RFC3339), attributes, elements)
+
+	buf := bytes.NewBuffer(nil)
+	fw, _ := flate.NewWriter(buf, flate.DefaultCompression)
+	fw.Write([]byte(authnRequest))
+	fw.Close()
+
+	query := url.Values{}
+	query.Set("RelayState", "_L5_YvLMqRfj0KX5A62TIKfOHMYVeboixBRg8yYxIwSjp7wmjca2OIRA")
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	return "http://localhost:8080/saml/idp/sso?" + query.Encode()
+}
+
+// serveSSO runs the ServeSSO action, with the test session cookie if withSession is set.
+func serveSSO(t *testing.T, samlRequestURL string, withSession bool) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", samlRequestURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if withSession {
+		req.AddCookie(&http.Cookie{Name: "session", Value: "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU="})
+	}
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveSSOCtx, err := app.NewServeSSOIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeSSO(serveSSOCtx)
+
+	return rw
+}
+
+func TestServeSSOTheme(t *testing.T) {
+	theme := &db.Theme{
+		ServiceID:       "https://localhost:8082/user-profile/saml/metadata",
+		DisplayName:     "User Profile",
+		LogoURL:         "https://cdn.example.com/logo.png",
+		BackgroundColor: "#f0f0f0",
+	}
+	if err := repository.AddTheme(theme); err != nil {
+		t.Fatal(err)
+	}
+	defer repository.DeleteTheme(theme.ServiceID)
+
+	body := serveSSO(t, newSamlRequestURL("", ""), false).Body.String()
+	for _, expected := range []string{"<title>User Profile: Sign In</title>", `src="https://cdn.example.com/logo.png"`, "background-color: #f0f0f0"} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %s on the login page, got: %s", expected, body)
+		}
+	}
+}
+
+func TestServeSSOIsPassive(t *testing.T) {
+	rw := serveSSO(t, newSamlRequestURL(`IsPassive="true"`, ""), false)
+	if !strings.Contains(rw.Body.String(), `action="https://localhost:8082/user-profile/saml/acs"`) {
+		t.Fatalf("Expected status response to the ACS, got: %s", rw.Body.String())
+	}
+
+	rw = serveSSO(t, newSamlRequestURL(`IsPassive="true" ForceAuthn="true"`, ""), true)
+	if !strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatalf("Expected status response to the ACS, got: %s", rw.Body.String())
+	}
+}
+
+func TestServeSSOForceAuthn(t *testing.T) {
+	rw := serveSSO(t, newSamlRequestURL("", ""), true)
+	if !strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatalf("Expected SAML response for the existing session, got: %s", rw.Body.String())
+	}
+
+	rw = serveSSO(t, newSamlRequestURL(`ForceAuthn="true"`, ""), true)
+	if strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatal("Expected login form, got SAML response")
+	}
+}
+
+func TestServeLoginCancel(t *testing.T) {
+	transactionID := startLoginTransaction(t)
+
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "cancel": {"true"}}, true)
+	if !strings.Contains(rw.Body.String(), `action="https://localhost:8082/user-profile/saml/acs"`) {
+		t.Fatalf("Expected status response to the ACS, got: %s", rw.Body.String())
+	}
+	if response := samlResponse(t, rw); !strings.Contains(response, jormungandrSamlIdp.StatusAuthnFailed) {
+		t.Fatalf("Expected AuthnFailed status response, got: %s", response)
+	}
+}
+
+func TestServeSSOBadRequest(t *testing.T) {
+	rw := serveSSO(t, "http://localhost:8080/saml/idp/sso?RelayState=state", false)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request page, got status %d", rw.Code)
+	}
+}
+
+func TestServeSSORequestedAuthnContext(t *testing.T) {
+	requested := `<samlp:RequestedAuthnContext Comparison="minimum">` +
+		`<saml:AuthnContextClassRef>urn:oasis:names:tc:SAML:2.0:ac:classes:Password</saml:AuthnContextClassRef>` +
+		`</samlp:RequestedAuthnContext>`
+	rw := serveSSO(t, newSamlRequestURL("", requested), true)
+	if !strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatalf("Expected SAML response for the existing session, got: %s", rw.Body.String())
+	}
+
+	requested = `<samlp:RequestedAuthnContext Comparison="exact">` +
+		`<saml:AuthnContextClassRef>https://refeds.org/profile/mfa</saml:AuthnContextClassRef>` +
+		`</samlp:RequestedAuthnContext>`
+	rw = serveSSO(t, newSamlRequestURL("", requested), true)
+	if !strings.Contains(rw.Body.String(), `action="https://localhost:8082/user-profile/saml/acs"`) {
+		t.Fatalf("Expected NoAuthnContext status response to the ACS, got: %s", rw.Body.String())
+	}
+}
+
+func TestServeSSOReplay(t *testing.T) {
+	samlRequestURL := newSamlRequestURL("", "")
+
+	rw := serveSSO(t, samlRequestURL, true)
+	if strings.Contains(samlResponse(t, rw), "RequestDenied") {
+		t.Fatal("Expected successful response to the first request")
+	}
+
+	rw = serveSSO(t, samlRequestURL, true)
+	if !strings.Contains(samlResponse(t, rw), "urn:oasis:names:tc:SAML:2.0:status:RequestDenied") {
+		t.Fatal("Expected RequestDenied status response to the replayed request")
+	}
+}
+
+// samlResponse returns the decoded SAMLResponse posted to the service provider
+func samlResponse(t *testing.T, rw *httptest.ResponseRecorder) string {
+	match := regexp.MustCompile(`name="SAMLResponse" value="([^"]*)"`).FindStringSubmatch(rw.Body.String())
+	if match == nil {
+		t.Fatalf("SAMLResponse not found in: %s", rw.Body.String())
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(html.UnescapeString(match[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf)
+}
+
+func TestLoginUserRelayState(t *testing.T) {
+	allowedRedirects := ctrl.Config.AllowedRedirects
+	ctrl.Config.AllowedRedirects = []string{"https://kong:8000/profiles"}
+	defer func() {
+		ctrl.Config.AllowedRedirects = allowedRedirects
+	}()
+
+	loginUser := func(relayState string) string {
+		req, err := http.NewRequest("GET", "http://localhost:8080/saml/idp/login?"+url.Values{"RelayState": {relayState}}.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rw := httptest.NewRecorder()
+		goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+		loginUserCtx, err := app.NewLoginUserIdpContext(goaCtx, req, goaService)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctrl.LoginUser(loginUserCtx)
+
+		return rw.Body.String()
+	}
+
+	if body := loginUser("https://kong:8000/profiles/me"); !strings.Contains(body, `name="RelayState" value="https://kong:8000/profiles/me"`) {
+		t.Fatalf("Expected the allowed RelayState in the login form, got: %s", body)
+	}
+
+	if body := loginUser("https://evil.com/\"><script>"); strings.Contains(body, "evil.com") {
+		t.Fatalf("Expected the RelayState to be dropped, got: %s", body)
+	}
+}
+
+func TestLoginUserReturnTo(t *testing.T) {
+	allowedRedirects := ctrl.Config.AllowedRedirects
+	ctrl.Config.AllowedRedirects = []string{"https://kong:8000/profiles", "/dashboard"}
+	defer func() {
+		ctrl.Config.AllowedRedirects = allowedRedirects
+	}()
+
+	tests := []struct {
+		query  url.Values
+		action string
+	}{
+		{url.Values{"return_to": {"https://kong:8000/profiles/me"}}, "/saml/idp/login?return_to=https%3A%2F%2Fkong%3A8000%2Fprofiles%2Fme\""},
+		{url.Values{"next": {"/dashboard/1"}}, "/saml/idp/login?return_to=%2Fdashboard%2F1\""},
+		{url.Values{"return_to": {"https://evil.com/profiles"}}, "/saml/idp/login\""},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", "http://localhost:8080/saml/idp/login?"+test.query.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rw := httptest.NewRecorder()
+		goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+		loginUserCtx, err := app.NewLoginUserIdpContext(goaCtx, req, goaService)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctrl.LoginUser(loginUserCtx)
+
+		if body := rw.Body.String(); !strings.Contains(body, test.action) {
+			t.Fatalf("%s: expected the form to be posted to %s, got: %s", test.query.Encode(), test.action, body)
+		}
+	}
+}
+
+func TestServeLoginUserReturnTo(t *testing.T) {
+	allowedRedirects := ctrl.Config.AllowedRedirects
+	ctrl.Config.AllowedRedirects = []string{"/dashboard"}
+	defer func() {
+		ctrl.Config.AllowedRedirects = allowedRedirects
+	}()
+
+	req := formRequest(t, "POST", "http://localhost:8080/saml/idp/login", url.Values{"return_to": {"/dashboard/1"}, "csrf_token": {csrfToken}})
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveLoginUserCtx, err := app.NewServeLoginUserIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeLoginUser(serveLoginUserCtx)
+
+	if body := rw.Body.String(); !strings.Contains(body, "/saml/idp/login?return_to=%2Fdashboard%2F1\"") {
+		t.Fatalf("Expected the return_to URL to be kept in the login form, got: %s", body)
+	}
+}
+
+func TestServeLoginTransaction(t *testing.T) {
+	rw := serveSSO(t, newSamlRequestURL("", ""), false)
+	if strings.Contains(rw.Body.String(), `name="SAMLRequest"`) {
+		t.Fatalf("Expected the SAMLRequest not to be sent through the login form, got: %s", rw.Body.String())
+	}
+
+	transactionID := startLoginTransaction(t)
+
+	rw = serveLogin(t, url.Values{"transaction": {transactionID}}, true)
+	if !strings.Contains(rw.Body.String(), `name="transaction" value="`+transactionID+`"`) {
+		t.Fatalf("Expected the login form of the same transaction, got: %s", rw.Body.String())
+	}
+
+	serveLogin(t, url.Values{"transaction": {transactionID}, "cancel": {"true"}}, true)
+
+	rw = serveLogin(t, url.Values{"transaction": {transactionID}}, true)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request page for the finished transaction, got status %d", rw.Code)
+	}
+}
+
+func TestServeLoginPostBinding(t *testing.T) {
+	req, err := http.NewRequest("GET", newSamlRequestURL("", "")+"&email=jon%40test.com&password=test123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveLoginCtx, err := app.NewServeLoginIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeLogin(serveLoginCtx)
+
+	if !strings.Contains(rw.Body.String(), `name="transaction"`) {
+		t.Fatalf("Expected the login form for the AuthnRequest posted by the service provider, got: %s", rw.Body.String())
+	}
+	if strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatal("Expected the credentials posted with the AuthnRequest to be ignored")
+	}
+}
+
+func TestServeLoginCSRF(t *testing.T) {
+	transactionID := startLoginTransaction(t)
+
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "cancel": {"true"}}, false)
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("Expected the cross-site post to be rejected, got status %d", rw.Code)
+	}
+
+	rw = serveLogin(t, url.Values{"transaction": {transactionID}, "cancel": {"true"}, "csrf_token": {"forged"}}, true)
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("Expected the post with a forged token to be rejected, got status %d", rw.Code)
+	}
+
+	// the rejected posts must not end the login
+	rw = serveLogin(t, url.Values{"transaction": {transactionID}}, true)
+	if !strings.Contains(rw.Body.String(), `name="transaction" value="`+transactionID+`"`) {
+		t.Fatalf("Expected the login form of the same transaction, got: %s", rw.Body.String())
+	}
+}
+
+func TestServeLoginUserCSRF(t *testing.T) {
+	req, err := http.NewRequest("POST", "http://localhost:8080/saml/idp/login?email=jon%40test.com&password=test123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveLoginUserCtx, err := app.NewServeLoginUserIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeLoginUser(serveLoginUserCtx)
+
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("Expected the cross-site post to be rejected, got status %d", rw.Code)
+	}
+	for _, cookie := range rw.Result().Cookies() {
+		if cookie.Name == "session" {
+			t.Fatal("Expected no session to be established")
+		}
+	}
+}
+
+// csrfToken is the CSRF token the tests post with the forms
+var csrfToken = base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+// startLoginTransaction runs the ServeSSO action without session and returns the login transaction
+// ID from the login form.
+func startLoginTransaction(t *testing.T) string {
+	rw := serveSSO(t, newSamlRequestURL("", ""), false)
+
+	match := regexp.MustCompile(`name="transaction" value="([^"]+)"`).FindStringSubmatch(rw.Body.String())
+	if match == nil {
+		t.Fatalf("Login transaction not found in: %s", rw.Body.String())
+	}
+
+	return match[1]
+}
+
+// formRequest creates the request with the form values, posted in the body or, for a GET request, in the query
+func formRequest(t *testing.T, method string, requestURL string, form url.Values) *http.Request {
+	if method == "GET" {
+		req, err := http.NewRequest(method, requestURL+"?"+form.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	req, err := http.NewRequest(method, requestURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+// serveLogin runs the ServeLogin action with the form values. If withCSRF is set, the CSRF cookie is
+// sent and the matching token is posted unless the form values already contain one.
+func serveLogin(t *testing.T, form url.Values, withCSRF bool) *httptest.ResponseRecorder {
+	if withCSRF && form.Get("csrf_token") == "" {
+		form.Set("csrf_token", csrfToken)
+	}
+
+	req := formRequest(t, "POST", "http://localhost:8080/saml/idp/sso", form)
+
+	if withCSRF {
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+	}
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveLoginCtx, err := app.NewServeLoginIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeLogin(serveLoginCtx)
+
+	return rw
+}
+
+// withSystemKey writes the system key the requests to the user service are signed with. It returns the
+// function that restores the configuration.
+func withSystemKey(t *testing.T) func() {
+	systemKey, err := ioutil.TempFile("", "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pem.Encode(systemKey, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey))})
+	systemKey.Close()
+
+	configuredKey := ctrl.Config.SystemKey
+	ctrl.Config.SystemKey = systemKey.Name()
+
+	return func() {
+		ctrl.Config.SystemKey = configuredKey
+		os.Remove(systemKey.Name())
+		gock.Off()
+	}
+}
+
+// withUserService makes the user service return the user for the credentials. It returns the function
+// that restores the configuration.
+func withUserService(t *testing.T, user map[string]interface{}) func() {
+	restore := withSystemKey(t)
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find").
+		Reply(200).
+		JSON(user)
+
+	return restore
+}
+
+// recordAudit records the audit events of the controller. It returns the function that restores the auditor.
+func recordAudit(events *[]string) func() {
+	auditor := ctrl.Auditor
+	ctrl.Auditor = jormungandrSamlIdp.AuditorFunc(func(event *jormungandrSamlIdp.AuditEvent) {
+		*events = append(*events, event.Type)
+	})
+
+	return func() {
+		ctrl.Auditor = auditor
+	}
+}
+
+func TestServeLoginAccountErrors(t *testing.T) {
+	cases := []struct {
+		user     map[string]interface{}
+		event    string
+		expected string
+	}{
+		{map[string]interface{}{"active": false}, jormungandrSamlIdp.AuditAccountInactive, "Account not activated"},
+		{map[string]interface{}{"active": true, "locked": true}, jormungandrSamlIdp.AuditAccountLocked, "Account locked"},
+		{map[string]interface{}{"active": true, "passwordExpired": true}, jormungandrSamlIdp.AuditPasswordExpired, "Password expired"},
+	}
+
+	for _, c := range cases {
+		events := []string{}
+		restoreAudit := recordAudit(&events)
+		restoreUserService := withUserService(t, c.user)
+
+		transactionID := startLoginTransaction(t)
+		rw := serveLogin(t, url.Values{"transaction": {transactionID}, "email": {"jon@test.com"}, "password": {"test123"}}, true)
+
+		restoreUserService()
+		restoreAudit()
+
+		body := rw.Body.String()
+		if rw.Code != http.StatusForbidden || !strings.Contains(body, c.expected) {
+			t.Fatalf("Expected the %s page, got %d: %s", c.expected, rw.Code, body)
+		}
+		if !strings.Contains(body, "/saml/idp/sso?transaction="+url.QueryEscape(transactionID)) {
+			t.Fatalf("Expected the link to the login form of the transaction, got: %s", body)
+		}
+		if len(events) != 1 || events[0] != c.event {
+			t.Fatalf("Expected the %s audit event, got %v", c.event, events)
+		}
+	}
+}
+
+func TestServeLoginPasswordExpiredRedirect(t *testing.T) {
+	ctrl.Config.Client["change-password"] = "https://kong:8000/profiles/change-password"
+	defer delete(ctrl.Config.Client, "change-password")
+
+	restoreUserService := withUserService(t, map[string]interface{}{"active": true, "passwordExpired": true})
+	defer restoreUserService()
+
+	transactionID := startLoginTransaction(t)
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "email": {"jon@test.com"}, "password": {"test123"}}, true)
+
+	expected := "https://kong:8000/profiles/change-password?return_to=" + url.QueryEscape("http://localhost:8080/saml/idp/sso?transaction="+url.QueryEscape(transactionID))
+	if rw.Code != http.StatusFound || rw.Header().Get("Location") != expected {
+		t.Fatalf("Expected redirect to %s, got %d %s", expected, rw.Code, rw.Header().Get("Location"))
+	}
+}
+
+func TestServeLoginUserWrongCredentials(t *testing.T) {
+	events := []string{}
+	defer recordAudit(&events)()
+
+	req := formRequest(t, "POST", "http://localhost:8080/saml/idp/login", url.Values{"email": {"jon@test.com"}, "password": {"test123"}, "csrf_token": {csrfToken}})
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveLoginUserCtx, err := app.NewServeLoginUserIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeLoginUser(serveLoginUserCtx)
+
+	if !strings.Contains(rw.Body.String(), "Wrong email or password!") {
+		t.Fatalf("Expected the login form with the generic failure, got: %s", rw.Body.String())
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditLoginFailed {
+		t.Fatalf("Expected the %s audit event, got %v", jormungandrSamlIdp.AuditLoginFailed, events)
+	}
+}
+
+// postForm runs the action with the form posted together with the CSRF token
+func postForm(t *testing.T, path string, form url.Values, action func(goaCtx context.Context, req *http.Request)) *httptest.ResponseRecorder {
+	form.Set("csrf_token", csrfToken)
+	req := formRequest(t, "POST", "http://localhost:8080"+path, form)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+
+	rw := httptest.NewRecorder()
+	action(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req)
+
+	return rw
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctrl.Config.PasswordReset.Enabled = true
+	defer func() {
+		ctrl.Config.PasswordReset.Enabled = false
+	}()
+	defer withSystemKey(t)()
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	repository.AddSession(&db.Session{Session: saml.Session{ID: "reset-session", UserName: "59804b3c0000000000000000", ExpireTime: saml.TimeNow().Add(time.Hour)}})
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com", "active": true})
+
+	requestReset := func(goaCtx context.Context, req *http.Request) {
+		resetCtx, err := app.NewRequestPasswordResetIdpContext(goaCtx, req, goaService)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctrl.RequestPasswordReset(resetCtx)
+	}
+	rw := postForm(t, "/saml/idp/password-reset", url.Values{"email": {"jon@test.com"}}, requestReset)
+	if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), "we have sent you a link") {
+		t.Fatalf("Expected the password reset sent page, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	message := mailSink.Last()
+	if message == nil || message.To != "jon@test.com" {
+		t.Fatalf("Expected the password reset email, got %v", message)
+	}
+	match := regexp.MustCompile(`http://kong:8000/saml/idp/password-reset/confirm\?token=(\S+)`).FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("Expected the password reset link in the email, got: %s", message.Body)
+	}
+	token, _ := url.QueryUnescape(match[1])
+
+	// the link of an earlier request the user has not used
+	olderToken, err := jormungandrSamlIdp.StartPasswordReset(repository, ctrl.IDP, "59804b3c0000000000000000", "jon@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/saml/idp/password-reset/confirm?token="+url.QueryEscape(token), nil)
+	rw = httptest.NewRecorder()
+	newPasswordCtx, err := app.NewNewPasswordFormIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.NewPasswordForm(newPasswordCtx)
+	if !strings.Contains(rw.Body.String(), `name="token" value="`+html.EscapeString(token)+`"`) {
+		t.Fatalf("Expected the new password form, got: %s", rw.Body.String())
+	}
+
+	resetPassword := func(goaCtx context.Context, req *http.Request) {
+		resetCtx, err := app.NewResetPasswordIdpContext(goaCtx, req, goaService)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctrl.ResetPassword(resetCtx)
+	}
+	rw = postForm(t, "/saml/idp/password-reset/confirm", url.Values{"token": {token}, "password": {"new-secret"}, "confirm": {"other-secret"}}, resetPassword)
+	if !strings.Contains(rw.Body.String(), "The passwords do not match.") {
+		t.Fatalf("Expected the new password form with the mismatch, got: %s", rw.Body.String())
+	}
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Put("/59804b3c0000000000000000/password").
+		Reply(200)
+
+	rw = postForm(t, "/saml/idp/password-reset/confirm", url.Values{"token": {token}, "password": {"new-secret"}, "confirm": {"new-secret"}}, resetPassword)
+	if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), "Your password has been changed") {
+		t.Fatalf("Expected the password changed page, got %d: %s", rw.Code, rw.Body.String())
+	}
+	if !gock.IsDone() {
+		t.Fatal("Expected the password to be updated in the user service")
+	}
+
+	sessionReq, _ := http.NewRequest("GET", "http://localhost:8080/saml/idp/sso", nil)
+	sessionReq.AddCookie(&http.Cookie{Name: "session", Value: "reset-session"})
+	if session, _ := repository.GetSession(nil, sessionReq, nil); session != nil {
+		t.Fatal("Expected the sessions of the user to be revoked")
+	}
+
+	rw = postForm(t, "/saml/idp/password-reset/confirm", url.Values{"token": {token}, "password": {"new-secret"}, "confirm": {"new-secret"}}, resetPassword)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the used link to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	rw = postForm(t, "/saml/idp/password-reset/confirm", url.Values{"token": {olderToken}, "password": {"other-secret"}, "confirm": {"other-secret"}}, resetPassword)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the older link to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	expected := []string{jormungandrSamlIdp.AuditPasswordResetRequested, jormungandrSamlIdp.AuditPasswordReset}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the audit events %v, got %v", expected, events)
+	}
+}
+
+func TestPasswordResetLimit(t *testing.T) {
+	ctrl.Config.PasswordReset.Enabled = true
+	limiter := ctrl.PasswordResetLimiter
+	ctrl.PasswordResetLimiter = jormungandrSamlIdp.NewRateLimiter(1, time.Hour)
+	defer func() {
+		ctrl.Config.PasswordReset.Enabled = false
+		ctrl.PasswordResetLimiter = limiter
+	}()
+
+	requestReset := func(goaCtx context.Context, req *http.Request) {
+		resetCtx, err := app.NewRequestPasswordResetIdpContext(goaCtx, req, goaService)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctrl.RequestPasswordReset(resetCtx)
+	}
+
+	// the user service is not configured, so no link is sent, but the requests are still counted
+	if rw := postForm(t, "/saml/idp/password-reset", url.Values{"email": {"ratelimit@test.com"}}, requestReset); !strings.Contains(rw.Body.String(), "we have sent you a link") {
+		t.Fatalf("Expected the password reset sent page, got: %s", rw.Body.String())
+	}
+
+	if rw := postForm(t, "/saml/idp/password-reset", url.Values{"email": {"RateLimit@test.com"}}, requestReset); !strings.Contains(rw.Body.String(), "Too many password resets were requested.") {
+		t.Fatalf("Expected the password reset form with the rate limit error, got: %s", rw.Body.String())
+	}
+}
+
+func TestPasswordResetDisabled(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://localhost:8080/saml/idp/password-reset", nil)
+	rw := httptest.NewRecorder()
+	resetCtx, err := app.NewPasswordResetFormIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.PasswordResetForm(resetCtx)
+
+	if rw.Code != http.StatusNotFound {
+		t.Fatalf("Expected the password reset to be disabled, got %d", rw.Code)
+	}
+}
+
+// withMagicLink enables the sign-in links for the test service provider. It returns the function that
+// restores the configuration.
+func withMagicLink() func() {
+	serviceProviders := ctrl.Config.ServiceProviders
+	ctrl.Config.ServiceProviders = map[string]*config.ServiceProviderConfig{
+		"https://localhost:8082/user-profile/saml/metadata": {MagicLink: true},
+	}
+
+	return func() {
+		ctrl.Config.ServiceProviders = serviceProviders
+	}
+}
+
+// magicLinkForm runs the MagicLinkForm action with the sign-in token
+func magicLinkForm(t *testing.T, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "http://localhost:8080/saml/idp/magic-link?token="+url.QueryEscape(token), nil)
+	rw := httptest.NewRecorder()
+	magicLinkCtx, err := app.NewMagicLinkFormIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.MagicLinkForm(magicLinkCtx)
+
+	return rw
+}
+
+// magicLinkLogin runs the MagicLinkLogin action with the sign-in token posted from the confirmation page
+func magicLinkLogin(t *testing.T, token string) *httptest.ResponseRecorder {
+	return postForm(t, "/saml/idp/magic-link", url.Values{"token": {token}}, func(goaCtx context.Context, req *http.Request) {
+		magicLinkCtx, err := app.NewMagicLinkLoginIdpContext(goaCtx, req, goaService)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctrl.MagicLinkLogin(magicLinkCtx)
+	})
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	defer withMagicLink()()
+	defer withSystemKey(t)()
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find/email").
+		Times(2).
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com", "roles": []string{"user"}, "active": true})
+
+	rw := serveSSO(t, newSamlRequestURL("", ""), false)
+	if !strings.Contains(rw.Body.String(), `name="magic-link"`) {
+		t.Fatalf("Expected the sign-in link button on the login form, got: %s", rw.Body.String())
+	}
+	transactionID := startLoginTransaction(t)
+
+	rw = serveLogin(t, url.Values{"transaction": {transactionID}, "email": {"jon@test.com"}, "magic-link": {"true"}}, true)
+	if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), "we have sent you a link to sign in") {
+		t.Fatalf("Expected the sign-in link sent page, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	message := mailSink.Last()
+	if message == nil || message.To != "jon@test.com" {
+		t.Fatalf("Expected the sign-in link email, got %v", message)
+	}
+	match := regexp.MustCompile(`http://kong:8000/saml/idp/magic-link\?token=(\S+)`).FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("Expected the sign-in link in the email, got: %s", message.Body)
+	}
+	token, _ := url.QueryUnescape(match[1])
+
+	// opening the link only shows the confirmation, so the link previews do not use it up
+	for i := 0; i < 2; i++ {
+		rw = magicLinkForm(t, token)
+		if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), "Continue to sign in as jon@test.com.") {
+			t.Fatalf("Expected the sign-in confirmation page, got %d: %s", rw.Code, rw.Body.String())
+		}
+		if !strings.Contains(rw.Body.String(), `name="csrf_token"`) {
+			t.Fatalf("Expected the CSRF token on the confirmation page, got: %s", rw.Body.String())
+		}
+	}
+
+	rw = magicLinkLogin(t, token)
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected the successful response, got: %s", response)
+	}
+
+	sessionReq := &http.Request{Header: http.Header{"Cookie": rw.Header()["Set-Cookie"]}}
+	session, _ := repository.GetSession(nil, sessionReq, nil)
+	if session == nil || session.UserEmail != "jon@test.com" || strings.Join(session.AuthnMethods, ",") != jormungandrSamlIdp.AuthnMethodMagicLink {
+		t.Fatalf("Expected the session of the user authenticated with the sign-in link, got %+v", session)
+	}
+
+	if rw = magicLinkLogin(t, token); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the used link to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+	if rw = magicLinkForm(t, token); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the confirmation of the used link to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	expected := []string{jormungandrSamlIdp.AuditMagicLinkRequested, jormungandrSamlIdp.AuditLoginSucceeded}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the audit events %v, got %v", expected, events)
+	}
+}
+
+func TestMagicLinkLimit(t *testing.T) {
+	defer withMagicLink()()
+
+	limiter := ctrl.MagicLinkLimiter
+	ctrl.MagicLinkLimiter = jormungandrSamlIdp.NewRateLimiter(1, time.Hour)
+	defer func() {
+		ctrl.MagicLinkLimiter = limiter
+	}()
+
+	// the user service is not configured, so no link is sent, but the requests are still counted
+	form := url.Values{"transaction": {startLoginTransaction(t)}, "email": {"ratelimit@test.com"}, "magic-link": {"true"}}
+	if rw := serveLogin(t, form, true); !strings.Contains(rw.Body.String(), "we have sent you a link to sign in") {
+		t.Fatalf("Expected the sign-in link sent page, got: %s", rw.Body.String())
+	}
+
+	form.Set("transaction", startLoginTransaction(t))
+	if rw := serveLogin(t, form, true); !strings.Contains(rw.Body.String(), "Too many sign-in links were requested.") {
+		t.Fatalf("Expected the login form with the rate limit error, got: %s", rw.Body.String())
+	}
+}
+
+func TestMagicLinkDisabled(t *testing.T) {
+	transactionID := startLoginTransaction(t)
+
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "email": {"jon@test.com"}, "magic-link": {"true"}}, true)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the sign-in links to be disabled, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	if rw := magicLinkForm(t, "not-a-token"); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the invalid link to be rejected, got %d", rw.Code)
+	}
+	if rw := magicLinkLogin(t, "not-a-token"); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the invalid link to be rejected, got %d", rw.Code)
+	}
+}
+
+func TestMagicLinkLoginWithoutCSRF(t *testing.T) {
+	req := formRequest(t, "POST", "http://localhost:8080/saml/idp/magic-link", url.Values{"token": {"not-a-token"}})
+	rw := httptest.NewRecorder()
+	magicLinkCtx, err := app.NewMagicLinkLoginIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.MagicLinkLogin(magicLinkCtx)
+
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("Expected the sign-in without the CSRF token to be rejected, got %d", rw.Code)
+	}
+}
+
+// upstreamSessions logs in every user of the test upstream identity provider
+type upstreamSessions struct{}
+
+func (upstreamSessions) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *saml.Session {
+	return &saml.Session{
+		ID:             "upstream-session",
+		CreateTime:     saml.TimeNow(),
+		ExpireTime:     saml.TimeNow().Add(time.Hour),
+		Index:          "1",
+		NameID:         "jane",
+		UserEmail:      "Jane@Upstream.example.com",
+		UserCommonName: "Jane Doe",
+		Groups:         []string{"staff"},
+	}
+}
+
+// upstreamServiceProviders makes the test upstream identity provider trust the IdP
+type upstreamServiceProviders struct{}
+
+func (upstreamServiceProviders) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	metadata := jormungandrSamlIdp.NewUpstreamServiceProvider(ctrl.Config, ctrl.IDP).Metadata()
+	if serviceProviderID != metadata.EntityID {
+		return nil, os.ErrNotExist
+	}
+
+	return metadata, nil
+}
+
+// withUpstream configures the in-process upstream identity provider "corp" for the users in the
+// upstream.example.com domain. It returns the upstream IdP and the function that restores the configuration.
+func withUpstream(t *testing.T, rules *config.ProvisioningConfig) (*saml.IdentityProvider, func()) {
+	// the upstream signs with a fresh certificate, the signatures are verified against the validity of the certificate
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "upstream.example.com"},
+		NotBefore:    saml.TimeNow().Add(-time.Hour),
+		NotAfter:     saml.TimeNow().Add(time.Hour),
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.(*rsa.PrivateKey).Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreamCert, _ := x509.ParseCertificate(certBytes)
+
+	metadataURL, _ := url.Parse("http://upstream.example.com/saml/metadata")
+	ssoURL, _ := url.Parse("http://upstream.example.com/saml/sso")
+	upstreamIdP := &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             upstreamCert,
+		Logger:                  logger.DefaultLogger,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		SessionProvider:         upstreamSessions{},
+		ServiceProviderProvider: upstreamServiceProviders{},
+	}
+
+	metadataFile, err := ioutil.TempFile("", "upstream-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml.NewEncoder(metadataFile).Encode(upstreamIdP.Metadata())
+	metadataFile.Close()
+
+	ctrl.Config.Upstreams = map[string]*config.UpstreamConfig{
+		"corp": {
+			DisplayName:  "Corp",
+			MetadataFile: metadataFile.Name(),
+			Domains:      []string{"upstream.example.com"},
+			Attributes:   map[string]string{"email": "eduPersonPrincipalName", "fullname": "cn", "roles": "eduPersonAffiliation"},
+			Roles:        []string{"user"},
+			AllowedRoles: []string{"staff"},
+			Provisioning: rules,
+		},
+	}
+	upstreams, err := jormungandrSamlIdp.NewUpstreams(ctrl.Config, ctrl.IDP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.Upstreams = upstreams
+	ctrl.Templates.Upstreams = upstreams
+
+	return upstreamIdP, func() {
+		ctrl.Config.Upstreams = nil
+		ctrl.Upstreams = nil
+		ctrl.Templates.Upstreams = nil
+		os.Remove(metadataFile.Name())
+	}
+}
+
+// upstreamACS posts the response of the upstream identity provider to the UpstreamACS action
+func upstreamACS(t *testing.T, form url.Values) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "http://kong:8000/saml/idp/upstream/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rw := httptest.NewRecorder()
+	acsCtx, err := app.NewUpstreamACSIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.UpstreamACS(acsCtx)
+
+	return rw
+}
+
+func TestUpstreamLogin(t *testing.T) {
+	upstreamIdP, restore := withUpstream(t, nil)
+	defer restore()
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	rw := serveSSO(t, newSamlRequestURL("", ""), false)
+	if !strings.Contains(rw.Body.String(), `name="upstream" value="corp"`) || !strings.Contains(rw.Body.String(), "Sign In with Corp") {
+		t.Fatalf("Expected the upstream button on the login form, got: %s", rw.Body.String())
+	}
+	transactionID := startLoginTransaction(t)
+
+	rw = serveLogin(t, url.Values{"transaction": {transactionID}, "upstream": {"corp"}}, true)
+	location := rw.Header().Get("Location")
+	if rw.Code != http.StatusFound || !strings.HasPrefix(location, "http://upstream.example.com/saml/sso?") {
+		t.Fatalf("Expected redirect to the upstream IdP, got %d %s", rw.Code, location)
+	}
+
+	// the upstream IdP logs the user in and posts the response back through the browser
+	upstreamReq, _ := http.NewRequest("GET", location, nil)
+	upstreamRw := httptest.NewRecorder()
+	upstreamIdP.ServeSSO(upstreamRw, upstreamReq)
+
+	relayState := regexp.MustCompile(`name="RelayState" value="([^"]*)"`).FindStringSubmatch(upstreamRw.Body.String())
+	if relayState == nil || html.UnescapeString(relayState[1]) != transactionID {
+		t.Fatalf("Expected the transaction as RelayState of the upstream response, got: %s", upstreamRw.Body.String())
+	}
+	form := url.Values{
+		"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(samlResponse(t, upstreamRw)))},
+		"RelayState":   {transactionID},
+	}
+
+	rw = upstreamACS(t, form)
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected the successful response, got: %s", response)
+	}
+
+	sessionReq := &http.Request{Header: http.Header{"Cookie": rw.Header()["Set-Cookie"]}}
+	session, _ := repository.GetSession(nil, sessionReq, nil)
+	if session == nil || session.UserEmail != "jane@upstream.example.com" || session.UserName != "corp:jane" ||
+		strings.Join(session.Groups, ",") != "user,staff" || strings.Join(session.AuthnMethods, ",") != jormungandrSamlIdp.AuthnMethodUpstream {
+		t.Fatalf("Expected the session of the upstream user, got %+v", session)
+	}
+
+	if rw = upstreamACS(t, form); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the used response to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	expected := []string{jormungandrSamlIdp.AuditUpstreamLoginStarted, jormungandrSamlIdp.AuditLoginSucceeded}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the audit events %v, got %v", expected, events)
+	}
+}
+
+func TestUpstreamLoginProvision(t *testing.T) {
+	upstreamIdP, restore := withUpstream(t, &config.ProvisioningConfig{
+		Create:     true,
+		Attributes: map[string]string{"roles": "eduPersonAffiliation"},
+	})
+	defer restore()
+	defer withSystemKey(t)()
+	defer repository.DeleteUserLink("upstream:corp:jane")
+
+	// the emails of the upstream are trusted in the domains of the upstream
+	if domains := ctrl.Upstreams[0].Provisioning.Domains; strings.Join(domains, ",") != "upstream.example.com" {
+		t.Fatalf("Expected the provisioning domains of the upstream, got %v", domains)
+	}
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(404).
+		BodyString("user not found")
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("").
+		JSON(map[string]interface{}{"email": "jane@upstream.example.com", "fullname": "Jane Doe", "roles": []string{"user", "staff"}, "active": true, "externalId": "upstream:corp:jane"}).
+		Reply(201).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000001", "email": "jane@upstream.example.com", "roles": []string{"user", "staff"}, "active": true})
+
+	transactionID := startLoginTransaction(t)
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "upstream": {"corp"}}, true)
+
+	upstreamReq, _ := http.NewRequest("GET", rw.Header().Get("Location"), nil)
+	upstreamRw := httptest.NewRecorder()
+	upstreamIdP.ServeSSO(upstreamRw, upstreamReq)
+
+	rw = upstreamACS(t, url.Values{
+		"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(samlResponse(t, upstreamRw)))},
+		"RelayState":   {transactionID},
+	})
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected the successful response, got: %s", response)
+	}
+
+	sessionReq := &http.Request{Header: http.Header{"Cookie": rw.Header()["Set-Cookie"]}}
+	if session, _ := repository.GetSession(nil, sessionReq, nil); session == nil || session.UserName != "59804b3c0000000000000001" {
+		t.Fatalf("Expected the session of the provisioned user, got %+v", session)
+	}
+	if link, err := repository.GetUserLink("upstream:corp:jane"); err != nil || link.UserID != "59804b3c0000000000000001" || !link.Provisioned {
+		t.Fatalf("Expected the link to the provisioned user, got %+v %v", link, err)
+	}
+}
+
+func TestUpstreamLoginDomainHint(t *testing.T) {
+	_, restore := withUpstream(t, nil)
+	defer restore()
+
+	transactionID := startLoginTransaction(t)
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "email": {"jane@UPSTREAM.example.com"}}, true)
+	if rw.Code != http.StatusFound || !strings.HasPrefix(rw.Header().Get("Location"), "http://upstream.example.com/saml/sso?") {
+		t.Fatalf("Expected redirect to the upstream IdP of the email domain, got %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+}
+
+func TestUpstreamLoginFailed(t *testing.T) {
+	_, restore := withUpstream(t, nil)
+	defer restore()
+
+	transactionID := startLoginTransaction(t)
+	serveLogin(t, url.Values{"transaction": {transactionID}, "upstream": {"corp"}}, true)
+
+	rw := upstreamACS(t, url.Values{"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte("<Response/>"))}, "RelayState": {transactionID}})
+	if response := samlResponse(t, rw); !strings.Contains(response, jormungandrSamlIdp.StatusAuthnFailed) {
+		t.Fatalf("Expected the AuthnFailed response, got: %s", response)
+	}
+}
+
+func TestUpstreamLoginNotConfigured(t *testing.T) {
+	transactionID := startLoginTransaction(t)
+
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "upstream": {"corp"}}, true)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the upstream login to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+}
+
+func TestUpstreamMetadata(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://kong:8000/saml/idp/upstream/metadata", nil)
+	rw := httptest.NewRecorder()
+	metadataCtx, err := app.NewUpstreamMetadataIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctrl.UpstreamMetadata(metadataCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(rw.Body.Bytes(), metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.EntityID != "http://kong:8000/saml/idp/upstream/metadata" || len(metadata.SPSSODescriptors) != 1 ||
+		metadata.SPSSODescriptors[0].AssertionConsumerServices[0].Location != "http://kong:8000/saml/idp/upstream/acs" {
+		t.Fatalf("Unexpected upstream service provider metadata %+v", metadata)
+	}
+}
+
+// withSocialProvider configures the mock OpenID Connect provider as the social login provider "mock". It returns
+// the provider and the function that restores the configuration.
+func withSocialProvider(t *testing.T, rules *config.ProvisioningConfig) (*oidctest.Server, func()) {
+	server := oidctest.NewServer("idp-client")
+
+	provider, err := oidc.NewProvider("mock", &config.SocialProviderConfig{
+		DisplayName:  "Mock",
+		ClientID:     "idp-client",
+		Issuer:       server.URL,
+		Provisioning: rules,
+	}, "http://kong:8000/saml/idp/social/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Client = server.Client()
+
+	ctrl.SocialProviders = []*oidc.Provider{provider}
+	ctrl.Templates.SocialProviders = ctrl.SocialProviders
+
+	return server, func() {
+		ctrl.SocialProviders = nil
+		ctrl.Templates.SocialProviders = nil
+		server.Close()
+	}
+}
+
+// startSocialLogin chooses the social login provider on the login form of a new login transaction and returns
+// the parameters of the authorization request the user is sent to
+func startSocialLogin(t *testing.T, server *oidctest.Server) url.Values {
+	rw := serveLogin(t, url.Values{"transaction": {startLoginTransaction(t)}, "social": {"mock"}}, true)
+	location, err := url.Parse(rw.Header().Get("Location"))
+	if rw.Code != http.StatusFound || err != nil || !strings.HasPrefix(location.String(), server.URL+"/authorize?") {
+		t.Fatalf("Expected redirect to the social login provider, got %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+
+	return location.Query()
+}
+
+// socialCallback runs the SocialCallback action with the parameters the provider sends the user back with
+func socialCallback(t *testing.T, params url.Values) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "http://kong:8000/saml/idp/social/callback?"+params.Encode(), nil)
+	rw := httptest.NewRecorder()
+	callbackCtx, err := app.NewSocialCallbackIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.SocialCallback(callbackCtx)
+
+	return rw
+}
+
+func TestSocialLogin(t *testing.T) {
+	server, restore := withSocialProvider(t, nil)
+	defer restore()
+	defer withSystemKey(t)()
+	defer repository.DeleteUserLink("social:mock:1234")
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find/email").
+		JSON(map[string]interface{}{"email": "jon@test.com"}).
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com", "roles": []string{"user"}, "active": true})
+
+	rw := serveSSO(t, newSamlRequestURL("", ""), false)
+	if !strings.Contains(rw.Body.String(), `name="social" value="mock"`) || !strings.Contains(rw.Body.String(), "Continue with Mock") {
+		t.Fatalf("Expected the social login button on the login form, got: %s", rw.Body.String())
+	}
+
+	authRequest := startSocialLogin(t, server)
+	server.Claims = map[string]interface{}{"sub": "1234", "email": "Jon@Test.com", "email_verified": true, "nonce": authRequest.Get("nonce")}
+
+	callback := url.Values{"code": {server.Code}, "state": {authRequest.Get("state")}}
+	rw = socialCallback(t, callback)
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected the successful response, got: %s", response)
+	}
+
+	verifier := server.TokenRequest().Get("code_verifier")
+	challenge := sha256.Sum256([]byte(verifier))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != authRequest.Get("code_challenge") {
+		t.Fatalf("Expected the code verifier of the challenge, got %s", verifier)
+	}
+
+	sessionReq := &http.Request{Header: http.Header{"Cookie": rw.Header()["Set-Cookie"]}}
+	session, _ := repository.GetSession(nil, sessionReq, nil)
+	if session == nil || session.UserEmail != "jon@test.com" || session.UserName != "59804b3c0000000000000000" ||
+		strings.Join(session.AuthnMethods, ",") != jormungandrSamlIdp.AuthnMethodSocial {
+		t.Fatalf("Expected the session of the linked user, got %+v", session)
+	}
+
+	if link, err := repository.GetUserLink("social:mock:1234"); err != nil || link.UserID != "59804b3c0000000000000000" || link.Provisioned {
+		t.Fatalf("Expected the link to the user with the email, got %+v %v", link, err)
+	}
+
+	if rw = socialCallback(t, callback); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the used code to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	expected := []string{jormungandrSamlIdp.AuditSocialLoginStarted, jormungandrSamlIdp.AuditUserLinked, jormungandrSamlIdp.AuditLoginSucceeded}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the audit events %v, got %v", expected, events)
+	}
+}
+
+func TestSocialLoginProvision(t *testing.T) {
+	server, restore := withSocialProvider(t, &config.ProvisioningConfig{Create: true})
+	defer restore()
+	defer withSystemKey(t)()
+	defer repository.DeleteUserLink("social:mock:1234")
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(404).
+		BodyString("user not found")
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("").
+		JSON(map[string]interface{}{"email": "jane@test.com", "fullname": "Jane Doe", "roles": []string{"user"}, "active": true, "externalId": "social:mock:1234"}).
+		Reply(201).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000001", "email": "jane@test.com", "roles": []string{"user"}, "active": true})
+
+	authRequest := startSocialLogin(t, server)
+	server.Claims = map[string]interface{}{"sub": "1234", "email": "jane@test.com", "email_verified": true, "name": "Jane Doe", "nonce": authRequest.Get("nonce")}
+
+	rw := socialCallback(t, url.Values{"code": {server.Code}, "state": {authRequest.Get("state")}})
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected the successful response, got: %s", response)
+	}
+
+	sessionReq := &http.Request{Header: http.Header{"Cookie": rw.Header()["Set-Cookie"]}}
+	if session, _ := repository.GetSession(nil, sessionReq, nil); session == nil || session.UserName != "59804b3c0000000000000001" {
+		t.Fatalf("Expected the session of the provisioned user, got %+v", session)
+	}
+	if link, err := repository.GetUserLink("social:mock:1234"); err != nil || link.UserID != "59804b3c0000000000000001" || !link.Provisioned {
+		t.Fatalf("Expected the link to the provisioned user, got %+v %v", link, err)
+	}
+
+	expected := []string{jormungandrSamlIdp.AuditSocialLoginStarted, jormungandrSamlIdp.AuditUserProvisioned, jormungandrSamlIdp.AuditLoginSucceeded}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the audit events %v, got %v", expected, events)
+	}
+}
+
+func TestSocialLoginLinked(t *testing.T) {
+	server, restore := withSocialProvider(t, nil)
+	defer restore()
+	defer withSystemKey(t)()
+
+	lastLogin := saml.TimeNow().Add(-24 * time.Hour)
+	repository.AddUserLink(&db.UserLink{ID: "social:mock:1234", Source: "social:mock", Subject: "1234", UserID: "59804b3c0000000000000000", LastLoginTime: lastLogin})
+	defer repository.DeleteUserLink("social:mock:1234")
+
+	// the linked user is found by the link, even though the email at the provider has changed
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Get("/59804b3c0000000000000000").
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com", "roles": []string{"user"}, "active": true})
+
+	authRequest := startSocialLogin(t, server)
+	server.Claims = map[string]interface{}{"sub": "1234", "email": "jon@other.com", "nonce": authRequest.Get("nonce")}
+
+	rw := socialCallback(t, url.Values{"code": {server.Code}, "state": {authRequest.Get("state")}})
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected the successful response, got: %s", response)
+	}
+
+	sessionReq := &http.Request{Header: http.Header{"Cookie": rw.Header()["Set-Cookie"]}}
+	if session, _ := repository.GetSession(nil, sessionReq, nil); session == nil || session.UserEmail != "jon@test.com" || session.UserName != "59804b3c0000000000000000" {
+		t.Fatalf("Expected the session of the linked user, got %+v", session)
+	}
+	if link, _ := repository.GetUserLink("social:mock:1234"); link == nil || !link.LastLoginTime.After(lastLogin) {
+		t.Fatalf("Expected the last login time of the link to be updated, got %+v", link)
+	}
+}
+
+func TestSocialLoginDomainNotAllowed(t *testing.T) {
+	server, restore := withSocialProvider(t, &config.ProvisioningConfig{Create: true, Domains: []string{"example.com"}})
+	defer restore()
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	authRequest := startSocialLogin(t, server)
+	server.Claims = map[string]interface{}{"sub": "1234", "email": "jon@test.com", "email_verified": true, "nonce": authRequest.Get("nonce")}
+
+	rw := socialCallback(t, url.Values{"code": {server.Code}, "state": {authRequest.Get("state")}})
+	if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), "Your Mock account cannot be used to sign in here.") {
+		t.Fatalf("Expected the login form with the error, got %d: %s", rw.Code, rw.Body.String())
+	}
+	if _, err := repository.GetUserLink("social:mock:1234"); err == nil {
+		t.Fatal("Expected no link to be saved")
+	}
+
+	expected := []string{jormungandrSamlIdp.AuditSocialLoginStarted, jormungandrSamlIdp.AuditProvisioningFailed}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the audit events %v, got %v", expected, events)
+	}
+}
+
+func TestSocialLoginErrors(t *testing.T) {
+	server, restore := withSocialProvider(t, nil)
+	defer restore()
+	defer withSystemKey(t)()
+
+	gock.New(ctrl.Config.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(404).
+		BodyString("user not found")
+
+	cases := []struct {
+		name     string
+		claims   map[string]interface{}
+		params   url.Values
+		expected string
+	}{
+		{"denied", nil, url.Values{"error": {"access_denied"}}, "The sign-in with Mock failed."},
+		{"invalid ID token", map[string]interface{}{"sub": "1234"}, url.Values{"code": {server.Code}}, "The sign-in with Mock failed."},
+		{"email not verified", map[string]interface{}{"sub": "1234", "email": "jon@test.com"}, url.Values{"code": {server.Code}}, "The email of your Mock account is not verified."},
+		{"no account", map[string]interface{}{"sub": "1234", "email": "jon@test.com", "email_verified": true}, url.Values{"code": {server.Code}}, "There is no account with the email of your Mock account."},
+	}
+
+	for _, c := range cases {
+		authRequest := startSocialLogin(t, server)
+		if c.claims != nil {
+			server.Claims = c.claims
+			if c.name != "invalid ID token" {
+				server.Claims["nonce"] = authRequest.Get("nonce")
+			}
+		}
+		c.params.Set("state", authRequest.Get("state"))
+
+		rw := socialCallback(t, c.params)
+		if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), html.EscapeString(c.expected)) || !strings.Contains(rw.Body.String(), `name="transaction"`) {
+			t.Fatalf("%s: expected the login form with %q, got %d: %s", c.name, c.expected, rw.Code, rw.Body.String())
+		}
+	}
+}
+
+func TestSocialLoginInvalidState(t *testing.T) {
+	server, restore := withSocialProvider(t, nil)
+	defer restore()
+
+	authRequest := startSocialLogin(t, server)
+	state := authRequest.Get("state")
+
+	for _, invalid := range []string{"", "no-transaction", state[:strings.LastIndex(state, ".")] + ".other"} {
+		if rw := socialCallback(t, url.Values{"code": {server.Code}, "state": {invalid}}); rw.Code != http.StatusBadRequest {
+			t.Fatalf("Expected the state %q to be rejected, got %d: %s", invalid, rw.Code, rw.Body.String())
+		}
+	}
+}
+
+func TestSocialLoginNotConfigured(t *testing.T) {
+	rw := serveLogin(t, url.Values{"transaction": {startLoginTransaction(t)}, "social": {"mock"}}, true)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the social login to be rejected, got %d: %s", rw.Code, rw.Body.String())
+	}
+}
+
+// withConsent enables the consent to the release of the attributes. It returns the function that disables it
+// and removes the consents of the test session.
+func withConsent() func() {
+	ctrl.Config.Consent.Enabled = true
+
+	return func() {
+		ctrl.Config.Consent.Enabled = false
+		repository.DeleteConsent(db.ConsentID("59ce17c60000000000000000", "https://localhost:8082/user-profile/saml/metadata"))
+	}
+}
+
+// consentRequest creates the request to the consent pages with the test session cookie and the CSRF token.
+func consentRequest(t *testing.T, method string, requestURL string, form url.Values) *http.Request {
+	form.Set("csrf_token", csrfToken)
+
+	req := formRequest(t, method, requestURL, form)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU="})
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+
+	return req
+}
+
+// serveConsent runs the Consent action with the decision of the user for the login transaction.
+func serveConsent(t *testing.T, transactionID string, decision string) *httptest.ResponseRecorder {
+	req := consentRequest(t, "POST", "http://localhost:8080/saml/idp/consent", url.Values{"transaction": {transactionID}, "consent": {decision}})
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	consentCtx, err := app.NewConsentIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.Consent(consentCtx)
+
+	return rw
+}
+
+// consentTransaction runs the ServeSSO action with the test session and returns the login transaction of the
+// consent form.
+func consentTransaction(t *testing.T) string {
+	rw := serveSSO(t, newSamlRequestURL("", ""), true)
+
+	body := rw.Body.String()
+	if !strings.Contains(body, `action="http://kong:8000/saml/idp/consent"`) || !strings.Contains(body, "example@host.com") {
+		t.Fatalf("Expected the consent form with the attributes, got: %s", body)
+	}
+
+	match := regexp.MustCompile(`name="transaction" value="([^"]+)"`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("Login transaction not found in: %s", body)
+	}
+
+	return match[1]
+}
+
+func TestConsentAccept(t *testing.T) {
+	defer withConsent()()
+	events := []string{}
+	defer recordAudit(&events)()
+
+	rw := serveConsent(t, consentTransaction(t), "accept")
+	if strings.Contains(samlResponse(t, rw), "RequestDenied") {
+		t.Fatalf("Expected successful response after the consent, got: %s", samlResponse(t, rw))
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditConsentGiven {
+		t.Fatalf("Expected the consent.given event, got %v", events)
+	}
+
+	// the consent is not asked for again
+	rw = serveSSO(t, newSamlRequestURL("", ""), true)
+	if !strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatalf("Expected SAML response for the consented attributes, got: %s", rw.Body.String())
+	}
+}
+
+func TestConsentDeny(t *testing.T) {
+	defer withConsent()()
+	transactionID := consentTransaction(t)
+
+	rw := serveConsent(t, transactionID, "deny")
+	if response := samlResponse(t, rw); !strings.Contains(response, jormungandrSamlIdp.StatusRequestDenied) {
+		t.Fatalf("Expected RequestDenied status response, got: %s", response)
+	}
+	if _, err := repository.GetConsent(db.ConsentID("59ce17c60000000000000000", "https://localhost:8082/user-profile/saml/metadata")); err == nil {
+		t.Fatal("Expected no consent to be saved")
+	}
+
+	// the transaction is ended
+	if rw := serveConsent(t, transactionID, "accept"); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request for the ended transaction, got %d", rw.Code)
+	}
+}
+
+func TestConsentNotRequested(t *testing.T) {
+	transactionID := startLoginTransaction(t)
+
+	if rw := serveConsent(t, transactionID, "accept"); rw.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request for the transaction without consent, got %d", rw.Code)
+	}
+}
+
+func TestConsentInternalServiceProvider(t *testing.T) {
+	defer withConsent()()
+	ctrl.Config.ServiceProviders = map[string]*config.ServiceProviderConfig{
+		"https://localhost:8082/user-profile/saml/metadata": {Internal: true},
+	}
+	defer func() {
+		ctrl.Config.ServiceProviders = nil
+	}()
+
+	rw := serveSSO(t, newSamlRequestURL("", ""), true)
+	if !strings.Contains(rw.Body.String(), `name="SAMLResponse"`) {
+		t.Fatalf("Expected SAML response without consent, got: %s", rw.Body.String())
+	}
+}
+
+func TestConsentIsPassive(t *testing.T) {
+	defer withConsent()()
+
+	rw := serveSSO(t, newSamlRequestURL(`IsPassive="true"`, ""), true)
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:NoPassive") {
+		t.Fatalf("Expected NoPassive status response, got: %s", response)
+	}
+}
+
+func TestConsentsRevoke(t *testing.T) {
+	defer withConsent()()
+	serveConsent(t, consentTransaction(t), "accept")
+
+	req := consentRequest(t, "GET", "http://localhost:8080/saml/idp/consents", url.Values{})
+	rw := httptest.NewRecorder()
+	consentsCtx, err := app.NewConsentsIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.Consents(consentsCtx)
+	if !strings.Contains(rw.Body.String(), `name="serviceProvider" value="https://localhost:8082/user-profile/saml/metadata"`) {
+		t.Fatalf("Expected the consented service provider, got: %s", rw.Body.String())
+	}
+
+	events := []string{}
+	defer recordAudit(&events)()
+
+	req = consentRequest(t, "POST", "http://localhost:8080/saml/idp/consents", url.Values{"serviceProvider": {"https://localhost:8082/user-profile/saml/metadata"}})
+	rw = httptest.NewRecorder()
+	revokeCtx, err := app.NewRevokeConsentIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.RevokeConsent(revokeCtx)
+	if rw.Code != http.StatusSeeOther || rw.Header().Get("Location") != "http://kong:8000/saml/idp/consents" {
+		t.Fatalf("Expected redirect to the consents page, got %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditConsentRevoked {
+		t.Fatalf("Expected the consent.revoked event, got %v", events)
+	}
+
+	// the consent is asked for again
+	consentTransaction(t)
+}
+
+func TestConsentsWithoutSession(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://localhost:8080/saml/idp/consents", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	consentsCtx, err := app.NewConsentsIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.Consents(consentsCtx)
+
+	expected := "http://kong:8000/saml/idp/login?return_to=" + url.QueryEscape("http://kong:8000/saml/idp/consents")
+	if rw.Code != http.StatusFound || rw.Header().Get("Location") != expected {
+		t.Fatalf("Expected redirect to the login, got %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+}
+
+// withSecondAccount adds the session of the second account of the browser. It returns the function that
+// removes the session.
+func withSecondAccount() func() {
+	repository.AddSession(&db.Session{
+		Session: saml.Session{
+			ID:         "admin-session",
+			CreateTime: saml.TimeNow(),
+			ExpireTime: saml.TimeNow().Add(sessionMaxAge),
+			Index:      "0a1b2c3d",
+			UserName:   "59ce17c60000000000000001",
+			Groups:     []string{"admin"},
+			UserEmail:  "admin@host.com",
+		},
+		AuthnMethods: []string{jormungandrSamlIdp.AuthnMethodPassword},
+	})
+
+	return func() {
+		repository.DeleteUserSessions("59ce17c60000000000000001")
+	}
+}
+
+// twoAccounts is the session cookie of the browser with the test session and the second account
+var twoAccounts = &http.Cookie{Name: "session", Value: "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=|admin-session"}
+
+// serveAccountChooser runs the ServeLogin action with the form of the account chooser and the session cookie.
+func serveAccountChooser(t *testing.T, form url.Values, sessionCookie *http.Cookie) *httptest.ResponseRecorder {
+	form.Set("csrf_token", csrfToken)
+
+	req := formRequest(t, "POST", "http://localhost:8080/saml/idp/sso", form)
+	req.AddCookie(sessionCookie)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+
+	rw := httptest.NewRecorder()
+	goaCtx := goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{})
+
+	serveLoginCtx, err := app.NewServeLoginIdpContext(goaCtx, req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.ServeLogin(serveLoginCtx)
+
+	return rw
+}
+
+// accountChooserTransaction runs the ServeSSO action with two accounts in the browser and returns the login
+// transaction of the account chooser.
+func accountChooserTransaction(t *testing.T) string {
+	req, err := http.NewRequest("GET", newSamlRequestURL("", ""), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(twoAccounts)
+
+	rw := httptest.NewRecorder()
+	serveSSOCtx, err := app.NewServeSSOIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.ServeSSO(serveSSOCtx)
+
+	body := rw.Body.String()
+	for _, expected := range []string{"example@host.com", "admin@host.com", `name="account" value="0a1b2c3d"`} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %s on the account chooser, got: %s", expected, body)
+		}
+	}
+
+	match := regexp.MustCompile(`name="transaction" value="([^"]+)"`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("Login transaction not found in: %s", body)
+	}
+
+	return match[1]
+}
+
+// sessionCookie returns the session cookie set by the response
+func sessionCookie(rw *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range (&http.Response{Header: rw.Header()}).Cookies() {
+		if cookie.Name == "session" {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestAccountChooser(t *testing.T) {
+	defer withSecondAccount()()
+	events := []string{}
+	defer recordAudit(&events)()
+
+	rw := serveAccountChooser(t, url.Values{"transaction": {accountChooserTransaction(t)}, "account": {"0a1b2c3d"}}, twoAccounts)
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected successful response for the chosen account, got: %s", response)
+	}
+	if cookie := sessionCookie(rw); cookie == nil || cookie.Value != "admin-session|K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=" {
+		t.Fatalf("Expected the chosen session to be selected, got %+v", cookie)
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditAccountChosen {
+		t.Fatalf("Expected the account.chosen event, got %v", events)
+	}
+}
+
+func TestAccountChooserSignOut(t *testing.T) {
+	defer withSecondAccount()()
+	transactionID := accountChooserTransaction(t)
+
+	rw := serveAccountChooser(t, url.Values{"transaction": {transactionID}, "sign-out": {"0a1b2c3d"}}, twoAccounts)
+	if body := rw.Body.String(); strings.Contains(body, "admin@host.com") || !strings.Contains(body, "example@host.com") {
+		t.Fatalf("Expected the account chooser without the signed out account, got: %s", body)
+	}
+	if cookie := sessionCookie(rw); cookie == nil || cookie.Value != "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=" {
+		t.Fatalf("Expected the signed out session to be removed from the cookie, got %+v", cookie)
+	}
+}
+
+func TestAccountChooserAddAccount(t *testing.T) {
+	defer withSecondAccount()()
+
+	rw := serveAccountChooser(t, url.Values{"transaction": {accountChooserTransaction(t)}, "add-account": {"true"}}, twoAccounts)
+	if !strings.Contains(rw.Body.String(), `name="password"`) {
+		t.Fatalf("Expected the login form, got: %s", rw.Body.String())
+	}
+}
+
+func TestAccountChooserEndedSession(t *testing.T) {
+	restore := withSecondAccount()
+	transactionID := accountChooserTransaction(t)
+	restore()
+
+	rw := serveAccountChooser(t, url.Values{"transaction": {transactionID}, "account": {"0a1b2c3d"}}, twoAccounts)
+	if body := rw.Body.String(); strings.Contains(body, "SAMLResponse") || strings.Contains(body, "admin@host.com") {
+		t.Fatalf("Expected the account chooser without the ended session, got: %s", body)
+	}
+}
+
+func TestAccountChooserIsPassive(t *testing.T) {
+	defer withSecondAccount()()
+
+	req, err := http.NewRequest("GET", newSamlRequestURL(`IsPassive="true"`, ""), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(twoAccounts)
+
+	rw := httptest.NewRecorder()
+	serveSSOCtx, err := app.NewServeSSOIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.ServeSSO(serveSSOCtx)
+
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected successful response for the selected session, got: %s", response)
+	}
+	if cookie := sessionCookie(rw); cookie != nil {
+		t.Fatalf("Expected the sessions of the browser to be kept, got %+v", cookie)
+	}
+}
+
+func TestLoginAddsAccount(t *testing.T) {
+	defer withUserService(t, map[string]interface{}{
+		"id":     "59ce17c60000000000000002",
+		"email":  "jane@host.com",
+		"roles":  []interface{}{"user"},
+		"active": true,
+	})()
+	defer repository.DeleteUserSessions("59ce17c60000000000000002")
+
+	transactionID := startLoginTransaction(t)
+	rw := serveAccountChooser(t, url.Values{"transaction": {transactionID}, "email": {"jane@host.com"}, "password": {"test123"}}, &http.Cookie{Name: "session", Value: "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=|ended-session"})
+
+	cookie := sessionCookie(rw)
+	if cookie == nil || !strings.HasSuffix(cookie.Value, "|K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=") || strings.Contains(cookie.Value, "ended-session") {
+		t.Fatalf("Expected the new session to be added to the valid sessions of the browser, got %+v", cookie)
+	}
+}
+
+// withBoundSession adds the session bound to the network 10.0.0.0/24 and sets the session binding mode. It
+// returns the function that removes the session and the binding.
+func withBoundSession(mode string) func() {
+	cfg.SessionBinding = config.SessionBindingConfig{Mode: mode}
+	repository.SessionBinding = &cfg.SessionBinding
+	repository.AddSession(&db.Session{
+		Session: saml.Session{
+			ID:         "bound-session",
+			CreateTime: saml.TimeNow(),
+			ExpireTime: saml.TimeNow().Add(sessionMaxAge),
+			Index:      "4e5f6a7b",
+			UserName:   "59ce17c60000000000000003",
+			Groups:     []string{"user"},
+			UserEmail:  "bound@host.com",
+		},
+		AuthnMethods: []string{jormungandrSamlIdp.AuthnMethodPassword},
+		Binding:      &db.SessionBinding{Network: "10.0.0.0/24"},
+	})
+
+	return func() {
+		cfg.SessionBinding = config.SessionBindingConfig{}
+		repository.SessionBinding = nil
+		repository.DeleteUserSessions("59ce17c60000000000000003")
+	}
+}
+
+// serveBoundSSO runs the ServeSSO action with the bound session from the client address
+func serveBoundSSO(t *testing.T, remoteAddr string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", newSamlRequestURL("", ""), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RemoteAddr = remoteAddr
+	req.AddCookie(&http.Cookie{Name: "session", Value: "bound-session"})
+
+	rw := httptest.NewRecorder()
+	serveSSOCtx, err := app.NewServeSSOIdpContext(goa.NewContext(goa.WithAction(context.Background(), "IdpTest"), rw, req, url.Values{}), req, goaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl.ServeSSO(serveSSOCtx)
+
+	return rw
+}
+
+func TestSessionBindingSameClient(t *testing.T) {
+	defer withBoundSession(db.SessionBindingStrict)()
+	events := []string{}
+	defer recordAudit(&events)()
+
+	rw := serveBoundSSO(t, "10.0.0.7:52000")
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected successful response for the session used from the same network, got: %s", response)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no audit events, got %v", events)
+	}
+}
+
+func TestSessionBindingStrict(t *testing.T) {
+	defer withBoundSession(db.SessionBindingStrict)()
+	events := []string{}
+	defer recordAudit(&events)()
+
+	rw := serveBoundSSO(t, "192.0.2.10:52000")
+	if !strings.Contains(rw.Body.String(), `name="password"`) {
+		t.Fatalf("Expected the login form for the session used from a different network, got: %s", rw.Body.String())
+	}
+	if cookie := sessionCookie(rw); cookie == nil || cookie.Value != "" {
+		t.Fatalf("Expected the bound session to be removed from the cookie, got %+v", cookie)
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditSessionBindingMismatch {
+		t.Fatalf("Expected the session.binding-mismatch event, got %v", events)
+	}
+}
+
+func TestSessionBindingAudit(t *testing.T) {
+	defer withBoundSession(db.SessionBindingAudit)()
+	events := []string{}
+	defer recordAudit(&events)()
+
+	rw := serveBoundSSO(t, "192.0.2.10:52000")
+	if response := samlResponse(t, rw); !strings.Contains(response, "urn:oasis:names:tc:SAML:2.0:status:Success") {
+		t.Fatalf("Expected successful response in the audit mode, got: %s", response)
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditSessionBindingMismatch {
+		t.Fatalf("Expected the session.binding-mismatch event, got %v", events)
+	}
+}
+
+func TestLoginBindsSession(t *testing.T) {
+	defer withBoundSession(db.SessionBindingStrict)()
+	defer withUserService(t, map[string]interface{}{
+		"id":     "59ce17c60000000000000002",
+		"email":  "jane@host.com",
+		"roles":  []interface{}{"user"},
+		"active": true,
+	})()
+	defer repository.DeleteUserSessions("59ce17c60000000000000002")
+
+	transactionID := startLoginTransaction(t)
+	rw := serveAccountChooser(t, url.Values{"transaction": {transactionID}, "email": {"jane@host.com"}, "password": {"test123"}}, &http.Cookie{Name: "session", Value: "ended-session"})
+
+	req := httptest.NewRequest("GET", "http://localhost:8080/saml/idp/sso", nil)
+	req.AddCookie(sessionCookie(rw))
+	sessions, err := repository.GetBrowserSessions(req)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected the new session, got %v, %v", sessions, err)
+	}
+	if binding := sessions[0].Binding; binding == nil || binding.UserAgentHash == "" {
+		t.Fatalf("Expected the session to be bound to the client, got %+v", binding)
+	}
+}
+
+func TestLoginSessionLimit(t *testing.T) {
+	repository.SessionLimits = &config.SessionLimitsConfig{MaxSessions: 1, Policy: db.SessionLimitReject}
+	defer func() {
+		repository.SessionLimits = nil
+	}()
+	defer withUserService(t, map[string]interface{}{
+		"id":     "59ce17c60000000000000000",
+		"email":  "example@host.com",
+		"roles":  []interface{}{"user"},
+		"active": true,
+	})()
+	events := []string{}
+	defer recordAudit(&events)()
+
+	transactionID := startLoginTransaction(t)
+	rw := serveLogin(t, url.Values{"transaction": {transactionID}, "email": {"example@host.com"}, "password": {"test123"}}, true)
+	if body := rw.Body.String(); !strings.Contains(body, "maximal number of devices") || strings.Contains(body, "SAMLResponse") {
+		t.Fatalf("Expected the login to be rejected, got: %s", body)
+	}
+	if len(events) != 1 || events[0] != jormungandrSamlIdp.AuditSessionLimitReached {
+		t.Fatalf("Expected the login.session-limit event, got %v", events)
+	}
+}
+
+func TestAccountChooserHostPrefix(t *testing.T) {
+	defer withSecondAccount()()
+	cfg.SessionCookie.HostPrefix = true
+	defer func() {
+		cfg.SessionCookie.HostPrefix = false
+	}()
+
+	rw := serveAccountChooser(t, url.Values{"transaction": {accountChooserTransaction(t)}, "account": {"0a1b2c3d"}}, twoAccounts)
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range (&http.Response{Header: rw.Header()}).Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	if cookie := cookies["__Host-session"]; cookie == nil || cookie.Value != "admin-session|K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=" || !cookie.Secure || cookie.Path != "/" {
+		t.Fatalf("Expected the session cookie with the __Host- prefix, got %+v", cookie)
+	}
+	if cookie := cookies["session"]; cookie == nil || cookie.MaxAge != -1 {
+		t.Fatalf("Expected the session cookie without the prefix to be removed, got %+v", cookie)
+	}
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes the emails to .eml files in a directory, so they can be read without a mail server.
+// Use only for development.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates the FileMailer for the directory, creating the directory if it does not exist.
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the directory of the file mailer is not set")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send writes the message to <time>-<random>.eml file in the directory.
+func (m *FileMailer) Send(message *Message) error {
+	message = withSender(message, m.from)
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), hex.EncodeToString(suffix))
+
+	return ioutil.WriteFile(filepath.Join(m.dir, name), message.Bytes(), 0600)
+}
@@ -0,0 +1,78 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/Microkubes/identity-provider/config"
+)
+
+// Types of the mailers.
+const (
+	// SMTP sends the emails through the SMTP server.
+	SMTP = "smtp"
+	// File writes the emails to a directory.
+	File = "file"
+	// Memory keeps the emails in memory.
+	Memory = "memory"
+)
+
+// Message is a plain text email.
+type Message struct {
+	// From is the sender address
+	From string
+	// To is the recipient address
+	To string
+	// Subject is the subject of the email
+	Subject string
+	// Body is the plain text body of the email
+	Body string
+}
+
+// Bytes returns the message formatted as RFC 5322 email.
+func (m *Message) Bytes() []byte {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(m.Body)
+
+	return buf.Bytes()
+}
+
+// Mailer sends the emails.
+type Mailer interface {
+	// Send sends the message. The sender is set to the configured address if the message has none.
+	Send(message *Message) error
+}
+
+// New creates the mailer configured with the mail settings.
+func New(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Mailer {
+	case "", SMTP:
+		return NewSMTPMailer(cfg), nil
+	case File:
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case Memory:
+		return NewMemoryMailer(cfg.From), nil
+	}
+
+	return nil, fmt.Errorf("unknown mailer %s", cfg.Mailer)
+}
+
+// withSender returns the message with the sender set to from, if it has none
+func withSender(message *Message, from string) *Message {
+	if message.From != "" {
+		return message
+	}
+
+	m := *message
+	m.From = from
+	return &m
+}
@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+)
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if m, err := New(&config.MailConfig{Host: "smtp.example.com"}); err != nil {
+		t.Fatal(err)
+	} else if smtpMailer, ok := m.(*SMTPMailer); !ok || smtpMailer.addr != "smtp.example.com:587" {
+		t.Fatalf("Expected the SMTP mailer on the submission port, got %#v", m)
+	}
+
+	if m, err := New(&config.MailConfig{Mailer: File, Dir: dir}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := m.(*FileMailer); !ok {
+		t.Fatalf("Expected the file mailer, got %#v", m)
+	}
+
+	if _, err := New(&config.MailConfig{Mailer: File}); err == nil {
+		t.Fatal("Nil error, expected: the directory of the file mailer is not set")
+	}
+
+	if _, err := New(&config.MailConfig{Mailer: "pigeon"}); err == nil {
+		t.Fatal("Nil error, expected: unknown mailer pigeon")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := NewFileMailer(dir, "idp@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Send(&Message{To: "jon@test.com", Subject: "Passwort zurücksetzen", Body: "https://idp.example.com/reset"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one email, got %v", files)
+	}
+
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"From: idp@example.com\r\n",
+		"To: jon@test.com\r\n",
+		"Subject: =?utf-8?q?Passwort_zur=C3=BCcksetzen?=\r\n",
+		"\r\n\r\nhttps://idp.example.com/reset",
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Fatalf("Expected %q in the email, got: %s", expected, b)
+		}
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer("idp@example.com")
+	if m.Last() != nil {
+		t.Fatal("Expected no messages")
+	}
+
+	m.Send(&Message{To: "jon@test.com", Subject: "first"})
+	m.Send(&Message{From: "admin@example.com", To: "jon@test.com", Subject: "second"})
+
+	messages := m.Messages()
+	if len(messages) != 2 || messages[0].From != "idp@example.com" || m.Last().From != "admin@example.com" {
+		t.Fatalf("Expected the messages in the order they were sent, got %v", messages)
+	}
+}
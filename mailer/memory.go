@@ -0,0 +1,47 @@
+package mailer
+
+import "sync"
+
+// MemoryMailer keeps the emails in memory. Use only for tests.
+type MemoryMailer struct {
+	from string
+
+	mutex    sync.Mutex
+	messages []*Message
+}
+
+// NewMemoryMailer creates an empty MemoryMailer.
+func NewMemoryMailer(from string) *MemoryMailer {
+	return &MemoryMailer{
+		from: from,
+	}
+}
+
+// Send keeps the message.
+func (m *MemoryMailer) Send(message *Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, withSender(message, m.from))
+	return nil
+}
+
+// Messages returns the messages sent so far, the oldest first.
+func (m *MemoryMailer) Messages() []*Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]*Message{}, m.messages...)
+}
+
+// Last returns the last message sent, or nil if no message was sent.
+func (m *MemoryMailer) Last() *Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.messages) == 0 {
+		return nil
+	}
+
+	return m.messages[len(m.messages)-1]
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+
+	"github.com/Microkubes/identity-provider/config"
+)
+
+// defaultSMTPPort is the SMTP submission port
+const defaultSMTPPort = 587
+
+// SMTPMailer sends the emails through the SMTP server. The connection is upgraded with STARTTLS if the
+// server supports it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates the SMTPMailer for the server in the mail settings.
+func NewSMTPMailer(cfg *config.MailConfig) *SMTPMailer {
+	port := cfg.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &SMTPMailer{
+		addr: fmt.Sprintf("%s:%d", cfg.Host, port),
+		auth: auth,
+		from: cfg.From,
+	}
+}
+
+// Send sends the message through the SMTP server.
+func (m *SMTPMailer) Send(message *Message) error {
+	message = withSender(message, m.from)
+
+	return smtp.SendMail(m.addr, m.auth, message.From, []string{message.To}, message.Bytes())
+}
@@ -3,12 +3,16 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 
 	"github.com/Microkubes/identity-provider/app"
 	"github.com/Microkubes/identity-provider/config"
 	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/Microkubes/identity-provider/mailer"
+	"github.com/Microkubes/identity-provider/oidc"
 	jormungandrSamlIdp "github.com/Microkubes/identity-provider/samlidp"
 	"github.com/Microkubes/microservice-tools/gateway"
 	"github.com/keitaroinc/goa"
@@ -44,7 +48,7 @@ func main() {
 	service.Use(middleware.Recover())
 
 	// Cretae IDP store
-	store, cleanup, err := db.NewIDPStore(cfg.Database)
+	store, cleanup, err := db.NewIDPStore(cfg)
 	if err != nil {
 		service.LogError("Creation of IDP store failed", "err", err)
 		return
@@ -57,8 +61,48 @@ func main() {
 		return
 	}
 
+	upstreams, err := jormungandrSamlIdp.NewUpstreams(cfg, &idpServer.IDP)
+	if err != nil {
+		service.LogError("Loading of the upstream identity providers failed", "err", err)
+		return
+	}
+
+	socialProviders, err := oidc.NewProviders(cfg)
+	if err != nil {
+		service.LogError("Loading of the social login providers failed", "err", err)
+		return
+	}
+
+	locales, err := i18n.NewLocales(cfg.I18n.Dir, cfg.I18n.DefaultLanguage)
+	if err != nil {
+		service.LogError("Loading of the message catalogs failed", "err", err)
+		return
+	}
+
+	templates, err := jormungandrSamlIdp.NewTemplates(cfg.Templates.Dir, cfg.Templates.Reload, locales)
+	if err != nil {
+		service.LogError("Parsing of the templates failed", "err", err)
+		return
+	}
+
+	if cfg.PasswordReset.Enabled {
+		templates.PasswordResetURL = fmt.Sprintf("%s/saml/idp/password-reset", cfg.GatewayURL)
+	}
+	templates.LoginIdentifiers = cfg.Login.Identifiers
+	templates.Upstreams = upstreams
+	templates.SocialProviders = socialProviders
+	templates.TrustedProxies = cfg.TrustedProxies
+
+	mail, err := mailer.New(&cfg.Mail)
+	if err != nil {
+		service.LogError("Creation of the mailer failed", "err", err)
+		return
+	}
+
 	// Mount "idp" controller
-	c1 := NewIdpController(service, store, &idpServer.IDP, cfg)
+	c1 := NewIdpController(service, store, &idpServer.IDP, cfg, templates, mail)
+	c1.Upstreams = upstreams
+	c1.SocialProviders = socialProviders
 	app.MountIdpController(service, c1)
 	// Mount "swagger" controller
 	c2 := NewSwaggerController(service)
@@ -0,0 +1,157 @@
+// Package oidctest provides a mock OpenID Connect provider for the tests of the social logins.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// KeyID is the ID of the key the ID tokens are signed with
+const KeyID = "test-key"
+
+// AccessToken is the access token issued by the server
+const AccessToken = "test-access-token"
+
+// Server is an OpenID Connect provider serving the discovery document, the keys, the token endpoint and the
+// user info endpoint. The issuer is the URL of the server.
+type Server struct {
+	*httptest.Server
+	// Key signs the ID tokens
+	Key *rsa.PrivateKey
+	// ClientID is the client the ID tokens are issued for
+	ClientID string
+	// Code is the authorization code accepted by the token endpoint
+	Code string
+	// Claims are the claims of the ID token, in addition to iss, aud, iat and exp
+	Claims map[string]interface{}
+	// UserInfo is returned from the user info endpoint
+	UserInfo map[string]interface{}
+	// Emails are returned from the /emails endpoint, as GitHub lists the emails of the user
+	Emails []map[string]interface{}
+
+	lock         sync.Mutex
+	tokenRequest url.Values
+}
+
+// NewServer starts the provider for the client. The caller should call Close when finished.
+func NewServer(clientID string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		Key:      key,
+		ClientID: clientID,
+		Code:     "test-code",
+		Claims:   map[string]interface{}{},
+		UserInfo: map[string]interface{}{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.serveDiscovery)
+	mux.HandleFunc("/jwks", s.serveKeys)
+	mux.HandleFunc("/token", s.serveToken)
+	mux.HandleFunc("/userinfo", s.serveUserInfo)
+	mux.HandleFunc("/emails", s.serveEmails)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// TokenRequest returns the form of the last token request.
+func (s *Server) TokenRequest() url.Values {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.tokenRequest
+}
+
+func (s *Server) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"userinfo_endpoint":      s.URL + "/userinfo",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) serveKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kid": KeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(s.Key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.Key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.lock.Lock()
+	s.tokenRequest = r.PostForm
+	s.lock.Unlock()
+
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != s.Code || r.PostForm.Get("client_id") != s.ClientID {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": s.URL,
+		"aud": s.ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range s.Claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	idToken, err := token.SignedString(s.Key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": AccessToken,
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) serveUserInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+AccessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.UserInfo)
+}
+
+func (s *Server) serveEmails(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+AccessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Emails)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,284 @@
+// Package oidc implements the OAuth 2.0 and OpenID Connect client the users sign in with at the social login
+// providers, e.g. Google, Microsoft or GitHub.
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Microkubes/identity-provider/config"
+)
+
+const (
+	// TypeOIDC is the type of the generic OpenID Connect providers
+	TypeOIDC = "oidc"
+	// TypeOAuth2 is the type of the generic OAuth 2.0 providers, which return the user from the user info endpoint
+	TypeOAuth2 = "oauth2"
+	// TypeGoogle is the type of the Google provider
+	TypeGoogle = "google"
+	// TypeMicrosoft is the type of the Microsoft identity platform provider
+	TypeMicrosoft = "microsoft"
+	// TypeGitHub is the type of the GitHub provider
+	TypeGitHub = "github"
+)
+
+// presets are the settings of the well-known providers, overridden by the configured settings
+var presets = map[string]config.SocialProviderConfig{
+	TypeGoogle: {
+		DisplayName: "Google",
+		Issuer:      "https://accounts.google.com",
+		Scopes:      []string{"openid", "email", "profile"},
+	},
+	TypeMicrosoft: {
+		DisplayName: "Microsoft",
+		Issuer:      "https://login.microsoftonline.com/common/v2.0",
+		Scopes:      []string{"openid", "email", "profile"},
+	},
+	TypeGitHub: {
+		DisplayName: "GitHub",
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+		Claims:      map[string]string{"subject": "id"},
+	},
+}
+
+// defaultClaims are the names of the claims the identity fields are taken from, unless configured otherwise
+var defaultClaims = map[string]string{
+	"subject":       "sub",
+	"email":         "email",
+	"emailVerified": "email_verified",
+	"name":          "name",
+}
+
+// Provider is an OAuth 2.0 or OpenID Connect provider the users may sign in with.
+type Provider struct {
+	// Name is the name of the provider in the configuration
+	Name string
+	// DisplayName is the name shown on the login form
+	DisplayName string
+	// ClientID is the client ID the IdP is registered with at the provider
+	ClientID string
+	// ClientSecret is the client secret the IdP is registered with at the provider
+	ClientSecret string
+	// RedirectURL is the URL the provider sends the user back to with the authorization code
+	RedirectURL string
+	// Issuer is the issuer of the ID tokens, empty for the OAuth 2.0 providers. An issuer containing
+	// "{tenantid}" matches the "tid" claim of the token, as the multi-tenant Microsoft issuer does.
+	Issuer string
+	// AuthURL is the URL of the authorization endpoint
+	AuthURL string
+	// TokenURL is the URL of the token endpoint
+	TokenURL string
+	// UserInfoURL is the URL of the user info endpoint
+	UserInfoURL string
+	// EmailsURL is the URL the verified emails of the user are fetched from, as GitHub lists them
+	EmailsURL string
+	// JWKSURL is the URL of the keys the ID tokens are signed with
+	JWKSURL string
+	// Scopes are the requested scopes
+	Scopes []string
+	// Claims is a map of <identity field>:<claim name> of the configured claim names
+	Claims map[string]string
+	// TrustEmails is set when the emails returned by the provider are verified, even without the claim
+	TrustEmails bool
+	// Provisioning holds the rules the users are linked to the local users by
+	Provisioning *config.ProvisioningConfig
+	// Client is the HTTP client the requests to the provider are sent with
+	Client *http.Client
+
+	keysLock sync.Mutex
+	keys     map[string]interface{}
+}
+
+// discovery is the OpenID Connect discovery document of a provider
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProviders creates the configured social login providers, ordered by name. The endpoints of the
+// OpenID Connect providers that are not configured are discovered from the issuer.
+func NewProviders(cfg *config.Config) ([]*Provider, error) {
+	names := []string{}
+	for name := range cfg.SocialProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providers := []*Provider{}
+	for _, name := range names {
+		provider, err := NewProvider(name, cfg.SocialProviders[name], fmt.Sprintf("%s/saml/idp/social/callback", cfg.GatewayURL))
+		if err != nil {
+			return nil, fmt.Errorf("social provider %s: %s", name, err)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+// NewProvider creates the social login provider from its settings, completed with the preset of its type.
+func NewProvider(name string, cfg *config.SocialProviderConfig, redirectURL string) (*Provider, error) {
+	providerType := cfg.Type
+	if providerType == "" {
+		providerType = TypeOIDC
+	}
+	preset, ok := presets[providerType]
+	if !ok && providerType != TypeOIDC && providerType != TypeOAuth2 {
+		return nil, fmt.Errorf("unknown type %q", providerType)
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("clientId is not set")
+	}
+
+	provider := &Provider{
+		Name:         name,
+		DisplayName:  firstOf(cfg.DisplayName, preset.DisplayName, name),
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  redirectURL,
+		Issuer:       firstOf(cfg.Issuer, preset.Issuer),
+		AuthURL:      firstOf(cfg.AuthURL, preset.AuthURL),
+		TokenURL:     firstOf(cfg.TokenURL, preset.TokenURL),
+		UserInfoURL:  firstOf(cfg.UserInfoURL, preset.UserInfoURL),
+		EmailsURL:    firstOf(cfg.EmailsURL, preset.EmailsURL),
+		Scopes:       cfg.Scopes,
+		Claims:       map[string]string{},
+		TrustEmails:  cfg.TrustEmails,
+		Provisioning: cfg.Provisioning,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+	}
+	if len(provider.Scopes) == 0 {
+		provider.Scopes = preset.Scopes
+	}
+	for field, claim := range preset.Claims {
+		provider.Claims[field] = claim
+	}
+	for field, claim := range cfg.Claims {
+		provider.Claims[field] = claim
+	}
+	if providerType == TypeOAuth2 {
+		provider.Issuer = ""
+	}
+
+	if provider.Issuer != "" {
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		if err := provider.discover(); err != nil {
+			return nil, err
+		}
+	}
+
+	if provider.AuthURL == "" || provider.TokenURL == "" {
+		return nil, fmt.Errorf("the authorization and the token endpoints are not set")
+	}
+	if provider.Issuer == "" && provider.UserInfoURL == "" {
+		return nil, fmt.Errorf("the user info endpoint is not set")
+	}
+
+	return provider, nil
+}
+
+// discover fetches the discovery document of the issuer and sets the endpoints that are not configured
+func (p *Provider) discover() error {
+	doc := &discovery{}
+	if err := p.getJSON(strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", "", doc); err != nil {
+		return fmt.Errorf("discovery failed: %s", err)
+	}
+
+	if doc.Issuer != "" {
+		p.Issuer = doc.Issuer
+	}
+	p.AuthURL = firstOf(p.AuthURL, doc.AuthorizationEndpoint)
+	p.TokenURL = firstOf(p.TokenURL, doc.TokenEndpoint)
+	p.UserInfoURL = firstOf(p.UserInfoURL, doc.UserInfoEndpoint)
+	p.JWKSURL = doc.JWKSURI
+	if p.JWKSURL == "" {
+		return fmt.Errorf("the discovery document has no jwks_uri")
+	}
+
+	return nil
+}
+
+// AuthCodeURL returns the URL of the authorization request the user is sent to. The code challenge of the
+// verifier is sent with the request (PKCE), and the nonce, if set, must be in the ID token.
+func (p *Provider) AuthCodeURL(state string, nonce string, verifier string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", strings.Join(p.Scopes, " "))
+	params.Set("state", state)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+	if nonce != "" {
+		params.Set("nonce", nonce)
+	}
+
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+
+	return p.AuthURL + separator + params.Encode()
+}
+
+// getJSON fetches the JSON document from the URL, with the access token if set
+func (p *Provider) getJSON(url string, accessToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s failed with status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// claim returns the name of the claim the identity field is taken from
+func (p *Provider) claim(field string) string {
+	if claim, ok := p.Claims[field]; ok {
+		return claim
+	}
+	return defaultClaims[field]
+}
+
+// firstOf returns the first value that is not empty
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
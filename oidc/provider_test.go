@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/oidc/oidctest"
+)
+
+func newTestProvider(t *testing.T, server *oidctest.Server, cfg *config.SocialProviderConfig) *Provider {
+	if cfg.ClientID == "" {
+		cfg.ClientID = server.ClientID
+	}
+	if cfg.Issuer == "" && cfg.Type != TypeOAuth2 {
+		cfg.Issuer = server.URL
+	}
+
+	provider, err := NewProvider("test", cfg, "https://idp.example.com/saml/idp/social/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Client = server.Client()
+
+	return provider
+}
+
+func TestNewProviderDiscovery(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{})
+
+	if provider.AuthURL != server.URL+"/authorize" || provider.TokenURL != server.URL+"/token" || provider.JWKSURL != server.URL+"/jwks" {
+		t.Fatalf("Expected the discovered endpoints, got %+v", provider)
+	}
+	if provider.DisplayName != "test" || len(provider.Scopes) != 3 {
+		t.Fatalf("Expected the default display name and scopes, got %+v", provider)
+	}
+}
+
+func TestNewProviderErrors(t *testing.T) {
+	cases := []*config.SocialProviderConfig{
+		{Type: "unknown", ClientID: "client"},
+		{Type: TypeGitHub},
+		{Type: TypeOAuth2, ClientID: "client", AuthURL: "https://example.com/authorize", TokenURL: "https://example.com/token"},
+		{Type: TypeOAuth2, ClientID: "client", UserInfoURL: "https://example.com/user"},
+	}
+
+	for _, c := range cases {
+		if _, err := NewProvider("test", c, ""); err == nil {
+			t.Fatalf("Expected an error for %+v", c)
+		}
+	}
+}
+
+func TestNewProviderPreset(t *testing.T) {
+	provider, err := NewProvider("github", &config.SocialProviderConfig{Type: TypeGitHub, ClientID: "client", DisplayName: "GitHub Enterprise"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if provider.DisplayName != "GitHub Enterprise" || provider.TokenURL != "https://github.com/login/oauth/access_token" || provider.claim("subject") != "id" {
+		t.Fatalf("Expected the preset completed with the configuration, got %+v", provider)
+	}
+	if provider.claim("email") != "email" {
+		t.Fatalf("Expected the default email claim, got %s", provider.claim("email"))
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{})
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state", "nonce", "verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := authURL.Query()
+	challenge := sha256.Sum256([]byte("verifier"))
+
+	expected := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client",
+		"redirect_uri":          "https://idp.example.com/saml/idp/social/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(challenge[:]),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range expected {
+		if params.Get(name) != value {
+			t.Fatalf("Expected %s=%s, got %s", name, value, params.Get(name))
+		}
+	}
+}
+
+func TestOpenIDConnectLogin(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+	server.Claims = map[string]interface{}{
+		"sub":            "1234",
+		"email":          "Jane@Example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+		"nonce":          "nonce",
+	}
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{ClientSecret: "secret"})
+
+	token, err := provider.Exchange(server.Code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form := server.TokenRequest(); form.Get("code_verifier") != "verifier" || form.Get("client_secret") != "secret" {
+		t.Fatalf("Expected the verifier and the secret in the token request, got %v", form)
+	}
+
+	identity, err := provider.Identity(token, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Identity{Subject: "1234", Email: "jane@example.com", EmailVerified: true, Name: "Jane Doe"}
+	if identity.Claims["nonce"] != "nonce" {
+		t.Fatalf("Expected the claims of the ID token, got %v", identity.Claims)
+	}
+	if identity.Claims = nil; !reflect.DeepEqual(identity, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, identity)
+	}
+}
+
+func TestOpenIDConnectLoginInvalidIDToken(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"wrong nonce", map[string]interface{}{"sub": "1234", "nonce": "other"}},
+		{"wrong audience", map[string]interface{}{"sub": "1234", "nonce": "nonce", "aud": "other"}},
+		{"wrong issuer", map[string]interface{}{"sub": "1234", "nonce": "nonce", "iss": "https://evil.example.com"}},
+		{"expired", map[string]interface{}{"sub": "1234", "nonce": "nonce", "exp": 1}},
+	}
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{})
+	for _, c := range cases {
+		server.Claims = c.claims
+
+		token, err := provider.Exchange(server.Code, "verifier")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Identity(token, "nonce"); err == nil {
+			t.Fatalf("%s: expected the ID token to be rejected", c.name)
+		}
+	}
+}
+
+func TestOpenIDConnectLoginUserInfo(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+	server.Claims = map[string]interface{}{"sub": "1234", "nonce": "nonce"}
+	server.UserInfo = map[string]interface{}{"sub": "1234", "email": "jane@example.com", "email_verified": true}
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{})
+
+	token, err := provider.Exchange(server.Code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := provider.Identity(token, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "jane@example.com" || !identity.EmailVerified {
+		t.Fatalf("Expected the email from the user info, got %+v", identity)
+	}
+
+	server.UserInfo["sub"] = "5678"
+	if _, err := provider.Identity(token, "nonce"); err == nil {
+		t.Fatal("Expected the user info of another subject to be rejected")
+	}
+}
+
+func TestOAuth2Login(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+	server.UserInfo = map[string]interface{}{"id": 583231, "login": "jane", "email": "jane@public.example.com", "name": "Jane Doe"}
+	server.Emails = []map[string]interface{}{
+		{"email": "jane@public.example.com", "primary": false, "verified": false},
+		{"email": "Jane@Example.com", "primary": true, "verified": true},
+	}
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{
+		Type:        TypeOAuth2,
+		AuthURL:     server.URL + "/authorize",
+		TokenURL:    server.URL + "/token",
+		UserInfoURL: server.URL + "/userinfo",
+		EmailsURL:   server.URL + "/emails",
+		Claims:      map[string]string{"subject": "id"},
+	})
+
+	token, err := provider.Exchange(server.Code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := provider.Identity(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Identity{Subject: "583231", Email: "jane@example.com", EmailVerified: true, Name: "Jane Doe"}
+	if identity.Claims["login"] != "jane" {
+		t.Fatalf("Expected the claims of the user info, got %v", identity.Claims)
+	}
+	if identity.Claims = nil; !reflect.DeepEqual(identity, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, identity)
+	}
+}
+
+func TestExchangeInvalidCode(t *testing.T) {
+	server := oidctest.NewServer("client")
+	defer server.Close()
+
+	provider := newTestProvider(t, server, &config.SocialProviderConfig{})
+
+	if _, err := provider.Exchange("other", "verifier"); err == nil {
+		t.Fatal("Expected an error for the invalid code")
+	}
+}
@@ -0,0 +1,257 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Token is the response of the token endpoint.
+type Token struct {
+	// AccessToken is the token the user info is fetched with
+	AccessToken string `json:"access_token"`
+	// TokenType is the type of the access token, "Bearer"
+	TokenType string `json:"token_type"`
+	// IDToken is the signed ID token of the OpenID Connect providers
+	IDToken string `json:"id_token"`
+}
+
+// Identity is the user authenticated by the provider.
+type Identity struct {
+	// Subject is the ID of the user at the provider
+	Subject string
+	// Email is the email of the user
+	Email string
+	// EmailVerified is set when the provider has verified that the email belongs to the user
+	EmailVerified bool
+	// Name is the full name of the user
+	Name string
+	// Claims are the claims of the ID token and the user info
+	Claims map[string]interface{}
+}
+
+// tokenError is the error response of the token endpoint
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// jsonWebKeySet is the set of keys the ID tokens are signed with
+type jsonWebKeySet struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// emailAddress is an item of the list of emails returned from the EmailsURL
+type emailAddress struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// Exchange exchanges the authorization code for the tokens, with the PKCE verifier the code was requested with.
+func (p *Provider) Exchange(code string, verifier string) (*Token, error) {
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("client_id", p.ClientID)
+	params.Set("client_secret", p.ClientSecret)
+	params.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, p.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		tokenErr := &tokenError{}
+		json.NewDecoder(resp.Body).Decode(tokenErr)
+		return nil, fmt.Errorf("the token request failed with status %d: %s %s", resp.StatusCode, tokenErr.Error, tokenErr.ErrorDescription)
+	}
+
+	token := &Token{}
+	if err := json.NewDecoder(resp.Body).Decode(token); err != nil {
+		return nil, err
+	}
+	// Some providers report the errors with the status 200
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("the token response has no access token")
+	}
+
+	return token, nil
+}
+
+// Identity returns the user the tokens were issued for. The ID token of the OpenID Connect providers is
+// verified and must contain the nonce, the claims it lacks are taken from the user info. The OAuth 2.0
+// providers return the user from the user info endpoint only.
+func (p *Provider) Identity(token *Token, nonce string) (*Identity, error) {
+	claims := map[string]interface{}{}
+	if p.Issuer != "" {
+		idClaims, err := p.verifyIDToken(token.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+		claims = idClaims
+	}
+
+	if p.UserInfoURL != "" && (p.Issuer == "" || claims[p.claim("email")] == nil) {
+		userInfo := map[string]interface{}{}
+		if err := p.getJSON(p.UserInfoURL, token.AccessToken, &userInfo); err != nil {
+			return nil, err
+		}
+		if p.Issuer != "" && userInfo["sub"] != claims["sub"] {
+			return nil, fmt.Errorf("the user info is of another subject")
+		}
+		for name, value := range userInfo {
+			if _, ok := claims[name]; !ok {
+				claims[name] = value
+			}
+		}
+	}
+
+	identity := &Identity{
+		Subject:       claimString(claims, p.claim("subject")),
+		Email:         strings.ToLower(claimString(claims, p.claim("email"))),
+		EmailVerified: p.TrustEmails || claimString(claims, p.claim("emailVerified")) == "true",
+		Name:          claimString(claims, p.claim("name")),
+		Claims:        claims,
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("the provider returned no subject")
+	}
+
+	if p.EmailsURL != "" {
+		emails := []emailAddress{}
+		if err := p.getJSON(p.EmailsURL, token.AccessToken, &emails); err != nil {
+			return nil, err
+		}
+		for _, email := range emails {
+			if email.Primary && email.Verified {
+				identity.Email = strings.ToLower(email.Email)
+				identity.EmailVerified = true
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// verifyIDToken verifies the signature, the issuer, the audience, the expiry and the nonce of the ID token and
+// returns its claims
+func (p *Provider) verifyIDToken(idToken string, nonce string) (map[string]interface{}, error) {
+	if idToken == "" {
+		return nil, fmt.Errorf("the token response has no ID token")
+	}
+
+	parsed, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+
+	issuer := strings.Replace(p.Issuer, "{tenantid}", claimString(claims, "tid"), 1)
+	if claims["iss"] != issuer {
+		return nil, fmt.Errorf("the ID token is issued by %v", claims["iss"])
+	}
+	if !hasAudience(claims["aud"], p.ClientID) {
+		return nil, fmt.Errorf("the ID token is not issued for the client")
+	}
+	if claims["nonce"] != nonce {
+		return nil, fmt.Errorf("the nonce of the ID token does not match")
+	}
+
+	return claims, nil
+}
+
+// key returns the public key with the ID. The keys are fetched again when the provider signs with a new key.
+func (p *Provider) key(kid string) (interface{}, error) {
+	p.keysLock.Lock()
+	defer p.keysLock.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	keySet := &jsonWebKeySet{}
+	if err := p.getJSON(p.JWKSURL, "", keySet); err != nil {
+		return nil, err
+	}
+
+	p.keys = map[string]interface{}{}
+	for _, jwk := range keySet.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		p.keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errors.New("the ID token is signed with an unknown key")
+}
+
+// hasAudience checks if the client is the audience, or one of the audiences, of the token
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimString returns the claim as a string. The numbers, e.g. the IDs of the GitHub users, and the booleans are
+// formatted, the missing claims are empty.
+func claimString(claims map[string]interface{}, name string) string {
+	switch value := claims[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
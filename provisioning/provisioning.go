@@ -0,0 +1,204 @@
+// Package provisioning links the users of the external identity sources, the upstream identity providers and
+// the social login providers, to the local users in the user store, and creates the local users just in time,
+// on their first login.
+package provisioning
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/service"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+var (
+	// ErrEmailNotVerified is returned when the external user without a link has no verified email, so it
+	// cannot be linked to a local user
+	ErrEmailNotVerified = errors.New("the email of the external user is not verified")
+	// ErrDomainNotAllowed is returned when the email of the external user is not in the allowed domains
+	ErrDomainNotAllowed = errors.New("the email domain of the external user is not allowed")
+	// ErrNoAccount is returned when there is no local user with the email of the external user and the users
+	// are not created
+	ErrNoAccount = errors.New("there is no local user with the email of the external user")
+)
+
+// LinkStore is the repository of the user links.
+type LinkStore interface {
+	// AddUserLink saves the user link
+	AddUserLink(link *db.UserLink) error
+	// GetUserLink looks up the user link by its ID
+	GetUserLink(linkID string) (*db.UserLink, error)
+	// DeleteUserLink deletes the user link
+	DeleteUserLink(linkID string) error
+}
+
+// ExternalUser is a user authenticated by an external identity source.
+type ExternalUser struct {
+	// Source is the external identity source, "upstream:<name>" or "social:<name>"
+	Source string
+	// Subject is the ID of the user at the source
+	Subject string
+	// Email is the email of the user
+	Email string
+	// EmailVerified is set when the source vouches that the email belongs to the user
+	EmailVerified bool
+	// Name is the full name of the user
+	Name string
+	// Attributes are the attributes, or claims, of the user at the source
+	Attributes map[string][]string
+}
+
+// LinkID returns the ID of the link of the external user.
+func (u *ExternalUser) LinkID() string {
+	return u.Source + ":" + u.Subject
+}
+
+// Account is the local user the external user logs in as.
+type Account struct {
+	// User is the local user, as returned by the user service
+	User map[string]interface{}
+	// Link is the link of the external user to the local user
+	Link *db.UserLink
+	// Created is set when the link was created on this login. Link.Provisioned tells if the local user was
+	// created too.
+	Created bool
+}
+
+// Provision returns the local user of the external user. The external users who have logged in before are
+// found by their link, so a later change of the email at the source does not move them to another local user.
+// On the first login the external user is linked to the local user with the same verified email, or a local
+// user is created if the rules allow it. The email must be in the allowed domains on every login.
+func Provision(store LinkStore, external *ExternalUser, rules *config.ProvisioningConfig, idp *saml.IdentityProvider, cfg *config.Config) (*Account, error) {
+	if rules == nil {
+		rules = &config.ProvisioningConfig{}
+	}
+	if !domainAllowed(external.Email, rules.Domains) {
+		return nil, ErrDomainNotAllowed
+	}
+
+	link, err := store.GetUserLink(external.LinkID())
+	if err == nil {
+		user, err := service.GetUser(link.UserID, idp, cfg)
+		if err == nil {
+			link.LastLoginTime = saml.TimeNow()
+			if err := store.AddUserLink(link); err != nil {
+				return nil, err
+			}
+			return &Account{User: user, Link: link}, nil
+		}
+		if !service.IsUserNotFound(err) {
+			return nil, err
+		}
+
+		// the local user has been deleted, so the external user is linked again
+		if err := store.DeleteUserLink(link.ID); err != nil {
+			return nil, err
+		}
+	} else if !isNotFound(err) {
+		return nil, err
+	}
+
+	if external.Email == "" || !external.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	provisioned := false
+	user, err := service.FindUserByEmail(external.Email, idp, cfg)
+	if service.IsUserNotFound(err) {
+		if !rules.Create {
+			return nil, ErrNoAccount
+		}
+		user, err = service.CreateUser(NewUser(external, rules), idp, cfg)
+		provisioned = true
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := user["id"].(string)
+	if userID == "" {
+		return nil, goa.ErrInternal("the user service returned a user without an ID")
+	}
+
+	link = &db.UserLink{
+		ID:            external.LinkID(),
+		Source:        external.Source,
+		Subject:       external.Subject,
+		UserID:        userID,
+		Email:         external.Email,
+		Provisioned:   provisioned,
+		CreateTime:    saml.TimeNow(),
+		LastLoginTime: saml.TimeNow(),
+	}
+	if err := store.AddUserLink(link); err != nil {
+		return nil, err
+	}
+
+	return &Account{User: user, Link: link, Created: true}, nil
+}
+
+// NewUser returns the local user created for the external user. The user is active, has the roles of the rules,
+// "user" by default, and the roles of the mapped "roles" attribute, and the external ID "<source>:<subject>".
+// The other mapped attributes are copied to the user fields.
+func NewUser(external *ExternalUser, rules *config.ProvisioningConfig) map[string]interface{} {
+	roles := rules.Roles
+	if len(roles) == 0 {
+		roles = []string{"user"}
+	}
+
+	user := map[string]interface{}{
+		"email":      external.Email,
+		"active":     true,
+		"externalId": external.LinkID(),
+	}
+	if external.Name != "" {
+		user["fullname"] = external.Name
+	}
+
+	for field, attribute := range rules.Attributes {
+		values := external.Attributes[attribute]
+		if len(values) == 0 {
+			continue
+		}
+		if field == "roles" {
+			roles = append(append([]string{}, roles...), values...)
+			continue
+		}
+		user[field] = values[0]
+	}
+	user["roles"] = roles
+
+	return user
+}
+
+// domainAllowed checks if the domain of the email is one of the allowed domains. All domains are allowed if
+// none is configured.
+func domainAllowed(email string, domains []string) bool {
+	return len(domains) == 0 || InDomains(email, domains)
+}
+
+// InDomains checks if the domain of the email is one of the domains. No email is in the domains if none is
+// given.
+func InDomains(email string, domains []string) bool {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return false
+	}
+	for _, domain := range domains {
+		if strings.EqualFold(domain, email[i+1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNotFound checks if the error of the repository is the not found error
+func isNotFound(err error) bool {
+	e, ok := err.(*goa.ErrorResponse)
+	return ok && e.Status == http.StatusNotFound
+}
@@ -0,0 +1,222 @@
+package provisioning
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/logger"
+)
+
+var idp = &saml.IdentityProvider{Logger: logger.DefaultLogger}
+
+var cfg = &config.Config{
+	Services: map[string]string{"microservice-user": "http://kong:8000/users"},
+}
+
+// withSystemKey writes the key the requests to the user service are signed with and returns the function that
+// removes it and the pending mocks of the user service
+func withSystemKey(t *testing.T) func() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile, err := ioutil.TempFile("", "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile.Close()
+	cfg.SystemKey = keyFile.Name()
+
+	return func() {
+		gock.Off()
+		os.Remove(keyFile.Name())
+	}
+}
+
+func janeAtCorp() *ExternalUser {
+	return &ExternalUser{
+		Source:        "upstream:corp",
+		Subject:       "jane",
+		Email:         "jane@corp.example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+		Attributes:    map[string][]string{"groups": {"staff", "admin"}, "department": {"Sales"}},
+	}
+}
+
+func TestProvisionLinkByEmail(t *testing.T) {
+	defer withSystemKey(t)()
+	store := db.New()
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find/email").
+		JSON(map[string]interface{}{"email": "jane@corp.example.com"}).
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jane@corp.example.com"})
+
+	account, err := Provision(store, janeAtCorp(), nil, idp, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Created || account.Link.Provisioned || account.Link.UserID != "59804b3c0000000000000000" {
+		t.Fatalf("Expected a new link to the user with the email, got %+v", account.Link)
+	}
+	if link, err := store.GetUserLink("upstream:corp:jane"); err != nil || link.Email != "jane@corp.example.com" {
+		t.Fatalf("Expected the link to be saved, got %+v %v", link, err)
+	}
+
+	// the user is found by the link on the next login
+	gock.New(cfg.Services["microservice-user"]).
+		Get("/59804b3c0000000000000000").
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jane@corp.example.com"})
+
+	external := janeAtCorp()
+	external.Email = "jane.doe@corp.example.com"
+	account, err = Provision(store, external, nil, idp, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Created || account.User["id"] != "59804b3c0000000000000000" {
+		t.Fatalf("Expected the linked user, got %+v", account)
+	}
+}
+
+func TestProvisionCreateUser(t *testing.T) {
+	defer withSystemKey(t)()
+	store := db.New()
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(404).
+		BodyString("user not found")
+	gock.New(cfg.Services["microservice-user"]).
+		Post("").
+		JSON(map[string]interface{}{
+			"email":      "jane@corp.example.com",
+			"fullname":   "Jane Doe",
+			"active":     true,
+			"externalId": "upstream:corp:jane",
+			"roles":      []string{"member", "staff", "admin"},
+		}).
+		Reply(201).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000001", "email": "jane@corp.example.com"})
+
+	rules := &config.ProvisioningConfig{Create: true, Roles: []string{"member"}, Attributes: map[string]string{"roles": "groups"}}
+	account, err := Provision(store, janeAtCorp(), rules, idp, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Created || !account.Link.Provisioned || account.Link.UserID != "59804b3c0000000000000001" {
+		t.Fatalf("Expected a link to the created user, got %+v", account.Link)
+	}
+}
+
+func TestProvisionDeletedUser(t *testing.T) {
+	defer withSystemKey(t)()
+	store := db.New()
+	store.AddUserLink(&db.UserLink{ID: "upstream:corp:jane", Source: "upstream:corp", Subject: "jane", UserID: "59804b3c0000000000000000"})
+
+	gock.New(cfg.Services["microservice-user"]).
+		Get("/59804b3c0000000000000000").
+		Reply(404).
+		BodyString("user not found")
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000001", "email": "jane@corp.example.com"})
+
+	account, err := Provision(store, janeAtCorp(), nil, idp, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Created || account.Link.UserID != "59804b3c0000000000000001" {
+		t.Fatalf("Expected the user to be linked again, got %+v", account.Link)
+	}
+}
+
+func TestProvisionErrors(t *testing.T) {
+	defer withSystemKey(t)()
+	store := db.New()
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find/email").
+		Persist().
+		Reply(404).
+		BodyString("user not found")
+
+	unverified := janeAtCorp()
+	unverified.EmailVerified = false
+
+	cases := []struct {
+		name     string
+		external *ExternalUser
+		rules    *config.ProvisioningConfig
+		expected error
+	}{
+		{"domain not allowed", janeAtCorp(), &config.ProvisioningConfig{Create: true, Domains: []string{"example.com"}}, ErrDomainNotAllowed},
+		{"email not verified", unverified, &config.ProvisioningConfig{Create: true}, ErrEmailNotVerified},
+		{"no account", janeAtCorp(), nil, ErrNoAccount},
+	}
+
+	for _, c := range cases {
+		if _, err := Provision(store, c.external, c.rules, idp, cfg); err != c.expected {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, err)
+		}
+	}
+	if _, err := store.GetUserLink("upstream:corp:jane"); err == nil {
+		t.Fatal("Expected no link to be saved")
+	}
+}
+
+func TestNewUser(t *testing.T) {
+	rules := &config.ProvisioningConfig{Attributes: map[string]string{"department": "department", "phone": "phone"}}
+
+	expected := map[string]interface{}{
+		"email":      "jane@corp.example.com",
+		"fullname":   "Jane Doe",
+		"active":     true,
+		"externalId": "upstream:corp:jane",
+		"roles":      []string{"user"},
+		"department": "Sales",
+	}
+	if user := NewUser(janeAtCorp(), rules); !reflect.DeepEqual(user, expected) {
+		t.Fatalf("Expected %v, got %v", expected, user)
+	}
+}
+
+func TestDomainAllowed(t *testing.T) {
+	cases := []struct {
+		email    string
+		domains  []string
+		expected bool
+	}{
+		{"jane@corp.example.com", nil, true},
+		{"jane@Corp.Example.com", []string{"corp.example.com"}, true},
+		{"jane@example.com", []string{"corp.example.com"}, false},
+		{"jane", []string{"corp.example.com"}, false},
+	}
+
+	for _, c := range cases {
+		if allowed := domainAllowed(c.email, c.domains); allowed != c.expected {
+			t.Fatalf("%s in %v: expected %v, got %v", c.email, c.domains, c.expected, allowed)
+		}
+	}
+
+	if InDomains("jane@corp.example.com", nil) {
+		t.Fatal("Expected no email to be in the empty domains")
+	}
+}
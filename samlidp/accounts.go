@@ -0,0 +1,50 @@
+package samlidp
+
+import (
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+// Fields of the account chooser form. The sessions are referenced by their session index, so the session IDs,
+// which authenticate the user, are never shown on the page.
+const (
+	// AccountParam is the name of the field that holds the index of the session the user continues with.
+	AccountParam = "account"
+	// AddAccountParam is the name of the field set when the user signs in with another account.
+	AddAccountParam = "add-account"
+	// SignOutParam is the name of the field that holds the index of the session the user signs out of.
+	SignOutParam = "sign-out"
+)
+
+// FindSession returns the session with the session index, nil if there is none.
+func FindSession(sessions []*db.Session, index string) *db.Session {
+	if index == "" {
+		return nil
+	}
+
+	for _, session := range sessions {
+		if session.Index == index {
+			return session
+		}
+	}
+
+	return nil
+}
+
+// AccountSessions returns the sessions the user may continue the login with: the sessions that satisfy the
+// authentication context requested by the service provider. No session is offered if the service provider
+// forces the user to authenticate again.
+func AccountSessions(sessions []*db.Session, req *saml.IdpAuthnRequest) []*db.Session {
+	accounts := []*db.Session{}
+	if ForceAuthn(req) {
+		return accounts
+	}
+
+	for _, session := range sessions {
+		if SatisfiesAuthnContext(req, session.AuthnMethods) {
+			accounts = append(accounts, session)
+		}
+	}
+
+	return accounts
+}
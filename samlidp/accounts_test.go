@@ -0,0 +1,54 @@
+package samlidp
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+func browserSessions() []*db.Session {
+	return []*db.Session{
+		{Session: saml.Session{ID: "personal", Index: "1", UserEmail: "jane@example.com"}, AuthnMethods: []string{AuthnMethodPassword}},
+		{Session: saml.Session{ID: "admin", Index: "2", UserEmail: "admin@example.com"}, AuthnMethods: []string{AuthnMethodWebAuthn}},
+	}
+}
+
+func TestFindSession(t *testing.T) {
+	sessions := browserSessions()
+
+	if session := FindSession(sessions, "2"); session == nil || session.ID != "admin" {
+		t.Fatalf("Expected the session with the index, got %+v", session)
+	}
+	if session := FindSession(sessions, "3"); session != nil {
+		t.Fatalf("Expected no session, got %+v", session)
+	}
+	if session := FindSession(sessions, ""); session != nil {
+		t.Fatalf("Expected no session for the empty index, got %+v", session)
+	}
+}
+
+func TestAccountSessions(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.IDP.SSOURL = url.URL{Scheme: "https", Host: "idp.example.com", Path: "/saml/idp/sso"}
+
+	req := authnRequestWithContext(&s.IDP, "")
+	if accounts := AccountSessions(browserSessions(), req); len(accounts) != 2 {
+		t.Fatalf("Expected all sessions, got %d", len(accounts))
+	}
+
+	req = authnRequestWithContext(&s.IDP, requestedContext("minimum", AuthnContextMFA))
+	if accounts := AccountSessions(browserSessions(), req); len(accounts) != 1 || accounts[0].ID != "admin" {
+		t.Fatalf("Expected the session satisfying the authentication context, got %+v", accounts)
+	}
+
+	forceAuthn := true
+	req.Request.ForceAuthn = &forceAuthn
+	if accounts := AccountSessions(browserSessions(), req); len(accounts) != 0 {
+		t.Fatalf("Expected no sessions when the login is forced, got %+v", accounts)
+	}
+}
@@ -0,0 +1,97 @@
+package samlidp
+
+import (
+	"fmt"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/crewjam/saml"
+)
+
+// AttributeNameFormatURI is the SAML name format for attributes named by URI.
+const AttributeNameFormatURI = "urn:oasis:names:tc:SAML:2.0:attrname-format:uri"
+
+// AttributeProfile produces the service provider specific attributes that are added to the assertion.
+type AttributeProfile interface {
+	// Attributes returns the attributes for the user of the session.
+	Attributes(req *saml.IdpAuthnRequest, session *saml.Session) ([]saml.Attribute, error)
+}
+
+// AssertionMaker makes the assertion with the saml.DefaultAssertionMaker and then adds the attributes
+// of the attribute profile configured for the service provider that sent the request.
+type AssertionMaker struct {
+	// Profiles is a map of <service provider entity ID>:<attribute profile>
+	Profiles map[string]AttributeProfile
+}
+
+// NewAssertionMaker creates an AssertionMaker with the attribute profiles set in the configuration.
+func NewAssertionMaker(cfg *config.Config) (*AssertionMaker, error) {
+	profiles := map[string]AttributeProfile{}
+
+	for entityID, spConfig := range cfg.ServiceProviders {
+		if spConfig == nil {
+			continue
+		}
+
+		switch spConfig.AttributeProfile {
+		case "":
+			continue
+		case AWSProfileName:
+			profile, err := NewAWSProfile(spConfig.AWS)
+			if err != nil {
+				return nil, fmt.Errorf("service provider %s: %s", entityID, err)
+			}
+			profiles[entityID] = profile
+		default:
+			return nil, fmt.Errorf("service provider %s: unknown attribute profile %q", entityID, spConfig.AttributeProfile)
+		}
+	}
+
+	return &AssertionMaker{
+		Profiles: profiles,
+	}, nil
+}
+
+// MakeAssertion creates the assertion for the request and assigns it to req.Assertion.
+func (m *AssertionMaker) MakeAssertion(req *saml.IdpAuthnRequest, session *saml.Session) error {
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		return err
+	}
+
+	if req.ServiceProviderMetadata == nil {
+		return nil
+	}
+
+	profile, ok := m.Profiles[req.ServiceProviderMetadata.EntityID]
+	if !ok {
+		return nil
+	}
+
+	attributes, err := profile.Attributes(req, session)
+	if err != nil {
+		return err
+	}
+
+	if len(req.Assertion.AttributeStatements) == 0 {
+		req.Assertion.AttributeStatements = []saml.AttributeStatement{{}}
+	}
+	req.Assertion.AttributeStatements[0].Attributes = append(req.Assertion.AttributeStatements[0].Attributes, attributes...)
+
+	return nil
+}
+
+// stringAttribute creates URI named attribute with xs:string values
+func stringAttribute(name string, values ...string) saml.Attribute {
+	attribute := saml.Attribute{
+		Name:       name,
+		NameFormat: AttributeNameFormatURI,
+	}
+
+	for _, value := range values {
+		attribute.Values = append(attribute.Values, saml.AttributeValue{
+			Type:  "xs:string",
+			Value: value,
+		})
+	}
+
+	return attribute
+}
@@ -0,0 +1,116 @@
+package samlidp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// Audit events of the logins, the sign-in links and the password resets.
+const (
+	// AuditLoginSucceeded is recorded when the user logs in.
+	AuditLoginSucceeded = "login.succeeded"
+	// AuditLoginFailed is recorded when the user could not be found with the credentials.
+	AuditLoginFailed = "login.failed"
+	// AuditSessionLimitReached is recorded when the login is rejected because the user already holds the maximal
+	// number of sessions.
+	AuditSessionLimitReached = "login.session-limit"
+	// AuditAccountInactive is recorded when the user has not activated the account.
+	AuditAccountInactive = "login.account-inactive"
+	// AuditAccountLocked is recorded when the account of the user is locked.
+	AuditAccountLocked = "login.account-locked"
+	// AuditPasswordExpired is recorded when the password of the user has expired.
+	AuditPasswordExpired = "login.password-expired"
+	// AuditPasswordResetRequested is recorded when the user requests the password reset email.
+	AuditPasswordResetRequested = "password-reset.requested"
+	// AuditPasswordReset is recorded when the user sets the new password.
+	AuditPasswordReset = "password-reset.completed"
+	// AuditMagicLinkRequested is recorded when the user requests the sign-in link.
+	AuditMagicLinkRequested = "magic-link.requested"
+	// AuditUpstreamLoginStarted is recorded when the user is sent to an upstream identity provider.
+	AuditUpstreamLoginStarted = "upstream-login.started"
+	// AuditUpstreamLoginFailed is recorded when the upstream identity provider did not authenticate the user.
+	AuditUpstreamLoginFailed = "upstream-login.failed"
+	// AuditSocialLoginStarted is recorded when the user is sent to a social login provider.
+	AuditSocialLoginStarted = "social-login.started"
+	// AuditSocialLoginFailed is recorded when the user could not sign in with a social login provider.
+	AuditSocialLoginFailed = "social-login.failed"
+	// AuditUserLinked is recorded when the user of an external identity source is linked to a local user.
+	AuditUserLinked = "user.linked"
+	// AuditUserProvisioned is recorded when a local user is created for the user of an external identity source.
+	AuditUserProvisioned = "user.provisioned"
+	// AuditProvisioningFailed is recorded when the user of an external identity source cannot be linked to a
+	// local user.
+	AuditProvisioningFailed = "provisioning.failed"
+	// AuditConsentGiven is recorded when the user consents to the release of the attributes to the service provider.
+	AuditConsentGiven = "consent.given"
+	// AuditConsentDenied is recorded when the user denies the release of the attributes to the service provider.
+	AuditConsentDenied = "consent.denied"
+	// AuditConsentRevoked is recorded when the user revokes the consent to a service provider.
+	AuditConsentRevoked = "consent.revoked"
+	// AuditAccountChosen is recorded when the user continues the login with one of the sessions on the account chooser.
+	AuditAccountChosen = "account.chosen"
+	// AuditSignedOut is recorded when the user signs out of one of the accounts on the account chooser.
+	AuditSignedOut = "session.signed-out"
+	// AuditSessionBindingMismatch is recorded when the bound session is used from a different client.
+	AuditSessionBindingMismatch = "session.binding-mismatch"
+)
+
+// AuditEvent records the outcome of a login or a password reset.
+type AuditEvent struct {
+	// Type is the type of the event, e.g. AuditLoginFailed
+	Type string
+	// Email is the email the user logged in with
+	Email string
+	// ServiceProvider is the entity ID of the service provider the user logged in to, empty for the standalone login
+	ServiceProvider string
+	// RemoteAddr is the address of the client
+	RemoteAddr string
+	// Time is the time of the event
+	Time time.Time
+}
+
+// Auditor records the audit events.
+type Auditor interface {
+	Audit(event *AuditEvent)
+}
+
+// AuditorFunc is a function used as Auditor.
+type AuditorFunc func(event *AuditEvent)
+
+// Audit calls the function with the event.
+func (f AuditorFunc) Audit(event *AuditEvent) {
+	f(event)
+}
+
+// LogAuditor returns the Auditor that writes the audit events to the log of the service.
+func LogAuditor(service *goa.Service) Auditor {
+	return AuditorFunc(func(event *AuditEvent) {
+		service.LogInfo("audit",
+			"event", event.Type,
+			"email", event.Email,
+			"serviceProvider", event.ServiceProvider,
+			"remoteAddr", event.RemoteAddr,
+			"time", event.Time.UTC().Format(time.RFC3339),
+		)
+	})
+}
+
+// NewAuditEvent creates the audit event of the login request. The service provider is taken from the
+// AuthnRequest, if any, and the address of the client from the X-Forwarded-For header of the trusted proxies.
+func NewAuditEvent(eventType string, r *http.Request, req *saml.IdpAuthnRequest, email string, trustedProxies []string) *AuditEvent {
+	event := &AuditEvent{
+		Type:       eventType,
+		Email:      email,
+		RemoteAddr: db.ClientIP(r, trustedProxies),
+		Time:       saml.TimeNow(),
+	}
+	if req != nil && req.ServiceProviderMetadata != nil {
+		event.ServiceProvider = req.ServiceProviderMetadata.EntityID
+	}
+
+	return event
+}
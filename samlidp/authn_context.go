@@ -0,0 +1,251 @@
+package samlidp
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/crewjam/saml"
+)
+
+// Authentication methods recorded in the session.
+const (
+	// AuthnMethodPassword - the user logged in with email and password.
+	AuthnMethodPassword = "password"
+	// AuthnMethodTOTP - the user entered a time-based one-time password.
+	AuthnMethodTOTP = "totp"
+	// AuthnMethodWebAuthn - the user authenticated with a WebAuthn authenticator.
+	AuthnMethodWebAuthn = "webauthn"
+	// AuthnMethodMagicLink - the user opened the sign-in link sent by email.
+	AuthnMethodMagicLink = "magic-link"
+	// AuthnMethodUpstream - the user logged in with an upstream identity provider.
+	AuthnMethodUpstream = "upstream"
+	// AuthnMethodSocial - the user logged in with a social login provider.
+	AuthnMethodSocial = "social"
+)
+
+// Authentication context classes, see the SAML 2.0 authentication context specification.
+const (
+	// AuthnContextUnspecified - the authentication method is not specified.
+	AuthnContextUnspecified = "urn:oasis:names:tc:SAML:2.0:ac:classes:unspecified"
+	// AuthnContextPassword - password over unprotected transport.
+	AuthnContextPassword = "urn:oasis:names:tc:SAML:2.0:ac:classes:Password"
+	// AuthnContextPasswordProtectedTransport - password over TLS.
+	AuthnContextPasswordProtectedTransport = "urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport"
+	// AuthnContextTimeSyncToken - password and time synchronized one-time password.
+	AuthnContextTimeSyncToken = "urn:oasis:names:tc:SAML:2.0:ac:classes:TimeSyncToken"
+	// AuthnContextMFA - multi-factor authentication as defined by the REFEDS MFA profile.
+	AuthnContextMFA = "https://refeds.org/profile/mfa"
+)
+
+// Comparison methods of the RequestedAuthnContext.
+const (
+	ComparisonExact   = "exact"
+	ComparisonMinimum = "minimum"
+	ComparisonMaximum = "maximum"
+	ComparisonBetter  = "better"
+)
+
+// SupportedAuthnMethods are the authentication methods the IdP can ask the user for.
+var SupportedAuthnMethods = []string{AuthnMethodPassword}
+
+// authnContextClass is an authentication context class the IdP can assert.
+type authnContextClass struct {
+	// Ref is the AuthnContextClassRef value
+	Ref string
+	// AnyOf lists the alternative sets of authentication methods that satisfy the class
+	AnyOf [][]string
+	// SecureTransport is set when the class requires the credentials to be sent over TLS
+	SecureTransport bool
+}
+
+// authnContextClasses are the supported authentication context classes ordered from the weakest to the strongest.
+var authnContextClasses = []authnContextClass{
+	{
+		// the sign-in link proves only the access to the mailbox, there is no class for it. The upstream
+		// identity providers do not tell how strong their authentication was
+		Ref:   AuthnContextUnspecified,
+		AnyOf: [][]string{{AuthnMethodMagicLink}, {AuthnMethodUpstream}, {AuthnMethodSocial}},
+	},
+	{
+		Ref:   AuthnContextPassword,
+		AnyOf: [][]string{{AuthnMethodPassword}},
+	},
+	{
+		Ref:             AuthnContextPasswordProtectedTransport,
+		AnyOf:           [][]string{{AuthnMethodPassword}},
+		SecureTransport: true,
+	},
+	{
+		Ref:             AuthnContextTimeSyncToken,
+		AnyOf:           [][]string{{AuthnMethodPassword, AuthnMethodTOTP}},
+		SecureTransport: true,
+	},
+	{
+		Ref:             AuthnContextMFA,
+		AnyOf:           [][]string{{AuthnMethodPassword, AuthnMethodTOTP}, {AuthnMethodWebAuthn}},
+		SecureTransport: true,
+	},
+}
+
+// requestedAuthnContext is the RequestedAuthnContext element of the AuthnRequest.
+type requestedAuthnContext struct {
+	Comparison            string   `xml:",attr"`
+	AuthnContextClassRefs []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion AuthnContextClassRef"`
+	AuthnContextDeclRefs  []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion AuthnContextDeclRef"`
+}
+
+type authnRequestContext struct {
+	RequestedAuthnContext *requestedAuthnContext `xml:"urn:oasis:names:tc:SAML:2.0:protocol RequestedAuthnContext"`
+}
+
+// SelectAuthnContext returns the authentication context class to assert for a session in which the user
+// authenticated with the given methods. StatusError with NoAuthnContext status is returned if the methods
+// do not satisfy the RequestedAuthnContext of the request. Sessions without recorded methods were created
+// with password login.
+func SelectAuthnContext(req *saml.IdpAuthnRequest, methods []string) (string, error) {
+	if len(methods) == 0 {
+		methods = []string{AuthnMethodPassword}
+	}
+
+	// satisfied holds the strength of every class the methods satisfy, from the weakest to the strongest
+	satisfied := []int{}
+	for i, class := range authnContextClasses {
+		if class.satisfiedBy(methods, req.IDP.SSOURL.Scheme == "https") {
+			satisfied = append(satisfied, i)
+		}
+	}
+	if len(satisfied) == 0 {
+		return "", noAuthnContext("the user is not authenticated")
+	}
+	strongest := authnContextClasses[satisfied[len(satisfied)-1]].Ref
+
+	authnRequest := authnRequestContext{}
+	if err := xml.Unmarshal(req.RequestBuffer, &authnRequest); err != nil {
+		return "", NewStatusError(StatusRequester, "", fmt.Sprintf("cannot parse the request: %s", err))
+	}
+	if authnRequest.RequestedAuthnContext == nil {
+		return strongest, nil
+	}
+	requested := authnRequest.RequestedAuthnContext
+
+	if len(requested.AuthnContextClassRefs) == 0 {
+		return "", noAuthnContext("only AuthnContextClassRef is supported in RequestedAuthnContext")
+	}
+
+	// strengths of the requested classes that are known to the IdP
+	requestedStrengths := []int{}
+	for _, ref := range requested.AuthnContextClassRefs {
+		if strength := authnContextStrength(ref); strength >= 0 {
+			requestedStrengths = append(requestedStrengths, strength)
+		}
+	}
+	if len(requestedStrengths) == 0 {
+		return "", noAuthnContext("none of the requested authentication context classes is supported")
+	}
+
+	minRequested, maxRequested := requestedStrengths[0], requestedStrengths[0]
+	for _, strength := range requestedStrengths {
+		if strength < minRequested {
+			minRequested = strength
+		}
+		if strength > maxRequested {
+			maxRequested = strength
+		}
+	}
+
+	switch requested.Comparison {
+	case "", ComparisonExact:
+		// the requested classes are in order of preference
+		for _, strength := range requestedStrengths {
+			for _, s := range satisfied {
+				if s == strength {
+					return authnContextClasses[strength].Ref, nil
+				}
+			}
+		}
+	case ComparisonMinimum:
+		if satisfied[len(satisfied)-1] >= minRequested {
+			return strongest, nil
+		}
+	case ComparisonBetter:
+		if satisfied[len(satisfied)-1] > minRequested {
+			return strongest, nil
+		}
+	case ComparisonMaximum:
+		for i := len(satisfied) - 1; i >= 0; i-- {
+			if satisfied[i] <= maxRequested {
+				return authnContextClasses[satisfied[i]].Ref, nil
+			}
+		}
+	default:
+		return "", NewStatusError(StatusRequester, "", fmt.Sprintf("unknown authentication context comparison %q", requested.Comparison))
+	}
+
+	return "", noAuthnContext("the requested authentication context cannot be satisfied")
+}
+
+// SatisfiesAuthnContext checks if the authentication methods satisfy the RequestedAuthnContext of the request.
+func SatisfiesAuthnContext(req *saml.IdpAuthnRequest, methods []string) bool {
+	_, err := SelectAuthnContext(req, methods)
+	return err == nil
+}
+
+// SetAuthnContext sets the authentication context class of the assertion made for the request.
+func SetAuthnContext(req *saml.IdpAuthnRequest, classRef string) {
+	if req.Assertion == nil {
+		return
+	}
+
+	for i := range req.Assertion.AuthnStatements {
+		req.Assertion.AuthnStatements[i].AuthnContext.AuthnContextClassRef = &saml.AuthnContextClassRef{
+			Value: classRef,
+		}
+	}
+}
+
+// satisfiedBy checks if the class is satisfied by the authentication methods.
+func (c authnContextClass) satisfiedBy(methods []string, secureTransport bool) bool {
+	if c.SecureTransport && !secureTransport {
+		return false
+	}
+
+	for _, required := range c.AnyOf {
+		if containsAll(methods, required) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authnContextStrength returns the strength of the authentication context class or -1 if the class is not supported.
+func authnContextStrength(classRef string) int {
+	for i, class := range authnContextClasses {
+		if class.Ref == classRef {
+			return i
+		}
+	}
+	return -1
+}
+
+// noAuthnContext creates the error returned when the requested authentication context cannot be satisfied
+func noAuthnContext(message string) *StatusError {
+	return NewStatusError(StatusResponder, StatusNoAuthnContext, message)
+}
+
+// containsAll checks if all of the values are in the list
+func containsAll(list []string, values []string) bool {
+	for _, value := range values {
+		found := false
+		for _, item := range list {
+			if item == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
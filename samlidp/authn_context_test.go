@@ -0,0 +1,109 @@
+package samlidp
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/crewjam/saml"
+)
+
+// authnRequestWithContext creates AuthnRequest with the given RequestedAuthnContext element
+func authnRequestWithContext(idp *saml.IdentityProvider, requestedAuthnContext string) *saml.IdpAuthnRequest {
+	return &saml.IdpAuthnRequest{
+		IDP: idp,
+		RequestBuffer: []byte(fmt.Sprintf(`<samlp:AuthnRequest xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="id-1" Version="2.0">`+
+			`<saml:Issuer>https://localhost:8082/user-profile/saml/metadata</saml:Issuer>%s</samlp:AuthnRequest>`, requestedAuthnContext)),
+	}
+}
+
+func requestedContext(comparison string, classRefs ...string) string {
+	refs := ""
+	for _, ref := range classRefs {
+		refs += fmt.Sprintf("<saml:AuthnContextClassRef>%s</saml:AuthnContextClassRef>", ref)
+	}
+	return fmt.Sprintf(`<samlp:RequestedAuthnContext Comparison="%s">%s</samlp:RequestedAuthnContext>`, comparison, refs)
+}
+
+func TestSelectAuthnContext(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpsIDP := s.IDP
+	httpsIDP.SSOURL = url.URL{Scheme: "https", Host: "idp.example.com", Path: "/saml/idp/sso"}
+
+	password := []string{AuthnMethodPassword}
+	passwordTOTP := []string{AuthnMethodPassword, AuthnMethodTOTP}
+	all := []string{AuthnMethodPassword, AuthnMethodTOTP, AuthnMethodWebAuthn}
+
+	tests := []struct {
+		name      string
+		idp       *saml.IdentityProvider
+		requested string
+		methods   []string
+		expected  string
+		status    string
+	}{
+		{"no requested context over http", &s.IDP, "", password, AuthnContextPassword, ""},
+		{"no requested context over https", &httpsIDP, "", password, AuthnContextPasswordProtectedTransport, ""},
+		{"sessions without methods", &httpsIDP, "", nil, AuthnContextPasswordProtectedTransport, ""},
+		{"exact", &httpsIDP, requestedContext("exact", AuthnContextPasswordProtectedTransport), password, AuthnContextPasswordProtectedTransport, ""},
+		{"exact by default", &httpsIDP, requestedContext("", AuthnContextTimeSyncToken, AuthnContextPassword), passwordTOTP, AuthnContextTimeSyncToken, ""},
+		{"exact not satisfied", &httpsIDP, requestedContext("exact", AuthnContextTimeSyncToken), password, "", StatusNoAuthnContext},
+		{"exact over http", &s.IDP, requestedContext("exact", AuthnContextPasswordProtectedTransport), password, "", StatusNoAuthnContext},
+		{"minimum", &httpsIDP, requestedContext("minimum", AuthnContextPassword), passwordTOTP, AuthnContextMFA, ""},
+		{"minimum not satisfied", &httpsIDP, requestedContext("minimum", AuthnContextTimeSyncToken), password, "", StatusNoAuthnContext},
+		{"better", &httpsIDP, requestedContext("better", AuthnContextPasswordProtectedTransport), all, AuthnContextMFA, ""},
+		{"better not satisfied", &httpsIDP, requestedContext("better", AuthnContextPasswordProtectedTransport), password, "", StatusNoAuthnContext},
+		{"maximum", &httpsIDP, requestedContext("maximum", AuthnContextPasswordProtectedTransport), all, AuthnContextPasswordProtectedTransport, ""},
+		{"maximum webauthn only", &httpsIDP, requestedContext("maximum", AuthnContextPasswordProtectedTransport), []string{AuthnMethodWebAuthn}, "", StatusNoAuthnContext},
+		{"magic link", &httpsIDP, "", []string{AuthnMethodMagicLink}, AuthnContextUnspecified, ""},
+		{"magic link not satisfying password", &httpsIDP, requestedContext("exact", AuthnContextPasswordProtectedTransport), []string{AuthnMethodMagicLink}, "", StatusNoAuthnContext},
+		{"magic link minimum", &httpsIDP, requestedContext("minimum", AuthnContextPassword), []string{AuthnMethodMagicLink}, "", StatusNoAuthnContext},
+		{"upstream", &httpsIDP, "", []string{AuthnMethodUpstream}, AuthnContextUnspecified, ""},
+		{"upstream not satisfying password", &httpsIDP, requestedContext("exact", AuthnContextPassword), []string{AuthnMethodUpstream}, "", StatusNoAuthnContext},
+		{"social", &httpsIDP, "", []string{AuthnMethodSocial}, AuthnContextUnspecified, ""},
+		{"unknown class", &httpsIDP, requestedContext("exact", "urn:example:unknown"), all, "", StatusNoAuthnContext},
+		{"unknown comparison", &httpsIDP, requestedContext("stronger", AuthnContextPassword), all, "", StatusRequester},
+	}
+
+	for _, test := range tests {
+		classRef, err := SelectAuthnContext(authnRequestWithContext(test.idp, test.requested), test.methods)
+
+		if test.status == "" {
+			if err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+			if classRef != test.expected {
+				t.Fatalf("%s: expected %s, got %s", test.name, test.expected, classRef)
+			}
+			continue
+		}
+
+		statusErr, ok := err.(*StatusError)
+		if !ok {
+			t.Fatalf("%s: expected StatusError, got %v", test.name, err)
+		}
+		if statusErr.SubStatus != test.status && statusErr.Status != test.status {
+			t.Fatalf("%s: expected status %s, got %s %s", test.name, test.status, statusErr.Status, statusErr.SubStatus)
+		}
+	}
+}
+
+func TestSatisfiesAuthnContext(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.IDP.SSOURL = url.URL{Scheme: "https", Host: "idp.example.com", Path: "/saml/idp/sso"}
+
+	req := authnRequestWithContext(&s.IDP, requestedContext("minimum", AuthnContextMFA))
+	if SatisfiesAuthnContext(req, SupportedAuthnMethods) {
+		t.Fatal("Expected MFA not to be satisfied with the supported methods")
+	}
+	if !SatisfiesAuthnContext(req, []string{AuthnMethodWebAuthn}) {
+		t.Fatal("Expected MFA to be satisfied with WebAuthn")
+	}
+}
@@ -0,0 +1,110 @@
+package samlidp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/crewjam/saml"
+)
+
+const (
+	// AWSProfileName is the name of the AWS IAM/console federation attribute profile.
+	AWSProfileName = "aws"
+
+	// AWSRoleAttribute holds the "<role ARN>,<provider ARN>" pairs the user may assume.
+	AWSRoleAttribute = "https://aws.amazon.com/SAML/Attributes/Role"
+
+	// AWSRoleSessionNameAttribute holds the identifier of the user in the AWS session.
+	AWSRoleSessionNameAttribute = "https://aws.amazon.com/SAML/Attributes/RoleSessionName"
+
+	// AWSSessionDurationAttribute holds the duration of the AWS console session in seconds.
+	AWSSessionDurationAttribute = "https://aws.amazon.com/SAML/Attributes/SessionDuration"
+)
+
+const (
+	awsDefaultSessionDuration = 3600
+	awsMinSessionDuration     = 900
+	awsMaxSessionDuration     = 43200
+	awsMaxRoleSessionName     = 64
+)
+
+// awsRoleSessionNameInvalid matches the characters AWS does not allow in RoleSessionName
+var awsRoleSessionNameInvalid = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// AWSProfile is the attribute profile for AWS IAM/console federation. The Role attribute is computed
+// from the user roles using the configured role to ARN mapping.
+type AWSProfile struct {
+	*config.AWSConfig
+}
+
+// NewAWSProfile creates the AWS attribute profile and validates its configuration.
+func NewAWSProfile(cfg *config.AWSConfig) (*AWSProfile, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("missing aws configuration")
+	}
+
+	for role, awsRoles := range cfg.Roles {
+		for _, awsRole := range awsRoles {
+			if awsRole.RoleARN == "" || awsRole.ProviderARN == "" {
+				return nil, fmt.Errorf("aws role mapping for %q must have both roleArn and providerArn", role)
+			}
+		}
+	}
+
+	if cfg.SessionDuration != 0 && (cfg.SessionDuration < awsMinSessionDuration || cfg.SessionDuration > awsMaxSessionDuration) {
+		return nil, fmt.Errorf("aws session duration must be between %d and %d seconds", awsMinSessionDuration, awsMaxSessionDuration)
+	}
+
+	switch cfg.RoleSessionName {
+	case "", "email", "username":
+	default:
+		return nil, fmt.Errorf("unknown aws role session name %q", cfg.RoleSessionName)
+	}
+
+	return &AWSProfile{cfg}, nil
+}
+
+// Attributes returns the Role, RoleSessionName and SessionDuration attributes.
+func (p *AWSProfile) Attributes(req *saml.IdpAuthnRequest, session *saml.Session) ([]saml.Attribute, error) {
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, group := range session.Groups {
+		for _, awsRole := range p.Roles[group] {
+			value := fmt.Sprintf("%s,%s", awsRole.RoleARN, awsRole.ProviderARN)
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			roles = append(roles, value)
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil, NewStatusError(StatusResponder, StatusRequestDenied, fmt.Sprintf("user %s is not allowed to assume any AWS role", session.UserName))
+	}
+
+	sessionName := session.UserEmail
+	if p.RoleSessionName == "username" {
+		sessionName = session.UserName
+	}
+	sessionName = awsRoleSessionNameInvalid.ReplaceAllString(sessionName, "_")
+	if len(sessionName) > awsMaxRoleSessionName {
+		sessionName = sessionName[:awsMaxRoleSessionName]
+	}
+	if len(sessionName) < 2 {
+		return nil, fmt.Errorf("cannot compute AWS role session name for user %s", session.UserName)
+	}
+
+	duration := p.SessionDuration
+	if duration == 0 {
+		duration = awsDefaultSessionDuration
+	}
+
+	return []saml.Attribute{
+		stringAttribute(AWSRoleAttribute, roles...),
+		stringAttribute(AWSRoleSessionNameAttribute, sessionName),
+		stringAttribute(AWSSessionDurationAttribute, strconv.Itoa(duration)),
+	}, nil
+}
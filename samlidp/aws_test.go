@@ -0,0 +1,188 @@
+package samlidp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+var awsConfig = &config.AWSConfig{
+	Roles: map[string][]config.AWSRole{
+		"admin": {
+			{RoleARN: "arn:aws:iam::123456789012:role/Admin", ProviderARN: "arn:aws:iam::123456789012:saml-provider/Jormungandr"},
+		},
+		"user": {
+			{RoleARN: "arn:aws:iam::123456789012:role/ReadOnly", ProviderARN: "arn:aws:iam::123456789012:saml-provider/Jormungandr"},
+		},
+	},
+	SessionDuration: 7200,
+}
+
+func TestNewAWSProfile(t *testing.T) {
+	if _, err := NewAWSProfile(awsConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAWSProfile(nil); err == nil {
+		t.Fatal("Nil error, expected: missing aws configuration")
+	}
+
+	_, err := NewAWSProfile(&config.AWSConfig{SessionDuration: 60})
+	if err == nil {
+		t.Fatal("Nil error, expected: aws session duration must be between 900 and 43200 seconds")
+	}
+
+	_, err = NewAWSProfile(&config.AWSConfig{
+		Roles: map[string][]config.AWSRole{
+			"admin": {{RoleARN: "arn:aws:iam::123456789012:role/Admin"}},
+		},
+	})
+	if err == nil {
+		t.Fatal("Nil error, expected: aws role mapping must have both roleArn and providerArn")
+	}
+}
+
+func TestAWSProfileAttributes(t *testing.T) {
+	profile, err := NewAWSProfile(awsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &saml.Session{
+		UserName:  "59ce17c60000000000000000",
+		UserEmail: "john+aws@host.com",
+		Groups:    []string{"admin", "user", "admin"},
+	}
+
+	attributes, err := profile.Attributes(nil, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []saml.Attribute{
+		{
+			Name:       "https://aws.amazon.com/SAML/Attributes/Role",
+			NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:uri",
+			Values: []saml.AttributeValue{
+				{Type: "xs:string", Value: "arn:aws:iam::123456789012:role/Admin,arn:aws:iam::123456789012:saml-provider/Jormungandr"},
+				{Type: "xs:string", Value: "arn:aws:iam::123456789012:role/ReadOnly,arn:aws:iam::123456789012:saml-provider/Jormungandr"},
+			},
+		},
+		{
+			Name:       "https://aws.amazon.com/SAML/Attributes/RoleSessionName",
+			NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:uri",
+			Values:     []saml.AttributeValue{{Type: "xs:string", Value: "john+aws@host.com"}},
+		},
+		{
+			Name:       "https://aws.amazon.com/SAML/Attributes/SessionDuration",
+			NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:uri",
+			Values:     []saml.AttributeValue{{Type: "xs:string", Value: "7200"}},
+		},
+	}
+
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Fatalf("Expected attributes %+v, got %+v", expected, attributes)
+	}
+
+	session.Groups = []string{"guest"}
+	if _, err := profile.Attributes(nil, session); err == nil {
+		t.Fatal("Nil error, expected: user is not allowed to assume any AWS role")
+	}
+}
+
+func TestAWSProfileRoleSessionName(t *testing.T) {
+	profile, err := NewAWSProfile(&config.AWSConfig{
+		Roles:           awsConfig.Roles,
+		RoleSessionName: "username",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes, err := profile.Attributes(nil, &saml.Session{
+		UserName: "john doe/ops",
+		Groups:   []string{"user"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := attributes[1].Values[0].Value; value != "john_doe_ops" {
+		t.Fatalf("Expected RoleSessionName john_doe_ops, got %s", value)
+	}
+	if value := attributes[2].Values[0].Value; value != "3600" {
+		t.Fatalf("Expected default SessionDuration 3600, got %s", value)
+	}
+}
+
+func TestMakeAssertionAWSProfile(t *testing.T) {
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso?RelayState=_L5_YvLMqRfj0KX5A62TIKfOHMYVeboixBRg8yYxIwSjp7wmjca2OIRA&SAMLRequest=nJJBj9MwEIX%2FijX3NE7CdlNrE6lshai0QLUtHLhNnCm15NjBMwH236M2i7RwqNBe7XnfvGe%2FO8bBj2Y9ySk80veJWNSvwQc254sGphRMRHZsAg7ERqzZrz88mHKhDTJTEhcDvJCM1zVjihJt9KC2mwZcn73BsqqON93t0tpiRcWqrrRdEna1LYpld2O17vqqrEF9ocQuhgbKhQa1ZZ5oG1gwSAOlLm%2BzQme6OpSFqbQp9GJV1V9BbYjFBZSL8iQymjz30aI%2FRRZT61rnZ9u568ecOYJa%2F0l1HwNPA6U9pR%2FO0ufHhxnA%2FxLKfGJK2Zji0XmacWgZ1O457FsXehe%2BXX%2BZbh5i8%2F5w2GW7T%2FsDtJffMZeoSb2LaUC5DjmfuD47XkYNBXHyBO1%2Fux5IsEfBu%2FzF4va5Ix9xoO1mF72zT68wIwkDOwoCau19%2FHmfCIUakDQR5O288u8mtr8DAAD%2F%2Fw%3D%3D", nil)
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertionMaker, err := NewAssertionMaker(&config.Config{
+		ServiceProviders: map[string]*config.ServiceProviderConfig{
+			"https://localhost:8082/user-profile/saml/metadata": {
+				AttributeProfile: "aws",
+				AWS:              awsConfig,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.IDP.AssertionMaker = assertionMaker
+	s.IDP.ServiceProviderProvider = db.New()
+	req, err := ValidateSamlRequest(&s.IDP, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &db.Session{
+		Session: saml.Session{
+			ID:        "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=",
+			UserName:  "59ce17c60000000000000000",
+			UserEmail: "example@host.com",
+			Groups:    []string{"user"},
+		},
+	}
+
+	if err = MakeAssertion(req, &s.IDP, session); err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	for _, attribute := range req.Assertion.AttributeStatements[0].Attributes {
+		found[attribute.Name] = true
+	}
+	for _, name := range []string{AWSRoleAttribute, AWSRoleSessionNameAttribute, AWSSessionDurationAttribute} {
+		if !found[name] {
+			t.Fatalf("Expected attribute %s in the assertion", name)
+		}
+	}
+
+	session.Groups = []string{"guest"}
+	if err = MakeAssertion(req, &s.IDP, session); err == nil {
+		t.Fatal("Nil error, expected: user is not allowed to assume any AWS role")
+	}
+}
+
+func TestNewAssertionMakerUnknownProfile(t *testing.T) {
+	_, err := NewAssertionMaker(&config.Config{
+		ServiceProviders: map[string]*config.ServiceProviderConfig{
+			"https://sp.example.com/metadata": {
+				AttributeProfile: "unknown",
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("Nil error, expected: unknown attribute profile")
+	}
+}
@@ -0,0 +1,144 @@
+package samlidp
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// ConsentParam is the name of the consent form field that holds the decision of the user.
+const ConsentParam = "consent"
+
+// ConsentAccept is the value of ConsentParam the user consents with.
+const ConsentAccept = "accept"
+
+// ConsentServiceProviderParam is the name of the form field that holds the entity ID of the service provider
+// the consent is revoked for.
+const ConsentServiceProviderParam = "serviceProvider"
+
+// ConsentStore keeps the consents of the users to release their attributes to the service providers.
+type ConsentStore interface {
+	// AddConsent saves the consent
+	AddConsent(consent *db.Consent) error
+	// GetConsent looks up the consent by its ID
+	GetConsent(consentID string) (*db.Consent, error)
+}
+
+// ReleasedAttribute is an attribute of the assertion, as shown to the user asked for consent.
+type ReleasedAttribute struct {
+	// Name is the friendly name of the attribute, or the name if it has none
+	Name string
+	// Values are the values of the attribute
+	Values []string
+}
+
+// ReleasedAttributes returns the attributes of the assertion made for the request, sorted by name. The
+// attributes with the same name are merged.
+func ReleasedAttributes(req *saml.IdpAuthnRequest) []ReleasedAttribute {
+	values := map[string][]string{}
+	if req.Assertion != nil {
+		for _, statement := range req.Assertion.AttributeStatements {
+			for _, attribute := range statement.Attributes {
+				name := attribute.FriendlyName
+				if name == "" {
+					name = attribute.Name
+				}
+				if _, ok := values[name]; !ok {
+					values[name] = []string{}
+				}
+				for _, value := range attribute.Values {
+					values[name] = append(values[name], value.Value)
+				}
+			}
+		}
+	}
+
+	attributes := []ReleasedAttribute{}
+	for name, v := range values {
+		attributes = append(attributes, ReleasedAttribute{Name: name, Values: v})
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Name < attributes[j].Name
+	})
+
+	return attributes
+}
+
+// AttributeNames returns the names of the attributes.
+func AttributeNames(attributes []ReleasedAttribute) []string {
+	names := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		names[i] = attribute.Name
+	}
+
+	return names
+}
+
+// ConsentRequired checks if the user of the session must consent before the attributes of the assertion made
+// for the request are released. The consent is not required when it is not enabled, for the internal service
+// providers, for the assertions without attributes and when the user has already consented to release the
+// same attributes, or more, to the service provider. The values of the attributes may change without a new
+// consent.
+func ConsentRequired(store ConsentStore, cfg *config.Config, req *saml.IdpAuthnRequest, session *db.Session) (bool, error) {
+	if !cfg.Consent.Enabled || req.ServiceProviderMetadata == nil {
+		return false, nil
+	}
+
+	if spConfig, ok := cfg.ServiceProviders[req.ServiceProviderMetadata.EntityID]; ok && spConfig != nil && spConfig.Internal {
+		return false, nil
+	}
+
+	names := AttributeNames(ReleasedAttributes(req))
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	consent, err := store.GetConsent(db.ConsentID(session.UserName, req.ServiceProviderMetadata.EntityID))
+	if err != nil {
+		if e, ok := err.(*goa.ErrorResponse); ok && e.Status == http.StatusNotFound {
+			return true, nil
+		}
+		return false, err
+	}
+
+	consented := map[string]bool{}
+	for _, name := range consent.Attributes {
+		consented[name] = true
+	}
+	for _, name := range names {
+		if !consented[name] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SaveConsent records the consent of the user to release the attributes to the service provider. The consent
+// replaces the earlier consent of the user to the service provider, if any.
+func SaveConsent(store ConsentStore, userID string, serviceProviderID string, attributes []string) error {
+	names := append([]string{}, attributes...)
+	sort.Strings(names)
+
+	return store.AddConsent(&db.Consent{
+		ID:                db.ConsentID(userID, serviceProviderID),
+		UserID:            userID,
+		ServiceProviderID: serviceProviderID,
+		Attributes:        names,
+		CreateTime:        saml.TimeNow(),
+	})
+}
+
+// ServiceProviderName returns the name of the service provider shown to the user: the display name of its
+// theme, or the entity ID if it has none.
+func ServiceProviderName(store ThemeStore, serviceProviderID string) string {
+	if theme, err := store.GetTheme(serviceProviderID); err == nil && theme.DisplayName != "" {
+		return theme.DisplayName
+	}
+
+	return serviceProviderID
+}
@@ -0,0 +1,95 @@
+package samlidp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+// consentRequest returns the request of the service provider with the assertion releasing the attributes
+func consentRequest(attributes ...saml.Attribute) *saml.IdpAuthnRequest {
+	return &saml.IdpAuthnRequest{
+		ServiceProviderMetadata: &saml.EntityDescriptor{EntityID: "https://sp.example.com/saml/metadata"},
+		Assertion: &saml.Assertion{
+			AttributeStatements: []saml.AttributeStatement{{Attributes: attributes}},
+		},
+	}
+}
+
+func TestReleasedAttributes(t *testing.T) {
+	req := consentRequest(
+		attribute("urn:oid:2.5.4.42", "givenName", "Jane"),
+		attribute("urn:oid:1.3.6.1.4.1.5923.1.1.1.1", "eduPersonAffiliation", "staff"),
+		attribute("department", "", "Sales"),
+		attribute("urn:oid:1.3.6.1.4.1.5923.1.1.1.1", "eduPersonAffiliation", "admin"),
+	)
+
+	expected := []ReleasedAttribute{
+		{Name: "department", Values: []string{"Sales"}},
+		{Name: "eduPersonAffiliation", Values: []string{"staff", "admin"}},
+		{Name: "givenName", Values: []string{"Jane"}},
+	}
+	if attributes := ReleasedAttributes(req); !reflect.DeepEqual(attributes, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, attributes)
+	}
+
+	if attributes := ReleasedAttributes(&saml.IdpAuthnRequest{}); len(attributes) != 0 {
+		t.Fatalf("Expected no attributes without the assertion, got %+v", attributes)
+	}
+}
+
+func TestConsentRequired(t *testing.T) {
+	store := db.New()
+	session := &db.Session{Session: saml.Session{UserName: "59ce17c60000000000000000"}}
+	cfg := &config.Config{Consent: config.ConsentConfig{Enabled: true}}
+	req := consentRequest(attribute("uid", "uid", "jane"), attribute("mail", "eduPersonPrincipalName", "jane@example.com"))
+
+	required, err := ConsentRequired(store, cfg, req, session)
+	if err != nil || !required {
+		t.Fatalf("Expected the consent to be required on the first login, got %v %v", required, err)
+	}
+
+	if err := SaveConsent(store, session.UserName, "https://sp.example.com/saml/metadata", []string{"uid", "eduPersonPrincipalName"}); err != nil {
+		t.Fatal(err)
+	}
+	consent, err := store.GetConsent(db.ConsentID(session.UserName, "https://sp.example.com/saml/metadata"))
+	if err != nil || !reflect.DeepEqual(consent.Attributes, []string{"eduPersonPrincipalName", "uid"}) {
+		t.Fatalf("Expected the sorted attribute names to be saved, got %+v %v", consent, err)
+	}
+
+	if required, err := ConsentRequired(store, cfg, req, session); err != nil || required {
+		t.Fatalf("Expected no consent for the consented attributes, got %v %v", required, err)
+	}
+
+	// a new attribute needs a new consent
+	req = consentRequest(attribute("uid", "uid", "jane"), attribute("sn", "sn", "Doe"))
+	if required, err := ConsentRequired(store, cfg, req, session); err != nil || !required {
+		t.Fatalf("Expected the consent to be required for the new attribute, got %v %v", required, err)
+	}
+
+	cfg.ServiceProviders = map[string]*config.ServiceProviderConfig{"https://sp.example.com/saml/metadata": {Internal: true}}
+	if required, _ := ConsentRequired(store, cfg, req, session); required {
+		t.Fatal("Expected no consent for the internal service provider")
+	}
+
+	cfg = &config.Config{}
+	if required, _ := ConsentRequired(store, cfg, req, session); required {
+		t.Fatal("Expected no consent when it is not enabled")
+	}
+}
+
+func TestConsentRequiredError(t *testing.T) {
+	store := db.New()
+	cfg := &config.Config{Consent: config.ConsentConfig{Enabled: true}}
+	req := consentRequest(attribute("uid", "uid", "jane"))
+
+	if err := SaveConsent(store, "internal-server-error", "https://sp.example.com/saml/metadata", []string{"uid"}); err == nil {
+		t.Fatal("Expected the error of the store")
+	}
+	if _, err := ConsentRequired(store, cfg, req, &db.Session{Session: saml.Session{UserName: "internal-server-error"}}); err == nil {
+		t.Fatal("Expected the error of the store")
+	}
+}
@@ -0,0 +1,85 @@
+package samlidp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+)
+
+// SameSite modes of the session cookie, see config.SessionCookieConfig.
+const (
+	// SameSiteNone sends the cookie with the cross-site requests too.
+	SameSiteNone = "none"
+	// SameSiteLax sends the cookie with the cross-site top-level navigations, but not with the cross-site posts.
+	SameSiteLax = "lax"
+	// SameSiteStrict sends the cookie with the same-site requests only.
+	SameSiteStrict = "strict"
+)
+
+// IsSecureRequest checks if the client connected over HTTPS, either to the IdP directly or to one of the trusted
+// proxies, which tell it with the X-Forwarded-Proto header. The header sent by the other clients is ignored, as
+// anyone can set it.
+func IsSecureRequest(r *http.Request, trustedProxies []string) bool {
+	if r.TLS != nil || r.URL.Scheme == "https" {
+		return true
+	}
+
+	if !db.IsTrustedProxy(r.RemoteAddr, trustedProxies) {
+		return false
+	}
+
+	// the proxies chained in front of each other append their protocols, only the last one was set by the
+	// trusted proxy, the client may have sent the others
+	protos := strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(protos[len(protos)-1]), "https")
+}
+
+// SessionCookies returns the session cookie with the value and max age, as configured. With the __Host- prefix,
+// the cookie without the prefix the browser still holds is returned too, expired, so the browser drops it.
+func SessionCookies(cfg *config.Config, r *http.Request, value string, maxAge int) []*http.Cookie {
+	cookieCfg := &cfg.SessionCookie
+
+	cookie := &http.Cookie{
+		Name:     db.SessionCookieName(cookieCfg),
+		Value:    value,
+		Domain:   cookieCfg.Domain,
+		Path:     cookieCfg.Path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+
+	switch strings.ToLower(cookieCfg.SameSite) {
+	case SameSiteLax:
+		cookie.SameSite = http.SameSiteLaxMode
+		cookie.Secure = IsSecureRequest(r, cfg.TrustedProxies)
+	case SameSiteStrict:
+		cookie.SameSite = http.SameSiteStrictMode
+		cookie.Secure = IsSecureRequest(r, cfg.TrustedProxies)
+	default:
+		// the browsers accept SameSite=None only for the Secure cookies
+		cookie.SameSite = http.SameSiteNoneMode
+		cookie.Secure = true
+	}
+
+	if !cookieCfg.HostPrefix || !cookie.Secure || cookie.Domain != "" || cookie.Path != "/" {
+		return []*http.Cookie{cookie}
+	}
+
+	name := cookie.Name
+	cookie.Name = db.HostCookiePrefix + name
+	cookies := []*http.Cookie{cookie}
+	if _, err := r.Cookie(name); err == nil {
+		expired := *cookie
+		expired.Name = name
+		expired.Value = ""
+		expired.MaxAge = -1
+		cookies = append(cookies, &expired)
+	}
+
+	return cookies
+}
@@ -0,0 +1,87 @@
+package samlidp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+)
+
+func TestIsSecureRequest(t *testing.T) {
+	trustedProxies := []string{"10.0.0.5", "172.16.0.0/12"}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		tls        bool
+		secure     bool
+	}{
+		{"plain HTTP", "192.0.2.10:52000", "", false, false},
+		{"direct TLS", "192.0.2.10:52000", "", true, true},
+		{"untrusted proxy", "192.0.2.10:52000", "https", false, false},
+		{"trusted proxy address", "10.0.0.5:52000", "https", false, true},
+		{"trusted proxy network", "172.20.1.2:52000", "https", false, true},
+		{"trusted proxy over HTTP", "10.0.0.5:52000", "http", false, false},
+		{"chained proxies", "10.0.0.5:52000", "http, https", false, true},
+		{"spoofed header", "10.0.0.5:52000", "https, http", false, false},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+		r.RemoteAddr = c.remoteAddr
+		r.TLS = nil
+		if c.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		if c.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", c.proto)
+		}
+
+		if secure := IsSecureRequest(r, trustedProxies); secure != c.secure {
+			t.Fatalf("%s: expected secure %v, got %v", c.name, c.secure, secure)
+		}
+	}
+}
+
+func TestSessionCookies(t *testing.T) {
+	r := httptest.NewRequest("GET", "/saml/idp/sso", nil)
+	r.RemoteAddr = "10.0.0.5:52000"
+
+	// SameSite=None by default, always Secure
+	cookies := SessionCookies(&config.Config{}, r, "id", 60)
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].SameSite != http.SameSiteNoneMode || !cookies[0].Secure || !cookies[0].HttpOnly || cookies[0].Path != "/" {
+		t.Fatalf("Expected the Secure SameSite=None session cookie, got %+v", cookies)
+	}
+
+	// SameSite=Lax is Secure only over HTTPS
+	cfg := &config.Config{SessionCookie: config.SessionCookieConfig{Name: "idp_session", Domain: "example.com", Path: "/saml", SameSite: "lax"}}
+	cookie := SessionCookies(cfg, r, "id", 60)[0]
+	if cookie.Name != "idp_session" || cookie.Domain != "example.com" || cookie.Path != "/saml" || cookie.SameSite != http.SameSiteLaxMode || cookie.Secure {
+		t.Fatalf("Expected the configured cookie without Secure, got %+v", cookie)
+	}
+
+	cfg.TrustedProxies = []string{"10.0.0.5"}
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if cookie := SessionCookies(cfg, r, "id", 60)[0]; !cookie.Secure {
+		t.Fatalf("Expected the Secure cookie behind the trusted proxy, got %+v", cookie)
+	}
+
+	// the __Host- prefix is not possible with the domain
+	cfg.SessionCookie.HostPrefix = true
+	if cookie := SessionCookies(cfg, r, "id", 60)[0]; cookie.Name != "idp_session" {
+		t.Fatalf("Expected no prefix for the cookie with a domain, got %+v", cookie)
+	}
+
+	cfg.SessionCookie = config.SessionCookieConfig{HostPrefix: true}
+	r.AddCookie(&http.Cookie{Name: "session", Value: "old-id"})
+	cookies = SessionCookies(cfg, r, "id", 60)
+	if len(cookies) != 2 || cookies[0].Name != "__Host-session" || cookies[0].Value != "id" {
+		t.Fatalf("Expected the cookie with the __Host- prefix, got %+v", cookies)
+	}
+	if cookies[1].Name != "session" || cookies[1].MaxAge != -1 {
+		t.Fatalf("Expected the cookie without the prefix to be removed, got %+v", cookies[1])
+	}
+}
@@ -0,0 +1,73 @@
+package samlidp
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/Microkubes/identity-provider/i18n"
+)
+
+// CSRFCookieName is the name of the cookie that holds the CSRF token.
+const CSRFCookieName = "csrf_token"
+
+// CSRFParam is the name of the form field that holds the CSRF token.
+const CSRFParam = "csrf_token"
+
+// csrfTokenLength is the length of the CSRF token in bytes
+const csrfTokenLength = 32
+
+// ErrInvalidCSRFToken is returned when the CSRF token of the form is missing or does not match the cookie.
+var ErrInvalidCSRFToken = i18n.NewError("invalid-csrf-token", "The form has expired or was not submitted from this site. Please try again.")
+
+// CSRFToken returns the CSRF token of the browser. The forms must post the token back in the csrf_token field
+// (double-submit cookie). A new token is generated and set as cookie if the browser does not have a valid one.
+// The cookie is Secure if the client connected over HTTPS, see IsSecureRequest.
+func CSRFToken(w http.ResponseWriter, r *http.Request, trustedProxies []string) string {
+	if token := csrfCookie(r); token != "" {
+		return token
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(RandomBytes(csrfTokenLength))
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		HttpOnly: true,
+		Secure:   IsSecureRequest(r, trustedProxies),
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+
+	return token
+}
+
+// CheckCSRF checks that the CSRF token posted with the form matches the CSRF cookie. Cross-site posts
+// cannot read the cookie, so they cannot send the matching token.
+func CheckCSRF(r *http.Request) error {
+	token := csrfCookie(r)
+	if token == "" {
+		return ErrInvalidCSRFToken
+	}
+
+	if subtle.ConstantTimeCompare([]byte(r.PostFormValue(CSRFParam)), []byte(token)) != 1 {
+		return ErrInvalidCSRFToken
+	}
+
+	return nil
+}
+
+// csrfCookie returns the CSRF token from the cookie or empty string if the cookie is not set or is not
+// a token generated by the IdP
+func csrfCookie(r *http.Request) string {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil {
+		return ""
+	}
+
+	token, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(token) != csrfTokenLength {
+		return ""
+	}
+
+	return cookie.Value
+}
@@ -0,0 +1,97 @@
+package samlidp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFToken(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/idp/login", nil)
+
+	token := CSRFToken(w, r, nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CSRFCookieName || cookies[0].Value != token {
+		t.Fatalf("Expected the CSRF cookie with the token, got: %v", cookies)
+	}
+	if !cookies[0].HttpOnly {
+		t.Fatal("Expected HttpOnly CSRF cookie")
+	}
+
+	// the token of the browser is reused
+	w = httptest.NewRecorder()
+	r.AddCookie(cookies[0])
+	if CSRFToken(w, r, nil) != token {
+		t.Fatal("Expected the token from the cookie")
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatal("Expected the CSRF cookie not to be set again")
+	}
+
+	// tokens not generated by the IdP are replaced
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "https://idp.example.com/saml/idp/login", nil)
+	r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: `"><script>`})
+	if token := CSRFToken(w, r, nil); strings.Contains(token, "script") {
+		t.Fatalf("Expected a new token, got %s", token)
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/idp/login", nil)
+	token := CSRFToken(w, r, nil)
+	cookie := w.Result().Cookies()[0]
+
+	tests := []struct {
+		name      string
+		formToken string
+		cookie    *http.Cookie
+		valid     bool
+	}{
+		{"matching token", token, cookie, true},
+		{"missing cookie", token, nil, false},
+		{"missing token", "", cookie, false},
+		{"forged token", "forged", cookie, false},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("POST", "https://idp.example.com/saml/idp/login", strings.NewReader(url.Values{CSRFParam: {test.formToken}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.cookie != nil {
+			r.AddCookie(test.cookie)
+		}
+
+		if err := CheckCSRF(r); (err == nil) != test.valid {
+			t.Fatalf("%s: expected valid to be %t, got error %v", test.name, test.valid, err)
+		}
+	}
+
+	// the token in the query is not accepted, only the posted one
+	r, _ = http.NewRequest("POST", "https://idp.example.com/saml/idp/login?"+url.Values{CSRFParam: {token}}.Encode(), nil)
+	r.AddCookie(cookie)
+	if err := CheckCSRF(r); err == nil {
+		t.Fatal("Expected the token in the query to be rejected")
+	}
+}
+
+func TestCSRFTokenBehindProxy(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/saml/idp/login", nil)
+	r.RemoteAddr = "10.0.0.5:52000"
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	CSRFToken(w, r, nil)
+	if cookie := w.Result().Cookies()[0]; cookie.Secure {
+		t.Fatal("Expected the X-Forwarded-Proto of the untrusted client to be ignored")
+	}
+
+	w = httptest.NewRecorder()
+	CSRFToken(w, r, []string{"10.0.0.0/8"})
+	if cookie := w.Result().Cookies()[0]; !cookie.Secure {
+		t.Fatal("Expected Secure CSRF cookie behind the trusted proxy")
+	}
+}
@@ -1,13 +1,26 @@
 package samlidp
 
 import (
+	"encoding/xml"
+	"fmt"
 	"net/http"
 
+	"github.com/Microkubes/identity-provider/db"
 	"github.com/crewjam/saml"
 	"github.com/keitaroinc/goa"
 )
 
+// authnRequestTarget holds the AuthnRequest elements needed to find the assertion consumer service.
+type authnRequestTarget struct {
+	Issuer                        string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	AssertionConsumerServiceURL   string `xml:",attr"`
+	AssertionConsumerServiceIndex string `xml:",attr"`
+}
+
 // ValidateSamlRequest validates the  SAML requst. If it is not valid error is returned.
+// When the request is not valid, but it comes from a known service provider and names one of its
+// HTTP-POST assertion consumer services, the request is returned together with StatusError so the
+// error can be reported back to the service provider with WriteStatusError.
 func ValidateSamlRequest(idp *saml.IdentityProvider, r *http.Request) (*saml.IdpAuthnRequest, error) {
 	req, err := saml.NewIdpAuthnRequest(idp, r)
 	if err != nil {
@@ -15,20 +28,93 @@ func ValidateSamlRequest(idp *saml.IdentityProvider, r *http.Request) (*saml.Idp
 	}
 
 	if err := req.Validate(); err != nil {
+		if findACSEndpoint(req) {
+			return req, NewStatusError(StatusRequester, StatusRequestDenied, err.Error())
+		}
 		return nil, goa.ErrInvalidRequest(err)
 	}
 
+	if err := CheckRelayState(req.RelayState); err != nil {
+		// the RelayState must be returned unchanged or not at all
+		req.RelayState = ""
+		return req, err
+	}
+
 	return req, nil
 }
 
-// MakeAssertion creates the assersion that is returned to the Service Provider
-func MakeAssertion(req *saml.IdpAuthnRequest, idp *saml.IdentityProvider, session *saml.Session) error {
+// findACSEndpoint looks up the assertion consumer service of a request that did not pass the validation.
+// It returns false if the service provider or the assertion consumer service is not known.
+func findACSEndpoint(req *saml.IdpAuthnRequest) bool {
+	target := authnRequestTarget{}
+	if err := xml.Unmarshal(req.RequestBuffer, &target); err != nil || target.Issuer == "" {
+		return false
+	}
+
+	if req.IDP.ServiceProviderProvider == nil {
+		return false
+	}
+
+	serviceProvider, err := req.IDP.ServiceProviderProvider.GetServiceProvider(req.HTTPRequest, target.Issuer)
+	if err != nil || serviceProvider == nil {
+		return false
+	}
+
+	for _, spssoDescriptor := range serviceProvider.SPSSODescriptors {
+		for _, acs := range spssoDescriptor.AssertionConsumerServices {
+			if acs.Binding != saml.HTTPPostBinding {
+				continue
+			}
+
+			switch {
+			case target.AssertionConsumerServiceURL != "":
+				if acs.Location != target.AssertionConsumerServiceURL {
+					continue
+				}
+			case target.AssertionConsumerServiceIndex != "":
+				if fmt.Sprint(acs.Index) != target.AssertionConsumerServiceIndex {
+					continue
+				}
+			}
+
+			endpoint := acs
+			req.ServiceProviderMetadata = serviceProvider
+			req.ACSEndpoint = &endpoint
+			return true
+		}
+	}
+
+	return false
+}
+
+// MakeAssertion creates the assersion that is returned to the Service Provider. The assertion holds the
+// authentication context class selected for the session, see SelectAuthnContext.
+func MakeAssertion(req *saml.IdpAuthnRequest, idp *saml.IdentityProvider, session *db.Session) error {
+	classRef, err := SelectAuthnContext(req, session.AuthnMethods)
+	if err != nil {
+		return err
+	}
+
 	assertionMaker := idp.AssertionMaker
 	if assertionMaker == nil {
 		assertionMaker = saml.DefaultAssertionMaker{}
 	}
 
-	assertionMaker.MakeAssertion(req, session)
+	if err := assertionMaker.MakeAssertion(req, &session.Session); err != nil {
+		return err
+	}
+
+	SetAuthnContext(req, classRef)
 
 	return nil
 }
+
+// ForceAuthn checks if the service provider requires the user to log in again even if there is a valid session.
+func ForceAuthn(req *saml.IdpAuthnRequest) bool {
+	return req.Request.ForceAuthn != nil && *req.Request.ForceAuthn
+}
+
+// IsPassive checks if the service provider requires the IdP not to interact with the user.
+func IsPassive(req *saml.IdpAuthnRequest) bool {
+	return req.Request.IsPassive != nil && *req.Request.IsPassive
+}
@@ -1,13 +1,18 @@
 package samlidp
 
 import (
+	"bytes"
+	"compress/flate"
 	"crypto"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"flag"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -146,18 +151,158 @@ func TestMakeAssertion(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	session := &saml.Session{
-		ID:            "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=",
-		CreateTime:    saml.TimeNow(),
-		ExpireTime:    saml.TimeNow().Add(sessionMaxAge),
-		Index:         "2f5eefac59e6fa6b24a078e4f8da1e48441ec3afc25222e00ac127a4ab1db1ed",
-		Groups:        []string{"user"},
-		UserEmail:     "example@host.com",
-		UserGivenName: "john",
+	session := &db.Session{
+		Session: saml.Session{
+			ID:            "K7nAHhSfcJzOfqkB6kSWiSJWCh6jroIX9FrxZt6inuU=",
+			CreateTime:    saml.TimeNow(),
+			ExpireTime:    saml.TimeNow().Add(sessionMaxAge),
+			Index:         "2f5eefac59e6fa6b24a078e4f8da1e48441ec3afc25222e00ac127a4ab1db1ed",
+			Groups:        []string{"user"},
+			UserEmail:     "example@host.com",
+			UserGivenName: "john",
+		},
+		AuthnMethods: []string{AuthnMethodPassword},
 	}
 
 	err = MakeAssertion(req, &s.IDP, session)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	classRef := req.Assertion.AuthnStatements[0].AuthnContext.AuthnContextClassRef
+	if classRef == nil || classRef.Value != AuthnContextPassword {
+		t.Fatalf("Expected authentication context class %s", AuthnContextPassword)
+	}
+}
+
+// newSamlRequest creates HTTP-Redirect binding request with fresh AuthnRequest from the test service provider.
+// The attributes are added to the AuthnRequest element.
+func newSamlRequest(attributes string) *http.Request {
+	authnRequest := fmt.Sprintf(`<samlp:AuthnRequest xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" `+
+		`ID="id-%x" Version="2.0" IssueInstant="%s" Destination="http://localhost:8080/saml/idp/sso" `+
+		`AssertionConsumerServiceURL="https://localhost:8082/user-profile/saml/acs" ProtocolBinding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" %s>`+
+		`<saml:Issuer Format="urn:oasis:names:tc:SAML:2.0:nameid-format:entity">https://localhost:8082/user-profile/saml/metadata</saml:Issuer>`+
+		`<samlp:NameIDPolicy Format="urn:oasis:names:tc:SAML:2.0:nameid-format:transient" AllowCreate="true"/>`+
+		`</samlp:AuthnRequest>`, RandomBytes(20), saml.TimeNow().UTC().Format(timeFormat), attributes)
+
+	buf := bytes.NewBuffer(nil)
+	fw, _ := flate.NewWriter(buf, flate.DefaultCompression)
+	fw.Write([]byte(authnRequest))
+	fw.Close()
+
+	query := url.Values{}
+	query.Set("RelayState", "_L5_YvLMqRfj0KX5A62TIKfOHMYVeboixBRg8yYxIwSjp7wmjca2OIRA")
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/saml/idp/sso?"+query.Encode(), nil)
+	return r
+}
+
+func TestForceAuthnIsPassive(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.IDP.ServiceProviderProvider = db.New()
+
+	req, err := ValidateSamlRequest(&s.IDP, newSamlRequest(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ForceAuthn(req) || IsPassive(req) {
+		t.Fatal("Expected ForceAuthn and IsPassive to be false when not set in the request")
+	}
+
+	req, err = ValidateSamlRequest(&s.IDP, newSamlRequest(`ForceAuthn="true" IsPassive="true"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ForceAuthn(req) {
+		t.Fatal("Expected ForceAuthn to be true")
+	}
+	if !IsPassive(req) {
+		t.Fatal("Expected IsPassive to be true")
+	}
+
+	req, err = ValidateSamlRequest(&s.IDP, newSamlRequest(`ForceAuthn="false" IsPassive="0"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ForceAuthn(req) || IsPassive(req) {
+		t.Fatal("Expected ForceAuthn and IsPassive to be false")
+	}
+}
+
+func TestValidateSamlRequestStatusError(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.IDP.ServiceProviderProvider = db.New()
+
+	r := newSamlRequest("")
+
+	timeNow := saml.TimeNow
+	saml.TimeNow = func() time.Time {
+		return timeNow().Add(time.Hour)
+	}
+	defer func() {
+		saml.TimeNow = timeNow
+	}()
+
+	req, err := ValidateSamlRequest(&s.IDP, r)
+	if err == nil {
+		t.Fatal("Nil error, expected: request expired")
+	}
+
+	statusErr, ok := err.(*StatusError)
+	if !ok {
+		t.Fatalf("Expected StatusError, got: %v", err)
+	}
+	if statusErr.Status != StatusRequester || statusErr.SubStatus != StatusRequestDenied {
+		t.Fatalf("Unexpected status codes: %s %s", statusErr.Status, statusErr.SubStatus)
+	}
+	if req == nil || req.ACSEndpoint == nil {
+		t.Fatal("Expected the request with known assertion consumer service")
+	}
+	if req.ACSEndpoint.Location != "https://localhost:8082/user-profile/saml/acs" {
+		t.Fatalf("Unexpected assertion consumer service %s", req.ACSEndpoint.Location)
+	}
+
+	s.IDP.ServiceProviderProvider = unknownServiceProviders{}
+	req, err = ValidateSamlRequest(&s.IDP, newSamlRequest(""))
+	if err == nil {
+		t.Fatal("Nil error, expected: cannot handle request from unknown service provider")
+	}
+	if req != nil {
+		t.Fatal("Expected nil request for unknown service provider")
+	}
+}
+
+// unknownServiceProviders is a ServiceProviderProvider that knows no service providers
+type unknownServiceProviders struct{}
+
+func (unknownServiceProviders) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	return nil, os.ErrNotExist
+}
+
+func TestValidateSamlRequestRelayStateTooLong(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.IDP.ServiceProviderProvider = db.New()
+
+	r := newSamlRequest("")
+	query := r.URL.Query()
+	query.Set("RelayState", strings.Repeat("a", MaxRelayStateLength+1))
+	r.URL.RawQuery = query.Encode()
+
+	req, err := ValidateSamlRequest(&s.IDP, r)
+	if err == nil {
+		t.Fatal("Nil error, expected: RelayState must not exceed 80 bytes")
+	}
+	if req == nil || req.RelayState != "" {
+		t.Fatal("Expected the request without the RelayState")
+	}
 }
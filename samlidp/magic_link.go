@@ -0,0 +1,206 @@
+package samlidp
+
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/crewjam/saml"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/keitaroinc/goa"
+)
+
+// MagicLinkTokenParam is the name of the parameter that holds the sign-in token.
+const MagicLinkTokenParam = "token"
+
+// MagicLinkMaxAge is the time the sign-in link can be used.
+var MagicLinkMaxAge = 10 * time.Minute
+
+// MaxMagicLinks is the number of sign-in links that can be requested in one login transaction.
+var MaxMagicLinks = 3
+
+const (
+	// magicLinkTokenType is the "typ" claim of the sign-in tokens, so no other token signed by the IdP
+	// is accepted as a sign-in token
+	magicLinkTokenType = "magic-link"
+
+	defaultMagicLinkRateLimit       = 5
+	defaultMagicLinkRateLimitWindow = time.Hour
+)
+
+// ErrInvalidMagicLink is returned when the sign-in link is invalid, expired or already used.
+var ErrInvalidMagicLink = i18n.NewError("magic-link-invalid", "The sign-in link is invalid or has expired.")
+
+// ErrTooManyMagicLinks is returned when the user has requested too many sign-in links.
+var ErrTooManyMagicLinks = i18n.NewError("magic-link-limit", "Too many sign-in links were requested. Check your email or try again later.")
+
+// SetMagicLinkMaxAge sets the time, in seconds, the sign-in link can be used.
+func SetMagicLinkMaxAge(seconds int) {
+	if seconds > 0 {
+		MagicLinkMaxAge = time.Duration(seconds) * time.Second
+	}
+}
+
+// NewMagicLinkLimiter creates the RateLimiter of the sign-in links sent to one email address.
+func NewMagicLinkLimiter(cfg *config.MagicLinkConfig) *RateLimiter {
+	limit := cfg.RateLimit
+	if limit <= 0 {
+		limit = defaultMagicLinkRateLimit
+	}
+
+	window := defaultMagicLinkRateLimitWindow
+	if cfg.RateLimitWindow > 0 {
+		window = time.Duration(cfg.RateLimitWindow) * time.Second
+	}
+
+	return NewRateLimiter(limit, window)
+}
+
+// MagicLinkEnabled checks if the user may log in with a sign-in link for the request. The link must be
+// enabled for the service provider, and the service provider must accept the authentication context of
+// the link.
+func MagicLinkEnabled(cfg *config.Config, req *saml.IdpAuthnRequest) bool {
+	if req.ServiceProviderMetadata == nil {
+		return false
+	}
+
+	spConfig, ok := cfg.ServiceProviders[req.ServiceProviderMetadata.EntityID]
+	if !ok || spConfig == nil || !spConfig.MagicLink {
+		return false
+	}
+
+	return SatisfiesAuthnContext(req, []string{AuthnMethodMagicLink})
+}
+
+// CountMagicLink records that a sign-in link was requested in the login transaction. ErrTooManyMagicLinks
+// is returned when the user has no links left. The requests are counted whether a link is sent or not, so
+// the count does not reveal the registered emails.
+func CountMagicLink(store TransactionStore, transaction *db.LoginTransaction) error {
+	if transaction.MagicLinksSent >= MaxMagicLinks {
+		return ErrTooManyMagicLinks
+	}
+
+	transaction.MagicLinksSent++
+	return store.AddLoginTransaction(transaction)
+}
+
+// StartMagicLink saves a new sign-in link for the user in the login transaction and returns the token of
+// the link. The link replaces the previous link of the transaction and expires after MagicLinkMaxAge, or
+// with the transaction if that is sooner.
+func StartMagicLink(store TransactionStore, idp *saml.IdentityProvider, transaction *db.LoginTransaction, userID string, email string) (string, error) {
+	now := saml.TimeNow()
+	link := &db.MagicLink{
+		ID:         base64.RawURLEncoding.EncodeToString(RandomBytes(32)),
+		UserID:     userID,
+		Email:      email,
+		ExpireTime: now.Add(MagicLinkMaxAge),
+	}
+	if link.ExpireTime.After(transaction.ExpireTime) {
+		link.ExpireTime = transaction.ExpireTime
+	}
+
+	transaction.MagicLink = link
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": link.ID,
+		"sub": transaction.ID,
+		"typ": magicLinkTokenType,
+		"iat": now.Unix(),
+		"exp": link.ExpireTime.Unix(),
+	})
+
+	return token.SignedString(signingKey(idp))
+}
+
+// LoadMagicLink verifies the sign-in token and returns its link together with the restored request of the
+// login transaction, without using the link up, so the user can confirm the sign-in first. ErrInvalidMagicLink
+// is returned if the token is not valid, the link was already used or the login transaction has expired.
+func LoadMagicLink(store TransactionStore, idp *saml.IdentityProvider, r *http.Request, tokenString string) (*saml.IdpAuthnRequest, *db.LoginTransaction, *db.MagicLink, error) {
+	transactionID, linkID, err := parseMagicLinkToken(idp, tokenString)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	transaction, err := store.GetLoginTransaction(transactionID)
+	if err != nil {
+		return nil, nil, nil, magicLinkError(err)
+	}
+
+	link := transaction.MagicLink
+	if link == nil || link.ID != linkID || saml.TimeNow().After(link.ExpireTime) {
+		return nil, nil, nil, ErrInvalidMagicLink
+	}
+
+	req, err := restoreAuthnRequest(idp, r, transaction)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return req, transaction, link, nil
+}
+
+// RedeemMagicLink verifies the sign-in token and returns its link together with the restored request of the
+// login transaction. The link is removed from the transaction in one conditional update, so it can be used
+// only once, even by concurrent requests. ErrInvalidMagicLink is returned if the token is not valid, the link
+// was already used or the login transaction has expired.
+func RedeemMagicLink(store TransactionStore, idp *saml.IdentityProvider, r *http.Request, tokenString string) (*saml.IdpAuthnRequest, *db.LoginTransaction, *db.MagicLink, error) {
+	transactionID, linkID, err := parseMagicLinkToken(idp, tokenString)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	transaction, err := store.RedeemMagicLink(transactionID, linkID)
+	if err != nil {
+		return nil, nil, nil, magicLinkError(err)
+	}
+
+	link := transaction.MagicLink
+	if saml.TimeNow().After(link.ExpireTime) {
+		return nil, nil, nil, ErrInvalidMagicLink
+	}
+	transaction.MagicLink = nil
+
+	req, err := restoreAuthnRequest(idp, r, transaction)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return req, transaction, link, nil
+}
+
+// parseMagicLinkToken verifies the sign-in token and returns the IDs of its login transaction and link
+func parseMagicLinkToken(idp *saml.IdentityProvider, tokenString string) (string, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidMagicLink
+		}
+		return signingKey(idp), nil
+	})
+	if err != nil || !token.Valid {
+		return "", "", ErrInvalidMagicLink
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != magicLinkTokenType {
+		return "", "", ErrInvalidMagicLink
+	}
+	linkID, _ := claims["jti"].(string)
+	transactionID, _ := claims["sub"].(string)
+
+	return transactionID, linkID, nil
+}
+
+// magicLinkError maps the not found error of the store to ErrInvalidMagicLink
+func magicLinkError(err error) error {
+	if e, ok := err.(*goa.ErrorResponse); ok && e.Status == http.StatusNotFound {
+		return ErrInvalidMagicLink
+	}
+
+	return err
+}
@@ -0,0 +1,173 @@
+package samlidp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+// startTestTransaction starts the login transaction of a fresh request from the test service provider
+func startTestTransaction(t *testing.T, idp *saml.IdentityProvider, store *db.DB) (*saml.IdpAuthnRequest, *db.LoginTransaction) {
+	idp.ServiceProviderProvider = store
+
+	req, err := ValidateSamlRequest(idp, newSamlRequest(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := StartLoginTransaction(store, req, []string{AuthnMethodPassword}, []string{AuthnMethodMagicLink})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return req, transaction
+}
+
+func TestMagicLink(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+	req, transaction := startTestTransaction(t, &s.IDP, store)
+
+	token, err := StartMagicLink(store, &s.IDP, transaction, "59804b3c0000000000000000", "jon@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := RedeemMagicLink(store, &s.IDP, newSamlRequest(""), token+"x"); err != ErrInvalidMagicLink {
+		t.Fatalf("Expected the tampered token to be rejected, got %v", err)
+	}
+
+	if _, _, _, err := LoadMagicLink(store, &s.IDP, newSamlRequest(""), token+"x"); err != ErrInvalidMagicLink {
+		t.Fatalf("Expected the tampered token to be rejected, got %v", err)
+	}
+
+	// loading the link for the confirmation does not use it up
+	for i := 0; i < 2; i++ {
+		if _, _, link, err := LoadMagicLink(store, &s.IDP, newSamlRequest(""), token); err != nil || link.Email != "jon@test.com" {
+			t.Fatalf("Expected the sign-in link of the user, got %+v: %v", link, err)
+		}
+	}
+
+	restored, loaded, link, err := RedeemMagicLink(store, &s.IDP, newSamlRequest(""), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.UserID != "59804b3c0000000000000000" || link.Email != "jon@test.com" {
+		t.Fatalf("Expected the sign-in link of the user, got %+v", link)
+	}
+	if loaded.ID != transaction.ID || restored.Request.ID != req.Request.ID {
+		t.Fatalf("Expected the request of the transaction %s, got %s", req.Request.ID, restored.Request.ID)
+	}
+
+	if _, _, _, err := RedeemMagicLink(store, &s.IDP, newSamlRequest(""), token); err != ErrInvalidMagicLink {
+		t.Fatalf("Expected the used link to be rejected, got %v", err)
+	}
+	if _, _, _, err := LoadMagicLink(store, &s.IDP, newSamlRequest(""), token); err != ErrInvalidMagicLink {
+		t.Fatalf("Expected the used link to be rejected, got %v", err)
+	}
+}
+
+func TestMagicLinkReplaced(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+	_, transaction := startTestTransaction(t, &s.IDP, store)
+
+	first, err := StartMagicLink(store, &s.IDP, transaction, "59804b3c0000000000000000", "jon@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartMagicLink(store, &s.IDP, transaction, "59804b3c0000000000000000", "jon@test.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := RedeemMagicLink(store, &s.IDP, newSamlRequest(""), first); err != ErrInvalidMagicLink {
+		t.Fatalf("Expected the replaced link to be rejected, got %v", err)
+	}
+}
+
+func TestMagicLinkExpiresWithTransaction(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+	_, transaction := startTestTransaction(t, &s.IDP, store)
+
+	maxAge := MagicLinkMaxAge
+	MagicLinkMaxAge = 24 * time.Hour
+	defer func() {
+		MagicLinkMaxAge = maxAge
+	}()
+
+	token, err := StartMagicLink(store, &s.IDP, transaction, "59804b3c0000000000000000", "jon@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !transaction.MagicLink.ExpireTime.Equal(transaction.ExpireTime) {
+		t.Fatalf("Expected the link to expire with the transaction at %s, got %s", transaction.ExpireTime, transaction.MagicLink.ExpireTime)
+	}
+
+	store.DeleteLoginTransaction(transaction.ID)
+	if _, _, _, err := RedeemMagicLink(store, &s.IDP, newSamlRequest(""), token); err != ErrInvalidMagicLink {
+		t.Fatalf("Expected the link of the ended transaction to be rejected, got %v", err)
+	}
+}
+
+func TestCountMagicLink(t *testing.T) {
+	store := db.New()
+	transaction := &db.LoginTransaction{ID: "count-magic-link", ExpireTime: saml.TimeNow().Add(time.Minute)}
+
+	for i := 0; i < MaxMagicLinks; i++ {
+		if err := CountMagicLink(store, transaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := CountMagicLink(store, transaction); err != ErrTooManyMagicLinks {
+		t.Fatalf("Expected ErrTooManyMagicLinks, got %v", err)
+	}
+
+	loaded, _ := store.GetLoginTransaction(transaction.ID)
+	if loaded.MagicLinksSent != MaxMagicLinks {
+		t.Fatalf("Expected %d links counted, got %d", MaxMagicLinks, loaded.MagicLinksSent)
+	}
+}
+
+func TestMagicLinkEnabled(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.IDP.ServiceProviderProvider = db.New()
+
+	req, err := ValidateSamlRequest(&s.IDP, newSamlRequest(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	if MagicLinkEnabled(cfg, req) {
+		t.Fatal("Expected the sign-in links to be disabled by default")
+	}
+
+	cfg.ServiceProviders = map[string]*config.ServiceProviderConfig{
+		"https://localhost:8082/user-profile/saml/metadata": {MagicLink: true},
+	}
+	if !MagicLinkEnabled(cfg, req) {
+		t.Fatal("Expected the sign-in links to be enabled for the service provider")
+	}
+
+	req = authnRequestWithContext(&s.IDP, requestedContext("exact", AuthnContextPasswordProtectedTransport))
+	if MagicLinkEnabled(cfg, req) {
+		t.Fatal("Expected the sign-in links to be disabled when the service provider requests a password")
+	}
+}
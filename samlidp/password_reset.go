@@ -0,0 +1,137 @@
+package samlidp
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/crewjam/saml"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/keitaroinc/goa"
+)
+
+// PasswordResetTokenParam is the name of the parameter that holds the password reset token.
+const PasswordResetTokenParam = "token"
+
+// PasswordResetMaxAge is the time the password reset link can be used.
+var PasswordResetMaxAge = time.Hour
+
+// SetPasswordResetMaxAge sets the time, in seconds, the password reset link can be used.
+func SetPasswordResetMaxAge(seconds int) {
+	if seconds > 0 {
+		PasswordResetMaxAge = time.Duration(seconds) * time.Second
+	}
+}
+
+const (
+	// passwordResetTokenType is the "typ" claim of the password reset tokens, so no other token signed by the
+	// IdP is accepted as a reset token
+	passwordResetTokenType = "password-reset"
+
+	defaultPasswordResetRateLimit       = 5
+	defaultPasswordResetRateLimitWindow = time.Hour
+)
+
+// ErrInvalidPasswordResetToken is returned when the password reset link is invalid, expired or already used.
+var ErrInvalidPasswordResetToken = i18n.NewError("password-reset-invalid", "The password reset link is invalid or has expired.")
+
+// ErrTooManyPasswordResets is returned when too many password resets were requested for the email.
+var ErrTooManyPasswordResets = i18n.NewError("password-reset-limit", "Too many password resets were requested. Check your email or try again later.")
+
+// NewPasswordResetLimiter creates the RateLimiter of the password resets requested for one email address.
+func NewPasswordResetLimiter(cfg *config.PasswordResetConfig) *RateLimiter {
+	limit := cfg.RateLimit
+	if limit <= 0 {
+		limit = defaultPasswordResetRateLimit
+	}
+
+	window := defaultPasswordResetRateLimitWindow
+	if cfg.RateLimitWindow > 0 {
+		window = time.Duration(cfg.RateLimitWindow) * time.Second
+	}
+
+	return NewRateLimiter(limit, window)
+}
+
+// PasswordResetStore keeps the password resets requested by the users.
+type PasswordResetStore interface {
+	// AddPasswordReset saves the password reset requested by the user
+	AddPasswordReset(reset *db.PasswordReset) error
+	// GetPasswordReset looks up the password reset by its ID
+	GetPasswordReset(resetID string) (*db.PasswordReset, error)
+	// DeletePasswordReset deletes the password reset
+	DeletePasswordReset(resetID string) error
+}
+
+// StartPasswordReset saves a new password reset for the user and returns the token of the reset link. The
+// token is signed by the IdP and expires after PasswordResetMaxAge. It can be used only once, as the reset
+// is deleted when the password is changed.
+func StartPasswordReset(store PasswordResetStore, idp *saml.IdentityProvider, userID string, email string) (string, error) {
+	now := saml.TimeNow()
+	reset := &db.PasswordReset{
+		ID:         base64.RawURLEncoding.EncodeToString(RandomBytes(32)),
+		UserID:     userID,
+		Email:      email,
+		CreateTime: now,
+		ExpireTime: now.Add(PasswordResetMaxAge),
+	}
+
+	if err := store.AddPasswordReset(reset); err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": reset.ID,
+		"sub": reset.UserID,
+		"typ": passwordResetTokenType,
+		"iat": now.Unix(),
+		"exp": reset.ExpireTime.Unix(),
+	})
+
+	return token.SignedString(signingKey(idp))
+}
+
+// LoadPasswordReset verifies the password reset token and returns its password reset.
+// ErrInvalidPasswordResetToken is returned if the token is not valid or the reset was already used.
+func LoadPasswordReset(store PasswordResetStore, idp *saml.IdentityProvider, tokenString string) (*db.PasswordReset, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidPasswordResetToken
+		}
+		return signingKey(idp), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != passwordResetTokenType {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	resetID, _ := claims["jti"].(string)
+	userID, _ := claims["sub"].(string)
+
+	reset, err := store.GetPasswordReset(resetID)
+	if err != nil {
+		if e, ok := err.(*goa.ErrorResponse); ok && e.Status == http.StatusNotFound {
+			return nil, ErrInvalidPasswordResetToken
+		}
+		return nil, err
+	}
+
+	if reset.UserID != userID {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	return reset, nil
+}
+
+// signingKey returns the key the IdP signs its HMAC tokens with
+func signingKey(idp *saml.IdentityProvider) []byte {
+	return x509.MarshalPKCS1PrivateKey(idp.Key.(*rsa.PrivateKey))
+}
@@ -0,0 +1,90 @@
+package samlidp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func TestPasswordReset(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+
+	token, err := StartPasswordReset(store, &s.IDP, "59804b3c0000000000000000", "jon@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reset, err := LoadPasswordReset(store, &s.IDP, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.UserID != "59804b3c0000000000000000" || reset.Email != "jon@test.com" {
+		t.Fatalf("Expected the password reset of the user, got %+v", reset)
+	}
+
+	if _, err := LoadPasswordReset(store, &s.IDP, token+"x"); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("Expected the tampered token to be rejected, got %v", err)
+	}
+
+	store.DeletePasswordReset(reset.ID)
+	if _, err := LoadPasswordReset(store, &s.IDP, token); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("Expected the used token to be rejected, got %v", err)
+	}
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+
+	maxAge := PasswordResetMaxAge
+	PasswordResetMaxAge = -time.Minute
+	defer func() {
+		PasswordResetMaxAge = maxAge
+	}()
+
+	token, err := StartPasswordReset(store, &s.IDP, "59804b3c0000000000000000", "jon@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadPasswordReset(store, &s.IDP, token); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("Expected the expired token to be rejected, got %v", err)
+	}
+}
+
+func TestPasswordResetTokenType(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+
+	store.AddPasswordReset(&db.PasswordReset{
+		ID:         "reset-id",
+		UserID:     "59804b3c0000000000000000",
+		ExpireTime: saml.TimeNow().Add(time.Hour),
+	})
+
+	// a token signed with the same key, but not issued as password reset token
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": "reset-id",
+		"sub": "59804b3c0000000000000000",
+	}).SignedString(signingKey(&s.IDP))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadPasswordReset(store, &s.IDP, token); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("Expected the token without the password reset type to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package samlidp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+)
+
+// RateLimiter limits the number of events per key within a sliding time window. The events are counted
+// in memory, so every instance of the IdP keeps its own counts.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mutex     sync.Mutex
+	events    map[string][]time.Time
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates the RateLimiter that allows limit events per key within the window.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		events: map[string][]time.Time{},
+	}
+}
+
+// Allow records the event for the key and reports whether it is within the limit. The events over
+// the limit are not recorded, so they do not extend the time the key is limited.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := saml.TimeNow()
+	if now.Sub(l.lastSweep) > l.window {
+		l.sweep(now)
+	}
+
+	events := l.recent(key, now)
+	if len(events) >= l.limit {
+		l.events[key] = events
+		return false
+	}
+
+	l.events[key] = append(events, now)
+	return true
+}
+
+// recent returns the events of the key within the window
+func (l *RateLimiter) recent(key string, now time.Time) []time.Time {
+	events := l.events[key]
+	for len(events) > 0 && now.Sub(events[0]) >= l.window {
+		events = events[1:]
+	}
+
+	return events
+}
+
+// sweep removes the keys without events within the window, so the keys seen only once are not kept forever
+func (l *RateLimiter) sweep(now time.Time) {
+	for key := range l.events {
+		if events := l.recent(key, now); len(events) > 0 {
+			l.events[key] = events
+		} else {
+			delete(l.events, key)
+		}
+	}
+
+	l.lastSweep = now
+}
@@ -0,0 +1,42 @@
+package samlidp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow := saml.TimeNow
+	saml.TimeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		saml.TimeNow = timeNow
+	}()
+
+	limiter := NewRateLimiter(2, time.Minute)
+
+	if !limiter.Allow("jon@test.com") || !limiter.Allow("jon@test.com") {
+		t.Fatal("Expected the events within the limit to be allowed")
+	}
+	if limiter.Allow("jon@test.com") {
+		t.Fatal("Expected the event over the limit to be rejected")
+	}
+	if !limiter.Allow("jane@test.com") {
+		t.Fatal("Expected the limit to apply per key")
+	}
+
+	now = now.Add(time.Minute)
+	if !limiter.Allow("jon@test.com") {
+		t.Fatal("Expected the event to be allowed after the window")
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiter.Allow("jon@test.com")
+	if _, ok := limiter.events["jane@test.com"]; ok {
+		t.Fatal("Expected the keys without recent events to be removed")
+	}
+}
@@ -0,0 +1,173 @@
+package samlidp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// MaxRelayStateLength is the maximal length of the RelayState in bytes, see section 3.4.3 of the
+// SAML 2.0 bindings specification.
+const MaxRelayStateLength = 80
+
+// Parameters of the standalone login that carry the URL the user is redirected to after the login.
+const (
+	// ReturnToParam is the preferred redirect parameter.
+	ReturnToParam = "return_to"
+	// NextParam is an alias of ReturnToParam.
+	NextParam = "next"
+)
+
+// CheckRelayState returns StatusError if the RelayState is longer than allowed by the specification.
+func CheckRelayState(relayState string) error {
+	if len(relayState) > MaxRelayStateLength {
+		return NewStatusError(StatusRequester, StatusRequestDenied, fmt.Sprintf("RelayState must not exceed %d bytes", MaxRelayStateLength))
+	}
+
+	return nil
+}
+
+// IsAllowedRedirect checks if the user may be redirected to the target URL. An absolute target is allowed if it
+// has the same scheme and host as one of the allowed URLs and its path is within the path of that URL. A relative
+// target is allowed if it is a local path (e.g. /profiles/me) within one of the allowed paths (e.g. /profiles).
+func IsAllowedRedirect(target string, allowed []string) bool {
+	if target == "" {
+		return false
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil || targetURL.User != nil {
+		return false
+	}
+
+	relative := !targetURL.IsAbs()
+	if relative && !isLocalPath(target) {
+		return false
+	}
+	if !relative && targetURL.Host == "" {
+		return false
+	}
+
+	for _, allowedTarget := range allowed {
+		allowedURL, err := url.Parse(allowedTarget)
+		if err != nil {
+			continue
+		}
+
+		if relative {
+			if !isLocalPath(allowedTarget) {
+				continue
+			}
+		} else {
+			if !allowedURL.IsAbs() {
+				continue
+			}
+			if !strings.EqualFold(targetURL.Scheme, allowedURL.Scheme) || !strings.EqualFold(targetURL.Host, allowedURL.Host) {
+				continue
+			}
+		}
+
+		if isWithinPath(targetURL.EscapedPath(), allowedURL.EscapedPath()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedirectURL returns the target if the user may be redirected to it, otherwise the fallback URL.
+func RedirectURL(target string, allowed []string, fallback string) string {
+	if IsAllowedRedirect(target, allowed) {
+		return target
+	}
+
+	return fallback
+}
+
+// ReturnTo returns the URL requested with the return_to (or next) parameter if the user may be redirected to it.
+// Empty string is returned if the parameter is not set or the URL is not allowed.
+func ReturnTo(r *http.Request, allowed []string) string {
+	returnTo := strings.TrimSpace(r.FormValue(ReturnToParam))
+	if returnTo == "" {
+		returnTo = strings.TrimSpace(r.FormValue(NextParam))
+	}
+
+	if !IsAllowedRedirect(returnTo, allowed) {
+		return ""
+	}
+
+	return returnTo
+}
+
+// ReturnToFormURL creates the URL the standalone login form is posted to, carrying the return_to URL.
+func ReturnToFormURL(formURL string, returnTo string) string {
+	query := url.Values{}
+	if returnTo != "" {
+		query.Set(ReturnToParam, returnTo)
+	}
+
+	return withQuery(formURL, query)
+}
+
+// RelayStateURL returns the URL that shows the standalone login form again with the same RelayState.
+func RelayStateURL(formURL string, relayState string) string {
+	query := url.Values{}
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+
+	return withQuery(formURL, query)
+}
+
+// withQuery appends the query to the URL
+func withQuery(rawURL string, query url.Values) string {
+	if len(query) == 0 {
+		return rawURL
+	}
+
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+
+	return rawURL + separator + query.Encode()
+}
+
+// isLocalPath checks if the URL is an absolute path on the same host. Scheme-relative URLs (//host/path)
+// and paths with backslashes, which some browsers treat as slashes, are not local.
+func isLocalPath(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "/") && !strings.HasPrefix(rawURL, "//") && !strings.Contains(rawURL, "\\")
+}
+
+// isWithinPath checks if the escaped path is equal to or under the escaped base path. The paths are decoded
+// and cleaned first, the way the browsers resolve them, so the encoded dot segments (%2e%2e) cannot leave the
+// base path.
+func isWithinPath(escapedPath string, escapedBasePath string) bool {
+	target, err := cleanPath(escapedPath)
+	if err != nil {
+		return false
+	}
+	base, err := cleanPath(escapedBasePath)
+	if err != nil {
+		return false
+	}
+
+	if base == "/" {
+		return true
+	}
+
+	return target == base || strings.HasPrefix(target, base+"/")
+}
+
+// cleanPath decodes the escaped path and resolves its dot segments. The backslashes are treated as slashes,
+// as the browsers do.
+func cleanPath(escapedPath string) (string, error) {
+	unescaped, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Clean("/" + strings.Replace(unescaped, "\\", "/", -1)), nil
+}
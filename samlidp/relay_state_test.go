@@ -0,0 +1,122 @@
+package samlidp
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckRelayState(t *testing.T) {
+	if err := CheckRelayState("_L5_YvLMqRfj0KX5A62TIKfOHMYVeboixBRg8yYxIwSjp7wmjca2OIRA"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckRelayState(strings.Repeat("a", MaxRelayStateLength+1)); err == nil {
+		t.Fatal("Nil error, expected: RelayState must not exceed 80 bytes")
+	}
+}
+
+func TestIsAllowedRedirect(t *testing.T) {
+	allowed := []string{"https://app.example.com/profiles", "https://admin.example.com"}
+
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{"https://app.example.com/profiles", true},
+		{"https://app.example.com/profiles/me?tab=1", true},
+		{"https://APP.example.com/profiles/me", true},
+		{"https://admin.example.com/users", true},
+		{"https://app.example.com/profilesX", false},
+		{"https://app.example.com/profiles/../admin", false},
+		{"https://app.example.com/profiles/%2e%2e/admin", false},
+		{"https://app.example.com/profiles/%2E%2E%2fadmin", false},
+		{"https://app.example.com/", false},
+		{"http://app.example.com/profiles", false},
+		{"https://app.example.com.evil.com/profiles", false},
+		{"https://user@app.example.com/profiles", false},
+		{"//app.example.com/profiles", false},
+		{"/profiles/me", false},
+		{"javascript:alert(1)", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if IsAllowedRedirect(test.target, allowed) != test.allowed {
+			t.Fatalf("%s: expected allowed to be %t", test.target, test.allowed)
+		}
+	}
+}
+
+func TestRedirectURL(t *testing.T) {
+	allowed := []string{"https://app.example.com"}
+
+	if url := RedirectURL("https://app.example.com/me", allowed, "https://fallback.example.com"); url != "https://app.example.com/me" {
+		t.Fatalf("Expected the allowed target, got %s", url)
+	}
+
+	if url := RedirectURL("https://evil.com", allowed, "https://fallback.example.com"); url != "https://fallback.example.com" {
+		t.Fatalf("Expected the fallback URL, got %s", url)
+	}
+}
+
+func TestIsAllowedRedirectLocalPath(t *testing.T) {
+	allowed := []string{"/profiles", "https://app.example.com/dashboard"}
+
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{"/profiles", true},
+		{"/profiles/me?tab=1", true},
+		{"/profilesX", false},
+		{"/profiles/../admin", false},
+		{"/profiles/%2e%2e/admin", false},
+		{"/dashboard", false},
+		{"//evil.com/profiles", false},
+		{"/\\evil.com/profiles", false},
+		{"profiles/me", false},
+		{"https://evil.com/profiles", false},
+		{"https://app.example.com/dashboard/1", true},
+	}
+
+	for _, test := range tests {
+		if IsAllowedRedirect(test.target, allowed) != test.allowed {
+			t.Fatalf("%s: expected allowed to be %t", test.target, test.allowed)
+		}
+	}
+}
+
+func TestReturnTo(t *testing.T) {
+	allowed := []string{"https://app.example.com", "/profiles"}
+
+	tests := []struct {
+		query    string
+		returnTo string
+	}{
+		{"return_to=https%3A%2F%2Fapp.example.com%2Fme", "https://app.example.com/me"},
+		{"next=%2Fprofiles%2Fme", "/profiles/me"},
+		{"return_to=%2Fprofiles%2F1&next=%2Fprofiles%2F2", "/profiles/1"},
+		{"return_to=https%3A%2F%2Fevil.com", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080/saml/idp/login?"+test.query, nil)
+		if returnTo := ReturnTo(r, allowed); returnTo != test.returnTo {
+			t.Fatalf("%s: expected %q, got %q", test.query, test.returnTo, returnTo)
+		}
+	}
+}
+
+func TestReturnToFormURL(t *testing.T) {
+	url := ReturnToFormURL("http://localhost:8080/saml/idp/login", "https://app.example.com/me?tab=1")
+	expected := "http://localhost:8080/saml/idp/login?return_to=https%3A%2F%2Fapp.example.com%2Fme%3Ftab%3D1"
+	if url != expected {
+		t.Fatalf("Expected %s, got %s", expected, url)
+	}
+
+	if url := ReturnToFormURL("http://localhost:8080/saml/idp/login", ""); url != "http://localhost:8080/saml/idp/login" {
+		t.Fatalf("Expected the form URL without query, got %s", url)
+	}
+}
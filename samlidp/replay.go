@@ -0,0 +1,64 @@
+package samlidp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/crewjam/saml"
+
+	"github.com/Microkubes/identity-provider/db"
+)
+
+// RequestStore keeps the IDs of the AuthnRequests the IdP has responded to.
+type RequestStore interface {
+	// AddAuthnRequest records the ID of an AuthnRequest the IdP has responded to, atomically. It returns
+	// db.ErrAuthnRequestRecorded if the ID is already recorded.
+	AddAuthnRequest(requestID string, expireTime time.Time) error
+	// HasAuthnRequest checks if the IdP has already responded to the AuthnRequest
+	HasAuthnRequest(requestID string) (bool, error)
+}
+
+// SetClockSkew sets the clock skew allowed when checking the IssueInstant of the AuthnRequests.
+// Requests issued earlier than the allowed skew are expired.
+func SetClockSkew(seconds int) {
+	if seconds > 0 {
+		saml.MaxIssueDelay = time.Duration(seconds) * time.Second
+	}
+}
+
+// CheckReplay returns StatusError if the IdP has already responded to the request. The check only rejects
+// the replayed requests early, RecordResponse claims the request before the response is sent.
+func CheckReplay(store RequestStore, req *saml.IdpAuthnRequest) error {
+	responded, err := store.HasAuthnRequest(req.Request.ID)
+	if err != nil {
+		return err
+	}
+
+	if responded {
+		return replayError(req)
+	}
+
+	return nil
+}
+
+// RecordResponse records that the IdP responds to the request. The record is kept until the request
+// expires, after that the request is rejected by the IssueInstant check anyway. It returns StatusError if
+// the IdP has already responded to the request, e.g. to the same request submitted concurrently, so the
+// response must not be sent.
+func RecordResponse(store RequestStore, req *saml.IdpAuthnRequest) error {
+	if req.Request.ID == "" {
+		return nil
+	}
+
+	err := store.AddAuthnRequest(req.Request.ID, req.Request.IssueInstant.Add(saml.MaxIssueDelay))
+	if err == db.ErrAuthnRequestRecorded {
+		return replayError(req)
+	}
+
+	return err
+}
+
+// replayError is returned for the request the IdP has already responded to
+func replayError(req *saml.IdpAuthnRequest) error {
+	return NewStatusError(StatusRequester, StatusRequestDenied, fmt.Sprintf("request %s has already been responded to", req.Request.ID))
+}
@@ -0,0 +1,67 @@
+package samlidp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+func TestCheckReplay(t *testing.T) {
+	store := db.New()
+
+	req := &saml.IdpAuthnRequest{}
+	req.Request.ID = "id-replay"
+	req.Request.IssueInstant = saml.TimeNow()
+
+	if err := CheckReplay(store, req); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecordResponse(store, req); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CheckReplay(store, req)
+	if err == nil {
+		t.Fatal("Nil error, expected: request has already been responded to")
+	}
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.SubStatus != StatusRequestDenied {
+		t.Fatalf("Expected RequestDenied status error, got: %v", err)
+	}
+
+	// the request that passed the check concurrently cannot be recorded again
+	err = RecordResponse(store, req)
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.SubStatus != StatusRequestDenied {
+		t.Fatalf("Expected RequestDenied status error for the second response, got: %v", err)
+	}
+
+	expired := &saml.IdpAuthnRequest{}
+	expired.Request.ID = "id-expired"
+	expired.Request.IssueInstant = saml.TimeNow().Add(-time.Hour)
+
+	if err := RecordResponse(store, expired); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckReplay(store, expired); err != nil {
+		t.Fatalf("Expected the expired record to be ignored, got: %s", err)
+	}
+}
+
+func TestSetClockSkew(t *testing.T) {
+	maxIssueDelay := saml.MaxIssueDelay
+	defer func() {
+		saml.MaxIssueDelay = maxIssueDelay
+	}()
+
+	SetClockSkew(0)
+	if saml.MaxIssueDelay != maxIssueDelay {
+		t.Fatal("Expected the default clock skew to be kept")
+	}
+
+	SetClockSkew(300)
+	if saml.MaxIssueDelay != 5*time.Minute {
+		t.Fatalf("Expected clock skew of 5 minutes, got %s", saml.MaxIssueDelay)
+	}
+}
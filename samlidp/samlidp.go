@@ -34,6 +34,15 @@ func New(cfg *config.Config) (*samlidp.Server, error) {
 		return nil, err
 	}
 
+	assertionMaker, err := NewAssertionMaker(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	SetClockSkew(cfg.ClockSkew)
+	SetPasswordResetMaxAge(cfg.PasswordReset.TokenMaxAge)
+	SetMagicLinkMaxAge(cfg.MagicLink.TokenMaxAge)
+
 	metadataURL := *baseURL
 	metadataURL.Path = metadataURL.Path + "/metadata"
 	ssoURL := *baseURL
@@ -41,11 +50,12 @@ func New(cfg *config.Config) (*samlidp.Server, error) {
 
 	s := &samlidp.Server{
 		IDP: saml.IdentityProvider{
-			Key:         keyPair.PrivateKey.(*rsa.PrivateKey),
-			Logger:      logr,
-			Certificate: keyPair.Leaf,
-			MetadataURL: metadataURL,
-			SSOURL:      ssoURL,
+			Key:            keyPair.PrivateKey.(*rsa.PrivateKey),
+			Logger:         logr,
+			Certificate:    keyPair.Leaf,
+			MetadataURL:    metadataURL,
+			SSOURL:         ssoURL,
+			AssertionMaker: assertionMaker,
 		},
 	}
 
@@ -0,0 +1,148 @@
+package samlidp
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/Microkubes/identity-provider/oidc"
+	"github.com/Microkubes/identity-provider/provisioning"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// SocialProviderParam is the name of the login form field that holds the name of the social login provider
+// chosen by the user.
+const SocialProviderParam = "social"
+
+// SocialLoginError returns the error shown on the login form when the user could not sign in with the provider.
+func SocialLoginError(provider *oidc.Provider) error {
+	return i18n.NewError("social-login-failed", "The sign-in with %s failed.", provider.DisplayName)
+}
+
+// SocialLoginEnabled checks if the user may log in with a social login provider for the request: the service
+// provider must accept the authentication context of the social logins.
+func SocialLoginEnabled(providers []*oidc.Provider, req *saml.IdpAuthnRequest) bool {
+	return len(providers) > 0 && SatisfiesAuthnContext(req, []string{AuthnMethodSocial})
+}
+
+// FindSocialProvider returns the social login provider with the name, nil if there is none.
+func FindSocialProvider(providers []*oidc.Provider, name string) *oidc.Provider {
+	for _, provider := range providers {
+		if provider.Name == name {
+			return provider
+		}
+	}
+
+	return nil
+}
+
+// StartSocialLogin sends the user to the authorization endpoint of the provider. The random state, nonce and
+// PKCE verifier are saved in the login transaction, and the state sent to the provider is the transaction
+// ID followed by the random state, so the authorization code completes the transaction it was requested for.
+func StartSocialLogin(w http.ResponseWriter, r *http.Request, store TransactionStore, provider *oidc.Provider, transaction *db.LoginTransaction) error {
+	socialLogin := &db.SocialLogin{
+		Provider:     provider.Name,
+		State:        base64.RawURLEncoding.EncodeToString(RandomBytes(32)),
+		CodeVerifier: base64.RawURLEncoding.EncodeToString(RandomBytes(32)),
+	}
+	if provider.Issuer != "" {
+		socialLogin.Nonce = base64.RawURLEncoding.EncodeToString(RandomBytes(32))
+	}
+
+	transaction.SocialLogin = socialLogin
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		return err
+	}
+
+	state := transaction.ID + "." + socialLogin.State
+	http.Redirect(w, r, provider.AuthCodeURL(state, socialLogin.Nonce, socialLogin.CodeVerifier), http.StatusFound)
+
+	return nil
+}
+
+// CompleteSocialLogin exchanges the authorization code the provider sent the user back with and returns the
+// identity of the user together with the restored request of the login transaction. The social login is
+// removed from the transaction, so the code cannot be used again. The error of SocialLoginError is returned
+// with the request and the transaction if the user did not sign in at the provider.
+func CompleteSocialLogin(idp *saml.IdentityProvider, store TransactionStore, providers []*oidc.Provider, r *http.Request) (*saml.IdpAuthnRequest, *db.LoginTransaction, *oidc.Provider, *oidc.Identity, error) {
+	state := r.FormValue("state")
+	i := strings.LastIndex(state, ".")
+	if i < 0 {
+		return nil, nil, nil, nil, goa.ErrBadRequest("the state of the social login is invalid")
+	}
+
+	req, transaction, err := loadLoginTransaction(idp, store, r, state[:i])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	socialLogin := transaction.SocialLogin
+	// the state does not prove that the request belongs to the login transaction, so the login is not ended
+	if socialLogin == nil || subtle.ConstantTimeCompare([]byte(socialLogin.State), []byte(state[i+1:])) != 1 {
+		return nil, nil, nil, nil, goa.ErrBadRequest("no social login was started in the login transaction")
+	}
+	provider := FindSocialProvider(providers, socialLogin.Provider)
+	if provider == nil {
+		return req, nil, nil, nil, goa.ErrBadRequest("the social login provider is no longer configured")
+	}
+
+	transaction.SocialLogin = nil
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		return req, nil, nil, nil, err
+	}
+
+	if code := r.FormValue("error"); code != "" {
+		idp.Logger.Printf("social provider %s returned the error %s: %s", provider.Name, code, r.FormValue("error_description"))
+		return req, transaction, provider, nil, SocialLoginError(provider)
+	}
+
+	token, err := provider.Exchange(r.FormValue("code"), socialLogin.CodeVerifier)
+	if err != nil {
+		idp.Logger.Printf("social provider %s: %s", provider.Name, err)
+		return req, transaction, provider, nil, SocialLoginError(provider)
+	}
+
+	identity, err := provider.Identity(token, socialLogin.Nonce)
+	if err != nil {
+		idp.Logger.Printf("social provider %s: %s", provider.Name, err)
+		return req, transaction, provider, nil, SocialLoginError(provider)
+	}
+
+	return req, transaction, provider, identity, nil
+}
+
+// SocialExternalUser returns the external user of the identity the provider returned, with the claims as
+// the attributes.
+func SocialExternalUser(provider *oidc.Provider, identity *oidc.Identity) *provisioning.ExternalUser {
+	attributes := map[string][]string{}
+	for name, value := range identity.Claims {
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			switch v := v.(type) {
+			case nil:
+			case float64:
+				attributes[name] = append(attributes[name], strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				attributes[name] = append(attributes[name], fmt.Sprint(v))
+			}
+		}
+	}
+
+	return &provisioning.ExternalUser{
+		Source:        "social:" + provider.Name,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+		Attributes:    attributes,
+	}
+}
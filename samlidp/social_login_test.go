@@ -0,0 +1,41 @@
+package samlidp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/oidc"
+	"github.com/Microkubes/identity-provider/provisioning"
+)
+
+func TestSocialExternalUser(t *testing.T) {
+	identity := &oidc.Identity{
+		Subject:       "1234",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+		Claims:        map[string]interface{}{"sub": "1234", "groups": []interface{}{"staff", "admin"}, "id": float64(12345678), "picture": nil},
+	}
+
+	external := SocialExternalUser(&oidc.Provider{Name: "google"}, identity)
+	expected := &provisioning.ExternalUser{
+		Source:        "social:google",
+		Subject:       "1234",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+		Attributes:    map[string][]string{"sub": {"1234"}, "groups": {"staff", "admin"}, "id": {"12345678"}},
+	}
+	if !reflect.DeepEqual(external, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, external)
+	}
+}
+
+func TestFindSocialProvider(t *testing.T) {
+	google := &oidc.Provider{Name: "google"}
+	providers := []*oidc.Provider{google, {Name: "github"}}
+
+	if FindSocialProvider(providers, "google") != google || FindSocialProvider(providers, "other") != nil {
+		t.Fatal("Expected the social login provider to be found by name")
+	}
+}
@@ -0,0 +1,188 @@
+package samlidp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/crewjam/saml"
+)
+
+// SAML status codes, see section 3.2.2.2 of the SAML 2.0 core specification.
+const (
+	// StatusSuccess - the request succeeded.
+	StatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	// StatusRequester - the request could not be performed due to an error on the part of the requester.
+	StatusRequester = "urn:oasis:names:tc:SAML:2.0:status:Requester"
+	// StatusResponder - the request could not be performed due to an error on the part of the IdP.
+	StatusResponder = "urn:oasis:names:tc:SAML:2.0:status:Responder"
+
+	// StatusAuthnFailed - the IdP was unable to successfully authenticate the principal.
+	StatusAuthnFailed = "urn:oasis:names:tc:SAML:2.0:status:AuthnFailed"
+	// StatusNoAuthnContext - the specified authentication context requirements cannot be met.
+	StatusNoAuthnContext = "urn:oasis:names:tc:SAML:2.0:status:NoAuthnContext"
+	// StatusNoPassive - the IdP cannot authenticate the principal passively.
+	StatusNoPassive = "urn:oasis:names:tc:SAML:2.0:status:NoPassive"
+	// StatusRequestDenied - the IdP has chosen not to respond to the request.
+	StatusRequestDenied = "urn:oasis:names:tc:SAML:2.0:status:RequestDenied"
+	// StatusUnknownPrincipal - the principal is not known to the IdP.
+	StatusUnknownPrincipal = "urn:oasis:names:tc:SAML:2.0:status:UnknownPrincipal"
+)
+
+const (
+	entityNameIDFormat = "urn:oasis:names:tc:SAML:2.0:nameid-format:entity"
+	timeFormat         = "2006-01-02T15:04:05.999Z07:00"
+)
+
+// StatusError is an error that is reported to the service provider with SAML Response.
+type StatusError struct {
+	// Status is the top-level status code.
+	Status string
+	// SubStatus is the optional second-level status code.
+	SubStatus string
+	// Message is the status message.
+	Message string
+}
+
+// NewStatusError creates a StatusError with the given status codes and message.
+func NewStatusError(status string, subStatus string, message string) *StatusError {
+	return &StatusError{
+		Status:    status,
+		SubStatus: subStatus,
+		Message:   message,
+	}
+}
+
+// Error returns the status message.
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.SubStatus != "" {
+		return e.SubStatus
+	}
+	return e.Status
+}
+
+// ToStatusError converts the error to StatusError. Errors that are not StatusError are
+// reported as Responder errors, without exposing the details to the service provider.
+func ToStatusError(err error) *StatusError {
+	if statusErr, ok := err.(*StatusError); ok {
+		return statusErr
+	}
+
+	return NewStatusError(StatusResponder, "", "The identity provider could not process the request.")
+}
+
+// statusResponse is a SAML Response without assertion. It is used to tell the
+// service provider why its request could not be fulfilled.
+type statusResponse struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol Response"`
+	ID           string   `xml:",attr"`
+	InResponseTo string   `xml:",attr,omitempty"`
+	Version      string   `xml:",attr"`
+	IssueInstant string   `xml:",attr"`
+	Destination  string   `xml:",attr,omitempty"`
+	Issuer       statusIssuer
+	Status       status
+}
+
+type statusIssuer struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Format  string   `xml:",attr"`
+	Value   string   `xml:",chardata"`
+}
+
+type status struct {
+	XMLName       xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol Status"`
+	StatusCode    statusCode
+	StatusMessage string `xml:"urn:oasis:names:tc:SAML:2.0:protocol StatusMessage,omitempty"`
+}
+
+type statusCode struct {
+	XMLName    xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol StatusCode"`
+	Value      string   `xml:",attr"`
+	StatusCode *statusCode
+}
+
+// postBindingTemplate is the HTTP-POST binding form that sends the response to the service provider
+var postBindingTemplate = template.Must(template.New("post-binding").Parse(`<html>` +
+	`<form method="post" action="{{.URL}}" id="SAMLResponseForm">` +
+	`<input type="hidden" name="SAMLResponse" value="{{.SAMLResponse}}" />` +
+	`<input type="hidden" name="RelayState" value="{{.RelayState}}" />` +
+	`<input id="SAMLSubmitButton" type="submit" value="Continue" />` +
+	`</form>` +
+	`<script>document.getElementById('SAMLSubmitButton').style.visibility='hidden';</script>` +
+	`<script>document.getElementById('SAMLResponseForm').submit();</script>` +
+	`</html>`))
+
+// MakeStatusResponse creates SAML Response for the request with the given top-level status code,
+// optional second-level status code and optional status message.
+func MakeStatusResponse(req *saml.IdpAuthnRequest, topLevelStatus string, secondLevelStatus string, message string) ([]byte, error) {
+	if req.ACSEndpoint == nil {
+		return nil, fmt.Errorf("the assertion consumer service of the request is not known")
+	}
+
+	resp := statusResponse{
+		ID:           fmt.Sprintf("id-%x", RandomBytes(20)),
+		InResponseTo: req.Request.ID,
+		Version:      "2.0",
+		IssueInstant: saml.TimeNow().UTC().Format(timeFormat),
+		Destination:  req.ACSEndpoint.Location,
+		Issuer: statusIssuer{
+			Format: entityNameIDFormat,
+			Value:  req.IDP.MetadataURL.String(),
+		},
+		Status: status{
+			StatusCode: statusCode{
+				Value: topLevelStatus,
+			},
+			StatusMessage: message,
+		},
+	}
+
+	if secondLevelStatus != "" {
+		resp.Status.StatusCode.StatusCode = &statusCode{
+			Value: secondLevelStatus,
+		}
+	}
+
+	return xml.Marshal(resp)
+}
+
+// WriteStatusResponse sends SAML Response with the given status codes to the assertion consumer
+// service of the request using the HTTP-POST binding. Error is returned if the request has no
+// known assertion consumer service.
+func WriteStatusResponse(w http.ResponseWriter, req *saml.IdpAuthnRequest, topLevelStatus string, secondLevelStatus string, message string) error {
+	resp, err := MakeStatusResponse(req, topLevelStatus, secondLevelStatus, message)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"URL":          req.ACSEndpoint.Location,
+		"SAMLResponse": base64.StdEncoding.EncodeToString(resp),
+		"RelayState":   req.RelayState,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := postBindingTemplate.Execute(buf, data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+
+	return err
+}
+
+// WriteStatusError sends SAML Response for the error to the assertion consumer service of the request.
+// See ToStatusError for the status codes used.
+func WriteStatusError(w http.ResponseWriter, req *saml.IdpAuthnRequest, err error) error {
+	statusErr := ToStatusError(err)
+	return WriteStatusResponse(w, req, statusErr.Status, statusErr.SubStatus, statusErr.Message)
+}
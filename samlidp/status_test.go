@@ -0,0 +1,99 @@
+package samlidp
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+)
+
+var samlResponseInput = regexp.MustCompile(`name="SAMLResponse" value="([^"]*)"`)
+
+func TestWriteStatusResponse(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.IDP.ServiceProviderProvider = db.New()
+
+	req, err := ValidateSamlRequest(&s.IDP, newSamlRequest(`IsPassive="true"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	if err := WriteStatusResponse(rw, req, StatusResponder, StatusNoPassive, "passive authentication is not possible"); err != nil {
+		t.Fatal(err)
+	}
+
+	match := samlResponseInput.FindStringSubmatch(rw.Body.String())
+	if match == nil {
+		t.Fatalf("SAMLResponse not found in: %s", rw.Body.String())
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(html.UnescapeString(match[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := statusResponse{}
+	if err := xml.Unmarshal(buf, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.InResponseTo != req.Request.ID {
+		t.Fatalf("Expected InResponseTo %s, got %s", req.Request.ID, resp.InResponseTo)
+	}
+	if resp.Destination != "https://localhost:8082/user-profile/saml/acs" {
+		t.Fatalf("Unexpected destination %s", resp.Destination)
+	}
+	if resp.Issuer.Value != s.IDP.MetadataURL.String() {
+		t.Fatalf("Unexpected issuer %s", resp.Issuer.Value)
+	}
+	if resp.Status.StatusCode.Value != StatusResponder {
+		t.Fatalf("Expected top-level status %s, got %s", StatusResponder, resp.Status.StatusCode.Value)
+	}
+	if resp.Status.StatusCode.StatusCode == nil || resp.Status.StatusCode.StatusCode.Value != StatusNoPassive {
+		t.Fatalf("Expected second-level status %s", StatusNoPassive)
+	}
+	if resp.Status.StatusMessage != "passive authentication is not possible" {
+		t.Fatalf("Unexpected status message %s", resp.Status.StatusMessage)
+	}
+}
+
+func TestWriteStatusResponseUnknownACS(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &saml.IdpAuthnRequest{
+		IDP: &s.IDP,
+	}
+
+	rw := httptest.NewRecorder()
+	if err := WriteStatusResponse(rw, req, StatusRequester, "", ""); err == nil {
+		t.Fatal("Nil error, expected: the assertion consumer service of the request is not known")
+	}
+}
+
+func TestToStatusError(t *testing.T) {
+	statusErr := NewStatusError(StatusResponder, StatusAuthnFailed, "login cancelled")
+	if ToStatusError(statusErr) != statusErr {
+		t.Fatal("Expected the same StatusError")
+	}
+
+	converted := ToStatusError(fmt.Errorf("database is down"))
+	if converted.Status != StatusResponder || converted.SubStatus != "" {
+		t.Fatalf("Unexpected status codes: %s %s", converted.Status, converted.SubStatus)
+	}
+	if converted.Message == "database is down" {
+		t.Fatal("Internal error details must not be sent to the service provider")
+	}
+}
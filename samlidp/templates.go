@@ -0,0 +1,309 @@
+package samlidp
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/Microkubes/identity-provider/oidc"
+)
+
+// Pages rendered by the IdP. Every page is a template file in the templates directory which defines the
+// "heading" and "content" (and optionally "title") templates used by layout.html.
+const (
+	// LoginPage is the login form.
+	LoginPage = "login"
+	// ErrorPage shows an error message.
+	ErrorPage = "error"
+	// BadRequestPage shows the reason the request was rejected.
+	BadRequestPage = "bad-request"
+	// AccountInactivePage tells the user that the account is not activated.
+	AccountInactivePage = "account-inactive"
+	// AccountLockedPage tells the user that the account is locked.
+	AccountLockedPage = "account-locked"
+	// PasswordExpiredPage tells the user that the password has expired.
+	PasswordExpiredPage = "password-expired"
+	// PasswordResetPage is the form requesting the password reset email.
+	PasswordResetPage = "password-reset"
+	// PasswordResetSentPage tells the user that the password reset email was sent.
+	PasswordResetSentPage = "password-reset-sent"
+	// NewPasswordPage is the form setting the new password.
+	NewPasswordPage = "new-password"
+	// PasswordChangedPage tells the user that the password was changed.
+	PasswordChangedPage = "password-changed"
+	// MagicLinkSentPage tells the user that the sign-in link was sent.
+	MagicLinkSentPage = "magic-link-sent"
+	// MagicLinkConfirmPage asks the user to confirm the sign-in with the link.
+	MagicLinkConfirmPage = "magic-link-confirm"
+	// ConsentPage asks the user to consent to the release of the attributes to the service provider.
+	ConsentPage = "consent"
+	// ConsentsPage lists the service providers the user has consented to and lets the user revoke the consents.
+	ConsentsPage = "consents"
+	// AccountChooserPage lets the user choose the account, one of the sessions of the browser, to log in with.
+	AccountChooserPage = "account-chooser"
+)
+
+// DefaultTemplatesDir is the directory the templates are loaded from if not configured.
+const DefaultTemplatesDir = "public/templates"
+
+const (
+	layoutTemplate  = "layout.html"
+	partialsDir     = "partials"
+	layoutName      = "layout"
+	templateFileExt = ".html"
+)
+
+// pages are the pages parsed by NewTemplates
+var pages = []string{
+	LoginPage, ErrorPage, BadRequestPage,
+	AccountInactivePage, AccountLockedPage, PasswordExpiredPage,
+	PasswordResetPage, PasswordResetSentPage, NewPasswordPage, PasswordChangedPage,
+	MagicLinkSentPage, MagicLinkConfirmPage, ConsentPage, ConsentsPage, AccountChooserPage,
+}
+
+// Templates holds the parsed HTML templates of the pages. The templates are loaded from a directory
+// with the following structure:
+//
+//	layout.html       - the "layout" template, the page skeleton
+//	partials/*.html   - templates shared by the pages
+//	<page>.html       - the page templates
+//
+// The embedded default template is used for every file that is missing from the directory. The pages are
+// rendered in the language of the user, the templates translate their texts with {{.Locale.T "code"}}.
+type Templates struct {
+	// PasswordResetURL is the URL of the password reset form, linked from the login form if set.
+	PasswordResetURL string
+	// LoginIdentifiers are the types of the identifiers ("email", "username", "phone") the login form asks
+	// for. The form asks for the email if empty.
+	LoginIdentifiers []string
+	// Upstreams are the upstream identity providers the login form of the login transactions offers.
+	Upstreams []*Upstream
+	// SocialProviders are the social login providers the login form of the login transactions offers.
+	SocialProviders []*oidc.Provider
+	// TrustedProxies are the proxies whose X-Forwarded-Proto header is trusted for the CSRF and the language
+	// cookies, see IsSecureRequest.
+	TrustedProxies []string
+
+	dir     string
+	reload  bool
+	locales *i18n.Locales
+
+	mutex sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// NewTemplates parses the templates of all pages in the directory. If reload is set, the templates are
+// parsed again on every render, so changes are visible without restarting the service (for development).
+// The pages are translated with the message catalogs of the locales, or only the embedded catalogs if nil.
+func NewTemplates(dir string, reload bool, locales *i18n.Locales) (*Templates, error) {
+	if dir == "" {
+		dir = DefaultTemplatesDir
+	}
+	if locales == nil {
+		locales = i18n.DefaultLocales()
+	}
+
+	t := &Templates{
+		dir:     dir,
+		reload:  reload,
+		locales: locales,
+	}
+
+	if err := t.Load(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Load parses the templates of all pages again.
+func (t *Templates) Load() error {
+	parsed := map[string]*template.Template{}
+	for _, page := range pages {
+		tmpl, err := t.parse(page)
+		if err != nil {
+			return err
+		}
+		parsed[page] = tmpl
+	}
+
+	t.mutex.Lock()
+	t.pages = parsed
+	t.mutex.Unlock()
+
+	return nil
+}
+
+// Render renders the page with the data and the given status code. The CSRF token is added to the
+// data of every page, so the forms can post it back in the csrf_token field. The locale of the user is
+// added as Locale, unless already set, and the links of the language switcher as Languages if the data
+// has the LanguageURL the switcher links to.
+func (t *Templates) Render(w http.ResponseWriter, r *http.Request, page string, statusCode int, data map[string]interface{}) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["CSRFToken"] = CSRFToken(w, r, t.TrustedProxies)
+
+	locale, ok := data["Locale"].(*i18n.Locale)
+	if !ok {
+		locale = t.Locale(w, r)
+		data["Locale"] = locale
+	}
+	if languageURL, ok := data["LanguageURL"].(string); ok && languageURL != "" {
+		data["Languages"] = t.languageLinks(locale, languageURL)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	tmpl, err := t.lookup(page)
+	if err == nil {
+		err = tmpl.ExecuteTemplate(buf, layoutName, data)
+	}
+	if err != nil {
+		http.Error(w, "A server error has occured.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(buf.Bytes())
+}
+
+// Locale returns the locale of the user, see i18n.Locales.Negotiate.
+func (t *Templates) Locale(w http.ResponseWriter, r *http.Request) *i18n.Locale {
+	return t.locales.Negotiate(w, r, IsSecureRequest(r, t.TrustedProxies))
+}
+
+// LanguageLink is a link of the language switcher.
+type LanguageLink struct {
+	// Language is the language tag, e.g. "en"
+	Language string
+	// Name is the name of the language in the language itself
+	Name string
+	// URL reloads the page in the language
+	URL string
+	// Current is set for the language of the page
+	Current bool
+}
+
+// languageLinks returns the links of the language switcher, or nil if there is only one language
+func (t *Templates) languageLinks(locale *i18n.Locale, languageURL string) []LanguageLink {
+	languages := t.locales.Languages()
+	if len(languages) < 2 {
+		return nil
+	}
+
+	u, err := url.Parse(languageURL)
+	if err != nil {
+		return nil
+	}
+
+	links := []LanguageLink{}
+	for _, language := range languages {
+		query := u.Query()
+		query.Set(i18n.LanguageParam, language)
+		u.RawQuery = query.Encode()
+
+		links = append(links, LanguageLink{
+			Language: language,
+			Name:     t.locales.Locale(language).T("language.name"),
+			URL:      u.String(),
+			Current:  language == locale.Language,
+		})
+	}
+
+	return links
+}
+
+// lookup returns the parsed template of the page
+func (t *Templates) lookup(page string) (*template.Template, error) {
+	if t.reload {
+		return t.parse(page)
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	tmpl, ok := t.pages[page]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return tmpl, nil
+}
+
+// parse parses the layout, the partials and the page template
+func (t *Templates) parse(page string) (*template.Template, error) {
+	files, err := t.partials()
+	if err != nil {
+		return nil, err
+	}
+	files = append([]string{layoutTemplate}, files...)
+	files = append(files, page+templateFileExt)
+
+	tmpl := template.New(page)
+	for _, file := range files {
+		src, err := t.source(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tmpl.New(file).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+// partials returns the names of the default partials and the partials in the templates directory
+func (t *Templates) partials() ([]string, error) {
+	names := map[string]bool{}
+	for name := range defaultTemplates {
+		if strings.HasPrefix(name, partialsDir+"/") {
+			names[name] = true
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(t.dir, partialsDir, "*"+templateFileExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		names[partialsDir+"/"+filepath.Base(match)] = true
+	}
+
+	partials := []string{}
+	for name := range names {
+		partials = append(partials, name)
+	}
+	sort.Strings(partials)
+
+	return partials, nil
+}
+
+// source reads the template file from the templates directory, or returns the embedded default
+// template if the file does not exist
+func (t *Templates) source(name string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(t.dir, filepath.FromSlash(name)))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		src, ok := defaultTemplates[name]
+		if !ok {
+			return "", err
+		}
+		return src, nil
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,349 @@
+package samlidp
+
+// defaultTemplates are the templates used when the template files are not found in the templates directory.
+// They are the same as the templates in public/templates.
+var defaultTemplates = map[string]string{
+	"layout.html": `{{define "layout"}}<!DOCTYPE html>
+<html lang="{{.Locale.Language}}">
+<head>
+  <meta charset="utf-8"/>
+  <title>{{block "title" .}}Jormungandr: {{.Locale.T "login.title"}}{{end}}</title>
+  <link rel="stylesheet" type="text/css" href="/saml/css/idp.css"/>
+  {{template "theme-style" .}}
+</head>
+<body>
+  <div class="card">
+    <div class="card-title">
+      {{template "heading" .}}
+    </div>
+    {{template "content" .}}
+  </div>
+  {{template "languages" .Languages}}
+</body>
+</html>
+{{end}}
+`,
+	"partials/languages.html": `{{define "languages"}}{{with .}}
+<div class="languages">
+  {{range .}}{{if .Current}}<span lang="{{.Language}}" class="current">{{.Name}}</span>{{else}}<a href="{{.URL}}" lang="{{.Language}}" hreflang="{{.Language}}">{{.Name}}</a>{{end}}
+  {{end}}
+</div>
+{{end}}{{end}}
+`,
+	"partials/message.html": `{{define "message"}}
+<div class="error">
+  {{.}}
+</div>
+{{end}}
+`,
+	"partials/theme.html": `{{define "theme-style"}}{{with .Theme}}{{if or .PrimaryColor .BackgroundColor}}
+  <style>
+    {{with .BackgroundColor}}body { background-color: {{.}}; }{{end}}
+    {{with .PrimaryColor}}.card .card-footer { background-color: {{.}}; }{{end}}
+  </style>
+{{end}}{{end}}{{end}}
+`,
+	"login.html": `{{define "title"}}{{with .Theme}}{{with .DisplayName}}{{.}}{{else}}Jormungandr{{end}}{{else}}Jormungandr{{end}}: {{.Locale.T "login.title"}}{{end}}
+
+{{define "heading"}}
+{{with .Theme}}{{with .LogoURL}}<img src="{{.}}" alt="" class="logo"/><br/>{{end}}{{end}}
+{{.Locale.T "login.welcome"}}<br/>{{.Locale.T "login.please-sign-in"}}
+{{with .Theme}}{{with .DisplayName}}<div class="display-name">{{$.Locale.T "login.to" .}}</div>{{end}}{{end}}
+{{end}}
+
+{{define "content"}}
+<form action="{{.URL}}" method="POST" class="form">
+  <div class="card-content">
+    {{with .Theme}}{{with .CustomText}}<p class="custom-text">{{.}}</p>{{end}}{{end}}
+    {{template "message" .Error}}
+    <div class="form-control">
+      <input type="text" name="email" placeholder="{{.LoginLabel}}" title="{{.Locale.T "login.identifier-title" .LoginLabel}}"/>
+    </div>
+    <div class="form-control">
+      <input type="password" name="password" placeholder="{{.Locale.T "login.password"}}" title="{{.Locale.T "login.password-title"}}"/>
+    </div>
+    {{with .PasswordResetURL}}<a href="{{.}}" class="forgot-password">{{$.Locale.T "login.forgot-password"}}</a>{{end}}
+
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+    {{if .Transaction}}
+    <input type="hidden" name="transaction" value="{{.Transaction}}" />
+    {{else}}
+    <input type="hidden" name="RelayState" value="{{.RelayState}}" />
+    {{end}}
+
+    {{with .Theme}}{{with .HelpLinks}}
+    <ul class="help-links">
+      {{range .}}<li><a href="{{.URL}}" target="_blank" rel="noopener noreferrer">{{.Title}}</a></li>{{end}}
+    </ul>
+    {{end}}{{end}}
+  </div>
+  <div class="card-footer">
+    <button value="Sign In" class="form-button">{{.Locale.T "login.sign-in"}}</button>
+    {{if .MagicLink}}
+    <button name="magic-link" value="true" class="form-button">{{.Locale.T "login.magic-link"}}</button>
+    {{end}}
+    {{range .Upstreams}}
+    <button name="upstream" value="{{.Name}}" class="form-button">{{$.Locale.T "login.upstream" .DisplayName}}</button>
+    {{end}}
+    {{range .SocialProviders}}
+    <button name="social" value="{{.Name}}" class="form-button social">{{$.Locale.T "login.social" .DisplayName}}</button>
+    {{end}}
+    {{if .Transaction}}
+    <button name="cancel" value="true" class="form-button danger">{{.Locale.T "login.cancel"}}</button>
+    {{else}}
+    <a href="#" class="form-button">{{.Locale.T "login.create-account"}}</a>
+    {{end}}
+  </div>
+</form>
+{{end}}
+`,
+	"error.html": `{{define "heading"}}{{.Locale.T "error.heading"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    {{template "message" .Message}}
+  </div>
+</div>
+{{end}}
+`,
+	"bad-request.html": `{{define "heading"}}{{.Locale.T "bad-request.heading"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    {{template "message" .Message}}
+  </div>
+</div>
+{{end}}
+`,
+	"account-inactive.html": `{{define "heading"}}{{.Locale.T "account-inactive.heading"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    {{template "message" .Locale.T "account-not-activated"}}
+    <p>{{.Locale.T "account-inactive.check-email"}}</p>
+  </div>
+  <div class="card-footer">
+    {{with .ResendActivationURL}}<a href="{{.}}" class="form-button">{{$.Locale.T "account-inactive.resend"}}</a>{{end}}
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "account.back-to-login"}}</a>
+  </div>
+</div>
+{{end}}
+`,
+	"account-locked.html": `{{define "heading"}}{{.Locale.T "account-locked.heading"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    {{template "message" .Locale.T "account-locked"}}
+    <p>{{.Locale.T "account.contact-administrator"}}</p>
+  </div>
+  <div class="card-footer">
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "account.back-to-login"}}</a>
+  </div>
+</div>
+{{end}}
+`,
+	"password-expired.html": `{{define "heading"}}{{.Locale.T "password-expired.heading"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    {{template "message" .Locale.T "password-expired"}}
+    <p>{{.Locale.T "account.contact-administrator"}}</p>
+  </div>
+  <div class="card-footer">
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "account.back-to-login"}}</a>
+  </div>
+</div>
+{{end}}
+`,
+	"password-reset.html": `{{define "title"}}Jormungandr: {{.Locale.T "password-reset.title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "password-reset.title"}}{{end}}
+
+{{define "content"}}
+<form action="{{.URL}}" method="POST" class="form">
+  <div class="card-content">
+    {{template "message" .Error}}
+    <p>{{.Locale.T "password-reset.instructions"}}</p>
+    <div class="form-control">
+      <input type="text" name="email" placeholder="{{.Locale.T "login.email"}}" title="{{.Locale.T "login.email-title"}}"/>
+    </div>
+
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+  </div>
+  <div class="card-footer">
+    <button value="Send" class="form-button">{{.Locale.T "password-reset.send"}}</button>
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "account.back-to-login"}}</a>
+  </div>
+</form>
+{{end}}
+`,
+	"password-reset-sent.html": `{{define "title"}}Jormungandr: {{.Locale.T "password-reset.title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "password-reset.title"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    <p>{{.Locale.T "password-reset.sent"}}</p>
+  </div>
+  <div class="card-footer">
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "account.back-to-login"}}</a>
+  </div>
+</div>
+{{end}}
+`,
+	"new-password.html": `{{define "title"}}Jormungandr: {{.Locale.T "new-password.title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "new-password.title"}}{{end}}
+
+{{define "content"}}
+<form action="{{.URL}}" method="POST" class="form">
+  <div class="card-content">
+    {{template "message" .Error}}
+    <div class="form-control">
+      <input type="password" name="password" placeholder="{{.Locale.T "new-password.password"}}" title="{{.Locale.T "new-password.password"}}" autocomplete="new-password"/>
+    </div>
+    <div class="form-control">
+      <input type="password" name="confirm" placeholder="{{.Locale.T "new-password.confirm"}}" title="{{.Locale.T "new-password.confirm"}}" autocomplete="new-password"/>
+    </div>
+
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+    <input type="hidden" name="token" value="{{.Token}}" />
+  </div>
+  <div class="card-footer">
+    <button value="Save" class="form-button">{{.Locale.T "new-password.save"}}</button>
+  </div>
+</form>
+{{end}}
+`,
+	"password-changed.html": `{{define "title"}}Jormungandr: {{.Locale.T "new-password.title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "new-password.title"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    <p>{{.Locale.T "new-password.changed"}}</p>
+  </div>
+  <div class="card-footer">
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "login.sign-in"}}</a>
+  </div>
+</div>
+{{end}}
+`,
+	"magic-link-sent.html": `{{define "title"}}Jormungandr: {{.Locale.T "magic-link.title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "magic-link.title"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    <p>{{.Locale.T "magic-link.sent" .MaxAge}}</p>
+  </div>
+  <div class="card-footer">
+    <a href="{{.LoginURL}}" class="form-button">{{.Locale.T "account.back-to-login"}}</a>
+  </div>
+</div>
+{{end}}
+`,
+	"magic-link-confirm.html": `{{define "title"}}Jormungandr: {{.Locale.T "magic-link.confirm-title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "magic-link.confirm-title"}}{{end}}
+
+{{define "content"}}
+<form action="{{.URL}}" method="POST" class="form">
+  <div class="card-content">
+    <p>{{.Locale.T "magic-link.confirm" .Email}}</p>
+
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+    <input type="hidden" name="token" value="{{.Token}}" />
+  </div>
+  <div class="card-footer">
+    <button value="Continue" class="form-button">{{.Locale.T "magic-link.continue"}}</button>
+  </div>
+</form>
+{{end}}
+`,
+	"consent.html": `{{define "title"}}{{with .Theme}}{{with .DisplayName}}{{.}}{{else}}Jormungandr{{end}}{{else}}Jormungandr{{end}}: {{.Locale.T "consent.title"}}{{end}}
+
+{{define "heading"}}
+{{with .Theme}}{{with .LogoURL}}<img src="{{.}}" alt="" class="logo"/><br/>{{end}}{{end}}
+{{.Locale.T "consent.title"}}
+{{end}}
+
+{{define "content"}}
+<form action="{{.URL}}" method="POST" class="form">
+  <div class="card-content">
+    <p>{{.Locale.T "consent.instructions" .ServiceProvider}}</p>
+    <ul class="attributes">
+      {{range .Attributes}}<li><strong>{{.Label}}</strong>: {{range $i, $value := .Values}}{{if $i}}, {{end}}{{$value}}{{end}}</li>{{end}}
+    </ul>
+
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+    <input type="hidden" name="transaction" value="{{.Transaction}}" />
+  </div>
+  <div class="card-footer">
+    <button name="consent" value="accept" class="form-button">{{.Locale.T "consent.accept"}}</button>
+    <button name="consent" value="deny" class="form-button danger">{{.Locale.T "consent.deny"}}</button>
+  </div>
+</form>
+{{end}}
+`,
+	"consents.html": `{{define "title"}}Jormungandr: {{.Locale.T "consents.title"}}{{end}}
+
+{{define "heading"}}{{.Locale.T "consents.title"}}{{end}}
+
+{{define "content"}}
+<div class="form">
+  <div class="card-content">
+    {{range .Consents}}
+    <form action="{{$.URL}}" method="POST" class="consent">
+      <strong>{{.Name}}</strong>
+      <p>{{$.Locale.T "consents.shared" (.CreateTime.Format "2006-01-02")}} {{range $i, $label := .Attributes}}{{if $i}}, {{end}}{{$label}}{{end}}</p>
+      <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}" />
+      <input type="hidden" name="serviceProvider" value="{{.ID}}" />
+      <button class="form-button danger">{{$.Locale.T "consents.revoke"}}</button>
+    </form>
+    {{else}}
+    <p>{{.Locale.T "consents.none"}}</p>
+    {{end}}
+  </div>
+</div>
+{{end}}
+`,
+	"account-chooser.html": `{{define "title"}}{{with .Theme}}{{with .DisplayName}}{{.}}{{else}}Jormungandr{{end}}{{else}}Jormungandr{{end}}: {{.Locale.T "accounts.title"}}{{end}}
+
+{{define "heading"}}
+{{with .Theme}}{{with .LogoURL}}<img src="{{.}}" alt="" class="logo"/><br/>{{end}}{{end}}
+{{.Locale.T "accounts.title"}}
+{{with .Theme}}{{with .DisplayName}}<div class="display-name">{{$.Locale.T "login.to" .}}</div>{{end}}{{end}}
+{{end}}
+
+{{define "content"}}
+<form action="{{.URL}}" method="POST" class="form">
+  <div class="card-content">
+    <ul class="accounts">
+      {{range .Accounts}}
+      <li class="account">
+        <button name="account" value="{{.Index}}" class="account-button">{{with .Name}}<strong>{{.}}</strong><br/>{{end}}{{.Email}}</button>
+        <button name="sign-out" value="{{.Index}}" class="sign-out" title="{{$.Locale.T "accounts.sign-out-title" .Email}}">{{$.Locale.T "accounts.sign-out"}}</button>
+      </li>
+      {{end}}
+    </ul>
+
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+    <input type="hidden" name="transaction" value="{{.Transaction}}" />
+  </div>
+  <div class="card-footer">
+    <button name="add-account" value="true" class="form-button">{{.Locale.T "accounts.add"}}</button>
+    <button name="cancel" value="true" class="form-button danger">{{.Locale.T "login.cancel"}}</button>
+  </div>
+</form>
+{{end}}
+`,
+}
@@ -0,0 +1,123 @@
+package samlidp
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplatesEscaping(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/idp/login", nil)
+
+	createTemplates(t).ErrorForm(w, r, errors.New(`<script>alert("xss")</script>`), 500)
+
+	if strings.Contains(w.Body.String(), "<script>") {
+		t.Fatalf("Expected the message to be escaped, got: %s", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("Unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestTemplatesLocaleBehindProxy(t *testing.T) {
+	templates := createTemplates(t)
+	templates.TrustedProxies = []string{"10.0.0.5"}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/saml/idp/login?lang=en", nil)
+	r.RemoteAddr = "10.0.0.5:52000"
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	templates.Locale(w, r)
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || !cookies[0].Secure {
+		t.Fatalf("Expected the Secure lang cookie behind the trusted proxy, got %v", cookies)
+	}
+}
+
+func TestTemplatesDefaultFallback(t *testing.T) {
+	templates, err := NewTemplates("does-not-exist", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/idp/login", nil)
+	templates.BadRequestForm(w, r, errors.New("missing SAMLRequest"))
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "missing SAMLRequest") {
+		t.Fatalf("Expected the default bad request page, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTemplatesDefaultsMatchFiles(t *testing.T) {
+	for name, src := range defaultTemplates {
+		b, err := ioutil.ReadFile(filepath.Join("../public/templates", filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != src {
+			t.Fatalf("The embedded default %s differs from public/templates/%s", name, name)
+		}
+	}
+}
+
+func TestTemplatesOverrideAndReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "partials"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	partial := filepath.Join(dir, "partials", "message.html")
+	if err := ioutil.WriteFile(partial, []byte(`{{define "message"}}<p class="custom">{{.}}</p>{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := NewTemplates(dir, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func() string {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "https://idp.example.com/saml/idp/login", nil)
+		templates.ErrorForm(w, r, errors.New("failed"), 500)
+		return w.Body.String()
+	}
+
+	if body := render(); !strings.Contains(body, `<p class="custom">failed</p>`) {
+		t.Fatalf("Expected the custom partial, got: %s", body)
+	}
+
+	if err := ioutil.WriteFile(partial, []byte(`{{define "message"}}<p class="changed">{{.}}</p>{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if body := render(); !strings.Contains(body, `<p class="changed">failed</p>`) {
+		t.Fatalf("Expected the changed partial to be reloaded, got: %s", body)
+	}
+}
+
+func TestNewTemplatesParseError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "login.html"), []byte(`{{define "content"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTemplates(dir, false, nil); err == nil {
+		t.Fatal("Nil error, expected: unexpected EOF")
+	}
+}
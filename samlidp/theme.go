@@ -0,0 +1,125 @@
+package samlidp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// DefaultThemeID is the service ID of the theme used for the service providers without a theme of their own
+// and for the standalone login.
+const DefaultThemeID = "default"
+
+// colorPattern matches the #rgb and #rrggbb colors. Only these are accepted, so the colors are safe to put
+// in the style of the page.
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ThemeStore looks up the themes of the service providers.
+type ThemeStore interface {
+	// GetTheme returns the theme of the service provider
+	GetTheme(serviceID string) (*db.Theme, error)
+}
+
+// ParseTheme reads the theme JSON and validates it.
+func ParseTheme(r io.Reader) (*db.Theme, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, goa.ErrBadRequest(err)
+	}
+
+	theme := &db.Theme{}
+	if err := json.Unmarshal(body, theme); err != nil {
+		return nil, goa.ErrBadRequest(err)
+	}
+
+	if err := ValidateTheme(theme); err != nil {
+		return nil, err
+	}
+
+	return theme, nil
+}
+
+// ValidateTheme checks that the theme has a service ID, that the colors are #rgb or #rrggbb colors and that
+// the logo and the help links are http(s) URLs.
+func ValidateTheme(theme *db.Theme) error {
+	if theme.ServiceID == "" {
+		return goa.ErrBadRequest("serviceId is required")
+	}
+
+	for _, color := range []string{theme.PrimaryColor, theme.BackgroundColor} {
+		if color != "" && !colorPattern.MatchString(color) {
+			return goa.ErrBadRequest(fmt.Sprintf("invalid color %q, expected #rgb or #rrggbb", color))
+		}
+	}
+
+	if theme.LogoURL != "" && !isHTTPURL(theme.LogoURL) {
+		return goa.ErrBadRequest(fmt.Sprintf("invalid logoUrl %q, expected http(s) URL", theme.LogoURL))
+	}
+
+	for _, link := range theme.HelpLinks {
+		if link.Title == "" {
+			return goa.ErrBadRequest("help link title is required")
+		}
+		if !isHTTPURL(link.URL) {
+			return goa.ErrBadRequest(fmt.Sprintf("invalid help link URL %q, expected http(s) URL", link.URL))
+		}
+	}
+
+	return nil
+}
+
+// ServiceProviderTheme returns the theme of the service provider, or the default theme if the service provider
+// does not have one. If the theme has no display name, the organization display name from the service
+// provider metadata is used. A missing theme never fails the login, the page is then shown without branding.
+func ServiceProviderTheme(store ThemeStore, serviceProvider *saml.EntityDescriptor) *db.Theme {
+	var theme *db.Theme
+	if serviceProvider != nil {
+		theme, _ = store.GetTheme(serviceProvider.EntityID)
+	}
+	if theme == nil {
+		theme, _ = store.GetTheme(DefaultThemeID)
+	}
+	if theme == nil {
+		theme = &db.Theme{ServiceID: DefaultThemeID}
+	}
+
+	if theme.DisplayName == "" && serviceProvider != nil {
+		theme.DisplayName = organizationDisplayName(serviceProvider)
+	}
+
+	return theme
+}
+
+// organizationDisplayName returns the organization display name from the service provider metadata,
+// preferring the English one
+func organizationDisplayName(serviceProvider *saml.EntityDescriptor) string {
+	if serviceProvider.Organization == nil {
+		return ""
+	}
+
+	name := ""
+	for _, displayName := range serviceProvider.Organization.OrganizationDisplayNames {
+		if name == "" || displayName.Lang == "en" {
+			name = displayName.Value
+		}
+	}
+
+	return name
+}
+
+// isHTTPURL checks that the URL is an absolute http or https URL
+func isHTTPURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,72 @@
+package samlidp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme(strings.NewReader(`{
+		"serviceId": "https://sp.example.com/saml/metadata",
+		"displayName": "Example",
+		"logoUrl": "https://cdn.example.com/logo.png",
+		"primaryColor": "#336699",
+		"backgroundColor": "#eee",
+		"helpLinks": [{"title": "Support", "url": "https://example.com/support"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if theme.ServiceID != "https://sp.example.com/saml/metadata" || len(theme.HelpLinks) != 1 {
+		t.Fatalf("Unexpected theme %+v", theme)
+	}
+}
+
+func TestValidateTheme(t *testing.T) {
+	invalid := []*db.Theme{
+		{},
+		{ServiceID: "sp", PrimaryColor: "red"},
+		{ServiceID: "sp", BackgroundColor: "#fff;background:url(x)"},
+		{ServiceID: "sp", LogoURL: "javascript:alert(1)"},
+		{ServiceID: "sp", HelpLinks: []db.HelpLink{{Title: "Help", URL: "/help"}}},
+		{ServiceID: "sp", HelpLinks: []db.HelpLink{{URL: "https://example.com/help"}}},
+	}
+
+	for _, theme := range invalid {
+		err := ValidateTheme(theme)
+		if e, ok := err.(*goa.ErrorResponse); !ok || e.Status != http.StatusBadRequest {
+			t.Fatalf("Expected bad request for %+v, got: %v", theme, err)
+		}
+	}
+}
+
+func TestServiceProviderTheme(t *testing.T) {
+	store := db.New()
+	serviceProvider := &saml.EntityDescriptor{
+		EntityID: "https://sp.example.com/saml/metadata",
+		Organization: &saml.Organization{
+			OrganizationDisplayNames: []saml.LocalizedName{{Lang: "de", Value: "Beispiel"}, {Lang: "en", Value: "Example"}},
+		},
+	}
+
+	theme := ServiceProviderTheme(store, serviceProvider)
+	if theme.ServiceID != DefaultThemeID || theme.DisplayName != "Example" {
+		t.Fatalf("Expected the built-in default theme with the organization name, got %+v", theme)
+	}
+
+	store.AddTheme(&db.Theme{ServiceID: DefaultThemeID, PrimaryColor: "#000"})
+	if theme := ServiceProviderTheme(store, nil); theme.PrimaryColor != "#000" {
+		t.Fatalf("Expected the default theme, got %+v", theme)
+	}
+
+	store.AddTheme(&db.Theme{ServiceID: serviceProvider.EntityID, DisplayName: "Example App"})
+	if theme := ServiceProviderTheme(store, serviceProvider); theme.DisplayName != "Example App" {
+		t.Fatalf("Expected the theme of the service provider, got %+v", theme)
+	}
+}
@@ -0,0 +1,171 @@
+package samlidp
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// LoginTransactionParam is the name of the login form field that holds the login transaction ID.
+const LoginTransactionParam = "transaction"
+
+// LoginTransactionMaxAge is the time the user has to complete the login.
+var LoginTransactionMaxAge = 10 * time.Minute
+
+// MaxLoginAttempts is the number of failed login attempts after which the login transaction is ended
+// and the service provider is told that the authentication failed.
+var MaxLoginAttempts = 5
+
+// TransactionStore keeps the login transactions in progress.
+type TransactionStore interface {
+	// AddLoginTransaction saves the login transaction
+	AddLoginTransaction(transaction *db.LoginTransaction) error
+	// GetLoginTransaction looks up the login transaction by its ID
+	GetLoginTransaction(transactionID string) (*db.LoginTransaction, error)
+	// DeleteLoginTransaction deletes the login transaction
+	DeleteLoginTransaction(transactionID string) error
+	// RedeemMagicLink removes the sign-in link from the login transaction, unless it has already been removed
+	RedeemMagicLink(transactionID string, linkID string) (*db.LoginTransaction, error)
+}
+
+// StartLoginTransaction saves the validated request as a new login transaction. The user has to
+// complete the given authentication steps, in order, before the response is sent, or log in with one of
+// the alternative methods: AuthnMethodMagicLink, AuthnMethodUpstream or AuthnMethodSocial.
+func StartLoginTransaction(store TransactionStore, req *saml.IdpAuthnRequest, steps []string, alternatives []string) (*db.LoginTransaction, error) {
+	if req.ServiceProviderMetadata == nil || req.ACSEndpoint == nil {
+		return nil, goa.ErrInvalidRequest("the request has not been validated")
+	}
+
+	now := saml.TimeNow()
+	transaction := &db.LoginTransaction{
+		ID:                base64.URLEncoding.EncodeToString(RandomBytes(32)),
+		AuthnRequest:      string(req.RequestBuffer),
+		RelayState:        req.RelayState,
+		ServiceProviderID: req.ServiceProviderMetadata.EntityID,
+		ACSBinding:        req.ACSEndpoint.Binding,
+		ACSLocation:       req.ACSEndpoint.Location,
+		ACSIndex:          req.ACSEndpoint.Index,
+		Steps:             steps,
+		MagicLinkEnabled:  containsMethod(alternatives, AuthnMethodMagicLink),
+		UpstreamsEnabled:  containsMethod(alternatives, AuthnMethodUpstream),
+		SocialEnabled:     containsMethod(alternatives, AuthnMethodSocial),
+		CreateTime:        now,
+		ExpireTime:        now.Add(LoginTransactionMaxAge),
+	}
+
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// LoadLoginTransaction looks up the login transaction posted with the login form and restores its request.
+// The request was validated when the transaction was started, so it is not validated again.
+func LoadLoginTransaction(idp *saml.IdentityProvider, store TransactionStore, r *http.Request) (*saml.IdpAuthnRequest, *db.LoginTransaction, error) {
+	transactionID := r.FormValue(LoginTransactionParam)
+	if transactionID == "" {
+		return nil, nil, goa.ErrBadRequest("login transaction is not set in the request")
+	}
+
+	return loadLoginTransaction(idp, store, r, transactionID)
+}
+
+// loadLoginTransaction looks up the login transaction by its ID and restores its request
+func loadLoginTransaction(idp *saml.IdentityProvider, store TransactionStore, r *http.Request, transactionID string) (*saml.IdpAuthnRequest, *db.LoginTransaction, error) {
+	transaction, err := store.GetLoginTransaction(transactionID)
+	if err != nil {
+		if e, ok := err.(*goa.ErrorResponse); ok && e.Status == http.StatusNotFound {
+			return nil, nil, goa.ErrBadRequest("The login has expired, return to the application and log in again.", i18n.MessageCodeKey, "login-expired")
+		}
+		return nil, nil, err
+	}
+
+	req, err := restoreAuthnRequest(idp, r, transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return req, transaction, nil
+}
+
+// FailLoginAttempt records a failed login attempt. StatusError with AuthnFailed status is returned
+// when the user has no attempts left.
+func FailLoginAttempt(store TransactionStore, transaction *db.LoginTransaction) error {
+	transaction.Attempts++
+	if transaction.Attempts >= MaxLoginAttempts {
+		store.DeleteLoginTransaction(transaction.ID)
+		return NewStatusError(StatusResponder, StatusAuthnFailed, "too many failed login attempts")
+	}
+
+	return store.AddLoginTransaction(transaction)
+}
+
+// CompleteAuthnStep records that the user completed the authentication step.
+func CompleteAuthnStep(transaction *db.LoginTransaction, method string) {
+	for _, completed := range transaction.AuthnMethods {
+		if completed == method {
+			return
+		}
+	}
+
+	transaction.AuthnMethods = append(transaction.AuthnMethods, method)
+}
+
+// containsMethod checks if the authentication method is in the list
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoginTransactionURL returns the URL that shows the login form of the transaction again.
+func LoginTransactionURL(formURL string, transaction *db.LoginTransaction) string {
+	return withQuery(formURL, url.Values{LoginTransactionParam: {transaction.ID}})
+}
+
+// restoreAuthnRequest creates the IdpAuthnRequest of the login transaction.
+func restoreAuthnRequest(idp *saml.IdentityProvider, r *http.Request, transaction *db.LoginTransaction) (*saml.IdpAuthnRequest, error) {
+	req := &saml.IdpAuthnRequest{
+		IDP:           idp,
+		HTTPRequest:   r,
+		RelayState:    transaction.RelayState,
+		RequestBuffer: []byte(transaction.AuthnRequest),
+		Now:           saml.TimeNow(),
+	}
+
+	if err := xml.Unmarshal(req.RequestBuffer, &req.Request); err != nil {
+		return nil, goa.ErrInternal(err)
+	}
+
+	serviceProvider, err := idp.ServiceProviderProvider.GetServiceProvider(r, transaction.ServiceProviderID)
+	if err != nil {
+		return nil, goa.ErrInvalidRequest("the service provider is no longer registered")
+	}
+	req.ServiceProviderMetadata = serviceProvider
+
+	for _, spssoDescriptor := range serviceProvider.SPSSODescriptors {
+		for _, acs := range spssoDescriptor.AssertionConsumerServices {
+			if acs.Binding == transaction.ACSBinding && acs.Location == transaction.ACSLocation && acs.Index == transaction.ACSIndex {
+				descriptor := spssoDescriptor
+				endpoint := acs
+				req.SPSSODescriptor = &descriptor
+				req.ACSEndpoint = &endpoint
+				return req, nil
+			}
+		}
+	}
+
+	return nil, goa.ErrInvalidRequest("the assertion consumer service is no longer registered")
+}
@@ -0,0 +1,99 @@
+package samlidp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/keitaroinc/goa"
+)
+
+func TestLoginTransaction(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+	s.IDP.ServiceProviderProvider = store
+
+	req, err := ValidateSamlRequest(&s.IDP, newSamlRequest(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := StartLoginTransaction(store, req, []string{AuthnMethodPassword}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := http.NewRequest("POST", "http://localhost:8080/saml/idp/sso?"+url.Values{LoginTransactionParam: {transaction.ID}}.Encode(), nil)
+	restored, loaded, err := LoadLoginTransaction(&s.IDP, store, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.ID != transaction.ID {
+		t.Fatalf("Expected transaction %s, got %s", transaction.ID, loaded.ID)
+	}
+	if restored.Request.ID != req.Request.ID {
+		t.Fatalf("Expected request %s, got %s", req.Request.ID, restored.Request.ID)
+	}
+	if restored.RelayState != req.RelayState {
+		t.Fatalf("Expected RelayState %s, got %s", req.RelayState, restored.RelayState)
+	}
+	if restored.ACSEndpoint == nil || restored.ACSEndpoint.Location != "https://localhost:8082/user-profile/saml/acs" {
+		t.Fatalf("Unexpected ACS endpoint %v", restored.ACSEndpoint)
+	}
+	if restored.ServiceProviderMetadata.EntityID != "https://localhost:8082/user-profile/saml/metadata" {
+		t.Fatalf("Unexpected service provider %s", restored.ServiceProviderMetadata.EntityID)
+	}
+}
+
+func TestLoadLoginTransactionUnknown(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := db.New()
+	s.IDP.ServiceProviderProvider = store
+
+	r, _ := http.NewRequest("POST", "http://localhost:8080/saml/idp/sso?transaction=unknown", nil)
+	_, _, err = LoadLoginTransaction(&s.IDP, store, r)
+	if e, ok := err.(*goa.ErrorResponse); !ok || e.Status != http.StatusBadRequest {
+		t.Fatalf("Expected bad request error, got: %v", err)
+	}
+}
+
+func TestFailLoginAttempt(t *testing.T) {
+	store := db.New()
+	transaction := &db.LoginTransaction{ID: "transaction-id"}
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < MaxLoginAttempts; i++ {
+		if err := FailLoginAttempt(store, transaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := FailLoginAttempt(store, transaction)
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.SubStatus != StatusAuthnFailed {
+		t.Fatalf("Expected AuthnFailed status error, got: %v", err)
+	}
+	if _, err := store.GetLoginTransaction(transaction.ID); err == nil {
+		t.Fatal("Expected the transaction to be deleted")
+	}
+}
+
+func TestCompleteAuthnStep(t *testing.T) {
+	transaction := &db.LoginTransaction{Steps: []string{AuthnMethodPassword}}
+
+	CompleteAuthnStep(transaction, AuthnMethodPassword)
+	CompleteAuthnStep(transaction, AuthnMethodPassword)
+
+	if len(transaction.AuthnMethods) != 1 || transaction.AuthnMethods[0] != AuthnMethodPassword {
+		t.Fatalf("Unexpected authentication methods %v", transaction.AuthnMethods)
+	}
+}
@@ -1,72 +1,323 @@
 package samlidp
 
 import (
-	"encoding/base64"
-	"io/ioutil"
 	"net/http"
-	"text/template"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
 	"github.com/crewjam/saml"
 )
 
-// LoginForm produces a form which requests a email and password and directs the user
-// back to the IDP authorize URL to restart the SAML login flow, this time establishing a
-// session based on the credentials that were provided.
-func LoginForm(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, url string, message string, file string) {
+// LoginForm produces a form which requests a email and password for the standalone login,
+// establishing a session based on the credentials that were provided. The SAML logins use
+// LoginTransactionForm. The page is branded with the theme and shows the message of the error, if any,
+// in the language of the user.
+func (t *Templates) LoginForm(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest, theme *db.Theme, formURL string, message error) {
+	locale := t.Locale(w, r)
 	data := map[string]interface{}{
-		"Error":       message,
-		"URL":         url,
-		"SAMLRequest": base64.StdEncoding.EncodeToString(req.RequestBuffer),
-		"RelayState":  req.RelayState,
+		"Locale":           locale,
+		"Error":            locale.Error(message),
+		"URL":              formURL,
+		"LanguageURL":      RelayStateURL(formURL, req.RelayState),
+		"RelayState":       req.RelayState,
+		"Theme":            theme,
+		"PasswordResetURL": t.PasswordResetURL,
+		"LoginLabel":       t.loginLabel(locale),
 	}
 
-	renderTemplate(file, 200, data, w, r)
+	t.Render(w, r, LoginPage, http.StatusOK, data)
+}
+
+// LoginTransactionForm produces the login form of a login transaction. Instead of the SAMLRequest,
+// the form posts back the transaction ID, the request itself is kept by the IdP. The page is branded
+// with the theme of the service provider.
+func (t *Templates) LoginTransactionForm(w http.ResponseWriter, r *http.Request, transaction *db.LoginTransaction, theme *db.Theme, formURL string, message error) {
+	locale := t.Locale(w, r)
+	data := map[string]interface{}{
+		"Locale":           locale,
+		"Error":            locale.Error(message),
+		"URL":              formURL,
+		"LanguageURL":      LoginTransactionURL(formURL, transaction),
+		"Transaction":      transaction.ID,
+		"Theme":            theme,
+		"PasswordResetURL": t.PasswordResetURL,
+		"LoginLabel":       t.loginLabel(locale),
+		"MagicLink":        transaction.MagicLinkEnabled,
+	}
+	if transaction.UpstreamsEnabled {
+		data["Upstreams"] = t.Upstreams
+	}
+	if transaction.SocialEnabled {
+		data["SocialProviders"] = t.SocialProviders
+	}
+
+	t.Render(w, r, LoginPage, http.StatusOK, data)
+}
+
+// loginLabel returns the name of the login field in the language of the locale, e.g. "email, username or
+// phone number"
+func (t *Templates) loginLabel(locale *i18n.Locale) string {
+	identifiers := t.LoginIdentifiers
+	if len(identifiers) == 0 {
+		identifiers = []string{"email"}
+	}
+
+	names := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		names[i] = locale.T("login.identifier." + identifier)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return locale.T("login.or", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
 }
 
 // ErrorForm shows error message if something went wrong.
-func ErrorForm(w http.ResponseWriter, r *http.Request, message string, statusCode int, file string) {
+func (t *Templates) ErrorForm(w http.ResponseWriter, r *http.Request, message error, statusCode int) {
+	locale := t.Locale(w, r)
 	data := map[string]interface{}{
-		"Message": message,
+		"Locale":      locale,
+		"Message":     locale.Error(message),
+		"LanguageURL": reloadURL(r),
 	}
 
-	renderTemplate(file, statusCode, data, w, r)
+	t.Render(w, r, ErrorPage, statusCode, data)
 }
 
 // BadRequestForm shows bad request message if SAML request is not valid
-func BadRequestForm(w http.ResponseWriter, r *http.Request, message string, file string) {
+func (t *Templates) BadRequestForm(w http.ResponseWriter, r *http.Request, message error) {
+	locale := t.Locale(w, r)
+	data := map[string]interface{}{
+		"Locale":      locale,
+		"Message":     locale.Error(message),
+		"LanguageURL": reloadURL(r),
+	}
+
+	t.Render(w, r, BadRequestPage, http.StatusBadRequest, data)
+}
+
+// AccountInactiveForm tells the user that the account is not activated yet. The page links to the login form
+// and to resendActivationURL, if set, where the user can get a new activation email.
+func (t *Templates) AccountInactiveForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, loginURL string, resendActivationURL string) {
+	data := map[string]interface{}{
+		"Theme":               theme,
+		"LoginURL":            loginURL,
+		"ResendActivationURL": resendActivationURL,
+	}
+
+	t.Render(w, r, AccountInactivePage, http.StatusForbidden, data)
+}
+
+// AccountLockedForm tells the user that the account is locked.
+func (t *Templates) AccountLockedForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, loginURL string) {
+	data := map[string]interface{}{
+		"Theme":    theme,
+		"LoginURL": loginURL,
+	}
+
+	t.Render(w, r, AccountLockedPage, http.StatusForbidden, data)
+}
+
+// PasswordExpiredForm tells the user that the password has expired. It is shown only if there is no
+// change-password page to redirect the user to.
+func (t *Templates) PasswordExpiredForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, loginURL string) {
+	data := map[string]interface{}{
+		"Theme":    theme,
+		"LoginURL": loginURL,
+	}
+
+	t.Render(w, r, PasswordExpiredPage, http.StatusForbidden, data)
+}
+
+// PasswordResetForm produces the form requesting the password reset email. The form is posted to formURL.
+func (t *Templates) PasswordResetForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, formURL string, loginURL string, message error) {
+	locale := t.Locale(w, r)
+	data := map[string]interface{}{
+		"Locale":      locale,
+		"Error":       locale.Error(message),
+		"URL":         formURL,
+		"LanguageURL": formURL,
+		"LoginURL":    loginURL,
+		"Theme":       theme,
+	}
+
+	t.Render(w, r, PasswordResetPage, http.StatusOK, data)
+}
+
+// PasswordResetSentForm tells the user that the password reset email was sent. The page is the same whether
+// the account exists or not, so it does not reveal the registered emails.
+func (t *Templates) PasswordResetSentForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, loginURL string) {
+	data := map[string]interface{}{
+		"LoginURL": loginURL,
+		"Theme":    theme,
+	}
+
+	t.Render(w, r, PasswordResetSentPage, http.StatusOK, data)
+}
+
+// NewPasswordForm produces the form setting the new password. The form posts back the password reset token.
+func (t *Templates) NewPasswordForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, formURL string, token string, message error) {
+	locale := t.Locale(w, r)
 	data := map[string]interface{}{
-		"Message": message,
+		"Locale":      locale,
+		"Error":       locale.Error(message),
+		"URL":         formURL,
+		"LanguageURL": withQuery(formURL, url.Values{PasswordResetTokenParam: {token}}),
+		"Token":       token,
+		"Theme":       theme,
 	}
 
-	renderTemplate(file, 400, data, w, r)
+	t.Render(w, r, NewPasswordPage, http.StatusOK, data)
+}
 
+// PasswordChangedForm tells the user that the password was changed.
+func (t *Templates) PasswordChangedForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, loginURL string) {
+	data := map[string]interface{}{
+		"LoginURL": loginURL,
+		"Theme":    theme,
+	}
+
+	t.Render(w, r, PasswordChangedPage, http.StatusOK, data)
 }
 
-// renderTemplate renders html template file
-func renderTemplate(templateFile string, statusCode int, data map[string]interface{}, w http.ResponseWriter, r *http.Request) {
-	tplContent, err := loadTemplateFile(templateFile)
-	if err != nil {
-		panic(err)
+// MagicLinkSentForm tells the user that the sign-in link was sent. The page is the same whether the account
+// exists or not, so it does not reveal the registered emails. loginURL shows the login form of the transaction again.
+func (t *Templates) MagicLinkSentForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, loginURL string) {
+	data := map[string]interface{}{
+		"LoginURL": loginURL,
+		"MaxAge":   int(MagicLinkMaxAge.Minutes()),
+		"Theme":    theme,
 	}
 
-	tmpl, err := template.New(templateFile).Parse(tplContent)
-	if err != nil {
-		panic(err)
+	t.Render(w, r, MagicLinkSentPage, http.StatusOK, data)
+}
+
+// MagicLinkConfirmForm asks the user to confirm the sign-in with the link sent to the email. The form posts
+// back the sign-in token, so the link is used only by the user, not by the mail scanners that open the link.
+func (t *Templates) MagicLinkConfirmForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, formURL string, token string, email string) {
+	data := map[string]interface{}{
+		"URL":         formURL,
+		"LanguageURL": withQuery(formURL, url.Values{MagicLinkTokenParam: {token}}),
+		"Token":       token,
+		"Email":       email,
+		"Theme":       theme,
 	}
 
-	w.WriteHeader(statusCode)
-	w.Header().Set("Content-Type", "text/html")
+	t.Render(w, r, MagicLinkConfirmPage, http.StatusOK, data)
+}
+
+// ConsentForm asks the user to consent to the release of the attributes to the service provider of the login
+// transaction. The form posts back the transaction ID with the decision of the user.
+func (t *Templates) ConsentForm(w http.ResponseWriter, r *http.Request, transaction *db.LoginTransaction, theme *db.Theme, formURL string, attributes []ReleasedAttribute) {
+	locale := t.Locale(w, r)
+
+	serviceProvider := transaction.ServiceProviderID
+	if theme != nil && theme.DisplayName != "" {
+		serviceProvider = theme.DisplayName
+	}
+
+	labeled := []map[string]interface{}{}
+	for _, attribute := range attributes {
+		labeled = append(labeled, map[string]interface{}{
+			"Label":  attributeLabel(locale, attribute.Name),
+			"Values": attribute.Values,
+		})
+	}
 
-	if err := tmpl.Execute(w, data); err != nil {
-		ErrorForm(w, r, err.Error(), 500, templateFile)
+	data := map[string]interface{}{
+		"Locale":          locale,
+		"URL":             formURL,
+		"Transaction":     transaction.ID,
+		"Theme":           theme,
+		"ServiceProvider": serviceProvider,
+		"Attributes":      labeled,
 	}
+
+	t.Render(w, r, ConsentPage, http.StatusOK, data)
 }
 
-// renderTemplate reads template file
-func loadTemplateFile(fileName string) (string, error) {
-	b, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		return "", err
+// AccountChooserForm lets the user choose the account of the login transaction among the sessions of the
+// browser, sign in with another account or sign out of one of the accounts. The form posts back the
+// transaction ID with the session index of the chosen account.
+func (t *Templates) AccountChooserForm(w http.ResponseWriter, r *http.Request, transaction *db.LoginTransaction, theme *db.Theme, formURL string, sessions []*db.Session) {
+	accounts := []map[string]interface{}{}
+	for _, session := range sessions {
+		accounts = append(accounts, map[string]interface{}{
+			"Index": session.Index,
+			"Email": session.UserEmail,
+			"Name":  session.UserCommonName,
+		})
 	}
-	return string(b), nil
+
+	data := map[string]interface{}{
+		"URL":         formURL,
+		"Transaction": transaction.ID,
+		"Theme":       theme,
+		"Accounts":    accounts,
+	}
+
+	t.Render(w, r, AccountChooserPage, http.StatusOK, data)
+}
+
+// ConsentedServiceProvider is a service provider listed on the consents page.
+type ConsentedServiceProvider struct {
+	// ID is the entity ID of the service provider
+	ID string
+	// Name is the name of the service provider, see ServiceProviderName
+	Name string
+	// Attributes are the names of the attributes the user consented to release
+	Attributes []string
+	// CreateTime is the time the user consented
+	CreateTime time.Time
+}
+
+// ConsentsForm lists the service providers the user has consented to release the attributes to. Every
+// consent is revoked with its own form posted to formURL.
+func (t *Templates) ConsentsForm(w http.ResponseWriter, r *http.Request, theme *db.Theme, formURL string, consents []ConsentedServiceProvider) {
+	locale := t.Locale(w, r)
+
+	labeled := []ConsentedServiceProvider{}
+	for _, consent := range consents {
+		labels := make([]string, len(consent.Attributes))
+		for i, name := range consent.Attributes {
+			labels[i] = attributeLabel(locale, name)
+		}
+		consent.Attributes = labels
+		labeled = append(labeled, consent)
+	}
+
+	data := map[string]interface{}{
+		"Locale":      locale,
+		"URL":         formURL,
+		"LanguageURL": reloadURL(r),
+		"Theme":       theme,
+		"Consents":    labeled,
+	}
+
+	t.Render(w, r, ConsentsPage, http.StatusOK, data)
+}
+
+// attributeLabel returns the name of the attribute in the language of the locale, or the attribute name if
+// the catalogs do not have it
+func attributeLabel(locale *i18n.Locale, name string) string {
+	code := "attribute." + name
+	if label := locale.T(code); label != code {
+		return label
+	}
+
+	return name
+}
+
+// reloadURL returns the URL that shows the page again, or empty string if the page was the response to a post
+func reloadURL(r *http.Request) string {
+	if r.Method != http.MethodGet {
+		return ""
+	}
+
+	u := url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	return u.String()
 }
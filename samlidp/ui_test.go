@@ -1,49 +1,45 @@
 package samlidp
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/i18n"
 )
 
-func TestLoadTemplateFile(t *testing.T) {
-	_, err := loadTemplateFile("../public/login/login-form.html")
+func createTemplates(t *testing.T) *Templates {
+	templates, err := NewTemplates("../public/templates", false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = loadTemplateFile("public/login/not-exists.html")
-	if err == nil {
-		t.Fatal("Nil error, expected: open public/login/not-exists.html: no such file or directory")
-	}
-}
-
-func TestRenderTemplate(t *testing.T) {
-	w := httptest.NewRecorder()
-	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
-	data := map[string]interface{}{
-		"Error":       "",
-		"URL":         "https://idp.example.com/saml/sso",
-		"SAMLRequest": "dhjsajdh2o3f78n24r89jndfsf78hfd78f",
-		"RelayState":  "sdi894878r2378h223h0228e7823e37232",
-	}
-	renderTemplate("../public/login/login-form.html", 200, data, w, r)
+	return templates
 }
 
 func TestBadRequestForm(t *testing.T) {
 	w := httptest.NewRecorder()
 	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
 
-	BadRequestForm(w, r, "Bad request", "../public/bad-request.html")
+	createTemplates(t).BadRequestForm(w, r, errors.New("Bad request"))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
 }
 
 func TestErrorForm(t *testing.T) {
 	w := httptest.NewRecorder()
 	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
 
-	ErrorForm(w, r, "Internal Server Error", 500, "../public/error.html")
+	createTemplates(t).ErrorForm(w, r, errors.New("Internal Server Error"), 500)
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Fatalf("Expected the error page, got %d: %s", w.Code, w.Body.String())
+	}
 }
 
 func TestLoginForm(t *testing.T) {
@@ -60,5 +56,159 @@ func TestLoginForm(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	LoginForm(w, r, req, "https://idp.example.com/saml/idp/login", "", "../public/login/login-form.html")
+	createTemplates(t).LoginForm(w, r, req, &db.Theme{}, "https://idp.example.com/saml/idp/login", nil)
+
+	if !strings.Contains(w.Body.String(), `action="https://idp.example.com/saml/idp/login"`) {
+		t.Fatalf("Expected the login form, got: %s", w.Body.String())
+	}
+}
+
+func TestLoginTransactionForm(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
+
+	createTemplates(t).LoginTransactionForm(w, r, &db.LoginTransaction{ID: "transaction-id"}, &db.Theme{}, "https://idp.example.com/saml/idp/sso", nil)
+
+	body := w.Body.String()
+	if !strings.Contains(body, `name="transaction" value="transaction-id"`) || !strings.Contains(body, `name="cancel"`) {
+		t.Fatalf("Expected the login form of the transaction, got: %s", body)
+	}
+}
+
+func TestLoginTransactionFormUsernames(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
+
+	templates := createTemplates(t)
+	templates.LoginIdentifiers = []string{"email", "username", "phone"}
+	templates.LoginTransactionForm(w, r, &db.LoginTransaction{ID: "transaction-id"}, &db.Theme{}, "https://idp.example.com/saml/idp/sso", nil)
+
+	if !strings.Contains(w.Body.String(), `placeholder="email, username or phone number"`) {
+		t.Fatalf("Expected the login form asking for the email, the username or the phone number, got: %s", w.Body.String())
+	}
+}
+
+func TestLoginTransactionFormTheme(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
+	theme := &db.Theme{
+		DisplayName:  "<b>Example</b>",
+		LogoURL:      "https://cdn.example.com/logo.png",
+		PrimaryColor: "#336699",
+		HelpLinks:    []db.HelpLink{{Title: "Support", URL: "https://example.com/support"}},
+		CustomText:   "Use your company account",
+	}
+
+	createTemplates(t).LoginTransactionForm(w, r, &db.LoginTransaction{ID: "transaction-id"}, theme, "https://idp.example.com/saml/idp/sso", nil)
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		"&lt;b&gt;Example&lt;/b&gt;",
+		`src="https://cdn.example.com/logo.png"`,
+		".card .card-footer { background-color: #336699; }",
+		`href="https://example.com/support"`,
+		"Use your company account",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %s on the login page, got: %s", expected, body)
+		}
+	}
+	if strings.Contains(body, "<b>Example</b>") {
+		t.Fatalf("Expected the display name to be escaped, got: %s", body)
+	}
+}
+
+func TestLoginTransactionFormLanguage(t *testing.T) {
+	locales, err := i18n.NewLocales("../public/locales", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := NewTemplates("../public/templates", false, locales)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
+	r.Header.Set("Accept-Language", "de-AT,de;q=0.9,en;q=0.8")
+
+	templates.LoginTransactionForm(w, r, &db.LoginTransaction{ID: "transaction-id"}, &db.Theme{}, "https://idp.example.com/saml/idp/sso", i18n.NewError("wrong-credentials", "Wrong email or password!"))
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		`<html lang="de">`,
+		locales.Locale("de").T("login.sign-in"),
+		locales.Locale("de").T("wrong-credentials"),
+		`href="https://idp.example.com/saml/idp/sso?lang=en&amp;transaction=transaction-id"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %s on the login page, got: %s", expected, body)
+		}
+	}
+}
+
+func TestConsentForm(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
+
+	attributes := []ReleasedAttribute{{Name: "eduPersonAffiliation", Values: []string{"staff", "admin"}}, {Name: "department", Values: []string{"Sales"}}}
+	createTemplates(t).ConsentForm(w, r, &db.LoginTransaction{ID: "transaction-id"}, &db.Theme{DisplayName: "Example App"}, "https://idp.example.com/saml/idp/consent", attributes)
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		`action="https://idp.example.com/saml/idp/consent"`,
+		`name="transaction" value="transaction-id"`,
+		"Example App",
+		"Roles",
+		"staff",
+		"department",
+		`value="accept"`,
+		`value="deny"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %s on the consent page, got: %s", expected, body)
+		}
+	}
+}
+
+func TestConsentsForm(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/idp/consents", nil)
+
+	consents := []ConsentedServiceProvider{{ID: "https://sp.example.com/saml/metadata", Name: "Example App", Attributes: []string{"uid"}}}
+	createTemplates(t).ConsentsForm(w, r, &db.Theme{}, "https://idp.example.com/saml/idp/consents", consents)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Example App") || !strings.Contains(body, `name="serviceProvider" value="https://sp.example.com/saml/metadata"`) {
+		t.Fatalf("Expected the consented service provider, got: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	createTemplates(t).ConsentsForm(w, r, &db.Theme{}, "https://idp.example.com/saml/idp/consents", nil)
+	if strings.Contains(w.Body.String(), `name="serviceProvider"`) {
+		t.Fatalf("Expected no consents, got: %s", w.Body.String())
+	}
+}
+
+func TestAccountChooserForm(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://idp.example.com/saml/sso", nil)
+
+	createTemplates(t).AccountChooserForm(w, r, &db.LoginTransaction{ID: "transaction-id"}, &db.Theme{}, "https://idp.example.com/saml/idp/sso", browserSessions())
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		`name="transaction" value="transaction-id"`,
+		`name="account" value="1"`,
+		"jane@example.com",
+		`name="sign-out" value="2"`,
+		`name="add-account"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %s on the account chooser, got: %s", expected, body)
+		}
+	}
+	if strings.Contains(body, `value="personal"`) {
+		t.Fatalf("Expected no session IDs on the account chooser, got: %s", body)
+	}
 }
@@ -0,0 +1,392 @@
+package samlidp
+
+import (
+	"crypto/rsa"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/db"
+	"github.com/Microkubes/identity-provider/provisioning"
+	"github.com/crewjam/saml"
+	"github.com/keitaroinc/goa"
+)
+
+// UpstreamParam is the name of the login form field that holds the name of the upstream IdP chosen by the user.
+const UpstreamParam = "upstream"
+
+// ErrUpstreamLoginFailed is sent to the service provider when the upstream IdP did not authenticate the user.
+var ErrUpstreamLoginFailed = NewStatusError(StatusResponder, StatusAuthnFailed, "The upstream identity provider did not authenticate the user.")
+
+// defaultUpstreamAttributes are the names of the assertion attributes the user fields are taken from,
+// unless configured otherwise. The roles are taken only from the configured attribute.
+var defaultUpstreamAttributes = map[string][]string{
+	"email":    {"email", "mail", "urn:oid:0.9.2342.19200300.100.1.3"},
+	"fullname": {"displayName", "urn:oid:2.16.840.1.113730.3.1.241"},
+}
+
+// Upstream is an upstream identity provider the users may log in with. The IdP acts as a service provider
+// toward it.
+type Upstream struct {
+	// Name is the name of the upstream in the configuration
+	Name string
+	// DisplayName is the name shown on the login form
+	DisplayName string
+	// Domains are the email domains of the users of the upstream
+	Domains []string
+	// Attributes is a map of <user field>:<attribute name> of the configured attribute names
+	Attributes map[string]string
+	// Roles are the roles every user of the upstream gets
+	Roles []string
+	// AllowedRoles are the roles the upstream may assert, the other asserted roles are ignored
+	AllowedRoles []string
+	// Provisioning holds the rules the users of the upstream are linked to the local users by, nil if they
+	// have no local users. The domains of the rules default to the Domains.
+	Provisioning *config.ProvisioningConfig
+	// ServiceProvider is the service provider that sends the AuthnRequests to the upstream
+	ServiceProvider *saml.ServiceProvider
+}
+
+// NewUpstreams creates the configured upstream IdPs, ordered by name. The metadata of the upstreams is read
+// from the file or fetched from the URL.
+func NewUpstreams(cfg *config.Config, idp *saml.IdentityProvider) ([]*Upstream, error) {
+	names := []string{}
+	for name := range cfg.Upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	upstreams := []*Upstream{}
+	for _, name := range names {
+		upstreamConfig := cfg.Upstreams[name]
+
+		metadata, err := loadUpstreamMetadata(upstreamConfig)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %s", name, err)
+		}
+
+		sp := NewUpstreamServiceProvider(cfg, idp)
+		sp.IDPMetadata = metadata
+
+		upstream := &Upstream{
+			Name:            name,
+			DisplayName:     upstreamConfig.DisplayName,
+			Domains:         upstreamConfig.Domains,
+			Attributes:      upstreamConfig.Attributes,
+			Roles:           upstreamConfig.Roles,
+			AllowedRoles:    upstreamConfig.AllowedRoles,
+			Provisioning:    upstreamConfig.Provisioning,
+			ServiceProvider: sp,
+		}
+		if upstream.DisplayName == "" {
+			upstream.DisplayName = name
+		}
+		if rules := upstream.Provisioning; rules != nil && len(rules.Domains) == 0 {
+			withDomains := *rules
+			withDomains.Domains = upstream.Domains
+			upstream.Provisioning = &withDomains
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+
+	return upstreams, nil
+}
+
+// NewUpstreamServiceProvider creates the service provider the IdP acts as toward the upstream IdPs. It signs
+// with the key of the IdP and receives the responses of all upstreams at the same assertion consumer service.
+func NewUpstreamServiceProvider(cfg *config.Config, idp *saml.IdentityProvider) *saml.ServiceProvider {
+	metadataURL, _ := url.Parse(fmt.Sprintf("%s/saml/idp/upstream/metadata", cfg.GatewayURL))
+	acsURL, _ := url.Parse(fmt.Sprintf("%s/saml/idp/upstream/acs", cfg.GatewayURL))
+
+	return &saml.ServiceProvider{
+		EntityID:    metadataURL.String(),
+		Key:         idp.Key.(*rsa.PrivateKey),
+		Certificate: idp.Certificate,
+		MetadataURL: *metadataURL,
+		AcsURL:      *acsURL,
+	}
+}
+
+// loadUpstreamMetadata reads the metadata of the upstream IdP from the file, or fetches it from the URL
+func loadUpstreamMetadata(cfg *config.UpstreamConfig) (*saml.EntityDescriptor, error) {
+	var data []byte
+	switch {
+	case cfg.MetadataFile != "":
+		content, err := ioutil.ReadFile(cfg.MetadataFile)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	case cfg.MetadataURL != "":
+		resp, err := http.Get(cfg.MetadataURL)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching of the metadata failed with status %d", resp.StatusCode)
+		}
+		if data, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("neither metadataFile nor metadataUrl is set")
+	}
+
+	metadata := &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(data, metadata); err != nil {
+		return nil, err
+	}
+	if len(metadata.IDPSSODescriptors) == 0 {
+		return nil, fmt.Errorf("the metadata has no IDPSSODescriptor")
+	}
+
+	return metadata, nil
+}
+
+// UpstreamsEnabled checks if the user may log in with an upstream IdP for the request: the service provider
+// must accept the authentication context of the upstream logins.
+func UpstreamsEnabled(upstreams []*Upstream, req *saml.IdpAuthnRequest) bool {
+	return len(upstreams) > 0 && SatisfiesAuthnContext(req, []string{AuthnMethodUpstream})
+}
+
+// FindUpstream returns the upstream IdP with the name, nil if there is none.
+func FindUpstream(upstreams []*Upstream, name string) *Upstream {
+	for _, upstream := range upstreams {
+		if upstream.Name == name {
+			return upstream
+		}
+	}
+
+	return nil
+}
+
+// UpstreamForLogin returns the upstream IdP of the email domain of the login, nil if the login is not an email
+// in the domains of any upstream.
+func UpstreamForLogin(upstreams []*Upstream, login string) *Upstream {
+	i := strings.LastIndex(login, "@")
+	if i < 0 {
+		return nil
+	}
+	domain := strings.TrimSpace(login[i+1:])
+
+	for _, upstream := range upstreams {
+		for _, d := range upstream.Domains {
+			if strings.EqualFold(d, domain) {
+				return upstream
+			}
+		}
+	}
+
+	return nil
+}
+
+// StartUpstreamLogin sends the user to the upstream IdP with a new AuthnRequest. The ID of the request is
+// saved in the login transaction, and the transaction ID is sent as RelayState, so the response of the
+// upstream completes the transaction. The HTTP-Redirect binding is used if the upstream supports it, the
+// HTTP-POST binding otherwise.
+func StartUpstreamLogin(w http.ResponseWriter, r *http.Request, store TransactionStore, upstream *Upstream, transaction *db.LoginTransaction) error {
+	sp := upstream.ServiceProvider
+
+	binding := saml.HTTPRedirectBinding
+	location := sp.GetSSOBindingLocation(binding)
+	if location == "" {
+		binding = saml.HTTPPostBinding
+		location = sp.GetSSOBindingLocation(binding)
+	}
+	if location == "" {
+		return goa.ErrInternal(fmt.Errorf("upstream %s has no SSO service with a supported binding", upstream.Name))
+	}
+
+	authnRequest, err := sp.MakeAuthenticationRequest(location, binding, saml.HTTPPostBinding)
+	if err != nil {
+		return err
+	}
+
+	transaction.UpstreamLogin = &db.UpstreamLogin{
+		Upstream:  upstream.Name,
+		RequestID: authnRequest.ID,
+	}
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		return err
+	}
+
+	if binding == saml.HTTPPostBinding {
+		w.Header().Set("Content-Type", "text/html")
+		_, err := w.Write([]byte(`<!DOCTYPE html><html><body>` + string(authnRequest.Post(transaction.ID)) + `</body></html>`))
+		return err
+	}
+
+	redirectURL, err := authnRequest.Redirect(transaction.ID, sp)
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+
+	return nil
+}
+
+// CompleteUpstreamLogin verifies the response of the upstream IdP posted to the assertion consumer service
+// and returns its assertion together with the restored request of the login transaction. The upstream login
+// is removed from the transaction, so the response cannot be used again. ErrUpstreamLoginFailed is returned
+// with the request if the response is not valid or the upstream did not authenticate the user.
+func CompleteUpstreamLogin(idp *saml.IdentityProvider, store TransactionStore, upstreams []*Upstream, r *http.Request) (*saml.IdpAuthnRequest, *db.LoginTransaction, *Upstream, *saml.Assertion, error) {
+	req, transaction, err := loadLoginTransaction(idp, store, r, r.FormValue("RelayState"))
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	upstreamLogin := transaction.UpstreamLogin
+	if upstreamLogin == nil {
+		return req, nil, nil, nil, goa.ErrBadRequest("no upstream login was started in the login transaction")
+	}
+	upstream := FindUpstream(upstreams, upstreamLogin.Upstream)
+	if upstream == nil {
+		return req, nil, nil, nil, goa.ErrBadRequest("the upstream identity provider is no longer configured")
+	}
+
+	transaction.UpstreamLogin = nil
+	if err := store.AddLoginTransaction(transaction); err != nil {
+		return req, nil, nil, nil, err
+	}
+
+	assertion, err := upstream.ServiceProvider.ParseResponse(r, []string{upstreamLogin.RequestID})
+	if err != nil {
+		if invalid, ok := err.(*saml.InvalidResponseError); ok && invalid.PrivateErr != nil {
+			idp.Logger.Printf("the response of upstream %s is invalid: %s", upstream.Name, invalid.PrivateErr)
+		}
+		return req, transaction, upstream, nil, ErrUpstreamLoginFailed
+	}
+
+	return req, transaction, upstream, assertion, nil
+}
+
+// UpstreamUser maps the attributes of the upstream assertion to the user the session is created for, in the
+// format of the users returned by the user service. The user ID is the NameID qualified with the name of the
+// upstream. The user gets the roles of the upstream and the asserted roles that are allowed for the upstream.
+// ErrUpstreamLoginFailed is returned if the assertion has no email, or an email outside the domains of the
+// upstream, if it has any.
+func UpstreamUser(upstream *Upstream, assertion *saml.Assertion) (map[string]interface{}, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, ErrUpstreamLoginFailed
+	}
+	nameID := assertion.Subject.NameID
+
+	email := upstream.attribute(assertion, "email")
+	if len(email) == 0 && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		email = []string{nameID.Value}
+	}
+	if len(email) == 0 || email[0] == "" {
+		return nil, ErrUpstreamLoginFailed
+	}
+	if len(upstream.Domains) > 0 && !provisioning.InDomains(email[0], upstream.Domains) {
+		return nil, ErrUpstreamLoginFailed
+	}
+
+	roles := []interface{}{}
+	for _, role := range upstream.Roles {
+		roles = append(roles, role)
+	}
+	for _, role := range upstream.attribute(assertion, "roles") {
+		if upstream.roleAllowed(role) {
+			roles = append(roles, role)
+		}
+	}
+
+	user := map[string]interface{}{
+		"id":       upstream.Name + ":" + nameID.Value,
+		"email":    strings.ToLower(email[0]),
+		"roles":    roles,
+		"upstream": upstream.Name,
+		"active":   true,
+	}
+	if fullname := upstream.attribute(assertion, "fullname"); len(fullname) > 0 {
+		user["fullname"] = fullname[0]
+	}
+
+	return user, nil
+}
+
+// UpstreamExternalUser returns the external user of the upstream assertion, for the upstreams whose users are
+// linked to the local users. The upstream IdP is trusted to assert the emails in the domains of the provisioning
+// rules only, so the users with other emails, or of the upstreams without domains, are not linked by the email.
+// ErrUpstreamLoginFailed is returned if the assertion has no email.
+func UpstreamExternalUser(upstream *Upstream, assertion *saml.Assertion) (*provisioning.ExternalUser, error) {
+	user, err := UpstreamUser(upstream, assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := map[string][]string{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			for _, value := range attribute.Values {
+				attributes[attribute.Name] = append(attributes[attribute.Name], value.Value)
+				if attribute.FriendlyName != "" {
+					attributes[attribute.FriendlyName] = append(attributes[attribute.FriendlyName], value.Value)
+				}
+			}
+		}
+	}
+
+	domains := []string{}
+	if upstream.Provisioning != nil {
+		domains = upstream.Provisioning.Domains
+	}
+
+	email := user["email"].(string)
+	fullname, _ := user["fullname"].(string)
+	return &provisioning.ExternalUser{
+		Source:        "upstream:" + upstream.Name,
+		Subject:       assertion.Subject.NameID.Value,
+		Email:         email,
+		EmailVerified: provisioning.InDomains(email, domains),
+		Name:          fullname,
+		Attributes:    attributes,
+	}, nil
+}
+
+// roleAllowed checks if the upstream may assert the role
+func (u *Upstream) roleAllowed(role string) bool {
+	for _, allowed := range u.AllowedRoles {
+		if role == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attribute returns the values of the assertion attribute the user field is mapped to. The attributes are
+// matched by the name or the friendly name.
+func (u *Upstream) attribute(assertion *saml.Assertion, field string) []string {
+	names := defaultUpstreamAttributes[field]
+	if name, ok := u.Attributes[field]; ok {
+		names = []string{name}
+	}
+
+	for _, name := range names {
+		for _, statement := range assertion.AttributeStatements {
+			for _, attribute := range statement.Attributes {
+				if attribute.Name != name && attribute.FriendlyName != name {
+					continue
+				}
+
+				values := []string{}
+				for _, value := range attribute.Values {
+					values = append(values, value.Value)
+				}
+				return values
+			}
+		}
+	}
+
+	return nil
+}
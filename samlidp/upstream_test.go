@@ -0,0 +1,194 @@
+package samlidp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/crewjam/saml"
+)
+
+func upstreamAssertion(nameID *saml.NameID, attributes ...saml.Attribute) *saml.Assertion {
+	return &saml.Assertion{
+		Subject:             &saml.Subject{NameID: nameID},
+		AttributeStatements: []saml.AttributeStatement{{Attributes: attributes}},
+	}
+}
+
+// attribute returns the assertion attribute with the string values
+func attribute(name string, friendlyName string, values ...string) saml.Attribute {
+	attr := saml.Attribute{Name: name, FriendlyName: friendlyName}
+	for _, value := range values {
+		attr.Values = append(attr.Values, saml.AttributeValue{Type: "xs:string", Value: value})
+	}
+
+	return attr
+}
+
+func TestUpstreamForLogin(t *testing.T) {
+	corp := &Upstream{Name: "corp", Domains: []string{"corp.example.com"}}
+	partner := &Upstream{Name: "partner", Domains: []string{"partner.example.com"}}
+	upstreams := []*Upstream{corp, partner}
+
+	cases := []struct {
+		login    string
+		expected *Upstream
+	}{
+		{"jane@corp.example.com", corp},
+		{"jane@PARTNER.example.com", partner},
+		{"jane@example.com", nil},
+		{"jane", nil},
+		{"", nil},
+	}
+
+	for _, c := range cases {
+		if upstream := UpstreamForLogin(upstreams, c.login); upstream != c.expected {
+			t.Fatalf("Expected %v for %q, got %v", c.expected, c.login, upstream)
+		}
+	}
+
+	if FindUpstream(upstreams, "partner") != partner || FindUpstream(upstreams, "other") != nil {
+		t.Fatal("Expected the upstream to be found by name")
+	}
+}
+
+func TestUpstreamUser(t *testing.T) {
+	upstream := &Upstream{Name: "corp", Roles: []string{"user"}, AllowedRoles: []string{"admin"}}
+
+	user, err := UpstreamUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"},
+		attribute("urn:oid:0.9.2342.19200300.100.1.3", "mail", "Jane@Corp.example.com"),
+		attribute("displayName", "", "Jane Doe"),
+		attribute("roles", "", "admin"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"id":       "corp:jane",
+		"email":    "jane@corp.example.com",
+		"fullname": "Jane Doe",
+		"roles":    []interface{}{"user"},
+		"upstream": "corp",
+		"active":   true,
+	}
+	if !reflect.DeepEqual(user, expected) {
+		t.Fatalf("Expected %v, got %v", expected, user)
+	}
+}
+
+func TestUpstreamUserMappedAttributes(t *testing.T) {
+	upstream := &Upstream{
+		Name:         "corp",
+		Attributes:   map[string]string{"email": "upn", "roles": "groups"},
+		Roles:        []string{"user"},
+		AllowedRoles: []string{"staff", "support"},
+	}
+
+	user, err := UpstreamUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"},
+		attribute("email", "", "other@corp.example.com"),
+		attribute("upn", "", "jane@corp.example.com"),
+		attribute("groups", "", "staff", "admin", "system"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user["email"] != "jane@corp.example.com" || !reflect.DeepEqual(user["roles"], []interface{}{"user", "staff"}) {
+		t.Fatalf("Expected the configured attributes and the allowed roles to be mapped, got %v", user)
+	}
+}
+
+func TestUpstreamUserForeignDomain(t *testing.T) {
+	upstream := &Upstream{Name: "partner", Domains: []string{"partner.example.com"}}
+
+	_, err := UpstreamUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"},
+		attribute("mail", "", "admin@corp.example.com"),
+	))
+	if err != ErrUpstreamLoginFailed {
+		t.Fatalf("Expected ErrUpstreamLoginFailed for the email outside the domains of the upstream, got %v", err)
+	}
+
+	user, err := UpstreamUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"},
+		attribute("mail", "", "Jane@Partner.example.com"),
+	))
+	if err != nil || user["email"] != "jane@partner.example.com" {
+		t.Fatalf("Expected the user with the email in the domains of the upstream, got %v, %v", user, err)
+	}
+}
+
+func TestUpstreamUserNameIDEmail(t *testing.T) {
+	upstream := &Upstream{Name: "corp"}
+
+	user, err := UpstreamUser(upstream, upstreamAssertion(&saml.NameID{Format: string(saml.EmailAddressNameIDFormat), Value: "jane@corp.example.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user["email"] != "jane@corp.example.com" {
+		t.Fatalf("Expected the email from the NameID, got %v", user)
+	}
+
+	if _, err := UpstreamUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"})); err != ErrUpstreamLoginFailed {
+		t.Fatalf("Expected ErrUpstreamLoginFailed without the email, got %v", err)
+	}
+	if _, err := UpstreamUser(upstream, upstreamAssertion(nil)); err != ErrUpstreamLoginFailed {
+		t.Fatalf("Expected ErrUpstreamLoginFailed without the NameID, got %v", err)
+	}
+}
+
+func TestNewUpstreamsWithoutMetadata(t *testing.T) {
+	cfg := &config.Config{
+		Upstreams: map[string]*config.UpstreamConfig{"corp": {}},
+	}
+
+	if _, err := NewUpstreams(cfg, &saml.IdentityProvider{}); err == nil {
+		t.Fatal("Expected an error for the upstream without metadata")
+	}
+}
+
+func TestUpstreamExternalUser(t *testing.T) {
+	upstream := &Upstream{Name: "corp", Provisioning: &config.ProvisioningConfig{Domains: []string{"corp.example.com"}}}
+
+	external, err := UpstreamExternalUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"},
+		attribute("urn:oid:0.9.2342.19200300.100.1.3", "mail", "Jane@Corp.example.com"),
+		attribute("displayName", "", "Jane Doe"),
+		attribute("groups", "", "staff", "admin"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if external.Source != "upstream:corp" || external.Subject != "jane" || external.Email != "jane@corp.example.com" ||
+		!external.EmailVerified || external.Name != "Jane Doe" {
+		t.Fatalf("Unexpected external user %+v", external)
+	}
+	if !reflect.DeepEqual(external.Attributes["mail"], []string{"Jane@Corp.example.com"}) ||
+		!reflect.DeepEqual(external.Attributes["groups"], []string{"staff", "admin"}) {
+		t.Fatalf("Expected the attributes by name and friendly name, got %v", external.Attributes)
+	}
+
+	if _, err := UpstreamExternalUser(upstream, upstreamAssertion(&saml.NameID{Value: "jane"})); err != ErrUpstreamLoginFailed {
+		t.Fatalf("Expected ErrUpstreamLoginFailed without the email, got %v", err)
+	}
+}
+
+func TestUpstreamExternalUserUntrustedEmail(t *testing.T) {
+	cases := []struct {
+		name     string
+		upstream *Upstream
+	}{
+		{"other domain", &Upstream{Name: "corp", Provisioning: &config.ProvisioningConfig{Domains: []string{"corp.example.com"}}}},
+		{"no domains", &Upstream{Name: "corp", Provisioning: &config.ProvisioningConfig{}}},
+	}
+
+	for _, c := range cases {
+		external, err := UpstreamExternalUser(c.upstream, upstreamAssertion(&saml.NameID{Value: "jane"},
+			attribute("mail", "", "jane@partner.example.com"),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if external.EmailVerified {
+			t.Fatalf("%s: expected the email not to be trusted", c.name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// breachedPasswordPrefixLength is the length of the hash prefix the ranges of the list are named by
+const breachedPasswordPrefixLength = 5
+
+// BreachedPasswordList is the list of the SHA-1 hashes of the breached passwords, in the layout of the Pwned
+// Passwords k-anonymity range API: the directory has one file per range, named by the first 5 hex characters
+// of the hashes (with or without the ".txt" extension), holding one "<35-char suffix>:<count>" line per
+// password. Only the range of the password is read, so the list is never loaded into memory.
+type BreachedPasswordList struct {
+	dir string
+}
+
+// NewBreachedPasswordList creates the BreachedPasswordList of the directory.
+func NewBreachedPasswordList(dir string) *BreachedPasswordList {
+	return &BreachedPasswordList{
+		dir: dir,
+	}
+}
+
+// Contains checks if the password is in the list. A missing range file means that no password of the range
+// was breached; an error is returned if the directory cannot be read. The padding lines with the count 0 are
+// not breached passwords.
+func (l *BreachedPasswordList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPasswordPrefixLength], hash[breachedPasswordPrefixLength:]
+
+	if _, err := os.Stat(l.dir); err != nil {
+		return false, err
+	}
+
+	f, err := l.openRange(prefix)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if !strings.EqualFold(parts[0], suffix) {
+			continue
+		}
+
+		return len(parts) == 1 || strings.TrimSpace(parts[1]) != "0", nil
+	}
+
+	return false, scanner.Err()
+}
+
+// openRange opens the file of the range of the hash prefix
+func (l *BreachedPasswordList) openRange(prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(l.dir, prefix))
+	if os.IsNotExist(err) {
+		return os.Open(filepath.Join(l.dir, prefix+".txt"))
+	}
+
+	return f, err
+}
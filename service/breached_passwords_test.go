@@ -0,0 +1,83 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeBreachedPasswords writes the list of the breached passwords in the layout of the Pwned Passwords range
+// API to a temporary directory. It returns the path of the directory and the function that removes it.
+func writeBreachedPasswords(t *testing.T, passwords ...string) (string, func()) {
+	ranges := map[string][]string{}
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		ranges[hash[:5]] = append(ranges[hash[:5]], fmt.Sprintf("%s:%d", hash[5:], i+1))
+	}
+
+	dir, err := ioutil.TempDir("", "breached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for prefix, lines := range ranges {
+		sort.Strings(lines)
+		if err := ioutil.WriteFile(filepath.Join(dir, prefix), []byte(strings.Join(lines, "\r\n")+"\r\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBreachedPasswordList(t *testing.T) {
+	passwords := []string{}
+	for i := 0; i < 200; i++ {
+		passwords = append(passwords, fmt.Sprintf("password%d", i))
+	}
+	dir, remove := writeBreachedPasswords(t, passwords...)
+	defer remove()
+	list := NewBreachedPasswordList(dir)
+
+	for _, password := range passwords {
+		if breached, err := list.Contains(password); err != nil || !breached {
+			t.Fatalf("Expected %q to be found, got %v %v", password, breached, err)
+		}
+	}
+
+	for _, password := range []string{"", "password200", "Password1", "correct horse battery staple"} {
+		if breached, err := list.Contains(password); err != nil || breached {
+			t.Fatalf("Expected %q not to be found, got %v %v", password, breached, err)
+		}
+	}
+}
+
+func TestBreachedPasswordListRangeFiles(t *testing.T) {
+	dir, remove := writeBreachedPasswords(t)
+	defer remove()
+	list := NewBreachedPasswordList(dir)
+
+	// "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8, the ranges of the downloader have the .txt extension
+	ioutil.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\r\n"), 0644)
+	if breached, err := list.Contains("password"); err != nil || !breached {
+		t.Fatalf("Expected the password to be found in the range file, got %v %v", breached, err)
+	}
+
+	// the padding lines of the range API are not breached passwords
+	ioutil.WriteFile(filepath.Join(dir, "5BAA6"), []byte("1e4c9b93f3f0682250b6cf8331b7ee68fd8:0\r\n"), 0644)
+	if breached, err := list.Contains("password"); err != nil || breached {
+		t.Fatalf("Expected the padding line not to count, got %v %v", breached, err)
+	}
+
+	if _, err := NewBreachedPasswordList(filepath.Join(dir, "missing")).Contains("password"); err == nil {
+		t.Fatal("Expected the error reading the missing directory")
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/i18n"
+)
+
+const (
+	// IdentifierEmail is the type of the email address logins
+	IdentifierEmail = "email"
+	// IdentifierUsername is the type of the username logins, e.g. the sAMAccountName of the LDAP users
+	IdentifierUsername = "username"
+	// IdentifierPhone is the type of the phone number logins
+	IdentifierPhone = "phone"
+)
+
+// maxUsernameLength is the maximal number of characters of a username
+const maxUsernameLength = 256
+
+var (
+	// ErrInvalidIdentifier is returned when the login is not of any accepted identifier type
+	ErrInvalidIdentifier = i18n.NewError("invalid-identifier", "You have entered an invalid email, username or phone number")
+
+	// phonePattern matches the logins that look like a phone number, with the usual separators
+	phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()./-]*$`)
+	// e164Pattern matches the phone numbers in the E.164 format
+	e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// Identifier is the normalized login of the user together with its type.
+type Identifier struct {
+	// Type is IdentifierEmail, IdentifierUsername or IdentifierPhone
+	Type string
+	// Value is the normalized email, username or E.164 phone number
+	Value string
+	// Realm is the realm of the username, if any
+	Realm string
+}
+
+// String returns the identifier as shown in the logs, "<username>@<realm>" for the usernames with a realm.
+func (i *Identifier) String() string {
+	if i.Realm != "" {
+		return i.Value + "@" + i.Realm
+	}
+	return i.Value
+}
+
+// Payload returns the fields the user service finds the user by.
+func (i *Identifier) Payload() map[string]interface{} {
+	payload := map[string]interface{}{
+		i.Type: i.Value,
+	}
+	if i.Realm != "" {
+		payload["realm"] = i.Realm
+	}
+
+	return payload
+}
+
+// IdentifierTypes returns the configured types of the login identifiers, only IdentifierEmail by default.
+func IdentifierTypes(cfg *config.LoginConfig) []string {
+	if len(cfg.Identifiers) == 0 {
+		return []string{IdentifierEmail}
+	}
+	return cfg.Identifiers
+}
+
+// ParseIdentifier determines the type of the login and normalizes it:
+//
+//	"user@corp" or "CORP\user" - the username "user" in the realm the domain hint "corp" maps to
+//	email                      - lower-cased
+//	phone number               - converted to E.164, with the default country code if it has none
+//	username                   - lower-cased, unless the usernames are case-sensitive, in the default realm
+//
+// The types that are not accepted are skipped, a login that is not a valid phone number is taken as a username. ErrInvalidEmail is returned for an invalid login if only
+// the emails are accepted, ErrInvalidIdentifier otherwise.
+func ParseIdentifier(login string, cfg *config.LoginConfig) (*Identifier, error) {
+	types := IdentifierTypes(cfg)
+	accepts := func(identifierType string) bool {
+		for _, t := range types {
+			if t == identifierType {
+				return true
+			}
+		}
+		return false
+	}
+
+	login = strings.TrimSpace(login)
+
+	if accepts(IdentifierUsername) {
+		if username, realm, ok := splitDomainHint(login, cfg.Realms); ok {
+			return newUsername(username, realm, cfg)
+		}
+	}
+
+	if strings.Contains(login, "@") {
+		if accepts(IdentifierEmail) {
+			if address, err := mail.ParseAddress(login); err == nil && address.Address == login {
+				return &Identifier{Type: IdentifierEmail, Value: strings.ToLower(login)}, nil
+			}
+		}
+	} else {
+		if accepts(IdentifierPhone) && phonePattern.MatchString(login) {
+			if phone, ok := normalizePhone(login, cfg.PhoneCountryCode); ok {
+				return &Identifier{Type: IdentifierPhone, Value: phone}, nil
+			}
+		}
+		if accepts(IdentifierUsername) {
+			return newUsername(login, cfg.DefaultRealm, cfg)
+		}
+	}
+
+	if len(types) == 1 && types[0] == IdentifierEmail {
+		return nil, ErrInvalidEmail
+	}
+	return nil, ErrInvalidIdentifier
+}
+
+// splitDomainHint splits "user@hint" and "HINT\user" into the username and the realm of the hint. It
+// reports false if the login has no configured domain hint.
+func splitDomainHint(login string, realms map[string]string) (string, string, bool) {
+	var username, hint string
+	if i := strings.LastIndex(login, "@"); i >= 0 {
+		username, hint = login[:i], login[i+1:]
+	} else if i := strings.Index(login, `\`); i >= 0 {
+		hint, username = login[:i], login[i+1:]
+	} else {
+		return "", "", false
+	}
+
+	for h, realm := range realms {
+		if strings.EqualFold(h, hint) {
+			return username, realm, true
+		}
+	}
+
+	return "", "", false
+}
+
+// newUsername creates the username identifier, lower-cased unless the usernames are case-sensitive.
+// ErrInvalidIdentifier is returned if the username is empty, too long or contains spaces or control
+// characters.
+func newUsername(username string, realm string, cfg *config.LoginConfig) (*Identifier, error) {
+	if username == "" || utf8.RuneCountInString(username) > maxUsernameLength {
+		return nil, ErrInvalidIdentifier
+	}
+	for _, r := range username {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return nil, ErrInvalidIdentifier
+		}
+	}
+
+	if !cfg.CaseSensitiveUsernames {
+		username = strings.ToLower(username)
+	}
+
+	return &Identifier{Type: IdentifierUsername, Value: username, Realm: realm}, nil
+}
+
+// normalizePhone converts the phone number to E.164. The numbers without the country code get the default
+// country code, with the leading trunk prefix "0" removed. It reports false if the result is not a valid
+// E.164 number.
+func normalizePhone(phone string, countryCode string) (string, bool) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		phone = "+" + digits
+	case strings.HasPrefix(digits, "00"):
+		phone = "+" + digits[2:]
+	case countryCode != "":
+		phone = "+" + strings.TrimPrefix(countryCode, "+") + strings.TrimPrefix(digits, "0")
+	default:
+		return "", false
+	}
+
+	return phone, e164Pattern.MatchString(phone)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+)
+
+func TestParseIdentifier(t *testing.T) {
+	cfg := &config.LoginConfig{
+		Identifiers:      []string{"email", "username", "phone"},
+		Realms:           map[string]string{"corp": "CORP.EXAMPLE.COM"},
+		PhoneCountryCode: "49",
+	}
+
+	cases := []struct {
+		login    string
+		expected *Identifier
+	}{
+		{" Jon@Test.com ", &Identifier{Type: IdentifierEmail, Value: "jon@test.com"}},
+		{"JSmith", &Identifier{Type: IdentifierUsername, Value: "jsmith"}},
+		{"jsmith@CORP", &Identifier{Type: IdentifierUsername, Value: "jsmith", Realm: "CORP.EXAMPLE.COM"}},
+		{`corp\JSmith`, &Identifier{Type: IdentifierUsername, Value: "jsmith", Realm: "CORP.EXAMPLE.COM"}},
+		{"+1 (555) 123-4567", &Identifier{Type: IdentifierPhone, Value: "+15551234567"}},
+		{"0049 30 1234567", &Identifier{Type: IdentifierPhone, Value: "+49301234567"}},
+		{"030 1234567", &Identifier{Type: IdentifierPhone, Value: "+49301234567"}},
+		{"12", &Identifier{Type: IdentifierUsername, Value: "12"}},
+		{"jon smith", nil},
+		{"jon@", nil},
+		{"Jon <jon@test.com>", nil},
+	}
+
+	for _, c := range cases {
+		identifier, err := ParseIdentifier(c.login, cfg)
+		if c.expected == nil {
+			if err != ErrInvalidIdentifier {
+				t.Fatalf("Expected ErrInvalidIdentifier for %q, got %v, %v", c.login, identifier, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", c.login, err)
+		}
+		if !reflect.DeepEqual(identifier, c.expected) {
+			t.Fatalf("Expected %+v for %q, got %+v", c.expected, c.login, identifier)
+		}
+	}
+}
+
+func TestParseIdentifierEmailOnly(t *testing.T) {
+	identifier, err := ParseIdentifier("test@example.org", &config.LoginConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identifier.Type != IdentifierEmail {
+		t.Fatalf("Expected the email, got %+v", identifier)
+	}
+
+	for _, login := range []string{"a#$%", "jon.smith", "+15551234567"} {
+		if _, err := ParseIdentifier(login, &config.LoginConfig{}); err != ErrInvalidEmail {
+			t.Fatalf("Expected ErrInvalidEmail for %q, got %v", login, err)
+		}
+	}
+}
+
+func TestParseIdentifierUsernames(t *testing.T) {
+	cfg := &config.LoginConfig{
+		Identifiers:            []string{"username"},
+		CaseSensitiveUsernames: true,
+		DefaultRealm:           "EXAMPLE.COM",
+	}
+
+	identifier, err := ParseIdentifier("JSmith", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identifier.Value != "JSmith" || identifier.Realm != "EXAMPLE.COM" || identifier.String() != "JSmith@EXAMPLE.COM" {
+		t.Fatalf("Expected the case-sensitive username in the default realm, got %+v", identifier)
+	}
+
+	if _, err := ParseIdentifier("jon@test.com", cfg); err != ErrInvalidIdentifier {
+		t.Fatalf("Expected ErrInvalidIdentifier for the email, got %v", err)
+	}
+}
+
+func TestParseIdentifierPhoneWithoutCountryCode(t *testing.T) {
+	cfg := &config.LoginConfig{
+		Identifiers: []string{"phone"},
+	}
+
+	if _, err := ParseIdentifier("030 1234567", cfg); err != ErrInvalidIdentifier {
+		t.Fatalf("Expected ErrInvalidIdentifier for the number without the country code, got %v", err)
+	}
+	if identifier, err := ParseIdentifier("+49 30 1234567", cfg); err != nil || identifier.Value != "+49301234567" {
+		t.Fatalf("Expected the E.164 number, got %v, %v", identifier, err)
+	}
+}
+
+func TestIdentifierPayload(t *testing.T) {
+	payload := (&Identifier{Type: IdentifierUsername, Value: "jsmith", Realm: "CORP.EXAMPLE.COM"}).Payload()
+	expected := map[string]interface{}{"username": "jsmith", "realm": "CORP.EXAMPLE.COM"}
+	if !reflect.DeepEqual(payload, expected) {
+		t.Fatalf("Expected %v, got %v", expected, payload)
+	}
+
+	payload = (&Identifier{Type: IdentifierPhone, Value: "+15551234567"}).Payload()
+	expected = map[string]interface{}{"phone": "+15551234567"}
+	if !reflect.DeepEqual(payload, expected) {
+		t.Fatalf("Expected %v, got %v", expected, payload)
+	}
+}
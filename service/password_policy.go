@@ -0,0 +1,107 @@
+package service
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/i18n"
+)
+
+const (
+	// minPasswordLength is the default minimal length of the new passwords
+	minPasswordLength = 6
+	// maxPasswordLength is the default maximal length of the new passwords
+	maxPasswordLength = 128
+)
+
+var (
+	// ErrPasswordTooShort is returned when the new password is shorter than allowed
+	ErrPasswordTooShort = i18n.NewError("password-too-short", "The password must be at least %d characters long.", minPasswordLength)
+	// ErrPasswordTooLong is returned when the new password is longer than allowed
+	ErrPasswordTooLong = i18n.NewError("password-too-long", "The password must be at most %d characters long.", maxPasswordLength)
+	// ErrPasswordTooSimple is returned when the new password has fewer character classes than required
+	ErrPasswordTooSimple = i18n.NewError("password-too-simple", "The password must contain at least %d of the following: lowercase letters, uppercase letters, digits and symbols.", 1)
+	// ErrPasswordBreached is returned when the new password is in the list of the breached passwords
+	ErrPasswordBreached = i18n.NewError("password-breached", "The password has appeared in a data breach. Choose a different password.")
+)
+
+// PasswordPolicy holds the rules the new passwords must follow.
+type PasswordPolicy struct {
+	// MinLength is the minimal number of characters
+	MinLength int
+	// MaxLength is the maximal number of characters
+	MaxLength int
+	// CharacterClasses is the number of character classes the password must contain
+	CharacterClasses int
+	// Breached is the list of the breached passwords, nil if the passwords are not checked
+	Breached *BreachedPasswordList
+}
+
+// NewPasswordPolicy creates the PasswordPolicy with the configured rules.
+func NewPasswordPolicy(cfg *config.PasswordPolicyConfig) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:        cfg.MinLength,
+		MaxLength:        cfg.MaxLength,
+		CharacterClasses: cfg.CharacterClasses,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = minPasswordLength
+	}
+	if policy.MaxLength <= 0 {
+		policy.MaxLength = maxPasswordLength
+	}
+	if cfg.BreachedPasswordsDir != "" {
+		policy.Breached = NewBreachedPasswordList(cfg.BreachedPasswordsDir)
+	}
+
+	return policy
+}
+
+// Validate checks the password against the rules. ErrPasswordTooShort, ErrPasswordTooLong, ErrPasswordTooSimple
+// or ErrPasswordBreached (with the limits of the policy) is returned if the password breaks a rule. Other errors
+// are returned if the list of the breached passwords cannot be read.
+func (p *PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return i18n.NewError(ErrPasswordTooShort.Code, ErrPasswordTooShort.Message, p.MinLength)
+	}
+	if length > p.MaxLength {
+		return i18n.NewError(ErrPasswordTooLong.Code, ErrPasswordTooLong.Message, p.MaxLength)
+	}
+	if characterClasses(password) < p.CharacterClasses {
+		return i18n.NewError(ErrPasswordTooSimple.Code, ErrPasswordTooSimple.Message, p.CharacterClasses)
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return ErrPasswordBreached
+		}
+	}
+
+	return nil
+}
+
+// characterClasses returns the number of character classes in the password: lowercase letters, uppercase
+// letters, digits and symbols (everything else)
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/i18n"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{
+		MinLength:        8,
+		MaxLength:        16,
+		CharacterClasses: 3,
+	})
+
+	cases := []struct {
+		password string
+		expected error
+	}{
+		{"Secret-pass", nil},
+		{"Sëcret-pass", nil},
+		{"Secret1", ErrPasswordTooShort},
+		{"Secret-pass-too-long", ErrPasswordTooLong},
+		{"secretpass", ErrPasswordTooSimple},
+		{"secret-pass", ErrPasswordTooSimple},
+		{"secret-pass1", nil},
+	}
+
+	for _, c := range cases {
+		if err := policy.Validate(c.password); i18n.Code(err) != i18n.Code(c.expected) {
+			t.Fatalf("Expected %v for %q, got %v", c.expected, c.password, err)
+		}
+	}
+
+	err := policy.Validate("short")
+	if err == nil || err.Error() != "The password must be at least 8 characters long." {
+		t.Fatalf("Expected the configured minimal length in the error, got %v", err)
+	}
+}
+
+func TestPasswordPolicyDefaults(t *testing.T) {
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{})
+
+	if policy.MinLength != minPasswordLength || policy.MaxLength != maxPasswordLength || policy.CharacterClasses != 0 || policy.Breached != nil {
+		t.Fatalf("Unexpected default policy %+v", policy)
+	}
+	if err := policy.Validate("123456"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordPolicyBreached(t *testing.T) {
+	path, remove := writeBreachedPasswords(t, "password", "123456")
+	defer remove()
+
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{BreachedPasswordsDir: path})
+	if err := policy.Validate("123456"); err != ErrPasswordBreached {
+		t.Fatalf("Expected ErrPasswordBreached, got %v", err)
+	}
+	if err := policy.Validate("correct horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+
+	policy.Breached = NewBreachedPasswordList(path + ".missing")
+	if err := policy.Validate("correct horse battery staple"); err == nil || i18n.Code(err) != "" {
+		t.Fatalf("Expected the error reading the list, got %v", err)
+	}
+}
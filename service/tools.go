@@ -4,17 +4,18 @@ import (
 	"bytes"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/mail"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/i18n"
 	// jormungandrSaml "github.com/Microkubes/microservice-security/saml"
 	"github.com/afex/hystrix-go/hystrix"
 	"github.com/crewjam/saml"
@@ -22,21 +23,157 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-// FindUser retrives the user by email and password
-func FindUser(email string, password string, idp *saml.IdentityProvider, cfg *config.Config) (map[string]interface{}, error) {
-	userPayload := map[string]interface{}{
-		"email":    email,
-		"password": password,
+var (
+	// ErrCredentialsRequired is returned when the email or the password is missing
+	ErrCredentialsRequired = i18n.NewError("credentials-required", "Credentials required!")
+	// ErrInvalidEmail is returned when the email is not a valid address
+	ErrInvalidEmail = i18n.NewError("invalid-email", "You have entered invalid email")
+
+	// The user service verifies the password before it returns the user, so the following errors are
+	// returned only for the correct credentials and do not reveal whether an account exists.
+
+	// ErrAccountNotActivated is returned when the user has not activated the account yet
+	ErrAccountNotActivated = i18n.NewError("account-not-activated", "Your account is not activated.")
+	// ErrAccountLocked is returned when the account has been locked
+	ErrAccountLocked = i18n.NewError("account-locked", "Your account is locked.")
+	// ErrPasswordExpired is returned when the user must change the password before logging in
+	ErrPasswordExpired = i18n.NewError("password-expired", "Your password has expired.")
+
+	// ErrPasswordMismatch is returned when the new password and its confirmation differ
+	ErrPasswordMismatch = i18n.NewError("password-mismatch", "The passwords do not match.")
+)
+
+// UserServiceError is returned when the user service responds with an error status. The message is the body
+// of the response.
+type UserServiceError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Body is the body of the response
+	Body string
+}
+
+func (e *UserServiceError) Error() string {
+	return e.Body
+}
+
+// IsUserNotFound checks if the error is the response of the user service for a user that does not exist.
+func IsUserNotFound(err error) bool {
+	serviceErr, ok := err.(*UserServiceError)
+	return ok && serviceErr.StatusCode == http.StatusNotFound
+}
+
+// FindUser retrives the user by the login identifier and password. ErrAccountNotActivated, ErrAccountLocked or
+// ErrPasswordExpired is returned if the user is found but cannot log in.
+func FindUser(identifier *Identifier, password string, idp *saml.IdentityProvider, cfg *config.Config) (map[string]interface{}, error) {
+	userPayload := identifier.Payload()
+	userPayload["password"] = password
+
+	body, err := callUserService("user-microservice.find_by_email", http.MethodPost, "/find", userPayload, idp, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]interface{}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	if err := CheckAccount(resp); err != nil {
+		return nil, err
 	}
-	payload, err := json.Marshal(userPayload)
+
+	return resp, nil
+}
+
+// CheckAccount checks if the user returned by the user service can log in. ErrAccountNotActivated,
+// ErrAccountLocked or ErrPasswordExpired is returned if not.
+func CheckAccount(user map[string]interface{}) error {
+	if active, ok := user["active"].(bool); ok && !active {
+		return ErrAccountNotActivated
+	}
+	if locked, ok := user["locked"].(bool); ok && locked {
+		return ErrAccountLocked
+	}
+	if expired, ok := user["passwordExpired"].(bool); ok && expired {
+		return ErrPasswordExpired
+	}
+
+	return nil
+}
+
+// FindUserByEmail retrives the user by email only, without checking the password. Used for the password reset and
+// the sign-in links.
+func FindUserByEmail(email string, idp *saml.IdentityProvider, cfg *config.Config) (map[string]interface{}, error) {
+	body, err := callUserService("user-microservice.find_by_email_only", http.MethodPost, "/find/email", map[string]interface{}{
+		"email": email,
+	}, idp, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]interface{}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetUser returns the user with the ID from the user service.
+func GetUser(userID string, idp *saml.IdentityProvider, cfg *config.Config) (map[string]interface{}, error) {
+	body, err := callUserService("user-microservice.get_user", http.MethodGet, "/"+url.PathEscape(userID), nil, idp, cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	var resp map[string]interface{}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// CreateUser creates the user in the user service and returns the created user.
+func CreateUser(user map[string]interface{}, idp *saml.IdentityProvider, cfg *config.Config) (map[string]interface{}, error) {
+	body, err := callUserService("user-microservice.create_user", http.MethodPost, "", user, idp, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]interface{}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// UpdatePassword sets the new password of the user in the user service.
+func UpdatePassword(userID string, password string, idp *saml.IdentityProvider, cfg *config.Config) error {
+	_, err := callUserService("user-microservice.update_password", http.MethodPut, fmt.Sprintf("/%s/password", url.PathEscape(userID)), map[string]interface{}{
+		"password": password,
+	}, idp, cfg)
+
+	return err
+}
+
+// callUserService sends the payload to the user service and returns the body of the response. The error
+// has the body of the response as message if the status code is not 200.
+func callUserService(command string, method string, path string, payload interface{}, idp *saml.IdentityProvider, cfg *config.Config) ([]byte, error) {
+	var data []byte
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+
 	client := &http.Client{}
 	output := make(chan *http.Response, 1)
-	errorsChan := hystrix.Go("user-microservice.find_by_email", func() error {
-		resp, err := postData(client, payload, fmt.Sprintf("%s/find", cfg.Services["microservice-user"]), idp, cfg)
+	errorsChan := hystrix.Go(command, func() error {
+		resp, err := sendData(client, method, data, cfg.Services["microservice-user"]+path, idp, cfg)
 		if err != nil {
 			return err
 		}
@@ -44,39 +181,31 @@ func FindUser(email string, password string, idp *saml.IdentityProvider, cfg *co
 		return nil
 	}, nil)
 
-	var createUserResp *http.Response
+	var userResp *http.Response
 	select {
 	case out := <-output:
-		createUserResp = out
+		userResp = out
 	case respErr := <-errorsChan:
 		return nil, respErr
 	}
+	defer userResp.Body.Close()
 
 	// Inspect status code from response
-	body, _ := ioutil.ReadAll(createUserResp.Body)
-	if createUserResp.StatusCode != 200 {
-		err := errors.New(string(body))
-		return nil, err
-	}
-
-	var resp map[string]interface{}
-	if err = json.Unmarshal(body, &resp); err != nil {
-		return nil, err
+	body, _ := ioutil.ReadAll(userResp.Body)
+	if userResp.StatusCode != http.StatusOK && userResp.StatusCode != http.StatusCreated {
+		return nil, &UserServiceError{StatusCode: userResp.StatusCode, Body: string(body)}
 	}
 
-	if active, ok := resp["active"]; ok {
-		if active != nil {
-			if _, ok := active.(bool); ok && !active.(bool) {
-				return nil, fmt.Errorf("account-not-activated")
-			}
-		}
-	}
-
-	return resp, nil
+	return body, nil
 }
 
 // postData makes post request
 func postData(client *http.Client, payload []byte, url string, idp *saml.IdentityProvider, cfg *config.Config) (*http.Response, error) {
+	return sendData(client, http.MethodPost, payload, url, idp, cfg)
+}
+
+// sendData makes request with the payload, authenticated with a system token
+func sendData(client *http.Client, method string, payload []byte, url string, idp *saml.IdentityProvider, cfg *config.Config) (*http.Response, error) {
 	key, err := ioutil.ReadFile(cfg.SystemKey)
 	if err != nil {
 		return nil, err
@@ -110,7 +239,7 @@ func postData(client *http.Client, payload []byte, url string, idp *saml.Identit
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(payload))
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
 	if err != nil {
 		return nil, err
 	}
@@ -125,45 +254,57 @@ func postData(client *http.Client, payload []byte, url string, idp *saml.Identit
 	return resp, err
 }
 
-// checks user credentials
-func CheckUserCredentials(r *http.Request, w http.ResponseWriter, req *saml.IdpAuthnRequest) (string, string, error) {
-	email := strings.TrimSpace(r.FormValue("email"))
+// CheckUserCredentials returns the login identifier and the password posted with the login form. The login
+// must be of one of the configured identifier types, see ParseIdentifier. The password is checked only by the
+// user service, so the users can log in with passwords that do not follow the current password policy.
+func CheckUserCredentials(r *http.Request, w http.ResponseWriter, req *saml.IdpAuthnRequest, cfg *config.Config) (*Identifier, string, error) {
+	login := strings.TrimSpace(r.FormValue("email"))
 	password := strings.TrimSpace(r.FormValue("password"))
 
-	if email == "" || password == "" {
-		return "", "", fmt.Errorf("Credentials required!")
+	if login == "" || password == "" {
+		return nil, "", ErrCredentialsRequired
 	}
 
-	if err := validateCredentials(email, password); err != nil {
-		return "", "", err
+	identifier, err := ParseIdentifier(login, &cfg.Login)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return email, password, nil
+	return identifier, password, nil
 }
 
-// ValidateCredentials validates the user credential( email/password )
-func validateCredentials(email, pass string) error {
-	if _, err := mail.ParseAddress(email); err != nil {
-		return fmt.Errorf("You have entered invalid email")
+// ValidateNewPassword checks the new password chosen by the user and its confirmation against the configured
+// password policy
+func ValidateNewPassword(password, confirmation string, cfg *config.Config) error {
+	if password == "" {
+		return ErrCredentialsRequired
 	}
-	if len(pass) < 6 {
-		return fmt.Errorf("You have entered invalid password")
+	if password != confirmation {
+		return ErrPasswordMismatch
 	}
-	return nil
+
+	return NewPasswordPolicy(&cfg.PasswordPolicy).Validate(password)
 }
 
-// generateSignedSAMLToken generates signed SAML token
+// generateSignedSAMLToken generates signed SAML token. The random "jti" claim makes the token of every login
+// unique, so each login of the user has its own session.
 func GenerateSignedSAMLToken(idp *saml.IdentityProvider, user map[string]interface{}) (string, error) {
 	roles := []string{}
 	for _, v := range user["roles"].([]interface{}) {
 		roles = append(roles, v.(string))
 	}
 
+	jti := make([]byte, 16)
+	if _, err := saml.RandReader.Read(jti); err != nil {
+		return "", err
+	}
+
 	encodedPrivatekKey := x509.MarshalPKCS1PrivateKey(idp.Key.(*rsa.PrivateKey))
 	claims := jwt.MapClaims{
 		"userId": user["id"].(string),
 		"email":  user["email"].(string),
 		"roles":  roles,
+		"jti":    hex.EncodeToString(jti),
 	}
 	tokenHS := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenStr, err := tokenHS.SignedString(encodedPrivatekKey)
@@ -20,6 +20,7 @@ import (
 	"gopkg.in/h2non/gock.v1"
 
 	"github.com/Microkubes/identity-provider/config"
+	"github.com/Microkubes/identity-provider/i18n"
 	"github.com/crewjam/saml"
 	"github.com/crewjam/saml/logger"
 	"github.com/crewjam/saml/samlidp"
@@ -181,7 +182,7 @@ func TestFindUser(t *testing.T) {
 			"active":     true,
 		})
 
-	user, err := FindUser("jon", "qwerty123", &s.IDP, cfg)
+	user, err := FindUser(&Identifier{Type: IdentifierEmail, Value: "jon"}, "qwerty123", &s.IDP, cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,6 +191,70 @@ func TestFindUser(t *testing.T) {
 	}
 }
 
+func TestFindUserUsername(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(s.IDP.Key.(*rsa.PrivateKey)),
+	})
+	ioutil.WriteFile("system", privateBytes, 0644)
+	defer os.Remove("system")
+	defer gock.Off()
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find").
+		JSON(map[string]interface{}{"username": "jon.smith", "realm": "CORP.EXAMPLE.COM", "password": "qwerty123"}).
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com", "active": true})
+
+	if _, err := FindUser(&Identifier{Type: IdentifierUsername, Value: "jon.smith", Realm: "CORP.EXAMPLE.COM"}, "qwerty123", &s.IDP, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("Expected the user to be looked up by username")
+	}
+}
+
+func TestFindUserAccountErrors(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey)),
+	})
+	ioutil.WriteFile("system", privateBytes, 0644)
+
+	defer os.Remove("system")
+
+	cases := []struct {
+		user     map[string]interface{}
+		expected error
+	}{
+		{map[string]interface{}{"active": false}, ErrAccountNotActivated},
+		{map[string]interface{}{"active": true, "locked": true}, ErrAccountLocked},
+		{map[string]interface{}{"active": true, "passwordExpired": true}, ErrPasswordExpired},
+	}
+
+	for _, c := range cases {
+		gock.New(cfg.Services["microservice-user"]).
+			Post("/find").
+			Reply(200).
+			JSON(c.user)
+
+		_, err := FindUser(&Identifier{Type: IdentifierEmail, Value: "jon@test.com"}, "qwerty123", &s.IDP, cfg)
+		if err != c.expected {
+			t.Fatalf("Expected %v, got %v", c.expected, err)
+		}
+	}
+}
+
 func TestFindUserBadConfig(t *testing.T) {
 	s, err := createSAMLIdP()
 	if err != nil {
@@ -220,7 +285,7 @@ func TestFindUserBadConfig(t *testing.T) {
 			"active":     false,
 		})
 
-	_, err = FindUser("jon", "qwerty123", &s.IDP, cfg)
+	_, err = FindUser(&Identifier{Type: IdentifierEmail, Value: "jon"}, "qwerty123", &s.IDP, cfg)
 	if err == nil {
 		t.Fatal("Nil error, expected: Post http://127.0.0.1:8081/not-exists/find: gock: cannot match any request")
 	}
@@ -252,22 +317,153 @@ func TestFindUserBadStatusCode(t *testing.T) {
 			"details": "Internal Server Error",
 		})
 
-	_, err = FindUser("jon", "qwerty123", &s.IDP, cfg)
+	_, err = FindUser(&Identifier{Type: IdentifierEmail, Value: "jon"}, "qwerty123", &s.IDP, cfg)
 
 	if err == nil {
 		t.Fatal("Nil error, expected: Internal Server Error")
 	}
 }
 
-func TestValidateCredentials(t *testing.T) {
-	err := validateCredentials("test@example.org", "test123")
+func TestValidateNewPassword(t *testing.T) {
+	cases := []struct {
+		password     string
+		confirmation string
+		expected     error
+	}{
+		{"new-secret", "new-secret", nil},
+		{"", "", ErrCredentialsRequired},
+		{"new-secret", "new-secrets", ErrPasswordMismatch},
+		{"short", "short", ErrPasswordTooShort},
+	}
+
+	for _, c := range cases {
+		if err := ValidateNewPassword(c.password, c.confirmation, &config.Config{}); i18n.Code(err) != i18n.Code(c.expected) {
+			t.Fatalf("Expected %v for %q/%q, got %v", c.expected, c.password, c.confirmation, err)
+		}
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	s, err := createSAMLIdP()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = validateCredentials("a#$%", "zz")
-	if err == nil {
-		t.Fatal("Nil error for invalid email/password")
+	privateBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey)),
+	})
+	ioutil.WriteFile("system", privateBytes, 0644)
+
+	defer os.Remove("system")
+
+	gock.New(cfg.Services["microservice-user"]).
+		Put("/59804b3c0000000000000000/password").
+		JSON(map[string]interface{}{"password": "new-secret"}).
+		Reply(200)
+
+	if err := UpdatePassword("59804b3c0000000000000000", "new-secret", &s.IDP, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	gock.New(cfg.Services["microservice-user"]).
+		Put("/59804b3c0000000000000000/password").
+		Reply(404).
+		JSON(map[string]interface{}{"details": "user not found"})
+
+	if err := UpdatePassword("59804b3c0000000000000000", "new-secret", &s.IDP, cfg); err == nil {
+		t.Fatal("Nil error, expected: user not found")
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey)),
+	})
+	ioutil.WriteFile("system", privateBytes, 0644)
+
+	defer os.Remove("system")
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("").
+		JSON(map[string]interface{}{"email": "jon@test.com", "active": true}).
+		Reply(201).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com", "active": true})
+
+	user, err := CreateUser(map[string]interface{}{"email": "jon@test.com", "active": true}, &s.IDP, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user["id"] != "59804b3c0000000000000000" {
+		t.Fatalf("Expected the created user, got %v", user)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey)),
+	})
+	ioutil.WriteFile("system", privateBytes, 0644)
+
+	defer os.Remove("system")
+
+	gock.New(cfg.Services["microservice-user"]).
+		Get("/59804b3c0000000000000000").
+		Reply(200).
+		JSON(map[string]interface{}{"id": "59804b3c0000000000000000", "email": "jon@test.com"})
+
+	user, err := GetUser("59804b3c0000000000000000", &s.IDP, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user["email"] != "jon@test.com" {
+		t.Fatalf("Expected the user, got %v", user)
+	}
+}
+
+func TestIsUserNotFound(t *testing.T) {
+	s, err := createSAMLIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey)),
+	})
+	ioutil.WriteFile("system", privateBytes, 0644)
+
+	defer os.Remove("system")
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(404).
+		BodyString("user not found")
+
+	_, err = FindUserByEmail("jon@test.com", &s.IDP, cfg)
+	if !IsUserNotFound(err) || err.Error() != "user not found" {
+		t.Fatalf("Expected the user not to be found, got %v", err)
+	}
+
+	gock.New(cfg.Services["microservice-user"]).
+		Post("/find/email").
+		Reply(500).
+		BodyString("internal error")
+
+	if _, err = FindUserByEmail("jon@test.com", &s.IDP, cfg); err == nil || IsUserNotFound(err) {
+		t.Fatalf("Expected an error other than not found, got %v", err)
 	}
 }
 
@@ -285,12 +481,12 @@ func TestCheckUserCredentials(t *testing.T) {
 		RelayState:  "relayState",
 	}
 
-	email, password, err := CheckUserCredentials(r, w, req)
+	identifier, password, err := CheckUserCredentials(r, w, req, cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if email != "test@example.org" {
-		t.Fatalf("Expected user name was %s, got %s", "test", email)
+	if identifier.Type != IdentifierEmail || identifier.Value != "test@example.org" {
+		t.Fatalf("Expected the email %s, got %s %s", "test@example.org", identifier.Type, identifier.Value)
 	}
 	if password != "test123" {
 		t.Fatalf("Expected password was %s, got %s", "test123", password)
@@ -311,7 +507,7 @@ func TestCheckUserCredentialsMissedCredentials(t *testing.T) {
 		RelayState:  "relayState",
 	}
 
-	_, _, err = CheckUserCredentials(r, w, req)
+	_, _, err = CheckUserCredentials(r, w, req, cfg)
 	if err == nil {
 		t.Fatal("Nil err, expected :'Credentials required!'")
 	}
@@ -331,7 +527,7 @@ func TestCheckUserCredentialsBadCredentials(t *testing.T) {
 		RelayState:  "relayState",
 	}
 
-	_, _, err = CheckUserCredentials(r, w, req)
+	_, _, err = CheckUserCredentials(r, w, req, cfg)
 	if err == nil {
 		t.Fatal("Nil error, expected: 'You have entered invalid user'")
 	}
@@ -349,10 +545,18 @@ func TestGenerateSignedSAMLToken(t *testing.T) {
 		"roles": []interface{}{"user"},
 	}
 
-	_, err = GenerateSignedSAMLToken(&s.IDP, user)
+	first, err := GenerateSignedSAMLToken(&s.IDP, user)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	second, err := GenerateSignedSAMLToken(&s.IDP, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("Expected a unique token for every login")
+	}
 }
 
 func TestPostData(t *testing.T) {
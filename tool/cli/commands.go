@@ -33,6 +33,21 @@ type (
 		PrettyPrint bool
 	}
 
+	// AddThemeIdpCommand is the command line data structure for the addTheme action of idp
+	AddThemeIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// ConsentIdpCommand is the command line data structure for the consent action of idp
+	ConsentIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// ConsentsIdpCommand is the command line data structure for the consents action of idp
+	ConsentsIdpCommand struct {
+		PrettyPrint bool
+	}
+
 	// DeleteServiceProviderIdpCommand is the command line data structure for the deleteServiceProvider action of idp
 	DeleteServiceProviderIdpCommand struct {
 		Payload     string
@@ -47,6 +62,13 @@ type (
 		PrettyPrint bool
 	}
 
+	// DeleteThemeIdpCommand is the command line data structure for the deleteTheme action of idp
+	DeleteThemeIdpCommand struct {
+		Payload     string
+		ContentType string
+		PrettyPrint bool
+	}
+
 	// GetGoogleMetadataIdpCommand is the command line data structure for the getGoogleMetadata action of idp
 	GetGoogleMetadataIdpCommand struct {
 		PrettyPrint bool
@@ -67,11 +89,46 @@ type (
 		PrettyPrint bool
 	}
 
+	// GetThemesIdpCommand is the command line data structure for the getThemes action of idp
+	GetThemesIdpCommand struct {
+		PrettyPrint bool
+	}
+
 	// LoginUserIdpCommand is the command line data structure for the loginUser action of idp
 	LoginUserIdpCommand struct {
 		PrettyPrint bool
 	}
 
+	// MagicLinkLoginIdpCommand is the command line data structure for the magicLinkLogin action of idp
+	MagicLinkLoginIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// NewPasswordFormIdpCommand is the command line data structure for the newPasswordForm action of idp
+	NewPasswordFormIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// PasswordResetFormIdpCommand is the command line data structure for the passwordResetForm action of idp
+	PasswordResetFormIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// RequestPasswordResetIdpCommand is the command line data structure for the requestPasswordReset action of idp
+	RequestPasswordResetIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// ResetPasswordIdpCommand is the command line data structure for the resetPassword action of idp
+	ResetPasswordIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// RevokeConsentIdpCommand is the command line data structure for the revokeConsent action of idp
+	RevokeConsentIdpCommand struct {
+		PrettyPrint bool
+	}
+
 	// ServeLoginIdpCommand is the command line data structure for the serveLogin action of idp
 	ServeLoginIdpCommand struct {
 		PrettyPrint bool
@@ -87,6 +144,21 @@ type (
 		PrettyPrint bool
 	}
 
+	// SocialCallbackIdpCommand is the command line data structure for the socialCallback action of idp
+	SocialCallbackIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// UpstreamACSIdpCommand is the command line data structure for the upstreamACS action of idp
+	UpstreamACSIdpCommand struct {
+		PrettyPrint bool
+	}
+
+	// UpstreamMetadataIdpCommand is the command line data structure for the upstreamMetadata action of idp
+	UpstreamMetadataIdpCommand struct {
+		PrettyPrint bool
+	}
+
 	// DownloadCommand is the command line data structure for the download command.
 	DownloadCommand struct {
 		// OutFile is the path to the download output file.
@@ -111,11 +183,53 @@ func RegisterCommands(app *cobra.Command, c *client.Client) {
 	sub.PersistentFlags().BoolVar(&tmp1.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "add-theme",
+		Short: `Add or update the branding theme of a service provider`,
+	}
+	tmp2 := new(AddThemeIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/themes"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp2.Run(c, args) },
+	}
+	tmp2.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp2.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "consent",
+		Short: `Consent to the release of the attributes to the service provider`,
+	}
+	tmp3 := new(ConsentIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/consent"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp3.Run(c, args) },
+	}
+	tmp3.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp3.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "consents",
+		Short: `Show the service providers the user has consented to`,
+	}
+	tmp4 := new(ConsentsIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/consents"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp4.Run(c, args) },
+	}
+	tmp4.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp4.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "delete-service-provider",
 		Short: `Delete a service provider`,
 	}
-	tmp2 := new(DeleteServiceProviderIdpCommand)
+	tmp5 := new(DeleteServiceProviderIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/services"]`,
 		Short: ``,
@@ -126,17 +240,17 @@ Payload example:
 {
    "serviceId": "Itaque nam vel non quis porro tempora."
 }`,
-		RunE: func(cmd *cobra.Command, args []string) error { return tmp2.Run(c, args) },
+		RunE: func(cmd *cobra.Command, args []string) error { return tmp5.Run(c, args) },
 	}
-	tmp2.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp2.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp5.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp5.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "delete-session",
 		Short: `Delete a service provider`,
 	}
-	tmp3 := new(DeleteSessionIdpCommand)
+	tmp6 := new(DeleteSessionIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/sessions"]`,
 		Short: ``,
@@ -147,125 +261,286 @@ Payload example:
 {
    "sessionId": "Quod asperiores."
 }`,
-		RunE: func(cmd *cobra.Command, args []string) error { return tmp3.Run(c, args) },
+		RunE: func(cmd *cobra.Command, args []string) error { return tmp6.Run(c, args) },
 	}
-	tmp3.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp3.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp6.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp6.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "delete-theme",
+		Short: `Delete the branding theme of a service provider`,
+	}
+	tmp7 := new(DeleteThemeIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/themes"]`,
+		Short: ``,
+		Long: `
+
+Payload example:
+
+{
+   "serviceId": "Itaque nam vel non quis porro tempora."
+}`,
+		RunE: func(cmd *cobra.Command, args []string) error { return tmp7.Run(c, args) },
+	}
+	tmp7.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp7.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "get-google-metadata",
 		Short: `Get Google's metadata`,
 	}
-	tmp4 := new(GetGoogleMetadataIdpCommand)
+	tmp8 := new(GetGoogleMetadataIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/metadata/google"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp4.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp8.Run(c, args) },
 	}
-	tmp4.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp4.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp8.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp8.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "get-metadata",
 		Short: `Get Jormungandr metadata`,
 	}
-	tmp5 := new(GetMetadataIdpCommand)
+	tmp9 := new(GetMetadataIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/metadata"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp5.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp9.Run(c, args) },
 	}
-	tmp5.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp5.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp9.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp9.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "get-service-providers",
 		Short: `Get all service providres`,
 	}
-	tmp6 := new(GetServiceProvidersIdpCommand)
+	tmp10 := new(GetServiceProvidersIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/services"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp6.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp10.Run(c, args) },
 	}
-	tmp6.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp6.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp10.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp10.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "get-sessions",
 		Short: `Get all sessions`,
 	}
-	tmp7 := new(GetSessionsIdpCommand)
+	tmp11 := new(GetSessionsIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/sessions"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp7.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp11.Run(c, args) },
 	}
-	tmp7.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp7.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp11.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp11.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "get-themes",
+		Short: `Get all branding themes`,
+	}
+	tmp12 := new(GetThemesIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/themes"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp12.Run(c, args) },
+	}
+	tmp12.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp12.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "login-user",
 		Short: `Login user`,
 	}
-	tmp8 := new(LoginUserIdpCommand)
+	tmp13 := new(LoginUserIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/login"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp8.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp13.Run(c, args) },
 	}
-	tmp8.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp8.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp13.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp13.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "magic-link-login",
+		Short: `Log in with the sign-in link sent by email`,
+	}
+	tmp14 := new(MagicLinkLoginIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/magic-link"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp14.Run(c, args) },
+	}
+	tmp14.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp14.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "new-password-form",
+		Short: `Show the new password form`,
+	}
+	tmp15 := new(NewPasswordFormIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/password-reset/confirm"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp15.Run(c, args) },
+	}
+	tmp15.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp15.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "password-reset-form",
+		Short: `Show the password reset form`,
+	}
+	tmp16 := new(PasswordResetFormIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/password-reset"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp16.Run(c, args) },
+	}
+	tmp16.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp16.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "request-password-reset",
+		Short: `Send the password reset email`,
+	}
+	tmp17 := new(RequestPasswordResetIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/password-reset"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp17.Run(c, args) },
+	}
+	tmp17.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp17.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "reset-password",
+		Short: `Set the new password`,
+	}
+	tmp18 := new(ResetPasswordIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/password-reset/confirm"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp18.Run(c, args) },
+	}
+	tmp18.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp18.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "revoke-consent",
+		Short: `Revoke the consent to a service provider`,
+	}
+	tmp19 := new(RevokeConsentIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/consents"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp19.Run(c, args) },
+	}
+	tmp19.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp19.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "serve-login",
 		Short: `Creare user session`,
 	}
-	tmp9 := new(ServeLoginIdpCommand)
+	tmp20 := new(ServeLoginIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/sso"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp9.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp20.Run(c, args) },
 	}
-	tmp9.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp9.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp20.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp20.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "serve-login-user",
 		Short: `Login user`,
 	}
-	tmp10 := new(ServeLoginUserIdpCommand)
+	tmp21 := new(ServeLoginUserIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/login"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp10.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp21.Run(c, args) },
 	}
-	tmp10.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp10.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp21.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp21.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 	command = &cobra.Command{
 		Use:   "servesso",
 		Short: `Serve Single Sign On`,
 	}
-	tmp11 := new(ServeSSOIdpCommand)
+	tmp22 := new(ServeSSOIdpCommand)
 	sub = &cobra.Command{
 		Use:   `idp ["/saml/idp/sso"]`,
 		Short: ``,
-		RunE:  func(cmd *cobra.Command, args []string) error { return tmp11.Run(c, args) },
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp22.Run(c, args) },
 	}
-	tmp11.RegisterFlags(sub, c)
-	sub.PersistentFlags().BoolVar(&tmp11.PrettyPrint, "pp", false, "Pretty print response body")
+	tmp22.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp22.PrettyPrint, "pp", false, "Pretty print response body")
 	command.AddCommand(sub)
 	app.AddCommand(command)
 
+	command = &cobra.Command{
+		Use:   "social-callback",
+		Short: `Receive the authorization code of the social login provider`,
+	}
+	tmp23 := new(SocialCallbackIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/social/callback"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp23.Run(c, args) },
+	}
+	tmp23.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp23.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "upstream-acs",
+		Short: `Receive the SAML response of the upstream identity provider`,
+	}
+	tmp24 := new(UpstreamACSIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/upstream/acs"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp24.Run(c, args) },
+	}
+	tmp24.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp24.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
+	command = &cobra.Command{
+		Use:   "upstream-metadata",
+		Short: `Get the service provider metadata the upstream identity providers trust`,
+	}
+	tmp25 := new(UpstreamMetadataIdpCommand)
+	sub = &cobra.Command{
+		Use:   `idp ["/saml/idp/upstream/metadata"]`,
+		Short: ``,
+		RunE:  func(cmd *cobra.Command, args []string) error { return tmp25.Run(c, args) },
+	}
+	tmp25.RegisterFlags(sub, c)
+	sub.PersistentFlags().BoolVar(&tmp25.PrettyPrint, "pp", false, "Pretty print response body")
+	command.AddCommand(sub)
+	app.AddCommand(command)
 	dl := new(DownloadCommand)
 	dlc := &cobra.Command{
 		Use:   "download [PATH]",
@@ -518,6 +793,78 @@ func (cmd *AddServiceProviderIdpCommand) Run(c *client.Client, args []string) er
 func (cmd *AddServiceProviderIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
 }
 
+// Run makes the HTTP request corresponding to the AddThemeIdpCommand command.
+func (cmd *AddThemeIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/themes"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.AddThemeIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *AddThemeIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the ConsentIdpCommand command.
+func (cmd *ConsentIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/consent"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.ConsentIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *ConsentIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the ConsentsIdpCommand command.
+func (cmd *ConsentsIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/consents"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.ConsentsIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *ConsentsIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
 // Run makes the HTTP request corresponding to the DeleteServiceProviderIdpCommand command.
 func (cmd *DeleteServiceProviderIdpCommand) Run(c *client.Client, args []string) error {
 	var path string
@@ -584,6 +931,39 @@ func (cmd *DeleteSessionIdpCommand) RegisterFlags(cc *cobra.Command, c *client.C
 	cc.Flags().StringVar(&cmd.ContentType, "content", "", "Request content type override, e.g. 'application/x-www-form-urlencoded'")
 }
 
+// Run makes the HTTP request corresponding to the DeleteThemeIdpCommand command.
+func (cmd *DeleteThemeIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/themes"
+	}
+	var payload client.DeleteSPPayload
+	if cmd.Payload != "" {
+		err := json.Unmarshal([]byte(cmd.Payload), &payload)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize payload: %s", err)
+		}
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.DeleteThemeIdp(ctx, path, &payload, cmd.ContentType)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *DeleteThemeIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+	cc.Flags().StringVar(&cmd.Payload, "payload", "", "Request body encoded in JSON")
+	cc.Flags().StringVar(&cmd.ContentType, "content", "", "Request content type override, e.g. 'application/x-www-form-urlencoded'")
+}
+
 // Run makes the HTTP request corresponding to the GetGoogleMetadataIdpCommand command.
 func (cmd *GetGoogleMetadataIdpCommand) Run(c *client.Client, args []string) error {
 	var path string
@@ -680,6 +1060,30 @@ func (cmd *GetSessionsIdpCommand) Run(c *client.Client, args []string) error {
 func (cmd *GetSessionsIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
 }
 
+// Run makes the HTTP request corresponding to the GetThemesIdpCommand command.
+func (cmd *GetThemesIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/themes"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.GetThemesIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *GetThemesIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
 // Run makes the HTTP request corresponding to the LoginUserIdpCommand command.
 func (cmd *LoginUserIdpCommand) Run(c *client.Client, args []string) error {
 	var path string
@@ -704,6 +1108,150 @@ func (cmd *LoginUserIdpCommand) Run(c *client.Client, args []string) error {
 func (cmd *LoginUserIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
 }
 
+// Run makes the HTTP request corresponding to the MagicLinkLoginIdpCommand command.
+func (cmd *MagicLinkLoginIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/magic-link"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.MagicLinkLoginIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *MagicLinkLoginIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the NewPasswordFormIdpCommand command.
+func (cmd *NewPasswordFormIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/password-reset/confirm"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.NewPasswordFormIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *NewPasswordFormIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the PasswordResetFormIdpCommand command.
+func (cmd *PasswordResetFormIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/password-reset"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.PasswordResetFormIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *PasswordResetFormIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the RequestPasswordResetIdpCommand command.
+func (cmd *RequestPasswordResetIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/password-reset"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.RequestPasswordResetIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *RequestPasswordResetIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the ResetPasswordIdpCommand command.
+func (cmd *ResetPasswordIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/password-reset/confirm"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.ResetPasswordIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *ResetPasswordIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the RevokeConsentIdpCommand command.
+func (cmd *RevokeConsentIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/consents"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.RevokeConsentIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *RevokeConsentIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
 // Run makes the HTTP request corresponding to the ServeLoginIdpCommand command.
 func (cmd *ServeLoginIdpCommand) Run(c *client.Client, args []string) error {
 	var path string
@@ -775,3 +1323,75 @@ func (cmd *ServeSSOIdpCommand) Run(c *client.Client, args []string) error {
 // RegisterFlags registers the command flags with the command line.
 func (cmd *ServeSSOIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
 }
+
+// Run makes the HTTP request corresponding to the SocialCallbackIdpCommand command.
+func (cmd *SocialCallbackIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/social/callback"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.SocialCallbackIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *SocialCallbackIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the UpstreamACSIdpCommand command.
+func (cmd *UpstreamACSIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/upstream/acs"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.UpstreamACSIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *UpstreamACSIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}
+
+// Run makes the HTTP request corresponding to the UpstreamMetadataIdpCommand command.
+func (cmd *UpstreamMetadataIdpCommand) Run(c *client.Client, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = "/saml/idp/upstream/metadata"
+	}
+	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+	ctx := goa.WithLogger(context.Background(), logger)
+	resp, err := c.UpstreamMetadataIdp(ctx, path)
+	if err != nil {
+		goa.LogError(ctx, "failed", "err", err)
+		return err
+	}
+
+	goaclient.HandleResponse(c.Client, resp, cmd.PrettyPrint)
+	return nil
+}
+
+// RegisterFlags registers the command flags with the command line.
+func (cmd *UpstreamMetadataIdpCommand) RegisterFlags(cc *cobra.Command, c *client.Client) {
+}